@@ -0,0 +1,91 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"pictureloader/app_microservice/models"
+	"pictureloader/common/eventbus"
+	"pictureloader/common/events"
+	"time"
+)
+
+// Publisher сериализует доменные события приложения и отправляет их в шину
+type Publisher struct {
+	bus eventbus.EventPublisher
+}
+
+func NewPublisher(bus eventbus.EventPublisher) *Publisher {
+	return &Publisher{bus: bus}
+}
+
+// PublishNewLike публикует лайк, id события строится из времени лайка:
+// повторная публикация того же лайка дает тот же id, а лайк после снятия - новый
+func (p *Publisher) PublishNewLike(ctx context.Context, post *models.Post, like *models.Like) error {
+	return p.publish(ctx, events.TopicNewLike, events.NewLike{
+		EventID:       fmt.Sprintf("like-%d-%d-%d", like.PostID, like.UserID, like.CreatedAt.UnixMicro()),
+		PostID:        post.ID,
+		Liker:         like.UserID,
+		Liked:         post.UserID,
+		LikerName:     like.User.Username,
+		PostName:      post.Name,
+		PostCreatedAt: post.CreatedAt,
+	})
+}
+
+func (p *Publisher) PublishPostCreated(ctx context.Context, post *models.Post) error {
+	return p.publish(ctx, events.TopicPostCreated, events.PostCreated{
+		EventID:   fmt.Sprintf("post-%d", post.ID),
+		PostID:    post.ID,
+		UserID:    post.UserID,
+		Name:      post.Name,
+		CreatedAt: post.CreatedAt,
+	})
+}
+
+func (p *Publisher) PublishImageUploaded(ctx context.Context, userID int, storageKey, description string) error {
+	return p.publish(ctx, events.TopicImageUploaded, events.ImageUploaded{
+		EventID:     "image-" + storageKey,
+		UserID:      userID,
+		StorageKey:  storageKey,
+		Description: description,
+	})
+}
+
+func (p *Publisher) PublishModerationDecided(ctx context.Context, decision *models.ModerationDecision) error {
+	return p.publish(ctx, events.TopicModerationDecided, events.ModerationDecided{
+		EventID:    fmt.Sprintf("moderation-%s-%s-%d", decision.TargetType, decision.TargetID, decision.DecidedAt.UnixMicro()),
+		TargetType: decision.TargetType,
+		TargetID:   decision.TargetID,
+		TargetName: decision.TargetName,
+		OwnerID:    decision.OwnerID,
+		Action:     decision.Action,
+		Reporters:  decision.Reporters,
+		DecidedAt:  decision.DecidedAt,
+	})
+}
+
+func (p *Publisher) PublishUserDeleted(ctx context.Context, deletion *models.AccountDeletion) error {
+	return p.publish(ctx, events.TopicUserDeleted, events.UserDeleted{
+		EventID:   fmt.Sprintf("user-deleted-%d", deletion.UserID),
+		UserID:    deletion.UserID,
+		DeletedAt: time.Now(),
+	})
+}
+
+func (p *Publisher) PublishExportReady(ctx context.Context, export *models.DataExport) error {
+	return p.publish(ctx, events.TopicExportReady, events.ExportReady{
+		EventID:     fmt.Sprintf("export-%d", export.ID),
+		UserID:      export.UserID,
+		DownloadURL: export.DownloadURL,
+		ExpiresAt:   *export.ExpiresAt,
+	})
+}
+
+func (p *Publisher) publish(ctx context.Context, topic string, event any) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.bus.Publish(ctx, topic, body)
+}
@@ -94,3 +94,112 @@ func (rr *RedisRepo) SetPost(ctx context.Context, postID int, resultJSON string)
 	}
 	return true, nil
 }
+
+func revokedSessionKey(sessionID string) string {
+	return "revoked_session:" + sessionID
+}
+
+// RevokeSession заносит сессию в denylist на ttl - дольше access токены этой сессии не живут
+func (rr *RedisRepo) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	return rr.rdb.Set(ctx, revokedSessionKey(sessionID), 1, ttl).Err()
+}
+
+func (rr *RedisRepo) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	count, err := rr.rdb.Exists(ctx, revokedSessionKey(sessionID)).Result()
+	return count > 0, err
+}
+
+func loginChallengeKey(challengeHash string) string {
+	return "login_challenge:" + challengeHash
+}
+
+func loginChallengeAttemptsKey(challengeHash string) string {
+	return "login_challenge_attempts:" + challengeHash
+}
+
+// SaveLoginChallenge запоминает, какой пользователь прошел проверку пароля и ждет второй фактор
+func (rr *RedisRepo) SaveLoginChallenge(ctx context.Context, challengeHash string, userID int, ttl time.Duration) error {
+	return rr.rdb.Set(ctx, loginChallengeKey(challengeHash), userID, ttl).Err()
+}
+
+// GetLoginChallenge возвращает пользователя челленджа, 0 - челлендж истек или не существует
+func (rr *RedisRepo) GetLoginChallenge(ctx context.Context, challengeHash string) (int, error) {
+	userID, err := rr.rdb.Get(ctx, loginChallengeKey(challengeHash)).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return userID, err
+}
+
+// FailLoginChallenge считает неверные коды челленджа и возвращает их количество
+func (rr *RedisRepo) FailLoginChallenge(ctx context.Context, challengeHash string, ttl time.Duration) (int, error) {
+	key := loginChallengeAttemptsKey(challengeHash)
+	pipe := rr.rdb.TxPipeline()
+	attempts := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(attempts.Val()), nil
+}
+
+func (rr *RedisRepo) DeleteLoginChallenge(ctx context.Context, challengeHash string) error {
+	return rr.rdb.Del(ctx, loginChallengeKey(challengeHash), loginChallengeAttemptsKey(challengeHash)).Err()
+}
+
+// LoginBlockedFor возвращает, сколько еще действует самая долгая из блокировок входа по ключам
+func (rr *RedisRepo) LoginBlockedFor(ctx context.Context, keys ...string) (time.Duration, error) {
+	var blocked time.Duration
+	for _, key := range keys {
+		ttl, err := rr.rdb.PTTL(ctx, "login_lock:"+key).Result()
+		if err != nil {
+			return 0, err
+		}
+		if ttl > blocked {
+			blocked = ttl
+		}
+	}
+	return blocked, nil
+}
+
+// RegisterLoginFailure увеличивает счетчик неудачных входов. Окно отсчитывается от первой неудачи.
+func (rr *RedisRepo) RegisterLoginFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	failures, err := rr.rdb.Incr(ctx, "login_fail:"+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if failures == 1 {
+		if err = rr.rdb.Expire(ctx, "login_fail:"+key, window).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return int(failures), nil
+}
+
+func (rr *RedisRepo) LockLogin(ctx context.Context, key string, ttl time.Duration) error {
+	return rr.rdb.Set(ctx, "login_lock:"+key, 1, ttl).Err()
+}
+
+// ResetLoginFailures снимает блокировку и обнуляет счетчик
+func (rr *RedisRepo) ResetLoginFailures(ctx context.Context, key string) error {
+	return rr.rdb.Del(ctx, "login_fail:"+key, "login_lock:"+key).Err()
+}
+
+// SaveOIDCState хранит параметры начатого входа через провайдера до возврата пользователя
+func (rr *RedisRepo) SaveOIDCState(ctx context.Context, state, value string, ttl time.Duration) error {
+	return rr.rdb.Set(ctx, "oidc_state:"+state, value, ttl).Err()
+}
+
+// TakeOIDCState достает и удаляет параметры входа, пустая строка - state неизвестен или истек
+func (rr *RedisRepo) TakeOIDCState(ctx context.Context, state string) (string, error) {
+	value, err := rr.rdb.GetDel(ctx, "oidc_state:"+state).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return value, err
+}
+
+// InvalidateMostLikedPosts сбрасывает кеш самых популярных постов
+func (rr *RedisRepo) InvalidateMostLikedPosts(ctx context.Context) error {
+	return rr.rdb.Del(ctx, "MostLikedPosts").Err()
+}
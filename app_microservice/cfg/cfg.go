@@ -4,6 +4,13 @@ import (
 	"github.com/joho/godotenv"
 	"log"
 	"os"
+	"pictureloader/app_microservice/safety/oidcauth"
+	"pictureloader/common/jwtauth"
+	"pictureloader/common/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -12,6 +19,43 @@ type Config struct {
 	MinioPASSWORD string
 	PsqlDBPath    string
 	ServerPort    string
+	EventBus      string // rabbitmq, nats или memory
+	EventBusURL   string
+	// ServiceToken - общий токен для внутренних запросов от других сервисов
+	ServiceToken string
+	// TrustProxy - сервис стоит за прокси, адрес клиента берется из X-Forwarded-For
+	TrustProxy bool
+	// Время жизни access токена и refresh токена (сессии без активности)
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// JWT - ключи подписи токенов
+	JWT jwtauth.Config
+	// PublicURL - адрес фронтенда для ссылок в письмах
+	PublicURL string
+	Mail      mail.Config
+	// Время жизни ссылок подтверждения почты и сброса пароля
+	VerifyEmailTTL   time.Duration
+	PasswordResetTTL time.Duration
+	// AdminUsers - id пользователей, которые при старте получают роль admin
+	AdminUsers []int
+	// Защита входа: порог неудач по имени и по IP за окно, первая и максимальная блокировка
+	LoginMaxFailures   int
+	LoginMaxIPFailures int
+	LoginFailureWindow time.Duration
+	LoginLockout       time.Duration
+	LoginMaxLockout    time.Duration
+	// OIDCProviders - провайдеры для входа через OpenID Connect
+	OIDCProviders []oidcauth.ProviderConfig
+	// Сколько удаленные посты и картинки лежат в корзине и как часто корзина чистится
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+	// AccountDeletionInterval - как часто запускаются задачи удаления аккаунтов
+	AccountDeletionInterval time.Duration
+	// NotifierURL - адрес сервиса уведомлений для внутренних запросов
+	NotifierURL string
+	// Сколько архив выгрузки данных доступен для скачивания и как часто собираются выгрузки
+	ExportTTL      time.Duration
+	ExportInterval time.Duration
 }
 
 func Init() *Config {
@@ -19,10 +63,163 @@ func Init() *Config {
 	if err != nil {
 		log.Fatal("Error loading .env file", err)
 	}
-	minioURL := os.Getenv("minioURL")
-	minioUSER := os.Getenv("minioUSER")
-	minioPASSWORD := os.Getenv("minioPASSWORD")
-	psqlDBPath := os.Getenv("DATABASE_URL")
-	serverPort := os.Getenv("PORT")
-	return &Config{minioURL, minioUSER, minioPASSWORD, psqlDBPath, serverPort}
+	return &Config{
+		MinioURL:        os.Getenv("minioURL"),
+		MinioUSER:       os.Getenv("minioUSER"),
+		MinioPASSWORD:   os.Getenv("minioPASSWORD"),
+		PsqlDBPath:      os.Getenv("DATABASE_URL"),
+		ServerPort:      os.Getenv("PORT"),
+		TrustProxy:      getBool("TRUST_PROXY", false),
+		EventBus:        getEnv("EVENT_BUS", "rabbitmq"),
+		EventBusURL:     os.Getenv("EVENT_BUS_URL"),
+		ServiceToken:    os.Getenv("SERVICE_TOKEN"),
+		AccessTokenTTL:  getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		JWT: jwtauth.Config{
+			Alg:            getEnv("JWT_ALG", jwtauth.AlgEdDSA),
+			KeyID:          getEnv("JWT_KEY_ID", "default"),
+			Secret:         os.Getenv("JWT_SECRET"),
+			PrivateKeyFile: os.Getenv("JWT_PRIVATE_KEY_FILE"),
+			VerifyKeyFiles: getKeyFiles("JWT_VERIFY_KEYS"),
+			Issuer:         getEnv("JWT_ISSUER", "pictureloader"),
+			Audience:       getEnv("JWT_AUDIENCE", "pictureloader"),
+			EphemeralKey:   getBool("JWT_EPHEMERAL_KEY", false),
+		},
+		PublicURL: strings.TrimRight(getEnv("PUBLIC_URL", "http://localhost:8080"), "/"),
+		Mail: mail.Config{
+			Kind:     getEnv("MAIL_SENDER", mail.KindFile),
+			From:     getEnv("MAIL_FROM", "Imgur 2.0 <noreply@localhost>"),
+			SMTPAddr: getEnv("SMTP_ADDR", "localhost:1025"),
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			Dir:      os.Getenv("MAIL_DIR"),
+		},
+		VerifyEmailTTL:          getDuration("VERIFY_EMAIL_TTL", 48*time.Hour),
+		PasswordResetTTL:        getDuration("PASSWORD_RESET_TTL", time.Hour),
+		AdminUsers:              getIntList("ADMIN_USERS"),
+		LoginMaxFailures:        getInt("LOGIN_MAX_FAILURES", 5),
+		LoginMaxIPFailures:      getInt("LOGIN_MAX_IP_FAILURES", 20),
+		LoginFailureWindow:      getDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		LoginLockout:            getDuration("LOGIN_LOCKOUT", time.Minute),
+		LoginMaxLockout:         getDuration("LOGIN_MAX_LOCKOUT", time.Hour),
+		OIDCProviders:           getOIDCProviders(),
+		TrashRetention:          getDuration("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval:      getDuration("TRASH_PURGE_INTERVAL", time.Hour),
+		AccountDeletionInterval: getDuration("ACCOUNT_DELETION_INTERVAL", time.Minute),
+		NotifierURL:             strings.TrimRight(getEnv("NOTIFIER_URL", "http://localhost:8081"), "/"),
+		ExportTTL:               getDuration("EXPORT_TTL", 48*time.Hour),
+		ExportInterval:          getDuration("EXPORT_INTERVAL", time.Minute),
+	}
+}
+
+// getOIDCProviders читает OIDC_PROVIDERS=company,mock и для каждого имени переменные
+// OIDC_<ИМЯ>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _SCOPES, _DISPLAY_NAME.
+// Все провайдеры возвращают пользователя на OIDC_REDIRECT_URL.
+func getOIDCProviders() []oidcauth.ProviderConfig {
+	var providers []oidcauth.ProviderConfig
+	names := os.Getenv("OIDC_PROVIDERS")
+	if names == "" {
+		return providers
+	}
+	redirectURL := getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/users/oidc/callback")
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := oidcauth.ProviderConfig{
+			Name:         name,
+			DisplayName:  getEnv(prefix+"DISPLAY_NAME", name),
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  redirectURL,
+			Scopes:       strings.Fields(strings.ReplaceAll(getEnv(prefix+"SCOPES", "openid email profile"), ",", " ")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Fatalf("%sISSUER and %sCLIENT_ID are required for provider %s", prefix, prefix, name)
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// getKeyFiles читает список "kid=путь,kid2=алгоритм:путь2". Без алгоритма ключ проверяется алгоритмом JWT_ALG.
+func getKeyFiles(key string) map[string]jwtauth.VerifyKeyFile {
+	result := map[string]jwtauth.VerifyKeyFile{}
+	value := os.Getenv(key)
+	if value == "" {
+		return result
+	}
+	for _, item := range strings.Split(value, ",") {
+		kid, path, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || kid == "" || path == "" {
+			log.Fatalf("Invalid key entry %q in %s, expected kid=path or kid=alg:path", item, key)
+		}
+		file := jwtauth.VerifyKeyFile{Path: path}
+		if alg, algPath, ok := strings.Cut(path, ":"); ok &&
+			slices.Contains([]string{jwtauth.AlgHS256, jwtauth.AlgRS256, jwtauth.AlgEdDSA}, alg) {
+			file = jwtauth.VerifyKeyFile{Alg: alg, Path: algPath}
+		}
+		result[kid] = file
+	}
+	return result
+}
+
+// getIntList читает список чисел через запятую
+func getIntList(key string) []int {
+	var result []int
+	value := os.Getenv(key)
+	if value == "" {
+		return result
+	}
+	for _, item := range strings.Split(value, ",") {
+		number, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			log.Fatalf("Invalid number %q in %s", item, key)
+		}
+		result = append(result, number)
+	}
+	return result
+}
+
+func getInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid number in %s: %v", key, err)
+	}
+	return number
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid duration in %s: %v", key, err)
+	}
+	return duration
+}
+
+func getBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Invalid boolean in %s: %v", key, err)
+	}
+	return flag
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"github.com/gorilla/mux"
 	"github.com/swaggo/http-swagger"
 	"log"
@@ -14,7 +15,15 @@ import (
 	_ "pictureloader/app_microservice/docs"
 	rest2 "pictureloader/app_microservice/handler"
 	"pictureloader/app_microservice/image_storage/minio"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/notifier"
+	"pictureloader/app_microservice/safety/jwtutils"
+	"pictureloader/app_microservice/safety/oidcauth"
 	service2 "pictureloader/app_microservice/service"
+	"pictureloader/common/eventbus"
+	"pictureloader/common/jwtauth"
+	"pictureloader/common/mail"
+	"time"
 )
 
 // @title Imgur 2.0 API
@@ -41,26 +50,97 @@ func main() {
 	slog.Info("Image and User repositories initialized")
 
 	cache := redis.NewRedisClient(imageRepo)
-	rabbitbroker := broker.NewRabbitBroker()
-	defer rabbitbroker.Close()
+	bus, err := eventbus.New(eventbus.Config{Kind: cfg.EventBus, URL: cfg.EventBusURL})
+	if err != nil {
+		log.Fatalf("Failed to initialize event bus: %v", err)
+	}
+	defer bus.Close()
+	slog.Info("Event bus initialized", "kind", cfg.EventBus)
+	publisher := broker.NewPublisher(bus)
 
-	imageService := service2.NewPictureLoader(minioprov, imageRepo, cache)
-	userService := service2.NewUserService(userRepo, minioprov)
-	postService := service2.NewPostService(postRepo, minioprov, cache, *rabbitbroker)
+	imageService := service2.NewPictureLoader(minioprov, imageRepo, cache, publisher)
+	keySet, err := jwtauth.NewKeySet(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+	mailSender, err := mail.New(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to initialize mail sender: %v", err)
+	}
+	accountRepo := postgres2.NewAccountRepository(psqlDB)
+	apiTokenService := service2.NewAPITokenService(postgres2.NewAPITokenRepository(psqlDB))
+	jwtUtils := jwtutils.NewUtilsJWT(keySet, cache, apiTokenService, accountRepo, cfg.AccessTokenTTL)
+	sessionService := service2.NewSessionService(postgres2.NewSessionRepository(psqlDB), cache, jwtUtils, cfg.RefreshTokenTTL)
+	userService := service2.NewUserService(userRepo, minioprov, sessionService)
+	accountService := service2.NewAccountService(accountRepo, userService, mailSender,
+		service2.AccountConfig{PublicURL: cfg.PublicURL, VerifyEmailTTL: cfg.VerifyEmailTTL, ResetTTL: cfg.PasswordResetTTL})
+	loginGuard := service2.NewLoginGuard(cache, postgres2.NewSecurityRepository(psqlDB), service2.LoginGuardConfig{
+		MaxAccountFailures: cfg.LoginMaxFailures,
+		MaxIPFailures:      cfg.LoginMaxIPFailures,
+		Window:             cfg.LoginFailureWindow,
+		Lockout:            cfg.LoginLockout,
+		MaxLockout:         cfg.LoginMaxLockout,
+	})
+	twoFactorService := service2.NewTwoFactorService(postgres2.NewTwoFactorRepository(psqlDB), cache, loginGuard)
+	identityRepo := postgres2.NewIdentityRepository(psqlDB)
+	oidcService := service2.NewOIDCService(identityRepo, cache)
+	for _, providerCfg := range cfg.OIDCProviders {
+		// недоступный при старте провайдер не должен ронять приложение, вход по паролю продолжит работать
+		discoveryCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		provider, err := oidcauth.NewProvider(discoveryCtx, providerCfg)
+		cancel()
+		if err != nil {
+			slog.Error("Failed to initialize identity provider", "provider", providerCfg.Name, "error", err)
+			continue
+		}
+		// привязки, созданные до хранения issuer, известны только по имени провайдера
+		if err = identityRepo.FillIssuer(context.Background(), provider.Name, providerCfg.Issuer); err != nil {
+			slog.Error("Failed to fill identity issuer", "provider", providerCfg.Name, "error", err)
+		}
+		oidcService.AddProvider(models.OIDCProviderInfo{Name: provider.Name, DisplayName: provider.DisplayName}, provider)
+		slog.Info("Identity provider initialized", "provider", providerCfg.Name)
+	}
+	postService := service2.NewPostService(postRepo, minioprov, cache, publisher)
+	moderationRepo := postgres2.NewModerationRepository(psqlDB)
+	adminService := service2.NewAdminService(postgres2.NewAdminRepository(psqlDB), sessionService, moderationRepo)
+	if err = adminService.PromoteAdmins(context.Background(), cfg.AdminUsers); err != nil {
+		log.Fatalf("Failed to promote admins: %v", err)
+	}
+	moderationService := service2.NewModerationService(moderationRepo, postService, imageService, adminService, cache, publisher)
+	trashService := service2.NewTrashService(postgres2.NewTrashRepository(psqlDB), postService, imageService, cache,
+		cfg.TrashRetention)
+	go trashService.RunPurge(context.Background(), cfg.TrashPurgeInterval)
+	avatarService := service2.NewAvatarService(postgres2.NewAvatarRepository(psqlDB), minioprov)
+	deletionService := service2.NewAccountDeletionService(postgres2.NewDeletionRepository(psqlDB), sessionService,
+		postService, imageService, avatarService, cache, publisher)
+	go deletionService.Run(context.Background(), cfg.AccountDeletionInterval)
+	exportService := service2.NewExportService(postgres2.NewExportRepository(psqlDB), userRepo, postRepo, imageRepo,
+		minioprov, notifier.NewClient(cfg.NotifierURL, cfg.ServiceToken), publisher, cfg.ExportTTL)
+	go exportService.Run(context.Background(), cfg.ExportInterval)
 	slog.Info("Image and User services initialized")
 
 	picturesServer := rest2.PictureNewServer(imageService)
-	userServer := rest2.NewUserServer(userService)
+	userServer := rest2.NewUserServer(userService, sessionService, apiTokenService, accountService, twoFactorService, loginGuard,
+		oidcService, deletionService, avatarService)
 	albumServer := rest2.NewPostServer(*postService)
 
 	slog.Info("User and Image server initialized")
 
 	//router init
 	mainRouter := mux.NewRouter()
+	mainRouter.Use(rest2.RealIP(cfg.TrustProxy))
 	mainRouter.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
-	rest2.PictureRouter(mainRouter, picturesServer)
-	rest2.UserRouter(mainRouter, userServer)
-	rest2.PostRouter(mainRouter, albumServer)
+	mainRouter.Handle("/.well-known/jwks.json", keySet.JWKSHandler()).Methods("GET")
+	rest2.PictureRouter(mainRouter, picturesServer, jwtUtils)
+	rest2.UserRouter(mainRouter, userServer, jwtUtils)
+	rest2.InternalUserRouter(mainRouter, userServer, cfg.ServiceToken)
+	rest2.PostRouter(mainRouter, albumServer, jwtUtils)
+	rest2.AdminRouter(mainRouter, rest2.NewAdminServer(loginGuard, adminService, moderationService, deletionService), jwtUtils)
+	rest2.ModerationRouter(mainRouter, rest2.NewModerationServer(moderationService), jwtUtils)
+	rest2.TrashRouter(mainRouter, rest2.NewTrashServer(trashService), jwtUtils)
+	rest2.ExportRouter(mainRouter, rest2.NewExportServer(exportService), jwtUtils)
+	rest2.ProfileRouter(mainRouter, rest2.NewProfileServer(service2.NewProfileService(postgres2.NewProfileRepository(psqlDB),
+		minioprov)), jwtUtils)
 	slog.Info("Routers are running")
 
 	slog.Info("Starting server on port ", "port", cfg.ServerPort)
@@ -0,0 +1,39 @@
+// mockidp запускает локальный OpenID Connect провайдер, чтобы проверять вход через SSO без сети.
+// Приложению достаточно указать OIDC_PROVIDERS=mock и OIDC_MOCK_ISSUER=http://localhost:9090.
+package main
+
+import (
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"pictureloader/app_microservice/mockidp"
+	"strings"
+)
+
+func main() {
+	addr := getEnv("MOCKIDP_ADDR", "localhost:9090")
+	idp, err := mockidp.New(mockidp.Config{
+		Issuer:       strings.TrimRight(getEnv("MOCKIDP_ISSUER", "http://"+addr), "/"),
+		ClientID:     getEnv("MOCKIDP_CLIENT_ID", "pictureloader"),
+		ClientSecret: getEnv("MOCKIDP_CLIENT_SECRET", "secret"),
+		Users: []mockidp.User{
+			{Subject: "1001", Email: "alice@example.com", EmailVerified: true, PreferredUsername: "alice", Name: "Alice"},
+			{Subject: "1002", Email: "bob@example.com", EmailVerified: false, PreferredUsername: "bob", Name: "Bob"},
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to create mock idp: %v", err)
+	}
+	slog.Info("Mock IdP is running", "addr", addr)
+	if err = http.ListenAndServe(addr, idp); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type AccountRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountRepository(db *gorm.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// CreateUserToken сохраняет новый токен, старые неиспользованные токены того же назначения
+// удаляются - действует только ссылка из последнего письма
+func (ar *AccountRepository) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	return ar.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Delete(&models.UserToken{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// UseUserToken атомарно помечает действующий токен использованным и возвращает id пользователя.
+// 0 - токен не найден, уже использован или истек.
+func (ar *AccountRepository) UseUserToken(ctx context.Context, tokenHash, purpose string, now time.Time) (int, error) {
+	var tokens []models.UserToken
+	err := ar.db.WithContext(ctx).Model(&tokens).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "user_id"}}}).
+		Where("hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, now).
+		Update("used_at", now).Error
+	if err != nil || len(tokens) == 0 {
+		return 0, err
+	}
+	return tokens[0].UserID, nil
+}
+
+// GetUserByEmail возвращает пользователя по почте, nil - такого нет
+func (ar *AccountRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := ar.db.WithContext(ctx).Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &user, err
+}
+
+// GetUserEmail возвращает почту пользователя и то, подтверждена ли она
+func (ar *AccountRepository) GetUserEmail(ctx context.Context, userID int) (string, bool, error) {
+	var user models.User
+	err := ar.db.WithContext(ctx).Select("email", "email_verified").First(&user, userID).Error
+	return user.Email, user.EmailVerified, err
+}
+
+// IsEmailVerified - может ли пользователь загружать контент
+func (ar *AccountRepository) IsEmailVerified(ctx context.Context, userID int) (bool, error) {
+	_, verified, err := ar.GetUserEmail(ctx, userID)
+	return verified, err
+}
+
+func (ar *AccountRepository) SetEmailVerified(ctx context.Context, userID int) error {
+	return ar.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("email_verified", true).Error
+}
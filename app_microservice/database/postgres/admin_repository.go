@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type AdminRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminRepository(db *gorm.DB) *AdminRepository {
+	return &AdminRepository{db: db}
+}
+
+// GetUsers возвращает страницу пользователей, поиск по подстроке имени или почты
+func (ar *AdminRepository) GetUsers(ctx context.Context, filter models.UserFilter) ([]models.AdminUser, error) {
+	users := make([]models.AdminUser, 0)
+	query := ar.db.WithContext(ctx).Model(&models.User{})
+	if filter.Query != "" {
+		pattern := "%" + filter.Query + "%"
+		query = query.Where("username ILIKE ? OR email ILIKE ?", pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	err := query.Order("id").Limit(filter.Limit).Offset(filter.Offset).Find(&users).Error
+	return users, err
+}
+
+func (ar *AdminRepository) GetUserAccess(ctx context.Context, userID int) (*models.UserAccess, error) {
+	return getUserAccess(ctx, ar.db, userID)
+}
+
+// SetUserBan блокирует пользователя (bannedAt != nil) или снимает блокировку, false - пользователя нет
+func (ar *AdminRepository) SetUserBan(ctx context.Context, userID int, bannedAt *time.Time, reason string) (bool, error) {
+	result := ar.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]any{"banned_at": bannedAt, "ban_reason": reason})
+	return result.RowsAffected > 0, result.Error
+}
+
+// SetUserRole меняет роль пользователя, false - пользователя нет
+func (ar *AdminRepository) SetUserRole(ctx context.Context, userID int, role string) (bool, error) {
+	result := ar.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("role", role)
+	return result.RowsAffected > 0, result.Error
+}
+
+// PromoteAdmins выдает роль admin пользователям из списка, несуществующие id пропускаются
+func (ar *AdminRepository) PromoteAdmins(ctx context.Context, userIDs []int) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return ar.db.WithContext(ctx).Model(&models.User{}).Where("id IN ?", userIDs).
+		Update("role", models.RoleAdmin).Error
+}
+
+// getUserAccess читает роль, блокировку и запрос на удаление пользователя, nil - пользователя нет
+func getUserAccess(ctx context.Context, db *gorm.DB, userID int) (*models.UserAccess, error) {
+	var access models.UserAccess
+	err := db.WithContext(ctx).Model(&models.User{}).Select("role", "banned_at", "deletion_requested_at").
+		Where("id = ?", userID).Take(&access).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &access, nil
+}
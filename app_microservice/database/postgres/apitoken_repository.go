@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type APITokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepository(db *gorm.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+func (ar *APITokenRepository) CreateAPIToken(ctx context.Context, token *models.APIToken) error {
+	return ar.db.WithContext(ctx).Create(token).Error
+}
+
+// GetAPITokenByHash возвращает неотозванный токен по хешу, nil - такого токена нет
+// или его владелец заблокирован
+func (ar *APITokenRepository) GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := ar.db.WithContext(ctx).Joins("JOIN users ON users.id = api_tokens.user_id AND users.banned_at IS NULL AND users.deletion_requested_at IS NULL").
+		Where("api_tokens.hash = ? AND api_tokens.revoked_at IS NULL", tokenHash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &token, err
+}
+
+// GetAPITokens возвращает неотозванные токены пользователя, включая истекшие
+func (ar *APITokenRepository) GetAPITokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := ar.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// TouchAPIToken обновляет время последнего использования не чаще раза в interval,
+// чтобы каждый запрос по токену не превращался в запись в базу
+func (ar *APITokenRepository) TouchAPIToken(ctx context.Context, tokenID int, now time.Time, interval time.Duration) error {
+	return ar.db.WithContext(ctx).Model(&models.APIToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", tokenID, now.Add(-interval)).
+		Update("last_used_at", now).Error
+}
+
+// RevokeAPIToken отзывает токен пользователя, false - такого активного токена нет
+func (ar *APITokenRepository) RevokeAPIToken(ctx context.Context, userID, tokenID int, now time.Time) (bool, error) {
+	result := ar.db.WithContext(ctx).Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", now)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pictureloader/app_microservice/models"
+)
+
+type AvatarRepository struct {
+	db *gorm.DB
+}
+
+func NewAvatarRepository(db *gorm.DB) *AvatarRepository {
+	return &AvatarRepository{db: db}
+}
+
+// IsAvatarSource проверяет, что картинка принадлежит пользователю, не скрыта и не лежит в корзине
+func (ar *AvatarRepository) IsAvatarSource(ctx context.Context, userID int, imageSK string) (bool, error) {
+	var count int64
+	err := ar.db.WithContext(ctx).Model(&models.Image{}).
+		Where("storage_key = ? AND user_id = ? AND hidden_at IS NULL", imageSK, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// SetAvatar сохраняет ключ нового аватара и возвращает ключ предыдущего, false - пользователя нет
+func (ar *AvatarRepository) SetAvatar(ctx context.Context, userID int, key string) (string, bool, error) {
+	var previous string
+	err := ar.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "profile_picture").
+			Take(&user, userID).Error
+		if err != nil {
+			return err
+		}
+		previous = user.ProfilePicture
+		return tx.Model(&user).Update("profile_picture", key).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	return previous, err == nil, err
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type DeletionRepository struct {
+	db *gorm.DB
+}
+
+func NewDeletionRepository(db *gorm.DB) *DeletionRepository {
+	return &DeletionRepository{db: db}
+}
+
+// RequestDeletion закрывает пользователю вход и ставит удаление аккаунта в очередь.
+// Повторный запрос возвращает уже созданную задачу, nil - пользователя нет.
+func (dr *DeletionRepository) RequestDeletion(ctx context.Context, userID int, now time.Time) (*models.AccountDeletion, error) {
+	var deletion models.AccountDeletion
+	err := dr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).Where("id = ? AND deletion_requested_at IS NULL", userID).
+			Update("deletion_requested_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return tx.Where("user_id = ?", userID).Take(&deletion).Error
+		}
+		deletion = models.AccountDeletion{
+			UserID:        userID,
+			Status:        models.DeletionPending,
+			Step:          models.DeletionSteps[0],
+			NextAttemptAt: now,
+			RequestedAt:   now,
+		}
+		return tx.Create(&deletion).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// ClaimDueDeletions забирает до limit задач, которым пора выполняться, и откладывает их на lease,
+// чтобы другие реплики не взяли их одновременно
+func (dr *DeletionRepository) ClaimDueDeletions(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.AccountDeletion, error) {
+	var deletions []models.AccountDeletion
+	err := dr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeletionPending, now).
+			Order("next_attempt_at").Limit(limit).Find(&deletions).Error
+		if err != nil || len(deletions) == 0 {
+			return err
+		}
+		ids := make([]int, len(deletions))
+		for i, deletion := range deletions {
+			ids[i] = deletion.ID
+		}
+		return tx.Model(&models.AccountDeletion{}).Where("id IN ?", ids).
+			UpdateColumn("next_attempt_at", now.Add(lease)).Error
+	})
+	return deletions, err
+}
+
+// SaveDeletion сохраняет шаг, счетчики и ошибку задачи
+func (dr *DeletionRepository) SaveDeletion(ctx context.Context, deletion *models.AccountDeletion) error {
+	return dr.db.WithContext(ctx).Save(deletion).Error
+}
+
+// DeleteUserLikes снимает все лайки пользователя и отдает id постов, с которых они сняты
+func (dr *DeletionRepository) DeleteUserLikes(ctx context.Context, userID int) ([]int, error) {
+	var postIDs []int
+	err := dr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Like{}).Where("user_id = ?", userID).Pluck("post_id", &postIDs).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.Like{}).Error
+	})
+	return postIDs, err
+}
+
+// GetAllUserPostIDs возвращает все посты пользователя, включая скрытые и лежащие в корзине
+func (dr *DeletionRepository) GetAllUserPostIDs(ctx context.Context, userID int) ([]int, error) {
+	var postIDs []int
+	err := dr.db.WithContext(ctx).Unscoped().Model(&models.Post{}).Where("user_id = ?", userID).
+		Pluck("id", &postIDs).Error
+	return postIDs, err
+}
+
+// GetAllUserImageKeys возвращает ключи всех картинок пользователя, включая скрытые и лежащие в корзине
+func (dr *DeletionRepository) GetAllUserImageKeys(ctx context.Context, userID int) ([]string, error) {
+	var imageSKs []string
+	err := dr.db.WithContext(ctx).Unscoped().Model(&models.Image{}).Where("user_id = ?", userID).
+		Pluck("storage_key", &imageSKs).Error
+	return imageSKs, err
+}
+
+// DeleteUser удаляет пользователя, сессии, токены, привязки и его жалобы удаляются каскадом
+func (dr *DeletionRepository) DeleteUser(ctx context.Context, userID int) error {
+	return dr.db.WithContext(ctx).Delete(&models.User{}, userID).Error
+}
+
+func (dr *DeletionRepository) GetDeletions(ctx context.Context, filter models.DeletionFilter) ([]models.AccountDeletion, error) {
+	deletions := make([]models.AccountDeletion, 0)
+	query := dr.db.WithContext(ctx).Model(&models.AccountDeletion{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	err := query.Order("requested_at DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&deletions).Error
+	return deletions, err
+}
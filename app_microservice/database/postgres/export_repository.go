@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type ExportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+func (er *ExportRepository) CreateExport(ctx context.Context, export *models.DataExport) error {
+	return er.db.WithContext(ctx).Create(export).Error
+}
+
+// GetActiveExport возвращает выгрузку пользователя, которая собирается или еще доступна, nil - такой нет
+func (er *ExportRepository) GetActiveExport(ctx context.Context, userID int, now time.Time) (*models.DataExport, error) {
+	var export models.DataExport
+	err := er.db.WithContext(ctx).
+		Where("user_id = ? AND (status = ? OR (status = ? AND expires_at > ?))",
+			userID, models.ExportPending, models.ExportReady, now).
+		Order("id DESC").Take(&export).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetUserExports возвращает выгрузки пользователя, новые первыми
+func (er *ExportRepository) GetUserExports(ctx context.Context, userID int) ([]models.DataExport, error) {
+	exports := make([]models.DataExport, 0)
+	err := er.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&exports).Error
+	return exports, err
+}
+
+// ClaimDueExports забирает до limit выгрузок, которые пора собирать, и откладывает их на lease
+func (er *ExportRepository) ClaimDueExports(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := er.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.ExportPending, now).
+			Order("next_attempt_at").Limit(limit).Find(&exports).Error
+		if err != nil || len(exports) == 0 {
+			return err
+		}
+		ids := make([]int, len(exports))
+		for i, export := range exports {
+			ids[i] = export.ID
+		}
+		return tx.Model(&models.DataExport{}).Where("id IN ?", ids).
+			UpdateColumn("next_attempt_at", now.Add(lease)).Error
+	})
+	return exports, err
+}
+
+func (er *ExportRepository) SaveExport(ctx context.Context, export *models.DataExport) error {
+	return er.db.WithContext(ctx).Save(export).Error
+}
+
+// GetExpiredExports возвращает до limit готовых выгрузок, архив которых пора удалить:
+// истекшие и выгрузки уже удаленных пользователей
+func (er *ExportRepository) GetExpiredExports(ctx context.Context, now time.Time, limit int) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := er.db.WithContext(ctx).
+		Where("status = ? AND (expires_at <= ? OR NOT EXISTS (SELECT 1 FROM users WHERE users.id = data_exports.user_id))",
+			models.ExportReady, now).
+		Order("expires_at").Limit(limit).Find(&exports).Error
+	return exports, err
+}
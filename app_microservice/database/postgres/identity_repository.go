@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"pictureloader/app_microservice/models"
+)
+
+type IdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// GetIdentity возвращает привязку внешнего аккаунта, nil - аккаунт еще не входил
+func (ir *IdentityRepository) GetIdentity(ctx context.Context, issuer, subject string) (*models.ExternalIdentity, error) {
+	var identity models.ExternalIdentity
+	err := ir.db.WithContext(ctx).Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &identity, err
+}
+
+// FillIssuer проставляет issuer привязкам провайдера, созданным, когда хранилось только его имя
+func (ir *IdentityRepository) FillIssuer(ctx context.Context, provider, issuer string) error {
+	return ir.db.WithContext(ctx).Model(&models.ExternalIdentity{}).Where("provider = ? AND issuer = ''", provider).
+		Update("issuer", issuer).Error
+}
+
+// GetUserAccess возвращает блокировку и запрос на удаление пользователя
+func (ir *IdentityRepository) GetUserAccess(ctx context.Context, userID int) (*models.UserAccess, error) {
+	return getUserAccess(ctx, ir.db, userID)
+}
+
+func (ir *IdentityRepository) CreateIdentity(ctx context.Context, identity *models.ExternalIdentity) error {
+	return ir.db.WithContext(ctx).Create(identity).Error
+}
+
+// CreateUserWithIdentity регистрирует пользователя, впервые вошедшего через провайдера
+func (ir *IdentityRepository) CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.ExternalIdentity) error {
+	return ir.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+func (ir *IdentityRepository) GetIdentities(ctx context.Context, userID int) ([]models.ExternalIdentity, error) {
+	var identities []models.ExternalIdentity
+	err := ir.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	return identities, err
+}
+
+// DeleteIdentity отвязывает внешний аккаунт пользователя, false - такой привязки нет
+func (ir *IdentityRepository) DeleteIdentity(ctx context.Context, userID, identityID int) (bool, error) {
+	result := ir.db.WithContext(ctx).Where("id = ? AND user_id = ?", identityID, userID).Delete(&models.ExternalIdentity{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// HasPassword - может ли пользователь войти по паролю. У созданных через провайдера пароля нет,
+// пока они не зададут его через сброс.
+func (ir *IdentityRepository) HasPassword(ctx context.Context, userID int) (bool, error) {
+	var count int64
+	err := ir.db.WithContext(ctx).Model(&models.User{}).Where("id = ? AND password <> ''", userID).Count(&count).Error
+	return count > 0, err
+}
+
+func (ir *IdentityRepository) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	var count int64
+	err := ir.db.WithContext(ctx).Model(&models.User{}).Where("LOWER(username) = LOWER(?)", username).Count(&count).Error
+	return count > 0, err
+}
+
+func (ir *IdentityRepository) EmailTaken(ctx context.Context, email string) (bool, error) {
+	var count int64
+	err := ir.db.WithContext(ctx).Model(&models.User{}).Where("LOWER(email) = LOWER(?)", email).Count(&count).Error
+	return count > 0, err
+}
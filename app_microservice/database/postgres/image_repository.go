@@ -30,15 +30,18 @@ func (i *ImageRepository) GetUserImagesID(ctx context.Context, userID int) ([]st
 	return imageIDS, nil
 }
 
+// GetImageDescription возвращает описание картинки, скрытая модератором картинка не находится
 func (i *ImageRepository) GetImageDescription(ctx context.Context, imageURL string) (string, error) {
-	var description string
-	err := i.db.WithContext(ctx).Model(&models.Image{}).Where("storage_key = ?", imageURL).Pluck("description", &description).Error
+	var image models.Image
+	err := i.db.WithContext(ctx).Select("description").
+		Where("storage_key = ? AND hidden_at IS NULL", imageURL).Take(&image).Error
 	if err != nil {
 		return "", err
 	}
-	return description, nil
+	return image.Description, nil
 }
 
+// DeleteImage переносит картинку в корзину, файл в хранилище не трогается
 func (i *ImageRepository) DeleteImage(ctx context.Context, imageID string) error {
 	err := i.db.WithContext(ctx).Where("storage_key  = ?", imageID).Delete(&models.Image{}).Error
 	if err != nil {
@@ -48,11 +51,26 @@ func (i *ImageRepository) DeleteImage(ctx context.Context, imageID string) error
 	return nil
 }
 
+// PurgeImage удаляет запись картинки навсегда вместе с ее связями с постами
+func (i *ImageRepository) PurgeImage(ctx context.Context, imageSK string) error {
+	return i.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var imageIDs []int
+		err := tx.Unscoped().Model(&models.Image{}).Where("storage_key = ?", imageSK).Pluck("id", &imageIDs).Error
+		if err != nil || len(imageIDs) == 0 {
+			return err
+		}
+		if err = tx.Where("image_id IN ?", imageIDs).Delete(&models.PostImage{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", imageIDs).Delete(&models.Image{}).Error
+	})
+}
+
 func (i *ImageRepository) GetImageIDBySK(ctx context.Context, imageSK string) (int, error) {
 	var imageID int
 	i.db.WithContext(ctx).Model(&models.Image{}).Where("storage_key  = ?", imageSK).Pluck("id", &imageID)
 	if imageID == 0 {
-		return 0, fmt.Errorf("no such image with SK %d", imageSK)
+		return 0, fmt.Errorf("no such image with SK %s", imageSK)
 	}
 	return imageID, nil
 }
@@ -80,3 +98,12 @@ func (i *ImageRepository) GetImageLinkedPost(ctx context.Context, imageSK string
 	}
 	return postID, nil
 }
+
+// ExportUserImages возвращает все картинки пользователя для выгрузки данных, включая скрытые и лежащие в корзине
+func (i *ImageRepository) ExportUserImages(ctx context.Context, userID int) ([]models.ExportImage, error) {
+	images := make([]models.ExportImage, 0)
+	err := i.db.WithContext(ctx).Unscoped().Model(&models.Image{}).
+		Select("storage_key", "description", "hidden_at IS NOT NULL AS hidden", "deleted_at IS NOT NULL AS in_trash").
+		Where("user_id = ?", userID).Order("id").Scan(&images).Error
+	return images, err
+}
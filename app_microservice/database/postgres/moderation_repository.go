@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pictureloader/app_microservice/models"
+	"strconv"
+	"time"
+)
+
+type ModerationRepository struct {
+	db *gorm.DB
+}
+
+func NewModerationRepository(db *gorm.DB) *ModerationRepository {
+	return &ModerationRepository{db: db}
+}
+
+func (mr *ModerationRepository) CreateReport(ctx context.Context, report *models.Report) error {
+	return mr.db.WithContext(ctx).Create(report).Error
+}
+
+// HasOpenReport проверяет, есть ли у пользователя неразобранная жалоба на объект
+func (mr *ModerationRepository) HasOpenReport(ctx context.Context, reporterID int, targetType, targetID string) (bool, error) {
+	var count int64
+	err := mr.db.WithContext(ctx).Model(&models.Report{}).
+		Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status = ?",
+			reporterID, targetType, targetID, models.ReportOpen).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetReports возвращает страницу жалоб, старые первыми: очередь разбирается по порядку
+func (mr *ModerationRepository) GetReports(ctx context.Context, filter models.ReportFilter) ([]models.Report, error) {
+	reports := make([]models.Report, 0)
+	query := mr.db.WithContext(ctx).Model(&models.Report{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.Reason != "" {
+		query = query.Where("reason = ?", filter.Reason)
+	}
+	err := query.Order("created_at, id").Limit(filter.Limit).Offset(filter.Offset).Find(&reports).Error
+	return reports, err
+}
+
+// GetReport возвращает жалобу по id, nil - такой жалобы нет
+func (mr *ModerationRepository) GetReport(ctx context.Context, reportID int) (*models.Report, error) {
+	var report models.Report
+	err := mr.db.WithContext(ctx).First(&report, reportID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &report, err
+}
+
+// ResolveReports закрывает все открытые жалобы на объект и возвращает id пожаловавшихся
+func (mr *ModerationRepository) ResolveReports(ctx context.Context, targetType, targetID, status, resolution string,
+	resolvedBy int, now time.Time) ([]int, error) {
+	var reports []models.Report
+	err := mr.db.WithContext(ctx).Model(&reports).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "reporter_id"}}}).
+		Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, models.ReportOpen).
+		Updates(map[string]any{
+			"status":      status,
+			"resolution":  resolution,
+			"resolved_by": resolvedBy,
+			"resolved_at": now,
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	reporters := make([]int, 0, len(reports))
+	for _, report := range reports {
+		reporters = append(reporters, report.ReporterID)
+	}
+	return reporters, nil
+}
+
+// GetReportTarget возвращает владельца и название объекта жалобы, nil - объекта нет
+func (mr *ModerationRepository) GetReportTarget(ctx context.Context, targetType, targetID string) (*models.ReportTarget, error) {
+	var target models.ReportTarget
+	query := mr.db.WithContext(ctx)
+	switch targetType {
+	case models.ReportTargetPost:
+		postID, err := strconv.Atoi(targetID)
+		if err != nil {
+			return nil, nil
+		}
+		query = query.Model(&models.Post{}).Select("user_id AS owner_id", "name").Where("id = ?", postID)
+	case models.ReportTargetImage:
+		query = query.Model(&models.Image{}).Select("user_id AS owner_id", "description AS name").
+			Where("storage_key = ?", targetID)
+	case models.ReportTargetUser:
+		userID, err := strconv.Atoi(targetID)
+		if err != nil {
+			return nil, nil
+		}
+		query = query.Model(&models.User{}).Select("id AS owner_id", "username AS name").Where("id = ?", userID)
+	default:
+		return nil, nil
+	}
+	err := query.Take(&target).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &target, nil
+}
+
+// SetPostHidden скрывает пост (hiddenAt != nil) или возвращает его, false - поста нет
+func (mr *ModerationRepository) SetPostHidden(ctx context.Context, postID int, hiddenAt *time.Time) (bool, error) {
+	result := mr.db.WithContext(ctx).Model(&models.Post{}).Where("id = ?", postID).Update("hidden_at", hiddenAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// SetImageHidden скрывает картинку или возвращает ее и отдает id постов, в которых она есть.
+// nil - картинки нет.
+func (mr *ModerationRepository) SetImageHidden(ctx context.Context, imageSK string, hiddenAt *time.Time) ([]int, error) {
+	var postIDs []int
+	err := mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Image{}).Where("storage_key = ?", imageSK).Update("hidden_at", hiddenAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		postIDs = make([]int, 0)
+		return tx.Table("post_images").
+			Joins("JOIN images ON post_images.image_id = images.id").
+			Where("images.storage_key = ?", imageSK).
+			Pluck("post_images.post_id", &postIDs).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return postIDs, err
+}
+
+func (mr *ModerationRepository) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	return mr.db.WithContext(ctx).Create(entry).Error
+}
+
+// GetAuditEntries возвращает страницу журнала аудита, новые записи первыми
+func (mr *ModerationRepository) GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	entries := make([]models.AuditEntry, 0)
+	query := mr.db.WithContext(ctx).Model(&models.AuditEntry{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&entries).Error
+	return entries, err
+}
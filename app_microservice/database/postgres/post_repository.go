@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gorm.io/gorm"
 	"pictureloader/app_microservice/models"
@@ -23,7 +24,7 @@ func (pr *PostRepository) CreatePost(ctx context.Context, post *models.Post) err
 func (pr *PostRepository) CreatePostAndImage(ctx context.Context, postID int, imageSK string) error {
 	query := `
 		INSERT INTO post_images (post_id, image_id)
-		SELECT ?, id FROM images WHERE storage_key = ?`
+		SELECT ?, id FROM images WHERE storage_key = ? AND deleted_at IS NULL`
 
 	result := pr.db.WithContext(ctx).Exec(query, postID, imageSK)
 	if result.Error != nil {
@@ -44,7 +45,9 @@ func (pr *PostRepository) GetPost(ctx context.Context, postID int) (models.PostU
 		Likes  int             `json:"likes_count"`
 	}
 	var post postsDBStruct
-	err := pr.db.Model(&models.Post{}).WithContext(ctx).
+	// скрытый модератором или удаленный в корзину пост не отдается, такие же картинки выпадают из поста.
+	// Raw запросы gorm не дополняет условием deleted_at, поэтому оно указано явно.
+	result := pr.db.Model(&models.Post{}).WithContext(ctx).
 		Raw(`SELECT posts.name AS Name,
        COALESCE(likes_count, 0) AS Likes,
        COALESCE(JSON_OBJECT_AGG(images.description, images.storage_key), '[]') AS Images
@@ -55,36 +58,51 @@ LEFT JOIN (
     GROUP BY post_id
 ) AS like_counts ON like_counts.post_id = posts.id
 LEFT JOIN post_images ON post_images.post_id = posts.id
-LEFT JOIN images ON post_images.image_id = images.id
-WHERE posts.id = ?
+LEFT JOIN images ON post_images.image_id = images.id AND images.hidden_at IS NULL AND images.deleted_at IS NULL
+WHERE posts.id = ? AND posts.hidden_at IS NULL AND posts.deleted_at IS NULL
 GROUP BY posts.name, posts.id, like_counts.likes_count;
-       `, postID).Scan(&post).Error
-	if err != nil {
-		return models.PostUnit{}, err
+       `, postID).Scan(&post)
+	if result.Error != nil {
+		return models.PostUnit{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.PostUnit{}, gorm.ErrRecordNotFound
 	}
 
 	images := make(map[string]string)
-	err = json.Unmarshal(post.Images, &images)
+	err := json.Unmarshal(post.Images, &images)
 	if err != nil {
 		return models.PostUnit{}, err
 	}
-	result := models.PostUnit{Name: post.Name, Images: images, Likes: post.Likes}
-
-	return result, nil
+	return models.PostUnit{Name: post.Name, Images: images, Likes: post.Likes}, nil
 }
 
 func (pr *PostRepository) GetUserPostIDs(ctx context.Context, userID int) ([]int, error) {
 	var idSlice []int
-	if err := pr.db.WithContext(ctx).Model(&models.Post{}).Where("user_id = ?", userID).Pluck("id", &idSlice).Error; err != nil {
+	if err := pr.db.WithContext(ctx).Model(&models.Post{}).Where("user_id = ? AND hidden_at IS NULL", userID).Pluck("id", &idSlice).Error; err != nil {
 		return nil, err
 	}
 	return idSlice, nil
 }
 
+// DeletePostByID переносит пост в корзину, лайки и картинки поста остаются до purge
 func (pr *PostRepository) DeletePostByID(ctx context.Context, postID int) error {
 	return pr.db.WithContext(ctx).Delete(&models.Post{}, postID).Error
 }
 
+// PurgePost удаляет пост навсегда вместе с лайками и связями с картинками, сами картинки остаются
+func (pr *PostRepository) PurgePost(ctx context.Context, postID int) error {
+	return pr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id = ?", postID).Delete(&models.Like{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("post_id = ?", postID).Delete(&models.PostImage{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Post{}, postID).Error
+	})
+}
+
 func (pr *PostRepository) DeletePostImage(ctx context.Context, postID int, imageSK string) error {
 	query := `DELETE FROM post_images 
 		USING images 
@@ -116,15 +134,28 @@ func (pr *PostRepository) GetPostLikesCount(ctx context.Context, postID int) (in
 	return int(count), err
 }
 
-func (pr *PostRepository) LikePost(ctx context.Context, postID, userID int) error {
-	err := pr.db.WithContext(ctx).Create(&models.Like{PostID: postID, UserID: userID}).Error
+func (pr *PostRepository) LikePost(ctx context.Context, postID, userID int) (*models.Like, error) {
+	like := &models.Like{PostID: postID, UserID: userID}
+	err := pr.db.WithContext(ctx).Create(like).Error
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	// имя лайкнувшего уходит в событие для уведомления
+	err = pr.db.WithContext(ctx).Select("id", "username").First(&like.User, userID).Error
+	if err != nil {
+		return nil, err
+	}
+	return like, nil
 }
 
 func (pr *PostRepository) UnlikePost(ctx context.Context, postID, userID int) error {
+	result := pr.db.WithContext(ctx).Where("post_id = ? AND user_id = ?", postID, userID).Delete(&models.Like{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("post is not liked")
+	}
 	return nil
 }
 
@@ -154,7 +185,8 @@ func (pr *PostRepository) GetMostLikedPosts(ctx context.Context) ([]models.PostU
 				GROUP BY post_id
 			) AS like_counts ON like_counts.post_id = posts.id
 			LEFT JOIN post_images ON post_images.post_id = posts.id
-			LEFT JOIN images ON images.id = post_images.image_id
+			LEFT JOIN images ON images.id = post_images.image_id AND images.hidden_at IS NULL AND images.deleted_at IS NULL
+			WHERE posts.hidden_at IS NULL AND posts.deleted_at IS NULL
 			GROUP BY posts.name, likes_count
 			ORDER BY likes_count DESC
 			LIMIT 3;
@@ -185,3 +217,62 @@ func (pr *PostRepository) GetPostOwner(ctx context.Context, postID int) (int, er
 	}
 	return ownerID, nil
 }
+
+// GetPostByID возвращает сам пост без картинок и лайков, скрытый пост не находится
+func (pr *PostRepository) GetPostByID(ctx context.Context, postID int) (*models.Post, error) {
+	var post models.Post
+	err := pr.db.WithContext(ctx).Where("hidden_at IS NULL").First(&post, postID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// ExportUserPosts возвращает все посты пользователя для выгрузки данных, включая скрытые и лежащие в корзине
+func (pr *PostRepository) ExportUserPosts(ctx context.Context, userID int) ([]models.ExportPost, error) {
+	type exportPostDB struct {
+		models.ExportPost
+		Images json.RawMessage
+	}
+	var rows []exportPostDB
+	err := pr.db.WithContext(ctx).Raw(`
+		SELECT posts.id, posts.name, posts.created_at,
+			posts.hidden_at IS NOT NULL AS hidden,
+			posts.deleted_at IS NOT NULL AS in_trash,
+			(SELECT COUNT(*) FROM likes WHERE likes.post_id = posts.id) AS likes,
+			COALESCE((SELECT JSON_AGG(images.storage_key)
+				FROM post_images JOIN images ON images.id = post_images.image_id
+				WHERE post_images.post_id = posts.id), '[]') AS images
+		FROM posts
+		WHERE posts.user_id = ?
+		ORDER BY posts.id`, userID).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	posts := make([]models.ExportPost, len(rows))
+	for i, row := range rows {
+		posts[i] = row.ExportPost
+		if err = json.Unmarshal(row.Images, &posts[i].Images); err != nil {
+			return nil, err
+		}
+	}
+	return posts, nil
+}
+
+// ExportLikesGiven возвращает лайки, которые поставил пользователь
+func (pr *PostRepository) ExportLikesGiven(ctx context.Context, userID int) ([]models.ExportLike, error) {
+	likes := make([]models.ExportLike, 0)
+	err := pr.db.WithContext(ctx).Model(&models.Like{}).Select("post_id", "created_at").
+		Where("user_id = ?", userID).Order("created_at").Scan(&likes).Error
+	return likes, err
+}
+
+// ExportLikesReceived возвращает лайки на посты пользователя
+func (pr *PostRepository) ExportLikesReceived(ctx context.Context, userID int) ([]models.ExportLike, error) {
+	likes := make([]models.ExportLike, 0)
+	err := pr.db.WithContext(ctx).Table("likes").
+		Select("likes.post_id", "likes.user_id", "likes.created_at").
+		Joins("JOIN posts ON posts.id = likes.post_id").
+		Where("posts.user_id = ?", userID).Order("likes.created_at").Scan(&likes).Error
+	return likes, err
+}
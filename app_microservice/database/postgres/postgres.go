@@ -12,9 +12,36 @@ func NewDataBase(dbPath string) *gorm.DB {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	err = database.AutoMigrate(&models.User{}, &models.Image{}, &models.Post{}, &models.PostImage{}, &models.Like{})
+	// один аватар могли выбрать несколько пользователей, уникальность profile_picture снята.
+	// AutoMigrate удаляет ограничение только под своим именем, а старые версии gorm создавали его под именем Postgres.
+	err = database.Exec(`ALTER TABLE IF EXISTS users
+		DROP CONSTRAINT IF EXISTS uni_users_profile_picture,
+		DROP CONSTRAINT IF EXISTS users_profile_picture_key`).Error
 	if err != nil {
 		log.Fatalln(err)
 	}
+	// подтверждение почты появилось, когда аккаунты уже были: колонка добавляется с false,
+	// поэтому существующим пользователям почта считается подтвержденной, иначе они сразу потеряют загрузку картинок
+	backfillVerified := database.Migrator().HasTable(&models.User{}) &&
+		!database.Migrator().HasColumn(&models.User{}, "EmailVerified")
+	err = database.AutoMigrate(&models.User{}, &models.Image{}, &models.Post{}, &models.PostImage{}, &models.Like{},
+		&models.Session{}, &models.RefreshToken{}, &models.APIToken{}, &models.UserToken{},
+		&models.TwoFactor{}, &models.RecoveryCode{}, &models.SecurityEvent{}, &models.ExternalIdentity{},
+		&models.Report{}, &models.AuditEntry{}, &models.AccountDeletion{},
+		&models.DataExport{}, &models.Follow{})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// привязки провайдеров раньше были уникальны по имени провайдера из конфига, теперь - по issuer
+	if database.Migrator().HasIndex(&models.ExternalIdentity{}, "idx_identity_provider_subject") {
+		if err = database.Migrator().DropIndex(&models.ExternalIdentity{}, "idx_identity_provider_subject"); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if backfillVerified {
+		if err = database.Exec("UPDATE users SET email_verified = true").Error; err != nil {
+			log.Fatalln(err)
+		}
+	}
 	return database
 }
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pictureloader/app_microservice/models"
+)
+
+type ProfileRepository struct {
+	db *gorm.DB
+}
+
+func NewProfileRepository(db *gorm.DB) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+// GetPublicProfile возвращает профиль со счетчиками, nil - пользователя нет, он заблокирован или удаляется.
+// Скрытые модераторами и удаленные в корзину посты не считаются.
+func (pr *ProfileRepository) GetPublicProfile(ctx context.Context, username string) (*models.PublicProfile, error) {
+	var profiles []models.PublicProfile
+	err := pr.db.WithContext(ctx).Raw(`
+		SELECT
+			users.id AS user_id,
+			users.username,
+			users.bio,
+			users.links,
+			users.profile_picture AS avatar,
+			users.created_at AS joined_at,
+			(SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id) AS followers,
+			(SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id) AS following,
+			(SELECT COUNT(*) FROM posts
+				WHERE posts.user_id = users.id AND posts.hidden_at IS NULL AND posts.deleted_at IS NULL) AS posts,
+			(SELECT COUNT(*) FROM likes JOIN posts ON posts.id = likes.post_id
+				WHERE posts.user_id = users.id AND posts.hidden_at IS NULL AND posts.deleted_at IS NULL) AS likes_received
+		FROM users
+		WHERE users.username = ? AND users.banned_at IS NULL AND users.deletion_requested_at IS NULL
+	`, username).Scan(&profiles).Error
+	if err != nil || len(profiles) == 0 {
+		return nil, err
+	}
+	return &profiles[0], nil
+}
+
+// GetRecentPosts возвращает последние limit видимых постов пользователя,
+// Cover - ключ первой видимой картинки поста
+func (pr *ProfileRepository) GetRecentPosts(ctx context.Context, userID int, limit int) ([]models.ProfilePost, error) {
+	posts := make([]models.ProfilePost, 0)
+	err := pr.db.WithContext(ctx).Raw(`
+		SELECT
+			posts.id,
+			posts.name,
+			posts.created_at,
+			(SELECT COUNT(*) FROM likes WHERE likes.post_id = posts.id) AS likes,
+			COALESCE((
+				SELECT images.storage_key FROM post_images
+				JOIN images ON images.id = post_images.image_id
+				WHERE post_images.post_id = posts.id AND images.hidden_at IS NULL AND images.deleted_at IS NULL
+				ORDER BY images.id
+				LIMIT 1
+			), '') AS cover
+		FROM posts
+		WHERE posts.user_id = ? AND posts.hidden_at IS NULL AND posts.deleted_at IS NULL
+		ORDER BY posts.created_at DESC, posts.id DESC
+		LIMIT ?
+	`, userID, limit).Scan(&posts).Error
+	return posts, err
+}
+
+// UpdateProfile меняет только заданные поля публичного профиля
+func (pr *ProfileRepository) UpdateProfile(ctx context.Context, userID int, update models.ProfileUpdate) error {
+	var user models.User
+	var fields []string
+	if update.Bio != nil {
+		user.Bio = *update.Bio
+		fields = append(fields, "bio")
+	}
+	if update.Links != nil {
+		user.Links = *update.Links
+		fields = append(fields, "links")
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return pr.db.WithContext(ctx).Model(&models.User{ID: userID}).Select(fields).Updates(&user).Error
+}
+
+// GetActiveUserID возвращает id пользователя, которого можно найти по имени, 0 - такого нет
+func (pr *ProfileRepository) GetActiveUserID(ctx context.Context, username string) (int, error) {
+	var user models.User
+	err := pr.db.WithContext(ctx).Select("id").
+		Where("username = ? AND banned_at IS NULL AND deletion_requested_at IS NULL", username).
+		Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return user.ID, err
+}
+
+// Follow подписывает followerID на followeeID, повторная подписка ничего не меняет
+func (pr *ProfileRepository) Follow(ctx context.Context, followerID, followeeID int) error {
+	return pr.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Follow{FollowerID: followerID, FolloweeID: followeeID}).Error
+}
+
+func (pr *ProfileRepository) Unfollow(ctx context.Context, followerID, followeeID int) error {
+	return pr.db.WithContext(ctx).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Delete(&models.Follow{}).Error
+}
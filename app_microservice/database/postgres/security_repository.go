@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"pictureloader/app_microservice/models"
+)
+
+type SecurityRepository struct {
+	db *gorm.DB
+}
+
+func NewSecurityRepository(db *gorm.DB) *SecurityRepository {
+	return &SecurityRepository{db: db}
+}
+
+func (sr *SecurityRepository) CreateSecurityEvent(ctx context.Context, event *models.SecurityEvent) error {
+	return sr.db.WithContext(ctx).Create(event).Error
+}
+
+// GetSecurityEvents возвращает последние события по имени пользователя, новые первыми
+func (sr *SecurityRepository) GetSecurityEvents(ctx context.Context, username string, limit int) ([]models.SecurityEvent, error) {
+	var events []models.SecurityEvent
+	err := sr.db.WithContext(ctx).Where("LOWER(username) = LOWER(?)", username).
+		Order("created_at DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
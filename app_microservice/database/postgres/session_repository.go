@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// CreateSession сохраняет новую сессию вместе с ее первым refresh токеном
+func (sr *SessionRepository) CreateSession(ctx context.Context, session *models.Session, tokenHash string) error {
+	return sr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.RefreshToken{Hash: tokenHash, SessionID: session.ID}).Error
+	})
+}
+
+// UseRefreshToken помечает токен использованным и возвращает его вместе с сессией.
+// Строка токена блокируется, поэтому два параллельных обмена одного токена не пройдут оба:
+// второй увидит UsedAt и будет считаться повторным использованием.
+func (sr *SessionRepository) UseRefreshToken(ctx context.Context, tokenHash string, now time.Time) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := sr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Session").
+			Where("hash = ?", tokenHash).First(&token).Error
+		if err != nil {
+			return err
+		}
+		if token.UsedAt != nil {
+			return nil
+		}
+		return tx.Model(&models.RefreshToken{}).Where("hash = ?", tokenHash).Update("used_at", now).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &token, err
+}
+
+// GetRefreshToken возвращает токен с сессией, не помечая его использованным
+func (sr *SessionRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := sr.db.WithContext(ctx).Preload("Session").Where("hash = ?", tokenHash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &token, err
+}
+
+// RotateRefreshToken добавляет в сессию новый refresh токен, продлевает ее и обновляет данные устройства
+func (sr *SessionRepository) RotateRefreshToken(ctx context.Context, session *models.Session, tokenHash string) error {
+	return sr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Session{}).Where("id = ?", session.ID).Updates(map[string]any{
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+			"ip":           session.IP,
+			"user_agent":   session.UserAgent,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.RefreshToken{Hash: tokenHash, SessionID: session.ID}).Error
+	})
+}
+
+// GetActiveSessions возвращает неотозванные и неистекшие сессии пользователя
+func (sr *SessionRepository) GetActiveSessions(ctx context.Context, userID int, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := sr.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession отзывает сессию пользователя, false - такой активной сессии нет
+func (sr *SessionRepository) RevokeSession(ctx context.Context, userID int, sessionID string, now time.Time) (bool, error) {
+	result := sr.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RevokeAllSessions отзывает все активные сессии пользователя и возвращает их id
+func (sr *SessionRepository) RevokeAllSessions(ctx context.Context, userID int, now time.Time) ([]string, error) {
+	var sessions []models.Session
+	err := sr.db.WithContext(ctx).Model(&sessions).Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+	return ids, nil
+}
+
+// GetUserAccess возвращает роль и блокировку пользователя
+func (sr *SessionRepository) GetUserAccess(ctx context.Context, userID int) (*models.UserAccess, error) {
+	return getUserAccess(ctx, sr.db, userID)
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type TrashRepository struct {
+	db *gorm.DB
+}
+
+func NewTrashRepository(db *gorm.DB) *TrashRepository {
+	return &TrashRepository{db: db}
+}
+
+// GetTrash возвращает посты и картинки пользователя в корзине, недавно удаленные первыми
+func (tr *TrashRepository) GetTrash(ctx context.Context, userID int) ([]models.TrashItem, error) {
+	items := make([]models.TrashItem, 0)
+	err := tr.db.WithContext(ctx).Raw(`
+		SELECT 'post' AS type, id::text AS id, name, deleted_at
+		FROM posts WHERE user_id = ? AND deleted_at IS NOT NULL
+		UNION ALL
+		SELECT 'image' AS type, storage_key AS id, description AS name, deleted_at
+		FROM images WHERE user_id = ? AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC`, userID, userID).Scan(&items).Error
+	return items, err
+}
+
+// RestorePost достает пост пользователя из корзины, false - такого поста в корзине нет
+func (tr *TrashRepository) RestorePost(ctx context.Context, userID, postID int) (bool, error) {
+	result := tr.db.WithContext(ctx).Unscoped().Model(&models.Post{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", postID, userID).
+		Update("deleted_at", nil)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RestoreImage достает картинку пользователя из корзины и отдает id постов, в которых она есть.
+// nil - такой картинки в корзине нет.
+func (tr *TrashRepository) RestoreImage(ctx context.Context, userID int, imageSK string) ([]int, error) {
+	var postIDs []int
+	err := tr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.Image{}).
+			Where("storage_key = ? AND user_id = ? AND deleted_at IS NOT NULL", imageSK, userID).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		postIDs = make([]int, 0)
+		return tx.Table("post_images").
+			Joins("JOIN images ON post_images.image_id = images.id").
+			Where("images.storage_key = ?", imageSK).
+			Pluck("post_images.post_id", &postIDs).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return postIDs, err
+}
+
+// GetExpiredPosts возвращает до limit постов, удаленных в корзину раньше before
+func (tr *TrashRepository) GetExpiredPosts(ctx context.Context, before time.Time, limit int) ([]int, error) {
+	var postIDs []int
+	err := tr.db.WithContext(ctx).Unscoped().Model(&models.Post{}).
+		Where("deleted_at < ?", before).Order("deleted_at").Limit(limit).Pluck("id", &postIDs).Error
+	return postIDs, err
+}
+
+// GetExpiredImages возвращает ключи до limit картинок, удаленных в корзину раньше before
+func (tr *TrashRepository) GetExpiredImages(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	var imageSKs []string
+	err := tr.db.WithContext(ctx).Unscoped().Model(&models.Image{}).
+		Where("deleted_at < ?", before).Order("deleted_at").Limit(limit).Pluck("storage_key", &imageSKs).Error
+	return imageSKs, err
+}
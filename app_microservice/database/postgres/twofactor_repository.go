@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type TwoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// GetTwoFactor возвращает настройку 2FA пользователя, nil - пользователь ее не начинал
+func (tr *TwoFactorRepository) GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	var twoFactor models.TwoFactor
+	err := tr.db.WithContext(ctx).Where("user_id = ?", userID).First(&twoFactor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &twoFactor, err
+}
+
+// SavePendingTwoFactor сохраняет новый неподтвержденный секрет поверх прежнего неподтвержденного
+func (tr *TwoFactorRepository) SavePendingTwoFactor(ctx context.Context, userID int, secret string) error {
+	return tr.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]any{"secret": secret, "created_at": time.Now(), "last_used_step": 0}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Eq{Column: clause.Column{Table: "two_factors", Name: "enabled"}, Value: false}}},
+	}).Create(&models.TwoFactor{UserID: userID, Secret: secret}).Error
+}
+
+// EnableTwoFactor включает 2FA и заменяет коды восстановления
+func (tr *TwoFactorRepository) EnableTwoFactor(ctx context.Context, userID int, step int64, codeHashes []string, now time.Time) error {
+	return tr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.TwoFactor{}).Where("user_id = ?", userID).Updates(map[string]any{
+			"enabled":        true,
+			"confirmed_at":   now,
+			"last_used_step": step,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// ReplaceRecoveryCodes выпускает новый набор кодов, старые перестают действовать
+func (tr *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	return tr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID int, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]models.RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = models.RecoveryCode{UserID: userID, Hash: hash}
+	}
+	return tx.Create(&codes).Error
+}
+
+// DisableTwoFactor удаляет секрет и коды восстановления
+func (tr *TwoFactorRepository) DisableTwoFactor(ctx context.Context, userID int) error {
+	return tr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error
+	})
+}
+
+// UseStep запоминает шаг принятого кода. false - код этого или более позднего шага уже использовался.
+func (tr *TwoFactorRepository) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	result := tr.db.WithContext(ctx).Model(&models.TwoFactor{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UseRecoveryCode гасит неиспользованный код восстановления, false - такого кода нет
+func (tr *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string, now time.Time) (bool, error) {
+	result := tr.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes - сколько кодов восстановления еще не использовано
+func (tr *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	var count int64
+	err := tr.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return int(count), err
+}
+
+// GetCredentials возвращает имя и хеш пароля: имя подписывает аккаунт в приложении-аутентификаторе,
+// пароль проверяется повторно перед изменением 2FA
+func (tr *TwoFactorRepository) GetCredentials(ctx context.Context, userID int) (*models.User, error) {
+	var user models.User
+	err := tr.db.WithContext(ctx).Select("id", "username", "password").First(&user, userID).Error
+	return &user, err
+}
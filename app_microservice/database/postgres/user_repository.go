@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"gorm.io/gorm"
 	"log"
 	"pictureloader/app_microservice/models"
@@ -25,13 +26,13 @@ func (u *UserRepository) GetUserByID(ctx context.Context, id int) (*models.UserP
 	return &user, err
 }
 
-func (u *UserRepository) DeleteUserByID(ctx context.Context, id int) error {
-	return u.db.WithContext(ctx).Delete(&models.User{}, id).Error
-}
-
 func (u *UserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := u.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	// аккаунт, который удаляется, для входа уже не существует
+	err := u.db.WithContext(ctx).Where("username = ? AND deletion_requested_at IS NULL", username).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -48,8 +49,3 @@ func (u *UserRepository) UpdatePasswordByID(ctx context.Context, userID int, new
 	err := u.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("password", newPass).Error
 	return err
 }
-
-func (u *UserRepository) UploadProfilePicture(ctx context.Context, userID int, imageSK string) error {
-	err := u.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("profile_picture", imageSK).Error
-	return err
-}
@@ -15,95 +15,114 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/pictures/create": {
-            "post": {
-                "description": "This endpoint allows a user to upload an image file.",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+        "/admin/deletions": {
+            "get": {
+                "description": "Admin only. Account deletion jobs, newest first. A completed job confirms that the user's data was erased.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Image"
+                    "Admin"
                 ],
-                "summary": "Upload an image",
+                "summary": "Account deletions",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "Image file",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Image description",
-                        "name": "desription",
-                        "in": "formData",
-                        "required": true
+                        "description": "pending or completed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 50 by default, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
-                "responses": {}
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AccountDeletion"
+                            }
+                        }
+                    }
+                }
             }
         },
-        "/pictures/my": {
-            "get": {
-                "description": "This endpoint allows a user to download his images.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/pictures/{imageSK}": {
+            "delete": {
+                "description": "Moderators and admins. Deletes a picture regardless of its owner and closes open reports on it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Image"
+                    "Admin"
+                ],
+                "summary": "Delete any picture",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Image storage key",
+                        "name": "imageSK",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Download an image(s)",
                 "responses": {}
             }
         },
-        "/pictures/{imageURL}": {
-            "get": {
-                "description": "This endpoint allows a user to download an image file.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/pictures/{imageSK}/unhide": {
+            "post": {
+                "description": "Moderators and admins. Returns a picture hidden by moderators to posts, dismisses open reports on it and notifies the owner.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Image"
+                    "Admin"
                 ],
-                "summary": "Download an image",
+                "summary": "Unhide a picture",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "StorageKey of the image",
-                        "name": "imageURL",
+                        "description": "Image storage key",
+                        "name": "imageSK",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {}
-            },
+            }
+        },
+        "/admin/posts/{postID}": {
             "delete": {
-                "description": "Delete an image for its url",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Moderators and admins. Deletes a post regardless of its owner and closes open reports on it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Image"
+                    "Admin"
                 ],
-                "summary": "Delete an image",
+                "summary": "Delete any post",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Image url",
-                        "name": "imageURL",
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "postID",
                         "in": "path",
                         "required": true
                     }
@@ -111,68 +130,80 @@ const docTemplate = `{
                 "responses": {}
             }
         },
-        "/posts": {
+        "/admin/posts/{postID}/unhide": {
             "post": {
-                "description": "Creates a new post for the user.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Moderators and admins. Returns a post hidden by moderators to feeds, dismisses open reports on it and notifies the owner.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
+                    "Admin"
                 ],
-                "summary": "Create a new post",
+                "summary": "Unhide a post",
                 "parameters": [
                     {
-                        "description": "Post",
-                        "name": "post",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.PostRegister"
-                        }
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "postID",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {}
             }
         },
-        "/posts/most-liked": {
+        "/admin/users": {
             "get": {
-                "description": "Returns a list of the most liked posts, ordered by like count in descending order.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Moderators and admins. Searches by a substring of the username or email, optionally filtered by role.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
-                ],
-                "summary": "Get most liked posts",
-                "responses": {}
-            }
-        },
-        "/posts/my": {
-            "get": {
-                "description": "Retrieves all posts of the currently authenticated user.",
-                "consumes": [
-                    "application/json"
+                    "Admin"
                 ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Posts"
+                "summary": "List users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username or email substring",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Role: user, moderator or admin",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 50 by default, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get all posts of the user",
-                "responses": {}
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AdminUser"
+                            }
+                        }
+                    }
+                }
             }
         },
-        "/posts/{postID}": {
-            "get": {
-                "description": "Retrieves images and details from a specific post.",
+        "/admin/users/{userID}/ban": {
+            "post": {
+                "description": "Moderators and admins. The user is logged out everywhere and can't log in until unbanned. Moderators can ban only users with the user role.",
                 "consumes": [
                     "application/json"
                 ],
@@ -180,22 +211,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
+                    "Admin"
                 ],
-                "summary": "Get post details",
+                "summary": "Ban a user",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Post ID",
-                        "name": "postID",
+                        "description": "User ID",
+                        "name": "userID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Reason",
+                        "name": "ban",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserBan"
+                        }
                     }
                 ],
                 "responses": {}
-            },
-            "delete": {
-                "description": "Removes a specific post and all its images from the system.",
+            }
+        },
+        "/admin/users/{userID}/role": {
+            "put": {
+                "description": "Admin only. A demoted user is logged out everywhere, a promoted one gets the new role on the next token refresh.",
                 "consumes": [
                     "application/json"
                 ],
@@ -203,39 +244,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
+                    "Admin"
                 ],
-                "summary": "Delete a post",
+                "summary": "Change a user's role",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Post ID",
-                        "name": "postID",
+                        "description": "User ID",
+                        "name": "userID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Role: user, moderator or admin",
+                        "name": "role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserRoleUpdate"
+                        }
                     }
                 ],
                 "responses": {}
             }
         },
-        "/posts/{postID}/like": {
+        "/admin/users/{userID}/unban": {
             "post": {
-                "description": "Increments the like count of a post and invalidates cache",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Moderators and admins. Moderators can unban only users with the user role.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
+                    "Admin"
                 ],
-                "summary": "Like a post",
+                "summary": "Unban a user",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Post ID",
-                        "name": "postID",
+                        "description": "User ID",
+                        "name": "userID",
                         "in": "path",
                         "required": true
                     }
@@ -243,61 +290,43 @@ const docTemplate = `{
                 "responses": {}
             }
         },
-        "/posts/{postID}/{imageSK}": {
-            "post": {
-                "description": "Adds an image to the specified post.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/users/{username}/security-events": {
+            "get": {
+                "description": "Admin only. Returns the last 100 failed logins, lockouts and unlocks for the username.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
+                    "Admin"
                 ],
-                "summary": "Add an image to a post",
+                "summary": "Security events of a user",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Post ID",
-                        "name": "postID",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "type": "string",
-                        "description": "Image Storage Key",
-                        "name": "imageSK",
+                        "description": "Username",
+                        "name": "username",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {}
-            },
-            "delete": {
-                "description": "Removes a specific image from the specified post.",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/admin/users/{username}/unlock": {
+            "post": {
+                "description": "Admin only. Clears failed login attempts and the lockout of the username, and the lockouts of IP addresses with recent failed logins to it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
+                    "Admin"
                 ],
-                "summary": "Delete an image from a post",
+                "summary": "Unlock a user's login",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Post ID",
-                        "name": "postID",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "type": "string",
-                        "description": "Image Storage Key",
-                        "name": "imageSK",
+                        "description": "Username",
+                        "name": "username",
                         "in": "path",
                         "required": true
                     }
@@ -305,108 +334,189 @@ const docTemplate = `{
                 "responses": {}
             }
         },
-        "/users/login": {
-            "post": {
-                "description": "This endpoint allows a user to log in by providing their username and password. If the credentials are correct, a JWT token will be generated and returned in a cookie for session management.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/exports": {
+            "get": {
+                "description": "Exports of the user, newest first. Ready exports carry a download link that expires with the archive.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Exports"
                 ],
-                "summary": "Login an existing user",
-                "parameters": [
-                    {
-                        "description": "User login credentials (username and password)",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
+                "summary": "List data exports",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.UserLogin"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.DataExport"
+                            }
                         }
                     }
-                ],
-                "responses": {}
-            }
-        },
-        "/users/logout": {
+                }
+            },
             "post": {
-                "description": "This endpoint allows a user to log out by deleting the authentication cookie from the client's browser.",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "User"
-                ],
-                "summary": "Log out a user (delete authentication cookie)",
-                "responses": {}
-            }
-        },
-        "/users/profile": {
-            "delete": {
-                "description": "This endpoint allows an authenticated user to delete their profile permanently.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Queues a zip archive with the profile, posts, original images, likes given and received, and notifications. The archive is built in the background; the user is notified when the download link is ready. While an export is pending or downloadable, it is returned instead of a new one.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Exports"
                 ],
-                "summary": "Delete user profile",
-                "responses": {}
+                "summary": "Request a data export",
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.DataExport"
+                        }
+                    }
+                }
             }
         },
-        "/users/profile/me": {
+        "/moderation/audit": {
             "get": {
-                "description": "Returns the user profile based on the JWT token.",
+                "description": "Admin only. Actions of moderators and admins, newest first.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Moderation"
                 ],
-                "summary": "Get user profile",
-                "responses": {}
+                "summary": "Audit log",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Moderator or admin ID",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "post, image or user",
+                        "name": "target_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target ID",
+                        "name": "target_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 50 by default, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AuditEntry"
+                            }
+                        }
+                    }
+                }
             }
         },
-        "/users/profile/password": {
-            "patch": {
-                "description": "Allows an authenticated user to change their password",
-                "consumes": [
+        "/moderation/reports": {
+            "get": {
+                "description": "Moderators and admins. Reports, oldest first.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "Moderation"
+                ],
+                "summary": "Moderation queue",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "open, actioned or dismissed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "post, image or user",
+                        "name": "target_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reason",
+                        "name": "reason",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 50 by default, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Report"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/reports/{reportID}": {
+            "get": {
+                "description": "Moderators and admins.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Moderation"
                 ],
-                "summary": "Change user password",
+                "summary": "Get a report",
                 "parameters": [
                     {
-                        "description": "New password request body",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                        "type": "integer",
+                        "description": "Report ID",
+                        "name": "reportID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.passwordReqChange"
+                            "$ref": "#/definitions/models.Report"
                         }
                     }
-                ],
-                "responses": {}
+                }
             }
         },
-        "/users/profile/profile_picture": {
+        "/moderation/reports/{reportID}/resolve": {
             "post": {
-                "description": "Allows the user to upload a profile picture by providing the path (PictureSK).",
+                "description": "Moderators and admins. Posts and images can be hidden, unhidden or removed, users banned; any report can be dismissed. The decision closes all open reports on the same content and notifies the reporters and the owner.",
                 "consumes": [
                     "application/json"
                 ],
@@ -414,26 +524,65 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Moderation"
                 ],
-                "summary": "Upload profile picture",
+                "summary": "Resolve a report",
                 "parameters": [
                     {
-                        "description": "Upload data",
-                        "name": "request",
+                        "type": "integer",
+                        "description": "Report ID",
+                        "name": "reportID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Action: hide, unhide, remove, ban or dismiss",
+                        "name": "decision",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.UploadProfilePicRequest"
+                            "$ref": "#/definitions/models.ReportResolve"
                         }
                     }
                 ],
                 "responses": {}
             }
         },
-        "/users/profile/username": {
-            "patch": {
-                "description": "This endpoint allows the user to change their username. The new username is passed in the body of the request.",
+        "/pictures/create": {
+            "post": {
+                "description": "This endpoint allows a user to upload an image file.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Image"
+                ],
+                "summary": "Upload an image",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Image file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image description",
+                        "name": "desription",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/pictures/my": {
+            "get": {
+                "description": "This endpoint allows a user to download his images.",
                 "consumes": [
                     "application/json"
                 ],
@@ -441,26 +590,63 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Image"
                 ],
-                "summary": "Change the username of the authenticated user",
+                "summary": "Download an image(s)",
+                "responses": {}
+            }
+        },
+        "/pictures/{imageURL}": {
+            "get": {
+                "description": "This endpoint allows a user to download an image file.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Image"
+                ],
+                "summary": "Download an image",
                 "parameters": [
                     {
-                        "description": "New Username",
-                        "name": "username",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.usernameReqChange"
-                        }
+                        "type": "string",
+                        "description": "StorageKey of the image",
+                        "name": "imageURL",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "delete": {
+                "description": "Moves an image to the trash. It can be restored until it is purged after the retention period.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Image"
+                ],
+                "summary": "Delete an image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Image url",
+                        "name": "imageURL",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {}
             }
         },
-        "/users/register": {
+        "/posts": {
             "post": {
-                "description": "This endpoint registers a new user, stores the user in the database, and generates a JWT token for the user.",
+                "description": "Creates a new post for the user.",
                 "consumes": [
                     "application/json"
                 ],
@@ -468,81 +654,1602 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Posts"
                 ],
-                "summary": "Register a new user",
+                "summary": "Create a new post",
                 "parameters": [
                     {
-                        "description": "User data for registration",
-                        "name": "user",
+                        "description": "Post",
+                        "name": "post",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UserRegister"
+                            "$ref": "#/definitions/models.PostRegister"
                         }
                     }
                 ],
                 "responses": {}
             }
-        }
-    },
-    "definitions": {
-        "models.PostRegister": {
-            "type": "object",
-            "properties": {
-                "name": {
-                    "type": "string"
-                }
-            }
-        },
-        "models.UserLogin": {
-            "type": "object",
-            "properties": {
-                "password": {
-                    "type": "string"
-                },
-                "username": {
-                    "type": "string"
-                }
-            }
         },
-        "models.UserRegister": {
-            "type": "object",
-            "properties": {
-                "email": {
-                    "type": "string"
-                },
-                "password": {
-                    "type": "string"
-                },
-                "username": {
-                    "type": "string"
-                }
+        "/posts/most-liked": {
+            "get": {
+                "description": "Returns a list of the most liked posts, ordered by like count in descending order.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Get most liked posts",
+                "responses": {}
             }
         },
-        "handler.UploadProfilePicRequest": {
-            "type": "object",
-            "properties": {
-                "picture_sk": {
-                    "type": "string"
-                }
+        "/posts/my": {
+            "get": {
+                "description": "Retrieves all posts of the currently authenticated user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Get all posts of the user",
+                "responses": {}
             }
         },
-        "handler.passwordReqChange": {
-            "type": "object",
-            "properties": {
-                "password": {
-                    "type": "string"
-                }
-            }
+        "/posts/{postID}": {
+            "get": {
+                "description": "Retrieves images and details from a specific post.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Get post details",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "postID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "delete": {
+                "description": "Moves a post to the trash. It can be restored until it is purged after the retention period; its pictures are not deleted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Delete a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "postID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/posts/{postID}/like": {
+            "post": {
+                "description": "Increments the like count of a post and invalidates cache",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Like a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "postID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "delete": {
+                "description": "Removes the current user's like from a post and invalidates cache",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Unlike a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "postID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/posts/{postID}/{imageSK}": {
+            "post": {
+                "description": "Adds an image to the specified post.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Add an image to a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "postID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image Storage Key",
+                        "name": "imageSK",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "delete": {
+                "description": "Removes a specific image from the specified post.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Delete an image from a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "postID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image Storage Key",
+                        "name": "imageSK",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/profiles/me": {
+            "patch": {
+                "description": "Omitted fields are left unchanged. The bio is at most 300 characters; up to 5 absolute http or https links of at most 200 characters.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Profile"
+                ],
+                "summary": "Edit the public profile",
+                "parameters": [
+                    {
+                        "description": "Profile fields",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ProfileUpdate"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/profiles/{username}": {
+            "get": {
+                "description": "Bio, links, join date, avatar, follower, following, post and like counts and the latest posts of the user. Available without login; the email is never shown. Banned users and accounts being deleted are not found.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Profile"
+                ],
+                "summary": "Public user profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PublicProfile"
+                        }
+                    }
+                }
+            }
+        },
+        "/profiles/{username}/follow": {
+            "post": {
+                "description": "Following a user twice has no effect.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Profile"
+                ],
+                "summary": "Follow a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Profile"
+                ],
+                "summary": "Unfollow a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/reports": {
+            "post": {
+                "description": "Flags content for moderators. target_id is the post ID, the image storage key or the user ID. Reasons: spam, abuse, nudity, violence, copyright, other.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Moderation"
+                ],
+                "summary": "Report a post, image or user",
+                "parameters": [
+                    {
+                        "description": "Report",
+                        "name": "report",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReportCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/trash": {
+            "get": {
+                "description": "Posts and pictures deleted by the user, most recently deleted first. Items are deleted forever at purge_at.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "List the trash",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TrashItem"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/trash/pictures/{imageSK}/restore": {
+            "post": {
+                "description": "The picture comes back to the posts it was added to.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Restore a picture from the trash",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Image storage key",
+                        "name": "imageSK",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/trash/posts/{postID}/restore": {
+            "post": {
+                "description": "The post comes back with its likes and the pictures that are not deleted themselves.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Restore a post from the trash",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "postID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/email/verify": {
+            "post": {
+                "description": "Confirms the email address with the token from the verification email. Uploading images and publishing posts require a verified email.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Verify email",
+                "parameters": [
+                    {
+                        "description": "Token from the email",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.EmailVerify"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/login": {
+            "post": {
+                "description": "This endpoint allows a user to log in by providing their username and password. If the credentials are correct, a JWT token will be generated and returned in a cookie for session management. If two-factor authentication is enabled, no cookie is set; the response contains a challenge for /users/login/2fa instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Login an existing user",
+                "parameters": [
+                    {
+                        "description": "User login credentials (username and password)",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserLogin"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/login/2fa": {
+            "post": {
+                "description": "Second login step for users with two-factor authentication. Takes the challenge returned by /users/login and a code from the authenticator app or a recovery code. The challenge expires after 5 minutes or 5 wrong codes. Wrong codes count towards the same account lockout as wrong passwords.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Complete login with a two-factor code",
+                "parameters": [
+                    {
+                        "description": "Challenge and code",
+                        "name": "login",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorLogin"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/logout": {
+            "post": {
+                "description": "This endpoint revokes the session of the refresh token cookie and deletes the authentication cookies from the client's browser.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Log out a user (revoke the session and delete authentication cookies)",
+                "responses": {}
+            }
+        },
+        "/users/oidc/callback": {
+            "get": {
+                "description": "Completes the sign-in started by /users/oidc/{provider}/login. The first sign-in creates a user with a generated username. If two-factor authentication is enabled, the response contains a challenge for /users/login/2fa.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Identity provider callback",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "State",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/oidc/providers": {
+            "get": {
+                "description": "Returns OpenID Connect providers available for \"Sign in with ...\".",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "List identity providers",
+                "responses": {}
+            }
+        },
+        "/users/oidc/{provider}/login": {
+            "get": {
+                "description": "Redirects to the provider (authorization code flow with PKCE). After sign-in the provider redirects back to /users/oidc/callback.",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Sign in with an identity provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/password/forgot": {
+            "post": {
+                "description": "Sends a single-use password reset link if the email belongs to an account. The response is the same for unknown emails.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Request a password reset",
+                "parameters": [
+                    {
+                        "description": "Account email",
+                        "name": "email",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PasswordForgot"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/password/reset": {
+            "post": {
+                "description": "Sets a new password using the token from the reset email. The token works once; all sessions of the user are revoked.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Reset the password",
+                "parameters": [
+                    {
+                        "description": "Token from the email and the new password",
+                        "name": "reset",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PasswordReset"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/profile": {
+            "delete": {
+                "description": "Logs the user out everywhere and queues the account for deletion. Posts, pictures, likes and notifications are deleted in the background; the response is the deletion job.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Delete user account",
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.AccountDeletion"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/profile/2fa": {
+            "get": {
+                "description": "Returns whether two-factor authentication is enabled and how many recovery codes are left.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Two-factor authentication status",
+                "responses": {}
+            }
+        },
+        "/users/profile/2fa/confirm": {
+            "post": {
+                "description": "Enables two-factor authentication with a first code from the authenticator app and returns one-time recovery codes. The codes are shown only once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Confirm two-factor enrolment",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorCode"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/profile/2fa/disable": {
+            "post": {
+                "description": "Requires the current password and a code from the authenticator app or a recovery code. Users without a password (signed up through an identity provider) leave the password empty and must use a code from the authenticator app. Wrong passwords and codes count towards the login lockout.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Disable two-factor authentication",
+                "parameters": [
+                    {
+                        "description": "Password and code",
+                        "name": "reauth",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorReauth"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/profile/2fa/enroll": {
+            "post": {
+                "description": "Generates a TOTP secret and returns it with an otpauth:// URI for a QR code. Two-factor authentication is enabled only after confirmation with a first code.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Start two-factor enrolment",
+                "responses": {}
+            }
+        },
+        "/users/profile/2fa/recovery-codes": {
+            "post": {
+                "description": "Requires the current password and a code. Users without a password must use a code from the authenticator app. Previous recovery codes stop working. Wrong passwords and codes count towards the login lockout.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Regenerate recovery codes",
+                "parameters": [
+                    {
+                        "description": "Password and code",
+                        "name": "reauth",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorReauth"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/profile/avatar": {
+            "post": {
+                "description": "Makes 32, 64 and 256 pixel square avatars from a PNG, JPEG or GIF file of at most 10 MB. The crop rectangle is in pixels of the file; without it the largest centered square is used, and a non-square rectangle is narrowed to its centered square. The previous avatar is deleted.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Upload an avatar",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Image file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Crop left edge",
+                        "name": "x",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Crop top edge",
+                        "name": "y",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Crop width",
+                        "name": "width",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Crop height",
+                        "name": "height",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AvatarURLs"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Remove the avatar",
+                "responses": {}
+            }
+        },
+        "/users/profile/email/verify": {
+            "post": {
+                "description": "Sends a new verification link to the email of the current user. Earlier links stop working.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Resend verification email",
+                "responses": {}
+            }
+        },
+        "/users/profile/identities": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "List linked identity providers",
+                "responses": {}
+            }
+        },
+        "/users/profile/identities/{identityID}": {
+            "delete": {
+                "description": "The last way to sign in cannot be unlinked: users without a password must keep one provider.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Unlink an identity provider",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Linked account ID",
+                        "name": "identityID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/profile/identities/{provider}/link": {
+            "post": {
+                "description": "Returns the provider URL to open. After sign-in at the provider, the account is linked to the current user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Link an identity provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/profile/me": {
+            "get": {
+                "description": "Returns the user profile based on the JWT token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get user profile",
+                "responses": {}
+            }
+        },
+        "/users/profile/password": {
+            "patch": {
+                "description": "Allows an authenticated user to change their password to one of at least 8 characters. All sessions are revoked, so the user has to log in again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Change user password",
+                "parameters": [
+                    {
+                        "description": "New password request body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.passwordReqChange"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/profile/profile_picture": {
+            "post": {
+                "description": "Makes 32, 64 and 256 pixel square avatars from a picture the user uploaded earlier. The crop rectangle is in pixels of the picture; without it the largest centered square is used, and a non-square rectangle is narrowed to its centered square. The previous avatar is deleted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Use a picture as the avatar",
+                "parameters": [
+                    {
+                        "description": "Picture storage key and crop",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AvatarFromPicture"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AvatarURLs"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/profile/sessions": {
+            "get": {
+                "description": "Returns active sessions of the authenticated user with device, IP and last use time. The session of the request is marked as current.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "List active sessions",
+                "responses": {}
+            },
+            "delete": {
+                "description": "Logs the user out on all devices, including the current one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Revoke all sessions",
+                "responses": {}
+            }
+        },
+        "/users/profile/sessions/{sessionID}": {
+            "delete": {
+                "description": "Logs the user out on the device of the session. Access tokens of the session stop working immediately.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Revoke a session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/profile/tokens": {
+            "get": {
+                "description": "Returns active tokens with scopes, expiry and last use time. Token values are never returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "List personal access tokens",
+                "responses": {}
+            },
+            "post": {
+                "description": "Creates a named token for scripts and CLI clients, sent as \"Authorization: Bearer \u003ctoken\u003e\". Available scopes: images:read, images:write, posts:read, posts:write, profile:read. The token is returned only once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Create a personal access token",
+                "parameters": [
+                    {
+                        "description": "Token name, scopes and lifetime in days (0 - no expiry)",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.APITokenCreate"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/profile/tokens/{tokenID}": {
+            "delete": {
+                "description": "The token stops working immediately.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Revoke a personal access token",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Token ID",
+                        "name": "tokenID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/profile/username": {
+            "patch": {
+                "description": "This endpoint allows the user to change their username. The new username is passed in the body of the request.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Change the username of the authenticated user",
+                "parameters": [
+                    {
+                        "description": "New Username",
+                        "name": "username",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.usernameReqChange"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/register": {
+            "post": {
+                "description": "This endpoint registers a new user, stores the user in the database, and generates a JWT token for the user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User data for registration",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserRegister"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/users/token/refresh": {
+            "post": {
+                "description": "Exchanges the refresh token cookie for a new access token and a new refresh token. A refresh token can be used only once; reusing it revokes the whole session.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Refresh the access token",
+                "responses": {}
+            }
+        }
+    },
+    "definitions": {
+        "handler.passwordReqChange": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.usernameReqChange": {
+            "type": "object",
+            "properties": {
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.APITokenCreate": {
+            "type": "object",
+            "properties": {
+                "expires_in_days": {
+                    "description": "0 - бессрочный токен",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.AccountDeletion": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "images_deleted": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "likes_deleted": {
+                    "type": "integer"
+                },
+                "posts_deleted": {
+                    "type": "integer"
+                },
+                "requested_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "step": {
+                    "description": "Step - следующий шаг, у завершенной задачи пустой",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
         },
-        "handler.usernameReqChange": {
+        "models.AdminUser": {
+            "type": "object",
+            "properties": {
+                "ban_reason": {
+                    "type": "string"
+                },
+                "banned_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "email_verified": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "report_id": {
+                    "type": "integer"
+                },
+                "target_id": {
+                    "type": "string"
+                },
+                "target_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AvatarCrop": {
+            "type": "object",
+            "properties": {
+                "height": {
+                    "type": "integer"
+                },
+                "width": {
+                    "type": "integer"
+                },
+                "x": {
+                    "type": "integer"
+                },
+                "y": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AvatarFromPicture": {
+            "type": "object",
+            "properties": {
+                "crop": {
+                    "$ref": "#/definitions/models.AvatarCrop"
+                },
+                "picture_sk": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AvatarURLs": {
+            "type": "object",
+            "additionalProperties": {
+                "type": "string"
+            }
+        },
+        "models.DataExport": {
+            "type": "object",
+            "properties": {
+                "download_url": {
+                    "description": "DownloadURL - временная ссылка на архив, выдается только готовой выгрузке",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ready_at": {
+                    "type": "string"
+                },
+                "requested_at": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EmailVerify": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PasswordForgot": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PasswordReset": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PostRegister": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ProfilePost": {
+            "type": "object",
+            "properties": {
+                "cover": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "likes_count": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "post_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ProfileUpdate": {
+            "type": "object",
+            "properties": {
+                "bio": {
+                    "type": "string"
+                },
+                "links": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.PublicProfile": {
+            "type": "object",
+            "properties": {
+                "avatar": {
+                    "type": "string"
+                },
+                "avatars": {
+                    "$ref": "#/definitions/models.AvatarURLs"
+                },
+                "bio": {
+                    "type": "string"
+                },
+                "followers_count": {
+                    "type": "integer"
+                },
+                "following_count": {
+                    "type": "integer"
+                },
+                "joined_at": {
+                    "type": "string"
+                },
+                "likes_count": {
+                    "type": "integer"
+                },
+                "links": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "posts_count": {
+                    "type": "integer"
+                },
+                "recent_posts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ProfilePost"
+                    }
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Report": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "reporter_id": {
+                    "type": "integer"
+                },
+                "resolution": {
+                    "type": "string"
+                },
+                "resolved_at": {
+                    "type": "string"
+                },
+                "resolved_by": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "target_id": {
+                    "type": "string"
+                },
+                "target_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ReportCreate": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "target_id": {
+                    "type": "string"
+                },
+                "target_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ReportResolve": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TrashItem": {
+            "type": "object",
+            "properties": {
+                "deleted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID - id поста или ключ картинки в хранилище",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "название поста или описание картинки",
+                    "type": "string"
+                },
+                "purge_at": {
+                    "description": "PurgeAt - когда элемент будет удален навсегда",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "post или image",
+                    "type": "string"
+                }
+            }
+        },
+        "models.TwoFactorCode": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TwoFactorLogin": {
+            "type": "object",
+            "properties": {
+                "challenge": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TwoFactorReauth": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserBan": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserLogin": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserRegister": {
             "type": "object",
             "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
                 "username": {
                     "type": "string"
                 }
             }
+        },
+        "models.UserRoleUpdate": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
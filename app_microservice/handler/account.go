@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"time"
+)
+
+// VerifyEmail confirms the email address of a user
+// @Summary Verify email
+// @Description Confirms the email address with the token from the verification email. Uploading images and publishing posts require a verified email.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param token body models.EmailVerify true "Token from the email"
+// @Router /users/email/verify [post]
+func (server *Server) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.EmailVerify
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := server.account.VerifyEmail(ctx, req.Token)
+	if errors.Is(err, service.ErrInvalidUserToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Email verified"}`))
+}
+
+// ResendVerification sends the verification email again
+// @Summary Resend verification email
+// @Description Sends a new verification link to the email of the current user. Earlier links stop working.
+// @Tags User
+// @Produce json
+// @Router /users/profile/email/verify [post]
+func (server *Server) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := server.account.SendVerification(ctx, userID)
+	if errors.Is(err, service.ErrEmailAlreadyVerified) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Verification email sent"}`))
+}
+
+// ForgotPassword starts the password reset
+// @Summary Request a password reset
+// @Description Sends a single-use password reset link if the email belongs to an account. The response is the same for unknown emails.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param email body models.PasswordForgot true "Account email"
+// @Router /users/password/forgot [post]
+func (server *Server) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordForgot
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := server.account.RequestPasswordReset(ctx, req.Email); err != nil {
+		http.Error(w, "Failed to request password reset", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"message":"If the email is registered, a reset link has been sent"}`))
+}
+
+// ResetPassword sets a new password with a reset token
+// @Summary Reset the password
+// @Description Sets a new password using the token from the reset email. The token works once; all sessions of the user are revoked.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param reset body models.PasswordReset true "Token from the email and the new password"
+// @Router /users/password/reset [post]
+func (server *Server) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordReset
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := server.account.ResetPassword(ctx, req.Token, req.Password)
+	if errors.Is(err, service.ErrInvalidUserToken) || errors.Is(err, service.ErrWeakPassword) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	clearAuthCookies(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Password has been reset"}`))
+}
@@ -0,0 +1,352 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/safety/jwtutils"
+	"pictureloader/app_microservice/service"
+	"strconv"
+	"time"
+)
+
+type AdminServer struct {
+	guard      *service.LoginGuard
+	admin      *service.AdminService
+	moderation *service.ModerationService
+	deletion   *service.AccountDeletionService
+}
+
+func NewAdminServer(guard *service.LoginGuard, admin *service.AdminService, moderation *service.ModerationService,
+	deletion *service.AccountDeletionService) *AdminServer {
+	return &AdminServer{guard: guard, admin: admin, moderation: moderation, deletion: deletion}
+}
+
+// AdminRouter - ручки модераторов и администраторов. Каждая ручка требует право,
+// которое дает роль из токена сессии.
+func AdminRouter(api *mux.Router, server *AdminServer, jwtUtils *jwtutils.UtilsJWT) {
+	router := api.PathPrefix("/admin").Subrouter()
+	router.Handle("/users", jwtUtils.RequirePermission(models.PermViewUsers, server.ListUsers)).Methods("GET")
+	router.Handle("/users/{userID:[0-9]+}/ban", jwtUtils.RequirePermission(models.PermBanUsers, server.BanUser)).Methods("POST")
+	router.Handle("/users/{userID:[0-9]+}/unban", jwtUtils.RequirePermission(models.PermBanUsers, server.UnbanUser)).Methods("POST")
+	router.Handle("/users/{userID:[0-9]+}/role", jwtUtils.RequirePermission(models.PermManageRoles, server.SetUserRole)).Methods("PUT")
+	router.Handle("/users/{username}/unlock", jwtUtils.RequirePermission(models.PermManageLogins, server.UnlockUser)).Methods("POST")
+	router.Handle("/users/{username}/security-events", jwtUtils.RequirePermission(models.PermManageLogins, server.GetSecurityEvents)).Methods("GET")
+	router.Handle("/deletions", jwtUtils.RequirePermission(models.PermViewAudit, server.ListDeletions)).Methods("GET")
+	router.Handle("/posts/{postID:[0-9]+}", jwtUtils.RequirePermission(models.PermModerateContent, server.DeletePost)).Methods("DELETE")
+	router.Handle("/pictures/{imageSK}", jwtUtils.RequirePermission(models.PermModerateContent, server.DeletePicture)).Methods("DELETE")
+	router.Handle("/posts/{postID:[0-9]+}/unhide", jwtUtils.RequirePermission(models.PermModerateContent, server.UnhidePost)).Methods("POST")
+	router.Handle("/pictures/{imageSK}/unhide", jwtUtils.RequirePermission(models.PermModerateContent, server.UnhidePicture)).Methods("POST")
+	router.Use(jwtUtils.AuthMiddleware)
+}
+
+func adminError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidRole), errors.Is(err, service.ErrSelfModeration):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrForbiddenUser):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, "Failed to manage user", http.StatusInternalServerError)
+	}
+	return true
+}
+
+// actorFromRequest - кто выполняет действие, по claims запроса
+func actorFromRequest(r *http.Request) service.Actor {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	return service.Actor{UserID: int(sub), Role: jwtutils.Role(r)}
+}
+
+// ListUsers lists users
+// @Summary List users
+// @Description Moderators and admins. Searches by a substring of the username or email, optionally filtered by role.
+// @Tags Admin
+// @Produce json
+// @Param query query string false "Username or email substring"
+// @Param role query string false "Role: user, moderator or admin"
+// @Param limit query int false "Page size, 50 by default, at most 100"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.AdminUser
+// @Router /admin/users [get]
+func (server *AdminServer) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	users, err := server.admin.ListUsers(ctx, models.UserFilter{
+		Query:  query.Get("query"),
+		Role:   query.Get("role"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if adminError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+// BanUser bans a user
+// @Summary Ban a user
+// @Description Moderators and admins. The user is logged out everywhere and can't log in until unbanned. Moderators can ban only users with the user role.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param userID path int true "User ID"
+// @Param ban body models.UserBan false "Reason"
+// @Router /admin/users/{userID}/ban [post]
+func (server *AdminServer) BanUser(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["userID"])
+
+	// причина необязательна, тело можно не передавать
+	var req models.UserBan
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if adminError(w, server.admin.BanUser(ctx, actorFromRequest(r), userID, req.Reason)) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"User banned"}`))
+}
+
+// UnbanUser unbans a user
+// @Summary Unban a user
+// @Description Moderators and admins. Moderators can unban only users with the user role.
+// @Tags Admin
+// @Produce json
+// @Param userID path int true "User ID"
+// @Router /admin/users/{userID}/unban [post]
+func (server *AdminServer) UnbanUser(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["userID"])
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if adminError(w, server.admin.UnbanUser(ctx, actorFromRequest(r), userID)) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"User unbanned"}`))
+}
+
+// SetUserRole changes the role of a user
+// @Summary Change a user's role
+// @Description Admin only. A demoted user is logged out everywhere, a promoted one gets the new role on the next token refresh.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param userID path int true "User ID"
+// @Param role body models.UserRoleUpdate true "Role: user, moderator or admin"
+// @Router /admin/users/{userID}/role [put]
+func (server *AdminServer) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["userID"])
+
+	var req models.UserRoleUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if adminError(w, server.admin.SetRole(ctx, actorFromRequest(r), userID, req.Role)) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Role updated"}`))
+}
+
+// ListDeletions lists account deletion jobs
+// @Summary Account deletions
+// @Description Admin only. Account deletion jobs, newest first. A completed job confirms that the user's data was erased.
+// @Tags Admin
+// @Produce json
+// @Param user_id query int false "User ID"
+// @Param status query string false "pending or completed"
+// @Param limit query int false "Page size, 50 by default, at most 100"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.AccountDeletion
+// @Router /admin/deletions [get]
+func (server *AdminServer) ListDeletions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID, _ := strconv.Atoi(query.Get("user_id"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	deletions, err := server.deletion.ListDeletions(ctx, models.DeletionFilter{
+		UserID: userID,
+		Status: query.Get("status"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if adminError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deletions)
+}
+
+// DeletePost deletes any post
+// @Summary Delete any post
+// @Description Moderators and admins. Deletes a post regardless of its owner and closes open reports on it.
+// @Tags Admin
+// @Produce json
+// @Param postID path int true "Post ID"
+// @Router /admin/posts/{postID} [delete]
+func (server *AdminServer) DeletePost(w http.ResponseWriter, r *http.Request) {
+	postID := mux.Vars(r)["postID"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := server.moderation.RemoveContent(ctx, actorFromRequest(r), models.ReportTargetPost, postID)
+	if moderationError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Post deleted"}`))
+}
+
+// DeletePicture deletes any picture
+// @Summary Delete any picture
+// @Description Moderators and admins. Deletes a picture regardless of its owner and closes open reports on it.
+// @Tags Admin
+// @Produce json
+// @Param imageSK path string true "Image storage key"
+// @Router /admin/pictures/{imageSK} [delete]
+func (server *AdminServer) DeletePicture(w http.ResponseWriter, r *http.Request) {
+	imageSK := mux.Vars(r)["imageSK"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := server.moderation.RemoveContent(ctx, actorFromRequest(r), models.ReportTargetImage, imageSK)
+	if moderationError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Picture deleted"}`))
+}
+
+// UnhidePost restores a hidden post
+// @Summary Unhide a post
+// @Description Moderators and admins. Returns a post hidden by moderators to feeds, dismisses open reports on it and notifies the owner.
+// @Tags Admin
+// @Produce json
+// @Param postID path int true "Post ID"
+// @Router /admin/posts/{postID}/unhide [post]
+func (server *AdminServer) UnhidePost(w http.ResponseWriter, r *http.Request) {
+	postID := mux.Vars(r)["postID"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := server.moderation.RestoreContent(ctx, actorFromRequest(r), models.ReportTargetPost, postID)
+	if moderationError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Post restored"}`))
+}
+
+// UnhidePicture restores a hidden picture
+// @Summary Unhide a picture
+// @Description Moderators and admins. Returns a picture hidden by moderators to posts, dismisses open reports on it and notifies the owner.
+// @Tags Admin
+// @Produce json
+// @Param imageSK path string true "Image storage key"
+// @Router /admin/pictures/{imageSK}/unhide [post]
+func (server *AdminServer) UnhidePicture(w http.ResponseWriter, r *http.Request) {
+	imageSK := mux.Vars(r)["imageSK"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := server.moderation.RestoreContent(ctx, actorFromRequest(r), models.ReportTargetImage, imageSK)
+	if moderationError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Picture restored"}`))
+}
+
+// UnlockUser removes a login lockout
+// @Summary Unlock a user's login
+// @Description Admin only. Clears failed login attempts and the lockout of the username, and the lockouts of IP addresses with recent failed logins to it.
+// @Tags Admin
+// @Produce json
+// @Param username path string true "Username"
+// @Router /admin/users/{username}/unlock [post]
+func (server *AdminServer) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	adminID := actorFromRequest(r).UserID
+	username := mux.Vars(r)["username"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := server.guard.Unlock(ctx, username, "admin "+strconv.Itoa(adminID), deviceFromRequest(r))
+	if err != nil {
+		http.Error(w, "Failed to unlock user", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"User unlocked"}`))
+}
+
+// GetSecurityEvents lists security events of a username
+// @Summary Security events of a user
+// @Description Admin only. Returns the last 100 failed logins, lockouts and unlocks for the username.
+// @Tags Admin
+// @Produce json
+// @Param username path string true "Username"
+// @Router /admin/users/{username}/security-events [get]
+func (server *AdminServer) GetSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	events, err := server.guard.SecurityEvents(ctx, username)
+	if err != nil {
+		http.Error(w, "Failed to get security events", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
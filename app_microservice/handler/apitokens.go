@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"net/http"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"strconv"
+	"time"
+)
+
+// CreateAPIToken creates a personal access token
+// @Summary Create a personal access token
+// @Description Creates a named token for scripts and CLI clients, sent as "Authorization: Bearer <token>". Available scopes: images:read, images:write, posts:read, posts:write, profile:read. The token is returned only once.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param token body models.APITokenCreate true "Token name, scopes and lifetime in days (0 - no expiry)"
+// @Router /users/profile/tokens [post]
+func (server *Server) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	var req models.APITokenCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	token, err := server.apiTokens.CreateToken(ctx, userID, req)
+	var validationErr *service.APITokenValidationError
+	if errors.As(err, &validationErr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+// GetAPITokens lists personal access tokens of the current user
+// @Summary List personal access tokens
+// @Description Returns active tokens with scopes, expiry and last use time. Token values are never returned.
+// @Tags User
+// @Produce json
+// @Router /users/profile/tokens [get]
+func (server *Server) GetAPITokens(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tokens, err := server.apiTokens.ListTokens(ctx, userID)
+	if err != nil {
+		http.Error(w, "Failed to get tokens", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// RevokeAPIToken revokes a personal access token
+// @Summary Revoke a personal access token
+// @Description The token stops working immediately.
+// @Tags User
+// @Produce json
+// @Param tokenID path int true "Token ID"
+// @Router /users/profile/tokens/{tokenID} [delete]
+func (server *Server) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+	tokenID, err := strconv.Atoi(mux.Vars(r)["tokenID"])
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err = server.apiTokens.RevokeToken(ctx, userID, tokenID)
+	if errors.Is(err, service.ErrAPITokenNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Token revoked"}`))
+}
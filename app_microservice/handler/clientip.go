@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"github.com/go-chi/httprate"
+	"github.com/gorilla/mux"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// RealIP подставляет в RemoteAddr адрес клиента из X-Forwarded-For, если сервис стоит за доверенным прокси.
+// Берется последний адрес: его дописал наш прокси, остальные клиент мог прислать сам.
+// Без прокси заголовок не читается, иначе его подменой можно обойти блокировки по IP.
+func RealIP(trustProxy bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if !trustProxy {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			forwarded := r.Header.Get("X-Forwarded-For")
+			if forwarded != "" {
+				hops := strings.Split(forwarded, ",")
+				if addr, err := netip.ParseAddr(strings.TrimSpace(hops[len(hops)-1])); err == nil {
+					r.RemoteAddr = netip.AddrPortFrom(addr, 0).String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP - адрес клиента, по нему считаются и лимиты запросов, и неудачные входы
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap().String()
+	}
+	return host
+}
+
+// limitByClientIP ограничивает число запросов с одного адреса clientIP за window
+func limitByClientIP(requestLimit int, window time.Duration) func(http.Handler) http.Handler {
+	return httprate.Limit(requestLimit, window, httprate.WithKeyFuncs(func(r *http.Request) (string, error) {
+		return clientIP(r), nil
+	}))
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	jwt2 "github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"net/http"
+	"pictureloader/app_microservice/safety/jwtutils"
+	"pictureloader/app_microservice/service"
+	"time"
+)
+
+type ExportServer struct {
+	exports *service.ExportService
+}
+
+func NewExportServer(exports *service.ExportService) *ExportServer {
+	return &ExportServer{exports: exports}
+}
+
+// ExportRouter - выгрузка всех данных пользователя
+func ExportRouter(api *mux.Router, server *ExportServer, jwtUtils *jwtutils.UtilsJWT) {
+	router := api.PathPrefix("/exports").Subrouter()
+	// выгрузка отдает все данные аккаунта, поэтому доступна только из сессии
+	router.Handle("", jwtUtils.RequireSession(server.RequestExport)).Methods("POST")
+	router.Handle("", jwtUtils.RequireSession(server.ListExports)).Methods("GET")
+	router.Use(jwtUtils.AuthMiddleware)
+	router.Use(limitByClientIP(10, time.Minute))
+}
+
+// RequestExport queues a data export
+// @Summary Request a data export
+// @Description Queues a zip archive with the profile, posts, original images, likes given and received, and notifications. The archive is built in the background; the user is notified when the download link is ready. While an export is pending or downloadable, it is returned instead of a new one.
+// @Tags Exports
+// @Produce json
+// @Success 202 {object} models.DataExport
+// @Router /exports [post]
+func (server *ExportServer) RequestExport(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	export, err := server.exports.RequestExport(ctx, userID)
+	if err != nil {
+		http.Error(w, "Failed to request export", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(export)
+}
+
+// ListExports lists the user's data exports
+// @Summary List data exports
+// @Description Exports of the user, newest first. Ready exports carry a download link that expires with the archive.
+// @Tags Exports
+// @Produce json
+// @Success 200 {array} models.DataExport
+// @Router /exports [get]
+func (server *ExportServer) ListExports(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	exports, err := server.exports.ListExports(ctx, userID)
+	if err != nil {
+		http.Error(w, "Failed to get exports", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(exports)
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"net/http"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/safety/jwtutils"
+	"pictureloader/app_microservice/service"
+	"strconv"
+	"time"
+)
+
+type ModerationServer struct {
+	moderation *service.ModerationService
+}
+
+func NewModerationServer(moderation *service.ModerationService) *ModerationServer {
+	return &ModerationServer{moderation: moderation}
+}
+
+// ModerationRouter - жалобы пользователей и очередь модерации
+func ModerationRouter(api *mux.Router, server *ModerationServer, jwtUtils *jwtutils.UtilsJWT) {
+	reports := api.PathPrefix("/reports").Subrouter()
+	// жалобы подаются только из сессии, персональные токены права на них не дают
+	reports.Handle("", jwtUtils.RequireSession(server.CreateReport)).Methods("POST")
+	reports.Use(jwtUtils.AuthMiddleware)
+	reports.Use(limitByClientIP(10, time.Minute))
+
+	router := api.PathPrefix("/moderation").Subrouter()
+	router.Handle("/reports", jwtUtils.RequirePermission(models.PermModerateContent, server.ListReports)).Methods("GET")
+	router.Handle("/reports/{reportID:[0-9]+}", jwtUtils.RequirePermission(models.PermModerateContent, server.GetReport)).Methods("GET")
+	router.Handle("/reports/{reportID:[0-9]+}/resolve", jwtUtils.RequirePermission(models.PermModerateContent, server.ResolveReport)).Methods("POST")
+	router.Handle("/audit", jwtUtils.RequirePermission(models.PermViewAudit, server.AuditLog)).Methods("GET")
+	router.Use(jwtUtils.AuthMiddleware)
+}
+
+func moderationError(w http.ResponseWriter, err error) bool {
+	var validationErr *service.ReportValidationError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &validationErr), errors.Is(err, service.ErrInvalidModerationAction):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrReportTargetNotFound), errors.Is(err, service.ErrReportNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrDuplicateReport), errors.Is(err, service.ErrReportResolved):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrForbiddenUser), errors.Is(err, service.ErrSelfModeration):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, "Moderation failed", http.StatusInternalServerError)
+	}
+	return true
+}
+
+// CreateReport reports content
+// @Summary Report a post, image or user
+// @Description Flags content for moderators. target_id is the post ID, the image storage key or the user ID. Reasons: spam, abuse, nudity, violence, copyright, other.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Param report body models.ReportCreate true "Report"
+// @Success 201 {object} models.Report
+// @Router /reports [post]
+func (server *ModerationServer) CreateReport(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	var req models.ReportCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	report, err := server.moderation.Report(ctx, userID, req)
+	if moderationError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// ListReports returns the moderation queue
+// @Summary Moderation queue
+// @Description Moderators and admins. Reports, oldest first.
+// @Tags Moderation
+// @Produce json
+// @Param status query string false "open, actioned or dismissed"
+// @Param target_type query string false "post, image or user"
+// @Param reason query string false "Reason"
+// @Param limit query int false "Page size, 50 by default, at most 100"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.Report
+// @Router /moderation/reports [get]
+func (server *ModerationServer) ListReports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	reports, err := server.moderation.ListReports(ctx, models.ReportFilter{
+		Status:     query.Get("status"),
+		TargetType: query.Get("target_type"),
+		Reason:     query.Get("reason"),
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if moderationError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reports)
+}
+
+// GetReport returns a report
+// @Summary Get a report
+// @Description Moderators and admins.
+// @Tags Moderation
+// @Produce json
+// @Param reportID path int true "Report ID"
+// @Success 200 {object} models.Report
+// @Router /moderation/reports/{reportID} [get]
+func (server *ModerationServer) GetReport(w http.ResponseWriter, r *http.Request) {
+	reportID, _ := strconv.Atoi(mux.Vars(r)["reportID"])
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	report, err := server.moderation.GetReport(ctx, reportID)
+	if moderationError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// ResolveReport applies a moderation decision
+// @Summary Resolve a report
+// @Description Moderators and admins. Posts and images can be hidden, unhidden or removed, users banned; any report can be dismissed. The decision closes all open reports on the same content and notifies the reporters and the owner.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Param reportID path int true "Report ID"
+// @Param decision body models.ReportResolve true "Action: hide, unhide, remove, ban or dismiss"
+// @Router /moderation/reports/{reportID}/resolve [post]
+func (server *ModerationServer) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	reportID, _ := strconv.Atoi(mux.Vars(r)["reportID"])
+
+	var req models.ReportResolve
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if moderationError(w, server.moderation.Resolve(ctx, actorFromRequest(r), reportID, req)) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Report resolved"}`))
+}
+
+// AuditLog returns the moderation audit log
+// @Summary Audit log
+// @Description Admin only. Actions of moderators and admins, newest first.
+// @Tags Moderation
+// @Produce json
+// @Param actor_id query int false "Moderator or admin ID"
+// @Param target_type query string false "post, image or user"
+// @Param target_id query string false "Target ID"
+// @Param limit query int false "Page size, 50 by default, at most 100"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.AuditEntry
+// @Router /moderation/audit [get]
+func (server *ModerationServer) AuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	actorID, _ := strconv.Atoi(query.Get("actor_id"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	entries, err := server.moderation.AuditLog(ctx, models.AuditFilter{
+		ActorID:    actorID,
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if moderationError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"net/http"
+	"pictureloader/app_microservice/service"
+	"strconv"
+	"time"
+)
+
+// oidcStateCookie привязывает ответ провайдера к браузеру, который начал вход
+const oidcStateCookie = "oidc-state"
+
+func setOIDCStateCookie(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		HttpOnly: true,
+		Secure:   false,                // HTTP & HTTPS
+		SameSite: http.SameSiteLaxMode, // кука нужна в редиректе от провайдера
+		Path:     "/users/oidc/callback",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+	})
+}
+
+// oidcError переводит ошибки входа через провайдера в ответ, false - ошибки нет
+func oidcError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrUnknownProvider), errors.Is(err, service.ErrIdentityNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidOIDCState), errors.Is(err, service.ErrOIDCAuthentication),
+		errors.Is(err, service.ErrOIDCEmailMissing):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrIdentityLinked), errors.Is(err, service.ErrOIDCEmailTaken),
+		errors.Is(err, service.ErrLastLoginMethod):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrUserBanned), errors.Is(err, service.ErrPendingDeletion):
+		http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, "Sign-in with identity provider failed", http.StatusInternalServerError)
+	}
+	return true
+}
+
+// GetOIDCProviders lists identity providers
+// @Summary List identity providers
+// @Description Returns OpenID Connect providers available for "Sign in with ...".
+// @Tags User
+// @Produce json
+// @Router /users/oidc/providers [get]
+func (server *Server) GetOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(server.oidc.Providers())
+}
+
+// OIDCLogin starts a sign-in with an identity provider
+// @Summary Sign in with an identity provider
+// @Description Redirects to the provider (authorization code flow with PKCE). After sign-in the provider redirects back to /users/oidc/callback.
+// @Tags User
+// @Param provider path string true "Provider name"
+// @Router /users/oidc/{provider}/login [get]
+func (server *Server) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	authURL, state, err := server.oidc.Begin(ctx, mux.Vars(r)["provider"], 0)
+	if oidcError(w, err) {
+		return
+	}
+	setOIDCStateCookie(w, state)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback completes a sign-in or account linking
+// @Summary Identity provider callback
+// @Description Completes the sign-in started by /users/oidc/{provider}/login. The first sign-in creates a user with a generated username. If two-factor authentication is enabled, the response contains a challenge for /users/login/2fa.
+// @Tags User
+// @Produce json
+// @Param state query string true "State"
+// @Param code query string true "Authorization code"
+// @Router /users/oidc/callback [get]
+func (server *Server) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		http.Error(w, "Identity provider returned an error: "+providerErr, http.StatusBadRequest)
+		return
+	}
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || cookie.Value != query.Get("state") {
+		http.Error(w, service.ErrInvalidOIDCState.Error(), http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/users/oidc/callback", MaxAge: -1})
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	userID, linking, err := server.oidc.Complete(ctx, query.Get("state"), query.Get("code"))
+	if oidcError(w, err) {
+		return
+	}
+	if linking {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message":"Account linked"}`))
+		return
+	}
+	server.completeLogin(ctx, w, r, userID, "")
+}
+
+// GetIdentities lists linked provider accounts
+// @Summary List linked identity providers
+// @Tags User
+// @Produce json
+// @Router /users/profile/identities [get]
+func (server *Server) GetIdentities(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	identities, err := server.oidc.ListIdentities(ctx, userID)
+	if oidcError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(identities)
+}
+
+// LinkIdentity starts linking a provider account
+// @Summary Link an identity provider
+// @Description Returns the provider URL to open. After sign-in at the provider, the account is linked to the current user.
+// @Tags User
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Router /users/profile/identities/{provider}/link [post]
+func (server *Server) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	authURL, state, err := server.oidc.Begin(ctx, mux.Vars(r)["provider"], userID)
+	if oidcError(w, err) {
+		return
+	}
+	setOIDCStateCookie(w, state)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"url": authURL})
+}
+
+// UnlinkIdentity unlinks a provider account
+// @Summary Unlink an identity provider
+// @Description The last way to sign in cannot be unlinked: users without a password must keep one provider.
+// @Tags User
+// @Produce json
+// @Param identityID path int true "Linked account ID"
+// @Router /users/profile/identities/{identityID} [delete]
+func (server *Server) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+	identityID, err := strconv.Atoi(mux.Vars(r)["identityID"])
+	if err != nil {
+		http.Error(w, "Invalid identity ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if oidcError(w, server.oidc.Unlink(ctx, userID, identityID)) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Account unlinked"}`))
+}
@@ -24,14 +24,15 @@ func PictureNewServer(core *service.PictureLoader) *PictureServer {
 	return &PictureServer{core: core}
 }
 
-func PictureRouter(api *mux.Router, server *PictureServer) {
-	jwtUtils := jwtutils.UtilsJWT{}
+func PictureRouter(api *mux.Router, server *PictureServer, jwtUtils *jwtutils.UtilsJWT) {
 	router := api.PathPrefix("/pictures").Subrouter()
 
 	privateRouter := router.PathPrefix("").Subrouter()
-	privateRouter.HandleFunc("/create", server.UploadImageHandler).Methods("POST")
-	privateRouter.HandleFunc("/my", server.MyPictures).Methods("GET")
-	privateRouter.HandleFunc("/{imageURL}", server.DeleteImageHadler).Methods("DELETE")
+	// загружать картинки можно только с подтвержденной почтой
+	privateRouter.Handle("/create", jwtUtils.RequireVerifiedEmail(
+		jwtUtils.RequireScope(models.ScopeImagesWrite, server.UploadImageHandler))).Methods("POST")
+	privateRouter.Handle("/my", jwtUtils.RequireScope(models.ScopeImagesRead, server.MyPictures)).Methods("GET")
+	privateRouter.Handle("/{imageURL}", jwtUtils.RequireScope(models.ScopeImagesWrite, server.DeleteImageHadler)).Methods("DELETE")
 	privateRouter.Use(jwtUtils.AuthMiddleware)
 
 	router.HandleFunc("/{imageURL}", server.DownloadFileHandler).Methods("GET")
@@ -145,7 +146,7 @@ func (s *PictureServer) MyPictures(w http.ResponseWriter, r *http.Request) {
 
 // DeleteImageHadler delete image
 // @Summary Delete an image
-// @Description Delete an image for its url
+// @Description Moves an image to the trash. It can be restored until it is purged after the retention period.
 // @Tags Image
 // @Accept json
 // @Produce json
@@ -3,7 +3,6 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"github.com/go-chi/httprate"
 	jwt2 "github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"net/http"
@@ -22,19 +21,29 @@ func NewPostServer(service service.PostService) *PostServer {
 	return &PostServer{service: service}
 }
 
-func PostRouter(api *mux.Router, server *PostServer) {
-	jwtUtils := jwtutils.UtilsJWT{}
+func PostRouter(api *mux.Router, server *PostServer, jwtUtils *jwtutils.UtilsJWT) {
 	router := api.PathPrefix("/posts").Subrouter()
-	router.HandleFunc("", server.CreatePostHandler).Methods("POST")
-	router.HandleFunc("/my", server.GetMyPosts).Methods("GET")
-	router.HandleFunc("/most-liked", server.GetMostLikedPosts).Methods("GET")
-	router.HandleFunc("/{postID}", server.GetPost).Methods("GET")
-	router.HandleFunc("/{postID}/like", server.LikePostHandler).Methods("POST")
-	router.HandleFunc("/{postID}/{imageSK}", server.AddImageToPost).Methods("POST")
-	router.HandleFunc("/{postID}", server.DeletePost).Methods("DELETE")
-	router.HandleFunc("/{postID}/{imageSK}", server.DeletePostImage).Methods("DELETE")
+	read := func(handler http.HandlerFunc) http.Handler {
+		return jwtUtils.RequireScope(models.ScopePostsRead, handler)
+	}
+	write := func(handler http.HandlerFunc) http.Handler {
+		return jwtUtils.RequireScope(models.ScopePostsWrite, handler)
+	}
+	// новый контент могут публиковать только пользователи с подтвержденной почтой
+	publish := func(handler http.HandlerFunc) http.Handler {
+		return jwtUtils.RequireVerifiedEmail(write(handler))
+	}
+	router.Handle("", publish(server.CreatePostHandler)).Methods("POST")
+	router.Handle("/my", read(server.GetMyPosts)).Methods("GET")
+	router.Handle("/most-liked", read(server.GetMostLikedPosts)).Methods("GET")
+	router.Handle("/{postID}", read(server.GetPost)).Methods("GET")
+	router.Handle("/{postID}/like", write(server.LikePostHandler)).Methods("POST")
+	router.Handle("/{postID}/like", write(server.UnlikePostHandler)).Methods("DELETE")
+	router.Handle("/{postID}/{imageSK}", publish(server.AddImageToPost)).Methods("POST")
+	router.Handle("/{postID}", write(server.DeletePost)).Methods("DELETE")
+	router.Handle("/{postID}/{imageSK}", write(server.DeletePostImage)).Methods("DELETE")
 	router.Use(jwtUtils.AuthMiddleware)
-	router.Use(httprate.LimitByRealIP(3, 3*time.Second))
+	router.Use(limitByClientIP(3, 3*time.Second))
 }
 
 // CreatePostHandler creates a new post for the user.
@@ -161,9 +170,9 @@ func (ps *PostServer) GetMyPosts(w http.ResponseWriter, r *http.Request) {
 	encoder.Encode(result)
 }
 
-// DeletePost removes a specific post and its images from the system.
+// DeletePost moves a post to the trash.
 // @Summary Delete a post
-// @Description Removes a specific post and all its images from the system.
+// @Description Moves a post to the trash. It can be restored until it is purged after the retention period; its pictures are not deleted.
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -259,6 +268,38 @@ func (ps *PostServer) LikePostHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status":"Post liked successfully"}`))
 }
 
+// UnlikePostHandler handles removing a like from a post.
+// @Summary     Unlike a post
+// @Description Removes the current user's like from a post and invalidates cache
+// @Tags        Posts
+// @Accept      json
+// @Produce     json
+// @Param       postID path int true "Post ID"
+// @Router      /posts/{postID}/like [delete]
+func (ps *PostServer) UnlikePostHandler(w http.ResponseWriter, r *http.Request) {
+	postIDstr := mux.Vars(r)["postID"]
+	postID, err := strconv.Atoi(postIDstr)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	sub := r.Context().Value("claims").(jwt2.MapClaims)["sub"]
+	userID := int(sub.(float64))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err = ps.service.UnlikePost(ctx, postID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"Post unliked successfully"}`))
+}
+
 // GetMostLikedPosts returns the most liked posts.
 // @Summary     Get most liked posts
 // @Description Returns a list of the most liked posts, ordered by like count in descending order.
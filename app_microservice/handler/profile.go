@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"net/http"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/safety/jwtutils"
+	"pictureloader/app_microservice/service"
+	"time"
+)
+
+type ProfileServer struct {
+	profiles *service.ProfileService
+}
+
+func NewProfileServer(profiles *service.ProfileService) *ProfileServer {
+	return &ProfileServer{profiles: profiles}
+}
+
+// ProfileRouter - публичные профили пользователей и подписки
+func ProfileRouter(api *mux.Router, server *ProfileServer, jwtUtils *jwtutils.UtilsJWT) {
+	// профиль открыт без входа, чтобы ссылкой на него можно было делиться
+	public := api.PathPrefix("/profiles").Subrouter()
+	public.HandleFunc("/{username}", server.GetProfile).Methods("GET")
+	public.Use(limitByClientIP(60, time.Minute))
+
+	router := api.PathPrefix("/profiles").Subrouter()
+	router.Handle("/me", jwtUtils.RequireSession(server.UpdateProfile)).Methods("PATCH")
+	// у персональных токенов нет права на изменение подписок
+	router.Handle("/{username}/follow", jwtUtils.RequireSession(server.Follow)).Methods("POST")
+	router.Handle("/{username}/follow", jwtUtils.RequireSession(server.Unfollow)).Methods("DELETE")
+	router.Use(jwtUtils.AuthMiddleware)
+}
+
+func profileError(w http.ResponseWriter, err error) bool {
+	var validationErr *service.ProfileValidationError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &validationErr), errors.Is(err, service.ErrSelfFollow):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "Profile operation failed", http.StatusInternalServerError)
+	}
+	return true
+}
+
+// GetProfile returns a public profile
+// @Summary Public user profile
+// @Description Bio, links, join date, avatar, follower, following, post and like counts and the latest posts of the user. Available without login; the email is never shown. Banned users and accounts being deleted are not found.
+// @Tags Profile
+// @Produce json
+// @Param username path string true "Username"
+// @Success 200 {object} models.PublicProfile
+// @Router /profiles/{username} [get]
+func (server *ProfileServer) GetProfile(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	profile, err := server.profiles.GetPublicProfile(ctx, mux.Vars(r)["username"])
+	if profileError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+// UpdateProfile edits the public profile
+// @Summary Edit the public profile
+// @Description Omitted fields are left unchanged. The bio is at most 300 characters; up to 5 absolute http or https links of at most 200 characters.
+// @Tags Profile
+// @Accept json
+// @Produce json
+// @Param profile body models.ProfileUpdate true "Profile fields"
+// @Router /profiles/me [patch]
+func (server *ProfileServer) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	var req models.ProfileUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if profileError(w, server.profiles.UpdateProfile(ctx, userID, req)) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Profile updated"}`))
+}
+
+// Follow follows a user
+// @Summary Follow a user
+// @Description Following a user twice has no effect.
+// @Tags Profile
+// @Produce json
+// @Param username path string true "Username"
+// @Router /profiles/{username}/follow [post]
+func (server *ProfileServer) Follow(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if profileError(w, server.profiles.Follow(ctx, userID, mux.Vars(r)["username"])) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Followed"}`))
+}
+
+// Unfollow unfollows a user
+// @Summary Unfollow a user
+// @Tags Profile
+// @Produce json
+// @Param username path string true "Username"
+// @Router /profiles/{username}/follow [delete]
+func (server *ProfileServer) Unfollow(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if profileError(w, server.profiles.Unfollow(ctx, userID, mux.Vars(r)["username"])) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Unfollowed"}`))
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"net/http"
+	"pictureloader/app_microservice/safety/jwtutils"
+	"pictureloader/app_microservice/service"
+	"pictureloader/common/jwtauth"
+	"time"
+)
+
+// refreshCookieName - кука с refresh токеном, отправляется только в ручки /users
+const refreshCookieName = "refresh-token"
+
+// startSession создает сессию и выставляет куки с токенами, false - ответ с ошибкой уже записан
+func (server *Server) startSession(ctx context.Context, w http.ResponseWriter, r *http.Request, userID int) bool {
+	tokens, err := server.sessions.StartSession(ctx, userID, deviceFromRequest(r))
+	if errors.Is(err, service.ErrUserBanned) {
+		http.Error(w, "Forbidden: account is banned", http.StatusForbidden)
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return false
+	}
+	setAuthCookies(w, tokens)
+	return true
+}
+
+// completeLogin завершает вход после проверки пароля или провайдера. С включенной 2FA
+// сессия не создается: в ответе челлендж для второго шага. username передается после входа по паролю:
+// счетчик неудач по имени сбрасывается, только когда пройдены все шаги, с 2FA - в CompleteChallenge.
+func (server *Server) completeLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, userID int, username string) {
+	twoFactor, err := server.twoFactor.Enabled(ctx, userID)
+	if err != nil {
+		http.Error(w, "Failed to check two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if twoFactor {
+		challenge, err := server.twoFactor.StartChallenge(ctx, userID)
+		if err != nil {
+			http.Error(w, "Failed to start two-factor login", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]any{"two_factor_required": true, "challenge": challenge})
+		return
+	}
+	if username != "" {
+		server.guard.Succeed(ctx, username)
+	}
+	if !server.startSession(ctx, w, r, userID) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Login successful"}`))
+}
+
+func deviceFromRequest(r *http.Request) service.Device {
+	return service.Device{UserAgent: r.UserAgent(), IP: clientIP(r)}
+}
+
+func setAuthCookies(w http.ResponseWriter, tokens service.IssuedTokens) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     jwtauth.CookieName,
+		Value:    tokens.AccessToken,
+		HttpOnly: true,
+		Secure:   false, // HTTP & HTTPS
+		SameSite: http.SameSiteStrictMode,
+		Path:     "/",
+		Expires:  tokens.AccessExpiresAt,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    tokens.RefreshToken,
+		HttpOnly: true,
+		Secure:   false, // HTTP & HTTPS
+		SameSite: http.SameSiteStrictMode,
+		Path:     "/users",
+		Expires:  tokens.RefreshExpiresAt,
+	})
+}
+
+func clearAuthCookies(w http.ResponseWriter) {
+	for name, path := range map[string]string{jwtauth.CookieName: "/", refreshCookieName: "/users"} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			HttpOnly: true,
+			Secure:   false, // HTTP & HTTPS
+			SameSite: http.SameSiteStrictMode,
+			Path:     path,
+			MaxAge:   -1,
+		})
+	}
+}
+
+// RefreshTokenHandler exchanges the refresh token for a new token pair
+// @Summary Refresh the access token
+// @Description Exchanges the refresh token cookie for a new access token and a new refresh token. A refresh token can be used only once; reusing it revokes the whole session.
+// @Tags User
+// @Produce json
+// @Router /users/token/refresh [post]
+func (server *Server) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(refreshCookieName)
+	if err != nil {
+		http.Error(w, "Unauthorized: No refresh token provided", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tokens, err := server.sessions.Refresh(ctx, cookie.Value, deviceFromRequest(r))
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
+		clearAuthCookies(w)
+		http.Error(w, "Unauthorized: Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if errors.Is(err, service.ErrUserBanned) {
+		clearAuthCookies(w)
+		http.Error(w, "Forbidden: account is banned", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	setAuthCookies(w, tokens)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Token refreshed"}`))
+}
+
+// GetSessions lists active sessions of the current user
+// @Summary List active sessions
+// @Description Returns active sessions of the authenticated user with device, IP and last use time. The session of the request is marked as current.
+// @Tags User
+// @Produce json
+// @Router /users/profile/sessions [get]
+func (server *Server) GetSessions(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	sessions, err := server.sessions.ListSessions(ctx, userID, jwtutils.SessionID(r))
+	if err != nil {
+		http.Error(w, "Failed to get sessions", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession revokes one session of the current user
+// @Summary Revoke a session
+// @Description Logs the user out on the device of the session. Access tokens of the session stop working immediately.
+// @Tags User
+// @Produce json
+// @Param sessionID path string true "Session ID"
+// @Router /users/profile/sessions/{sessionID} [delete]
+func (server *Server) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+	sessionID := mux.Vars(r)["sessionID"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := server.sessions.RevokeSession(ctx, userID, sessionID)
+	if errors.Is(err, service.ErrSessionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	if sessionID == jwtutils.SessionID(r) {
+		clearAuthCookies(w)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Session revoked"}`))
+}
+
+// RevokeAllSessions revokes every session of the current user
+// @Summary Revoke all sessions
+// @Description Logs the user out on all devices, including the current one.
+// @Tags User
+// @Produce json
+// @Router /users/profile/sessions [delete]
+func (server *Server) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := server.sessions.RevokeAllSessions(ctx, userID)
+	if err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+	clearAuthCookies(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"All sessions revoked"}`))
+}
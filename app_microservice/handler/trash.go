@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"net/http"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/safety/jwtutils"
+	"pictureloader/app_microservice/service"
+	"strconv"
+	"time"
+)
+
+type TrashServer struct {
+	trash *service.TrashService
+}
+
+func NewTrashServer(trash *service.TrashService) *TrashServer {
+	return &TrashServer{trash: trash}
+}
+
+// TrashRouter - корзина удаленных постов и картинок пользователя
+func TrashRouter(api *mux.Router, server *TrashServer, jwtUtils *jwtutils.UtilsJWT) {
+	router := api.PathPrefix("/trash").Subrouter()
+	// в корзине и посты, и картинки, персональному токену нужны оба права на чтение
+	router.Handle("", jwtUtils.RequireScope(models.ScopePostsRead,
+		jwtUtils.RequireScope(models.ScopeImagesRead, server.ListTrash).ServeHTTP)).Methods("GET")
+	router.Handle("/posts/{postID:[0-9]+}/restore", jwtUtils.RequireScope(models.ScopePostsWrite, server.RestorePost)).Methods("POST")
+	router.Handle("/pictures/{imageSK}/restore", jwtUtils.RequireScope(models.ScopeImagesWrite, server.RestorePicture)).Methods("POST")
+	router.Use(jwtUtils.AuthMiddleware)
+}
+
+func trashError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrTrashItemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "Trash operation failed", http.StatusInternalServerError)
+	}
+	return true
+}
+
+// ListTrash lists deleted posts and pictures
+// @Summary List the trash
+// @Description Posts and pictures deleted by the user, most recently deleted first. Items are deleted forever at purge_at.
+// @Tags Trash
+// @Produce json
+// @Success 200 {array} models.TrashItem
+// @Router /trash [get]
+func (server *TrashServer) ListTrash(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	items, err := server.trash.ListTrash(ctx, userID)
+	if trashError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(items)
+}
+
+// RestorePost restores a deleted post
+// @Summary Restore a post from the trash
+// @Description The post comes back with its likes and the pictures that are not deleted themselves.
+// @Tags Trash
+// @Produce json
+// @Param postID path int true "Post ID"
+// @Router /trash/posts/{postID}/restore [post]
+func (server *TrashServer) RestorePost(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+	postID, _ := strconv.Atoi(mux.Vars(r)["postID"])
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if trashError(w, server.trash.RestorePost(ctx, userID, postID)) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Post restored"}`))
+}
+
+// RestorePicture restores a deleted picture
+// @Summary Restore a picture from the trash
+// @Description The picture comes back to the posts it was added to.
+// @Tags Trash
+// @Produce json
+// @Param imageSK path string true "Image storage key"
+// @Router /trash/pictures/{imageSK}/restore [post]
+func (server *TrashServer) RestorePicture(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+	imageSK := mux.Vars(r)["imageSK"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if trashError(w, server.trash.RestorePicture(ctx, userID, imageSK)) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Picture restored"}`))
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	jwt2 "github.com/golang-jwt/jwt/v5"
+	"math"
+	"net/http"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"strconv"
+	"time"
+)
+
+// twoFactorError переводит ошибки 2FA в ответ, false - ошибки нет
+func twoFactorError(w http.ResponseWriter, err error) bool {
+	var blocked *service.LoginBlockedError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &blocked):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+	case errors.Is(err, service.ErrInvalidTwoFactor), errors.Is(err, service.ErrInvalidPassword),
+		errors.Is(err, service.ErrInvalidChallenge):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, service.ErrTwoFactorEnabled), errors.Is(err, service.ErrTwoFactorNotEnabled),
+		errors.Is(err, service.ErrTwoFactorNotStarted):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Two-factor authentication failed", http.StatusInternalServerError)
+	}
+	return true
+}
+
+// LoginTwoFactorHandler completes a login of a user with 2FA
+// @Summary Complete login with a two-factor code
+// @Description Second login step for users with two-factor authentication. Takes the challenge returned by /users/login and a code from the authenticator app or a recovery code. The challenge expires after 5 minutes or 5 wrong codes. Wrong codes count towards the same account lockout as wrong passwords.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param login body models.TwoFactorLogin true "Challenge and code"
+// @Router /users/login/2fa [post]
+func (server *Server) LoginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorLogin
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Challenge == "" {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	userID, err := server.twoFactor.CompleteChallenge(ctx, req.Challenge, req.Code, deviceFromRequest(r))
+	if twoFactorError(w, err) {
+		return
+	}
+	if !server.startSession(ctx, w, r, userID) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Login successful"}`))
+}
+
+// GetTwoFactor shows the 2FA status
+// @Summary Two-factor authentication status
+// @Description Returns whether two-factor authentication is enabled and how many recovery codes are left.
+// @Tags User
+// @Produce json
+// @Router /users/profile/2fa [get]
+func (server *Server) GetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	status, err := server.twoFactor.Status(ctx, userID)
+	if twoFactorError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// EnrollTwoFactor starts 2FA enrolment
+// @Summary Start two-factor enrolment
+// @Description Generates a TOTP secret and returns it with an otpauth:// URI for a QR code. Two-factor authentication is enabled only after confirmation with a first code.
+// @Tags User
+// @Produce json
+// @Router /users/profile/2fa/enroll [post]
+func (server *Server) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	enrollment, err := server.twoFactor.Enroll(ctx, userID)
+	if twoFactorError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// ConfirmTwoFactor enables 2FA
+// @Summary Confirm two-factor enrolment
+// @Description Enables two-factor authentication with a first code from the authenticator app and returns one-time recovery codes. The codes are shown only once.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param code body models.TwoFactorCode true "Code from the authenticator app"
+// @Router /users/profile/2fa/confirm [post]
+func (server *Server) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	var req models.TwoFactorCode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	codes, err := server.twoFactor.Confirm(ctx, userID, req.Code)
+	if twoFactorError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.RecoveryCodes{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor disables 2FA
+// @Summary Disable two-factor authentication
+// @Description Requires the current password and a code from the authenticator app or a recovery code. Users without a password (signed up through an identity provider) leave the password empty and must use a code from the authenticator app. Wrong passwords and codes count towards the login lockout.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param reauth body models.TwoFactorReauth true "Password and code"
+// @Router /users/profile/2fa/disable [post]
+func (server *Server) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	var req models.TwoFactorReauth
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if twoFactorError(w, server.twoFactor.Disable(ctx, userID, req, deviceFromRequest(r))) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Two-factor authentication disabled"}`))
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes
+// @Summary Regenerate recovery codes
+// @Description Requires the current password and a code. Users without a password must use a code from the authenticator app. Previous recovery codes stop working. Wrong passwords and codes count towards the login lockout.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param reauth body models.TwoFactorReauth true "Password and code"
+// @Router /users/profile/2fa/recovery-codes [post]
+func (server *Server) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	var req models.TwoFactorReauth
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка обработки JSON", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	codes, err := server.twoFactor.RegenerateRecoveryCodes(ctx, userID, req, deviceFromRequest(r))
+	if twoFactorError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.RecoveryCodes{RecoveryCodes: codes})
+}
@@ -3,37 +3,90 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	jwt2 "github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
+	"log/slog"
+	"math"
 	"net/http"
 	"pictureloader/app_microservice/models"
 	"pictureloader/app_microservice/safety/jwtutils"
 	"pictureloader/app_microservice/service"
+	"pictureloader/common/servicetoken"
+	"strconv"
 	"time"
 )
 
 type Server struct {
-	core *service.UserService
+	core      *service.UserService
+	sessions  *service.SessionService
+	apiTokens *service.APITokenService
+	account   *service.AccountService
+	twoFactor *service.TwoFactorService
+	guard     *service.LoginGuard
+	oidc      *service.OIDCService
+	deletion  *service.AccountDeletionService
+	avatars   *service.AvatarService
 }
 
-func NewUserServer(core *service.UserService) *Server {
-	return &Server{core: core}
+func NewUserServer(core *service.UserService, sessions *service.SessionService, apiTokens *service.APITokenService,
+	account *service.AccountService, twoFactor *service.TwoFactorService, guard *service.LoginGuard,
+	oidc *service.OIDCService, deletion *service.AccountDeletionService, avatars *service.AvatarService) *Server {
+	return &Server{core: core, sessions: sessions, apiTokens: apiTokens, account: account, twoFactor: twoFactor,
+		guard: guard, oidc: oidc, deletion: deletion, avatars: avatars}
 }
 
-func UserRouter(api *mux.Router, server *Server) {
+func UserRouter(api *mux.Router, server *Server, jwtUtils *jwtutils.UtilsJWT) {
 	router := api.PathPrefix("/users").Subrouter()
 	router.HandleFunc("/register", server.RegisterHandler).Methods("POST")
 	router.HandleFunc("/login", server.LoginUserHandler).Methods("POST")
 	router.HandleFunc("/logout", server.LogoutHandler).Methods("POST")
+	router.HandleFunc("/token/refresh", server.RefreshTokenHandler).Methods("POST")
+	router.HandleFunc("/email/verify", server.VerifyEmail).Methods("POST")
+	router.HandleFunc("/oidc/providers", server.GetOIDCProviders).Methods("GET")
+	router.HandleFunc("/oidc/callback", server.OIDCCallback).Methods("GET")
+	router.HandleFunc("/oidc/{provider}/login", server.OIDCLogin).Methods("GET")
+	router.Handle("/login/2fa", limitByClientIP(10, time.Minute)(http.HandlerFunc(server.LoginTwoFactorHandler))).Methods("POST")
+
+	// письма со ссылками отправляются по запросу без входа, поэтому частота ограничена
+	passwordRouter := router.PathPrefix("/password").Subrouter()
+	passwordRouter.HandleFunc("/forgot", server.ForgotPassword).Methods("POST")
+	passwordRouter.HandleFunc("/reset", server.ResetPassword).Methods("POST")
+	passwordRouter.Use(limitByClientIP(5, time.Minute))
 
-	jwtutils := jwtutils.UtilsJWT{}
 	subrouter := router.PathPrefix("/profile").Subrouter()
-	subrouter.HandleFunc("", server.DeleteProfile).Methods("DELETE")
-	subrouter.HandleFunc("/me", server.GetMyProfile).Methods("GET")
-	subrouter.HandleFunc("/profile_picture", server.UploadProfilePic).Methods("POST")
-	subrouter.HandleFunc("/username", server.ChangeUsername).Methods("PATCH")
-	subrouter.HandleFunc("/password", server.ChangePassword).Methods("PATCH")
-	subrouter.Use(jwtutils.AuthMiddleware)
+	// настройки аккаунта доступны только из сессии, персональному токену - лишь чтение профиля
+	session := jwtUtils.RequireSession
+	subrouter.Handle("", session(server.DeleteProfile)).Methods("DELETE")
+	subrouter.Handle("/me", jwtUtils.RequireScope(models.ScopeProfileRead, server.GetMyProfile)).Methods("GET")
+	subrouter.Handle("/profile_picture", jwtUtils.RequireVerifiedEmail(session(server.UploadProfilePic))).Methods("POST")
+	subrouter.Handle("/avatar", jwtUtils.RequireVerifiedEmail(session(server.UploadAvatar))).Methods("POST")
+	subrouter.Handle("/avatar", session(server.DeleteAvatar)).Methods("DELETE")
+	subrouter.Handle("/username", session(server.ChangeUsername)).Methods("PATCH")
+	subrouter.Handle("/password", session(server.ChangePassword)).Methods("PATCH")
+	subrouter.Handle("/sessions", session(server.GetSessions)).Methods("GET")
+	subrouter.Handle("/sessions", session(server.RevokeAllSessions)).Methods("DELETE")
+	subrouter.Handle("/sessions/{sessionID}", session(server.RevokeSession)).Methods("DELETE")
+	subrouter.Handle("/email/verify", session(server.ResendVerification)).Methods("POST")
+	subrouter.Handle("/2fa", session(server.GetTwoFactor)).Methods("GET")
+	subrouter.Handle("/2fa/enroll", session(server.EnrollTwoFactor)).Methods("POST")
+	subrouter.Handle("/2fa/confirm", session(server.ConfirmTwoFactor)).Methods("POST")
+	subrouter.Handle("/2fa/disable", session(server.DisableTwoFactor)).Methods("POST")
+	subrouter.Handle("/2fa/recovery-codes", session(server.RegenerateRecoveryCodes)).Methods("POST")
+	subrouter.Handle("/identities", session(server.GetIdentities)).Methods("GET")
+	subrouter.Handle("/identities/{provider}/link", session(server.LinkIdentity)).Methods("POST")
+	subrouter.Handle("/identities/{identityID:[0-9]+}", session(server.UnlinkIdentity)).Methods("DELETE")
+	subrouter.Handle("/tokens", session(server.CreateAPIToken)).Methods("POST")
+	subrouter.Handle("/tokens", session(server.GetAPITokens)).Methods("GET")
+	subrouter.Handle("/tokens/{tokenID:[0-9]+}", session(server.RevokeAPIToken)).Methods("DELETE")
+	subrouter.Use(jwtUtils.AuthMiddleware)
+}
+
+// InternalUserRouter - ручки для других сервисов, доступны только с сервисным токеном
+func InternalUserRouter(api *mux.Router, server *Server, serviceToken string) {
+	router := api.PathPrefix("/internal/users").Subrouter()
+	router.HandleFunc("/{userID:[0-9]+}", server.GetUserForService).Methods("GET")
+	router.Use(servicetoken.Middleware(serviceToken))
 }
 
 // RegisterHandler handles user registration
@@ -63,21 +116,15 @@ func (server *Server) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//создание jwt
-	jwtUtils := jwtutils.UtilsJWT{}
-	jwtValue, err := jwtUtils.GenerateToken(user.ID)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	// письмо не должно ломать регистрацию, его можно запросить повторно
+	if err = server.account.SendVerification(ctx, user.ID); err != nil {
+		slog.Warn("Send verification email", "error", err, "userID", user.ID)
+	}
+
+	//создание сессии и jwt
+	if !server.startSession(ctx, w, r, user.ID) {
+		return
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     "user-cookie",
-		Value:    jwtValue,
-		HttpOnly: true,
-		Secure:   false, // HTTP & HTTPS
-		SameSite: http.SameSiteStrictMode,
-		Path:     "/",
-		Expires:  time.Now().Add(time.Hour * 1),
-	})
 	//ответ
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -86,7 +133,7 @@ func (server *Server) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 
 // LoginUserHandler handles user login
 // @Summary Login an existing user
-// @Description This endpoint allows a user to log in by providing their username and password. If the credentials are correct, a JWT token will be generated and returned in a cookie for session management.
+// @Description This endpoint allows a user to log in by providing their username and password. If the credentials are correct, a JWT token will be generated and returned in a cookie for session management. If two-factor authentication is enabled, no cookie is set; the response contains a challenge for /users/login/2fa instead.
 // @Tags User
 // @Accept  json
 // @Produce  json
@@ -104,60 +151,64 @@ func (server *Server) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
+	// блокировка не проверяет пароль и одинакова для существующих и несуществующих имен
+	device := deviceFromRequest(r)
+	blocked, err := server.guard.BlockedFor(ctx, userLogin.Username, device)
+	if err != nil {
+		http.Error(w, "Login check unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if blocked > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(blocked.Seconds()))))
+		http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
 	isCorrect, userID := server.core.LoginUser(ctx, &userLogin)
 
 	if !isCorrect {
+		if err = server.guard.Fail(ctx, userLogin.Username, device); err != nil {
+			http.Error(w, "Login check unavailable", http.StatusServiceUnavailable)
+			return
+		}
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte("incorrect username or password"))
 		return
 	}
-	jwtUtils := jwtutils.UtilsJWT{}
-	jwtValue, err := jwtUtils.GenerateToken(userID)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     "user-cookie",
-		Value:    jwtValue,
-		HttpOnly: true,
-		Secure:   false, // HTTP & HTTPS
-		SameSite: http.SameSiteStrictMode,
-		Path:     "/",
-		Expires:  time.Now().Add(time.Hour * 1),
-	})
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message":"Login successful"}`))
+	server.completeLogin(ctx, w, r, userID, userLogin.Username)
 }
 
 // LogoutHandler handles user logout
-// @Summary Log out a user (delete authentication cookie)
-// @Description This endpoint allows a user to log out by deleting the authentication cookie from the client's browser.
+// @Summary Log out a user (revoke the session and delete authentication cookies)
+// @Description This endpoint revokes the session of the refresh token cookie and deletes the authentication cookies from the client's browser.
 // @Tags User
 // @Accept  json
 // @Produce  json
 // @Router /users/logout [post]
 func (server *Server) LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "user-cookie",
-		Value:    "",
-		HttpOnly: true,
-		Secure:   false, // HTTP & HTTPS
-		SameSite: http.SameSiteStrictMode,
-		Path:     "/",
-		MaxAge:   0,
-	})
+	if cookie, err := r.Cookie(refreshCookieName); err == nil {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		// сессия могла быть уже отозвана, куки все равно удаляются
+		err = server.sessions.EndSession(ctx, cookie.Value)
+		if err != nil && !errors.Is(err, service.ErrInvalidRefreshToken) && !errors.Is(err, service.ErrSessionNotFound) {
+			http.Error(w, "Failed to end session", http.StatusInternalServerError)
+			return
+		}
+	}
+	clearAuthCookies(w)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message":"Logout successful"}`))
 }
 
-// DeleteProfile deletes the authenticated user's profile
-// @Summary Delete user profile
-// @Description This endpoint allows an authenticated user to delete their profile permanently.
+// DeleteProfile deletes the authenticated user's account
+// @Summary Delete user account
+// @Description Logs the user out everywhere and queues the account for deletion. Posts, pictures, likes and notifications are deleted in the background; the response is the deletion job.
 // @Tags User
 // @Accept json
 // @Produce json
+// @Success 202 {object} models.AccountDeletion
 // @Router /users/profile [delete]
 func (server *Server) DeleteProfile(w http.ResponseWriter, r *http.Request) {
 	claims := r.Context().Value("claims").(jwt2.MapClaims)
@@ -167,24 +218,16 @@ func (server *Server) DeleteProfile(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	err := server.core.DeleteUserByID(ctx, userID)
+	deletion, err := server.deletion.RequestDeletion(ctx, userID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(err.Error()))
 		return
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     "user-cookie",
-		Value:    "",
-		HttpOnly: true,
-		Secure:   false, // HTTP & HTTPS
-		SameSite: http.SameSiteStrictMode,
-		Path:     "/",
-		MaxAge:   0,
-	})
+	clearAuthCookies(w)
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message":"Delete successful"}`))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(deletion)
 }
 
 type usernameReqChange struct {
@@ -232,7 +275,7 @@ type passwordReqChange struct {
 
 // ChangePassword handles user password change
 // @Summary Change user password
-// @Description Allows an authenticated user to change their password
+// @Description Allows an authenticated user to change their password to one of at least 8 characters. All sessions are revoked, so the user has to log in again.
 // @Tags User
 // @Accept json
 // @Produce json
@@ -255,12 +298,17 @@ func (server *Server) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	err := server.core.UpdatePassword(ctx, userID, request.Password)
+	if errors.Is(err, service.ErrWeakPassword) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
+	clearAuthCookies(w)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message":"Change successful"}`))
@@ -291,27 +339,39 @@ func (server *Server) GetMyProfile(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
-type UploadProfilePicRequest struct {
-	PictureSK string `json:"picture_sk"`
+func avatarError(w http.ResponseWriter, err error) bool {
+	var validationErr *service.AvatarValidationError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &validationErr):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrAvatarSourceNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "Avatar update failed", http.StatusInternalServerError)
+	}
+	return true
 }
 
-// UploadProfilePic uploads the user's profile picture.
-// @Summary      Upload profile picture
-// @Description  Allows the user to upload a profile picture by providing the path (PictureSK).
+// UploadProfilePic sets one of the user's pictures as the avatar.
+// @Summary      Use a picture as the avatar
+// @Description  Makes 32, 64 and 256 pixel square avatars from a picture the user uploaded earlier. The crop rectangle is in pixels of the picture; without it the largest centered square is used, and a non-square rectangle is narrowed to its centered square. The previous avatar is deleted.
 // @Tags         User
 // @Accept       json
 // @Produce      json
-// @Param        request body UploadProfilePicRequest true "Upload data"
+// @Param        request body models.AvatarFromPicture true "Picture storage key and crop"
+// @Success      200 {object} models.AvatarURLs
 // @Router       /users/profile/profile_picture [post]
 func (server *Server) UploadProfilePic(w http.ResponseWriter, r *http.Request) {
 	claims := r.Context().Value("claims").(jwt2.MapClaims)
 	sub := claims["sub"].(float64)
 	userID := int(sub)
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	var pic UploadProfilePicRequest
+	var pic models.AvatarFromPicture
 	if err := json.NewDecoder(r.Body).Decode(&pic); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Header().Set("Content-Type", "application/json")
@@ -319,13 +379,109 @@ func (server *Server) UploadProfilePic(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := server.core.UploadProfilePicture(ctx, userID, pic.PictureSK)
+	avatars, err := server.avatars.SetAvatarFromPicture(ctx, userID, pic)
+	if avatarError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(avatars)
+}
+
+// UploadAvatar uploads a new avatar.
+// @Summary      Upload an avatar
+// @Description  Makes 32, 64 and 256 pixel square avatars from a PNG, JPEG or GIF file of at most 10 MB. The crop rectangle is in pixels of the file; without it the largest centered square is used, and a non-square rectangle is narrowed to its centered square. The previous avatar is deleted.
+// @Tags         User
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file formData file true "Image file"
+// @Param        x formData int false "Crop left edge"
+// @Param        y formData int false "Crop top edge"
+// @Param        width formData int false "Crop width"
+// @Param        height formData int false "Crop height"
+// @Success      200 {object} models.AvatarURLs
+// @Router       /users/profile/avatar [post]
+func (server *Server) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	// запас сверх файла на остальные поля формы
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxAvatarUploadSize+1<<20)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Error retrieving file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	var crop *models.AvatarCrop
+	if r.FormValue("width") != "" || r.FormValue("height") != "" {
+		crop = &models.AvatarCrop{}
+		for field, value := range map[string]*int{"x": &crop.X, "y": &crop.Y, "width": &crop.Width, "height": &crop.Height} {
+			raw := r.FormValue(field)
+			if raw == "" {
+				continue
+			}
+			if *value, err = strconv.Atoi(raw); err != nil {
+				http.Error(w, "Invalid crop "+field, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	avatars, err := server.avatars.UploadAvatar(ctx, userID, file, crop)
+	if avatarError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(avatars)
+}
+
+// DeleteAvatar removes the avatar.
+// @Summary      Remove the avatar
+// @Tags         User
+// @Produce      json
+// @Router       /users/profile/avatar [delete]
+func (server *Server) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("claims").(jwt2.MapClaims)
+	sub := claims["sub"].(float64)
+	userID := int(sub)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if avatarError(w, server.avatars.DeleteAvatar(ctx, userID)) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Avatar removed"}`))
+}
+
+type internalUserResponse struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// GetUserForService отдает контактные данные пользователя другим сервисам (например, нотификатору для писем)
+func (server *Server) GetUserForService(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["userID"])
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	user, err := server.core.GetUserByID(ctx, userID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte(err.Error()))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message":"Upload successful"}`))
+	json.NewEncoder(w).Encode(internalUserResponse{ID: userID, Username: user.Username, Email: user.Email})
 }
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"github.com/minio/minio-go/v7"
+	"io"
+	"net/url"
+	"pictureloader/app_microservice/image_storage"
 	"pictureloader/app_microservice/models"
 	"sync"
 	"time"
@@ -63,3 +66,35 @@ func (m *MinioProvider) DeleteFileByURL(ctx context.Context, imageURL string) er
 	err := m.client.RemoveObject(ctx, bucketName, imageURL, minio.RemoveObjectOptions{})
 	return err
 }
+
+// GetFile открывает объект на чтение. Minio отдает ошибку отсутствия объекта только при чтении,
+// поэтому объект сначала проверяется через Stat.
+func (m *MinioProvider) GetFile(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := m.client.GetObject(ctx, bucketName, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, image_storage.ErrFileNotFound
+		}
+		return nil, err
+	}
+	return object, nil
+}
+
+func (m *MinioProvider) PutFile(ctx context.Context, key string, payload io.Reader, size int64, contentType string) error {
+	_, err := m.client.PutObject(ctx, bucketName, key, payload, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (m *MinioProvider) GetDownloadURL(ctx context.Context, key, filename string, ttl time.Duration) (string, error) {
+	params := url.Values{}
+	params.Set("response-content-disposition", `attachment; filename="`+filename+`"`)
+	link, err := m.client.PresignedGetObject(ctx, bucketName, key, ttl, params)
+	if err != nil {
+		return "", err
+	}
+	return link.String(), nil
+}
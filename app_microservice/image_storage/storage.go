@@ -2,13 +2,22 @@ package image_storage
 
 import (
 	"context"
+	"errors"
+	"io"
 	"pictureloader/app_microservice/models"
+	"time"
 )
 
+var ErrFileNotFound = errors.New("file not found")
+
 type ImageStorage interface {
 	Connect() error                                                       // Инициализатор подключения
 	UploadFile(context.Context, models.ImageUnit, string) (string, error) // Загрузка файлов
 	GetFileURL(context.Context, string) (string, error)
 	GetFileURLS(ctx context.Context, imageURLS []string) ([]string, error) // Скачивание файлов
 	DeleteFileByURL(ctx context.Context, imageURL string) error
+	GetFile(ctx context.Context, key string) (io.ReadCloser, error) // Чтение файла, ErrFileNotFound если его нет
+	PutFile(ctx context.Context, key string, payload io.Reader, size int64, contentType string) error
+	// GetDownloadURL - ссылка на скачивание файла под именем filename, действует ttl
+	GetDownloadURL(ctx context.Context, key, filename string, ttl time.Duration) (string, error)
 }
@@ -0,0 +1,220 @@
+// Package mockidp - минимальный OpenID Connect провайдер для локальной разработки и тестов.
+// Пользователь не вводит пароль: authorize сразу выдает код для пользователя из login_hint
+// (или первого из списка). Поддерживается только authorization code с PKCE S256.
+package mockidp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/url"
+	"pictureloader/common/jwtauth"
+	"slices"
+	"sync"
+	"time"
+)
+
+const codeTTL = time.Minute
+
+type User struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+type Config struct {
+	Issuer       string // адрес, на котором слушает сервер, без слеша на конце
+	ClientID     string
+	ClientSecret string
+	RedirectURLs []string // пустой список - любой redirect_uri
+	Users        []User
+}
+
+type authCode struct {
+	user        User
+	clientID    string
+	redirectURI string
+	nonce       string
+	challenge   string
+	expiresAt   time.Time
+}
+
+type IdP struct {
+	cfg   Config
+	keys  *jwtauth.KeySet
+	mux   *http.ServeMux
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+func New(cfg Config) (*IdP, error) {
+	if len(cfg.Users) == 0 {
+		return nil, errors.New("mock idp needs at least one user")
+	}
+	// ключ создается заново при каждом запуске, провайдеры перечитывают JWKS по kid
+	keys, err := jwtauth.NewKeySet(jwtauth.Config{Alg: jwtauth.AlgRS256, KeyID: "mockidp",
+		Issuer: cfg.Issuer, Audience: cfg.ClientID, EphemeralKey: true})
+	if err != nil {
+		return nil, err
+	}
+	idp := &IdP{cfg: cfg, keys: keys, mux: http.NewServeMux(), codes: map[string]authCode{}}
+	idp.mux.HandleFunc("GET /.well-known/openid-configuration", idp.discovery)
+	idp.mux.Handle("GET /jwks", keys.JWKSHandler())
+	idp.mux.HandleFunc("GET /authorize", idp.authorize)
+	idp.mux.HandleFunc("POST /token", idp.token)
+	return idp, nil
+}
+
+func (idp *IdP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	idp.mux.ServeHTTP(w, r)
+}
+
+func (idp *IdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                idp.cfg.Issuer,
+		"authorization_endpoint":                idp.cfg.Issuer + "/authorize",
+		"token_endpoint":                        idp.cfg.Issuer + "/token",
+		"jwks_uri":                              idp.cfg.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{jwtauth.AlgRS256},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+func (idp *IdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	if query.Get("client_id") != idp.cfg.ClientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if redirectURI == "" || (len(idp.cfg.RedirectURLs) > 0 && !slices.Contains(idp.cfg.RedirectURLs, redirectURI)) {
+		http.Error(w, "redirect_uri is not allowed", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" {
+		http.Error(w, "only response_type=code is supported", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	user := idp.cfg.Users[0]
+	if hint := query.Get("login_hint"); hint != "" {
+		index := slices.IndexFunc(idp.cfg.Users, func(u User) bool { return u.PreferredUsername == hint })
+		if index < 0 {
+			http.Error(w, "unknown login_hint", http.StatusBadRequest)
+			return
+		}
+		user = idp.cfg.Users[index]
+	}
+
+	code := randomString()
+	idp.mu.Lock()
+	idp.codes[code] = authCode{
+		user:        user,
+		clientID:    idp.cfg.ClientID,
+		redirectURI: redirectURI,
+		nonce:       query.Get("nonce"),
+		challenge:   query.Get("code_challenge"),
+		expiresAt:   time.Now().Add(codeTTL),
+	}
+	idp.mu.Unlock()
+
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := target.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (idp *IdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != idp.cfg.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(idp.cfg.ClientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	// код одноразовый, удаляется и при неудачной проверке
+	idp.mu.Lock()
+	code, found := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+	if !found || time.Now().After(code.expiresAt) || code.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, "invalid_grant")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":                code.user.Subject,
+		"email":              code.user.Email,
+		"email_verified":     code.user.EmailVerified,
+		"preferred_username": code.user.PreferredUsername,
+		"name":               code.user.Name,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+	}
+	if code.nonce != "" {
+		claims["nonce"] = code.nonce
+	}
+	idToken, err := idp.keys.Sign(claims)
+	if err != nil {
+		http.Error(w, "failed to sign id token", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func randomString() string {
+	buf := make([]byte, 24)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
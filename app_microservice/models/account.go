@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// UserToken - одноразовый токен из письма (подтверждение почты или сброс пароля).
+// Как и refresh токены, хранится только sha256 хеш.
+type UserToken struct {
+	Hash      string `gorm:"primaryKey"`
+	UserID    int    `gorm:"index;not null"`
+	Purpose   string `gorm:"not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	User      User `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+type EmailVerify struct {
+	Token string `json:"token"`
+}
+
+type PasswordForgot struct {
+	Email string `json:"email"`
+}
+
+type PasswordReset struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
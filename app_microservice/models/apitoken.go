@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// APITokenPrefix отличает персональные токены от JWT в заголовке Authorization
+const APITokenPrefix = "pl_"
+
+const (
+	ScopeImagesRead  = "images:read"
+	ScopeImagesWrite = "images:write"
+	ScopePostsRead   = "posts:read"
+	ScopePostsWrite  = "posts:write"
+	ScopeProfileRead = "profile:read"
+)
+
+// Scopes - все права, которые можно выдать персональному токену
+var Scopes = []string{ScopeImagesRead, ScopeImagesWrite, ScopePostsRead, ScopePostsWrite, ScopeProfileRead}
+
+// APIToken - персональный токен для скриптов и CLI. Сам токен показывается один раз при создании,
+// в базе хранится только sha256 хеш.
+type APIToken struct {
+	ID         int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     int        `gorm:"index;not null" json:"-"`
+	Name       string     `gorm:"not null" json:"name"`
+	Hint       string     `json:"hint"` // начало токена, чтобы пользователь мог его узнать
+	Hash       string     `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes     []string   `gorm:"serializer:json;type:jsonb" json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"-"`
+	User       User       `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// HasScope проверяет, выдано ли токену право scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type APITokenCreate struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 - бессрочный токен
+}
+
+// APITokenCreated - ответ на создание, единственный раз, когда клиент видит токен целиком
+type APITokenCreated struct {
+	APIToken
+	Token string `json:"token"`
+}
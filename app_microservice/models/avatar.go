@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AvatarPrefix - префикс ключей аватаров в хранилище, отдельный от картинок пользователей
+const AvatarPrefix = "avatars/"
+
+// AvatarSizes - стороны квадратных аватаров в пикселях, от меньшего к большему
+var AvatarSizes = []int{32, 64, 256}
+
+// AvatarCrop - прямоугольник исходной картинки, из которого делается аватар.
+// Пустой прямоугольник - наибольший квадрат по центру картинки.
+type AvatarCrop struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// AvatarFromPicture - аватар из уже загруженной картинки пользователя
+type AvatarFromPicture struct {
+	PictureSK string      `json:"picture_sk"`
+	Crop      *AvatarCrop `json:"crop,omitempty"`
+}
+
+// IsAvatarKey отличает аватар от ключа картинки, который раньше хранился в ProfilePicture
+func IsAvatarKey(key string) bool {
+	return strings.HasPrefix(key, AvatarPrefix)
+}
+
+// AvatarFileKey - ключ файла аватара размера size
+func AvatarFileKey(key string, size int) string {
+	return fmt.Sprintf("%s/%d.png", key, size)
+}
+
+// AvatarURLs - ссылки на аватар по размерам, ключ - сторона в пикселях
+type AvatarURLs map[string]string
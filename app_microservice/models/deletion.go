@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+const (
+	DeletionPending   = "pending"
+	DeletionCompleted = "completed"
+)
+
+// Шаги удаления аккаунта, выполняются по порядку. Каждый шаг можно повторить,
+// поэтому задача после сбоя продолжается с шага, на котором остановилась.
+const (
+	DeletionStepLikes  = "likes"
+	DeletionStepPosts  = "posts"
+	DeletionStepImages = "images"
+	DeletionStepUser   = "user"
+	DeletionStepEvent  = "event"
+)
+
+var DeletionSteps = []string{DeletionStepLikes, DeletionStepPosts, DeletionStepImages, DeletionStepUser, DeletionStepEvent}
+
+// AccountDeletion - задача удаления аккаунта. Запись остается после удаления пользователя
+// и подтверждает выполнение запроса на удаление данных, поэтому у UserID нет внешнего ключа.
+type AccountDeletion struct {
+	ID     int    `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID int    `gorm:"not null;uniqueIndex" json:"user_id"`
+	Status string `gorm:"not null;default:pending;index" json:"status"`
+	// Step - следующий шаг, у завершенной задачи пустой
+	Step          string     `json:"step,omitempty"`
+	LikesDeleted  int        `json:"likes_deleted"`
+	PostsDeleted  int        `json:"posts_deleted"`
+	ImagesDeleted int        `json:"images_deleted"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"index" json:"-"`
+	RequestedAt   time.Time  `json:"requested_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+}
+
+type DeletionFilter struct {
+	UserID int
+	Status string
+	Limit  int
+	Offset int
+}
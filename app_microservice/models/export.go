@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+// DataExport - запрос пользователя на выгрузку всех его данных. Архив собирается в фоне,
+// лежит в хранилище до ExpiresAt и отдается по временной ссылке.
+// Внешнего ключа нет: архив удаленного пользователя должен дождаться очистки.
+type DataExport struct {
+	ID         int    `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     int    `gorm:"not null;index" json:"-"`
+	Status     string `gorm:"not null;default:pending;index" json:"status"`
+	StorageKey string `json:"-"`
+	Size       int64  `json:"size,omitempty"`
+	Attempts   int    `json:"-"`
+	LastError  string `json:"-"`
+	// NextAttemptAt - когда задачу можно взять в работу
+	NextAttemptAt time.Time  `gorm:"index" json:"-"`
+	RequestedAt   time.Time  `json:"requested_at"`
+	ReadyAt       *time.Time `json:"ready_at,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	// DownloadURL - временная ссылка на архив, выдается только готовой выгрузке
+	DownloadURL string `gorm:"-" json:"download_url,omitempty"`
+}
+
+// Содержимое архива выгрузки: JSON файлы рядом с папкой images с оригиналами картинок
+
+type ExportImage struct {
+	StorageKey  string `json:"storage_key"`
+	Description string `json:"description"`
+	// File - путь к оригиналу внутри архива
+	File    string `json:"file"`
+	Hidden  bool   `json:"hidden_by_moderator"`
+	InTrash bool   `json:"in_trash"`
+}
+
+type ExportPost struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Images    []string  `json:"images"`
+	Likes     int       `json:"likes_count"`
+	Hidden    bool      `json:"hidden_by_moderator"`
+	InTrash   bool      `json:"in_trash"`
+}
+
+type ExportLike struct {
+	PostID int `json:"post_id"`
+	// UserID - кто поставил лайк, в лайках, полученных пользователем
+	UserID    int       `json:"user_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
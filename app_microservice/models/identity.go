@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ExternalIdentity - аккаунт у OpenID Connect провайдера, привязанный к пользователю.
+// Провайдер однозначно определяет человека парой (issuer, sub), почта может меняться.
+// Provider - имя провайдера в конфиге, его можно переименовать, не теряя привязок.
+// Issuer пустой только у привязок, созданных до этой колонки, пока его не проставит FillIssuer.
+type ExternalIdentity struct {
+	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    int       `gorm:"index;not null" json:"-"`
+	Provider  string    `gorm:"not null" json:"provider"`
+	Issuer    string    `gorm:"uniqueIndex:idx_identity_issuer_subject,where:issuer <> '';not null;default:''" json:"-"`
+	Subject   string    `gorm:"uniqueIndex:idx_identity_issuer_subject;not null" json:"-"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	User      User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// OIDCProviderInfo - провайдер, через которого можно войти
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
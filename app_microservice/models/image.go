@@ -1,12 +1,20 @@
 package models
 
-import "io"
+import (
+	"gorm.io/gorm"
+	"io"
+	"time"
+)
 
 type Image struct {
 	ID          int    `gorm:"primary_key" json:"id"`
 	StorageKey  string `json:"storage_key" gorm:"not null"`
 	UserID      int    `json:"user_id" gorm:"not null"`
 	Description string `json:"description" gorm:"size:150"`
+	// HiddenAt - картинка скрыта модератором, по ссылке и в постах ее не видно
+	HiddenAt *time.Time `json:"-"`
+	// DeletedAt - картинка в корзине владельца, файл в хранилище удаляется только при purge
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 type ImageUnit struct {
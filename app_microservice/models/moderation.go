@@ -0,0 +1,113 @@
+package models
+
+import "time"
+
+// На что можно пожаловаться. Комментариев в приложении пока нет.
+const (
+	ReportTargetPost  = "post"
+	ReportTargetImage = "image"
+	ReportTargetUser  = "user"
+)
+
+const (
+	ReportOpen      = "open"
+	ReportActioned  = "actioned"
+	ReportDismissed = "dismissed"
+)
+
+// Решения модератора по жалобе: скрыть, вернуть скрытый или удалить контент, заблокировать пользователя,
+// отклонить жалобу
+const (
+	ModerationHide    = "hide"
+	ModerationUnhide  = "unhide"
+	ModerationRemove  = "remove"
+	ModerationBan     = "ban"
+	ModerationDismiss = "dismiss"
+)
+
+// Действия администраторов над пользователями в журнале аудита
+const (
+	AuditUnban = "unban"
+	AuditRole  = "role"
+)
+
+var (
+	ReportTargets  = []string{ReportTargetPost, ReportTargetImage, ReportTargetUser}
+	ReportStatuses = []string{ReportOpen, ReportActioned, ReportDismissed}
+	ReportReasons  = []string{"spam", "abuse", "nudity", "violence", "copyright", "other"}
+)
+
+// Report - жалоба пользователя. TargetID - id поста или пользователя, для картинки - ключ в хранилище.
+// OwnerID запоминается при создании, чтобы уведомить владельца и после удаления контента.
+type Report struct {
+	ID         int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	ReporterID int        `gorm:"index;not null" json:"reporter_id"`
+	TargetType string     `gorm:"not null;index:idx_report_target" json:"target_type"`
+	TargetID   string     `gorm:"not null;index:idx_report_target" json:"target_id"`
+	OwnerID    int        `gorm:"index" json:"owner_id"`
+	Reason     string     `gorm:"not null" json:"reason"`
+	Details    string     `gorm:"size:1000" json:"details,omitempty"`
+	Status     string     `gorm:"not null;default:open;index" json:"status"`
+	Resolution string     `json:"resolution,omitempty"`
+	ResolvedBy *int       `json:"resolved_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Reporter   User       `gorm:"foreignKey:ReporterID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// AuditEntry - запись журнала действий модераторов и администраторов
+type AuditEntry struct {
+	ID         int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ActorID    int       `gorm:"index;not null" json:"actor_id"`
+	Action     string    `gorm:"not null" json:"action"`
+	TargetType string    `gorm:"index:idx_audit_target" json:"target_type"`
+	TargetID   string    `gorm:"index:idx_audit_target" json:"target_id"`
+	ReportID   *int      `json:"report_id,omitempty"`
+	Details    string    `json:"details,omitempty"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// ReportTarget - владелец и название контента, на который жалуются
+type ReportTarget struct {
+	OwnerID int
+	Name    string
+}
+
+// ModerationDecision - решение по жалобам на один объект, уходит в нотификатор
+type ModerationDecision struct {
+	TargetType string
+	TargetID   string
+	TargetName string
+	OwnerID    int
+	Action     string
+	Reporters  []int
+	DecidedAt  time.Time
+}
+
+type ReportCreate struct {
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	Reason     string `json:"reason"`
+	Details    string `json:"details"`
+}
+
+type ReportResolve struct {
+	Action string `json:"action"`
+	Note   string `json:"note"`
+}
+
+type ReportFilter struct {
+	Status     string
+	TargetType string
+	Reason     string
+	Limit      int
+	Offset     int
+}
+
+type AuditFilter struct {
+	ActorID    int
+	TargetType string
+	TargetID   string
+	Limit      int
+	Offset     int
+}
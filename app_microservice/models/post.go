@@ -1,12 +1,22 @@
 package models
 
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
 type Post struct {
-	ID     int     `gorm:"primaryKey;autoIncrement" json:"post_id"`
-	Name   string  `gorm:"not null" json:"name"`
-	UserID int     `json:"user_id" gorm:"not null"`
-	Likes  []Like  `json:"likes" gorm:"foreignKey:PostID"`
-	User   User    `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
-	Images []Image `gorm:"many2many:post_images"`
+	ID        int       `gorm:"primaryKey;autoIncrement" json:"post_id"`
+	Name      string    `gorm:"not null" json:"name"`
+	UserID    int       `json:"user_id" gorm:"not null"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	// HiddenAt - пост скрыт модератором и не показывается в ленте и выдаче
+	HiddenAt *time.Time `json:"-"`
+	// DeletedAt - пост в корзине владельца, пока его не вычистит purge
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	Likes     []Like         `json:"likes" gorm:"foreignKey:PostID"`
+	User      User           `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Images    []Image        `gorm:"many2many:post_images"`
 }
 
 type PostUnit struct {
@@ -26,7 +36,9 @@ type PostImage struct {
 }
 
 type Like struct {
-	PostID int  `gorm:"primaryKey"`
-	UserID int  `gorm:"primaryKey"`
-	User   User `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	PostID int `gorm:"primaryKey"`
+	UserID int `gorm:"primaryKey"`
+	// CreatedAt отличает повторный лайк после снятия от предыдущего
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	User      User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
 }
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Follow - подписка Follower на обновления Followee
+type Follow struct {
+	FollowerID int       `gorm:"primaryKey"`
+	FolloweeID int       `gorm:"primaryKey;index"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	Follower   User      `gorm:"foreignKey:FollowerID;references:ID;constraint:OnDelete:CASCADE"`
+	Followee   User      `gorm:"foreignKey:FolloweeID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// PublicProfile - профиль, который видят все, без почты и служебных полей
+type PublicProfile struct {
+	UserID        int           `json:"-"`
+	Username      string        `json:"username"`
+	Bio           string        `json:"bio"`
+	Links         []string      `gorm:"serializer:json" json:"links"`
+	Avatar        string        `json:"avatar"`
+	Avatars       AvatarURLs    `gorm:"-" json:"avatars,omitempty"`
+	JoinedAt      time.Time     `json:"joined_at"`
+	Followers     int           `json:"followers_count"`
+	Following     int           `json:"following_count"`
+	Posts         int           `json:"posts_count"`
+	LikesReceived int           `json:"likes_count"`
+	RecentPosts   []ProfilePost `gorm:"-" json:"recent_posts"`
+}
+
+// ProfilePost - пост в профиле, Cover - ссылка на первую картинку поста
+type ProfilePost struct {
+	ID        int       `json:"post_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Likes     int       `json:"likes_count"`
+	Cover     string    `json:"cover,omitempty"`
+}
+
+// ProfileUpdate - изменение публичного профиля, nil поля не меняются
+type ProfileUpdate struct {
+	Bio   *string   `json:"bio"`
+	Links *[]string `json:"links"`
+}
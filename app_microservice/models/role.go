@@ -0,0 +1,76 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Права, которые проверяются на ручках. Роль дает набор прав, ручка требует одно право.
+const (
+	PermModerateContent = "content:moderate"
+	PermViewUsers       = "users:read"
+	PermBanUsers        = "users:ban"
+	PermManageRoles     = "users:roles"
+	PermManageLogins    = "users:logins"
+	PermViewAudit       = "audit:read"
+)
+
+// Roles - роли по возрастанию полномочий
+var Roles = []string{RoleUser, RoleModerator, RoleAdmin}
+
+var rolePermissions = map[string][]string{
+	RoleUser:      {},
+	RoleModerator: {PermModerateContent, PermViewUsers, PermBanUsers},
+	RoleAdmin:     {PermModerateContent, PermViewUsers, PermBanUsers, PermManageRoles, PermManageLogins, PermViewAudit},
+}
+
+// RoleCan сообщает, есть ли у роли право permission. Неизвестная роль прав не имеет.
+func RoleCan(role, permission string) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// RoleRank - место роли в Roles, -1 для неизвестной роли
+func RoleRank(role string) int {
+	return slices.Index(Roles, role)
+}
+
+// UserAccess - то, что нужно знать о пользователе при выдаче токена
+type UserAccess struct {
+	Role     string
+	BannedAt *time.Time
+	// DeletionRequestedAt - пользователь удаляет аккаунт, войти уже нельзя
+	DeletionRequestedAt *time.Time
+}
+
+// AdminUser - пользователь в списке для администраторов
+type AdminUser struct {
+	ID            int        `json:"id"`
+	Username      string     `json:"username"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	EmailVerified bool       `json:"email_verified"`
+	BannedAt      *time.Time `json:"banned_at,omitempty"`
+	BanReason     string     `json:"ban_reason,omitempty"`
+}
+
+// UserFilter - параметры списка пользователей
+type UserFilter struct {
+	Query  string
+	Role   string
+	Limit  int
+	Offset int
+}
+
+type UserBan struct {
+	Reason string `json:"reason"`
+}
+
+type UserRoleUpdate struct {
+	Role string `json:"role"`
+}
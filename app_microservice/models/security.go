@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	SecurityLoginFailed = "login_failed"
+	SecurityLockout     = "lockout"
+	SecurityUnlock      = "unlock"
+)
+
+// SecurityEvent - запись журнала безопасности. Пишется по имени из запроса,
+// а не по id: неудачные входы бывают и для несуществующих пользователей.
+type SecurityEvent struct {
+	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Type      string    `gorm:"not null" json:"type"`
+	Username  string    `gorm:"index" json:"username"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Session - вход пользователя с одного устройства. Refresh токены сессии образуют одну цепочку:
+// каждый обмен выдает новый токен, а повторное использование старого отзывает всю сессию.
+type Session struct {
+	ID         string     `gorm:"primaryKey" json:"id"`
+	UserID     int        `gorm:"index;not null" json:"-"`
+	UserAgent  string     `json:"device"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `gorm:"-" json:"current"`
+	User       User       `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// RefreshToken хранится только как sha256 хеш, сам токен есть лишь у клиента
+type RefreshToken struct {
+	Hash      string `gorm:"primaryKey"`
+	SessionID string `gorm:"index;not null"`
+	CreatedAt time.Time
+	UsedAt    *time.Time // время обмена на новый токен, повторный обмен - признак кражи
+	Session   Session    `gorm:"foreignKey:SessionID;references:ID;constraint:OnDelete:CASCADE"`
+}
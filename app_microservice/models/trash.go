@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+const (
+	TrashPost  = "post"
+	TrashImage = "image"
+)
+
+// TrashItem - удаленный пост или картинка в корзине пользователя
+type TrashItem struct {
+	Type string `json:"type"` // post или image
+	// ID - id поста или ключ картинки в хранилище
+	ID        string    `json:"id"`
+	Name      string    `json:"name"` // название поста или описание картинки
+	DeletedAt time.Time `json:"deleted_at"`
+	// PurgeAt - когда элемент будет удален навсегда
+	PurgeAt time.Time `json:"purge_at"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// TwoFactor - TOTP второй фактор пользователя. До подтверждения первым кодом Enabled = false
+// и вход по-прежнему проходит только по паролю.
+type TwoFactor struct {
+	UserID       int    `gorm:"primaryKey;autoIncrement:false"`
+	Secret       string `gorm:"not null"` // base32, нужен в открытом виде для вычисления кодов
+	Enabled      bool   `gorm:"not null;default:false"`
+	LastUsedStep int64  // шаг последнего принятого кода, защищает от повторного использования
+	CreatedAt    time.Time
+	ConfirmedAt  *time.Time
+	User         User `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// RecoveryCode - одноразовый код на случай потери телефона, хранится sha256 хеш
+type RecoveryCode struct {
+	ID     int    `gorm:"primaryKey;autoIncrement"`
+	UserID int    `gorm:"index;not null"`
+	Hash   string `gorm:"uniqueIndex;not null"`
+	UsedAt *time.Time
+	User   User `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorReauth - подтверждение личности для отключения 2FA и выпуска новых кодов восстановления.
+// Code - код из приложения или код восстановления. У пользователей без пароля Password пустой,
+// и подходит только код из приложения.
+type TwoFactorReauth struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// TwoFactorLogin - второй шаг входа
+type TwoFactorLogin struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -1,19 +1,38 @@
 package models
 
+import "time"
+
 type User struct {
-	ID             int     `gorm:"primary_key;autoIncrement" json:"id"`
-	Username       string  `gorm:"unique" json:"username"`
-	Email          string  `gorm:"unique" json:"email"`
-	Password       string  `json:"password"`
-	ProfilePicture string  `gorm:"unique" json:"profilePictureStorageKey"`
-	Images         []Image `json:"images"`
-	Albums         []Post  `json:"albums"`
+	ID             int        `gorm:"primary_key;autoIncrement" json:"id"`
+	Username       string     `gorm:"unique" json:"username"`
+	Email          string     `gorm:"unique" json:"email"`
+	Password       string     `json:"password"`
+	ProfilePicture string     `json:"profilePictureStorageKey"`
+	EmailVerified  bool       `gorm:"not null;default:false" json:"email_verified"`
+	Role           string     `gorm:"not null;default:user;index" json:"role"`
+	BannedAt       *time.Time `json:"banned_at,omitempty"`
+	BanReason      string     `json:"ban_reason,omitempty"`
+	Bio            string     `gorm:"not null;default:''" json:"bio"`
+	// Links - ссылки на сайты пользователя в публичном профиле
+	Links     []string  `gorm:"serializer:json;type:jsonb" json:"links"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"joined_at"`
+	// DeletionRequestedAt - аккаунт в очереди на удаление, данные удаляет AccountDeletion
+	DeletionRequestedAt *time.Time `json:"-"`
+	Images              []Image    `json:"images"`
+	Albums              []Post     `json:"albums"`
 }
 
 type UserProfile struct {
-	Username       string `json:"username"`
-	Email          string `json:"email"`
-	ProfilePicture string `json:"profile_picture"`
+	Username       string    `json:"username"`
+	Email          string    `json:"email"`
+	ProfilePicture string    `json:"profile_picture"`
+	EmailVerified  bool      `json:"email_verified"`
+	Role           string    `json:"role"`
+	Bio            string    `json:"bio"`
+	Links          []string  `gorm:"serializer:json" json:"links"`
+	CreatedAt      time.Time `json:"joined_at"`
+	// Avatars - ссылки на аватар по размерам, ProfilePicture - ссылка на самый большой
+	Avatars AvatarURLs `gorm:"-" json:"avatars,omitempty"`
 }
 
 type UserLogin struct {
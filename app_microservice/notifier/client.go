@@ -0,0 +1,53 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"pictureloader/common/servicetoken"
+	"time"
+)
+
+// Client ходит во внутренние ручки нотификатора с сервисным токеном
+type Client struct {
+	baseURL      string
+	serviceToken string
+	httpClient   *http.Client
+}
+
+func NewClient(baseURL, serviceToken string) *Client {
+	return &Client{
+		baseURL:      baseURL,
+		serviceToken: serviceToken,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetNotifications возвращает все уведомления пользователя в том виде, в каком их отдает нотификатор
+func (c *Client) GetNotifications(ctx context.Context, userID int) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/notifications/likes/%d", c.baseURL, userID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(servicetoken.Header, c.serviceToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get notifications of user %d: unexpected status %d", userID, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("get notifications of user %d: invalid JSON", userID)
+	}
+	return body, nil
+}
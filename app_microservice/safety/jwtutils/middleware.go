@@ -2,18 +2,29 @@ package jwtutils
 
 import (
 	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"log/slog"
 	"net/http"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"pictureloader/common/jwtauth"
+	"slices"
+	"strings"
 )
 
 func (u *UtilsJWT) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("user-cookie")
+		tokenString, err := jwtauth.TokenFromRequest(r)
 		if err != nil {
 			http.Error(w, "Unauthorized: No token provided", http.StatusUnauthorized)
 			return
 		}
 
-		tokenString := cookie.Value
+		if strings.HasPrefix(tokenString, models.APITokenPrefix) {
+			u.authenticateAPIToken(w, r, next, tokenString)
+			return
+		}
 
 		claims, err := u.ValidateAndExtractPayload(tokenString)
 		if err != nil {
@@ -21,7 +32,129 @@ func (u *UtilsJWT) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// токен без сессии нельзя отозвать, такие больше не принимаются
+		sessionID, ok := claims["sid"].(string)
+		if !ok || sessionID == "" {
+			http.Error(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
+			return
+		}
+		revoked, err := u.denylist.IsSessionRevoked(r.Context(), sessionID)
+		if err != nil {
+			slog.Error("Check session revocation", "error", err)
+			http.Error(w, "Session check unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if revoked {
+			http.Error(w, "Unauthorized: Session revoked", http.StatusUnauthorized)
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), "claims", claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// authenticateAPIToken пропускает запрос с персональным токеном. Хендлеры получают те же claims,
+// что и для сессии, но вместо sid в них лежат права токена.
+func (u *UtilsJWT) authenticateAPIToken(w http.ResponseWriter, r *http.Request, next http.Handler, raw string) {
+	token, err := u.apiTokens.Authenticate(r.Context(), raw)
+	if errors.Is(err, service.ErrInvalidAPIToken) {
+		http.Error(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Token check unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"sub":    float64(token.UserID),
+		"tid":    float64(token.ID),
+		"scopes": token.Scopes,
+	}
+	ctx := context.WithValue(r.Context(), "claims", claims)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireScope ограничивает ручку для персональных токенов правом scope.
+// Сессия пользователя имеет все права.
+func (u *UtilsJWT) RequireScope(scope string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := r.Context().Value("claims").(jwt.MapClaims)
+		scopes, isAPIToken := claims["scopes"].([]string)
+		if isAPIToken && !slices.Contains(scopes, scope) {
+			http.Error(w, "Forbidden: token lacks scope "+scope, http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// RequirePermission пропускает только пользователей, чья роль дает право permission.
+// Роль есть только в токене сессии, персональные токены действуют с правами обычного пользователя.
+func (u *UtilsJWT) RequirePermission(permission string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !models.RoleCan(Role(r), permission) {
+			http.Error(w, "Forbidden: permission "+permission+" required", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// RequireSession закрывает ручку для персональных токенов: управлять аккаунтом,
+// сессиями и самими токенами можно только после входа по паролю
+func (u *UtilsJWT) RequireSession(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if SessionID(r) == "" {
+			http.Error(w, "Forbidden: session required", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// RequireVerifiedEmail закрывает ручку для пользователей с неподтвержденной почтой
+func (u *UtilsJWT) RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := r.Context().Value("claims").(jwt.MapClaims)
+		userID, err := jwtauth.UserID(claims)
+		if err != nil {
+			http.Error(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
+			return
+		}
+		verified, err := u.verified.IsEmailVerified(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "Failed to check email verification", http.StatusInternalServerError)
+			return
+		}
+		if !verified {
+			http.Error(w, "Forbidden: email is not verified", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SessionID достает id сессии из claims запроса, прошедшего AuthMiddleware
+func SessionID(r *http.Request) string {
+	claims, ok := r.Context().Value("claims").(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	sessionID, _ := claims["sid"].(string)
+	return sessionID
+}
+
+// Role достает роль из claims запроса, прошедшего AuthMiddleware
+func Role(r *http.Request) string {
+	claims, ok := r.Context().Value("claims").(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	role, ok := claims["role"].(string)
+	if !ok {
+		return models.RoleUser
+	}
+	return role
+}
@@ -1,59 +1,59 @@
 package jwtutils
 
 import (
-	"fmt"
+	"context"
 	"github.com/golang-jwt/jwt/v5"
+	"pictureloader/app_microservice/models"
+	"pictureloader/common/jwtauth"
 	"time"
 )
 
+// Denylist хранит отозванные сессии, пока их access токены еще не истекли
+type Denylist interface {
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+// APITokenAuthenticator проверяет персональные токены из заголовка Authorization
+type APITokenAuthenticator interface {
+	Authenticate(ctx context.Context, raw string) (*models.APIToken, error)
+}
+
+// EmailVerificationChecker сообщает, подтвердил ли пользователь почту
+type EmailVerificationChecker interface {
+	IsEmailVerified(ctx context.Context, userID int) (bool, error)
+}
+
 type UtilsJWT struct {
+	keys      *jwtauth.KeySet
+	denylist  Denylist
+	apiTokens APITokenAuthenticator
+	verified  EmailVerificationChecker
+	accessTTL time.Duration
+}
+
+func NewUtilsJWT(keys *jwtauth.KeySet, denylist Denylist, apiTokens APITokenAuthenticator,
+	verified EmailVerificationChecker, accessTTL time.Duration) *UtilsJWT {
+	return &UtilsJWT{keys: keys, denylist: denylist, apiTokens: apiTokens, verified: verified, accessTTL: accessTTL}
 }
 
-var jwtSecret = []byte("xyeettttttttttttttttttta")
+// AccessTTL - время жизни access токена
+func (u *UtilsJWT) AccessTTL() time.Duration {
+	return u.accessTTL
+}
 
-func (u *UtilsJWT) GenerateToken(userID int) (string, error) {
+// GenerateToken выдает короткоживущий access токен сессии sessionID с ролью пользователя
+func (u *UtilsJWT) GenerateToken(userID int, sessionID string, role string) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"sub": userID,
-		"exp": time.Now().Add(time.Hour * 1).Unix(),
-		"iat": time.Now().Unix(),
+		"sub":  userID,
+		"sid":  sessionID,
+		"role": role,
+		"exp":  now.Add(u.accessTTL).Unix(),
+		"iat":  now.Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
-}
-
-func (u *UtilsJWT) ValidateToken(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("неизвестный метод подписи")
-		}
-		return jwtSecret, nil
-	})
-	if err != nil {
-		return nil, err
-	}
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
-	}
-	return token, err
+	return u.keys.Sign(claims)
 }
 
 func (u *UtilsJWT) ValidateAndExtractPayload(tokenString string) (jwt.MapClaims, error) {
-	token, err := u.ValidateToken(tokenString)
-	if err != nil {
-		return nil, err
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, fmt.Errorf("cant get claims")
-	}
-	exp, ok := claims["exp"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("exp claim is missing or invalid")
-	}
-
-	if int64(exp) <= time.Now().Unix() {
-		return nil, fmt.Errorf("token is expired")
-	}
-	return claims, nil
+	return u.keys.Verify(tokenString)
 }
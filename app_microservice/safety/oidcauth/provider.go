@@ -0,0 +1,118 @@
+// Package oidcauth - вход через внешних OpenID Connect провайдеров (authorization code + PKCE)
+package oidcauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ProviderConfig - настройки одного провайдера из конфига
+type ProviderConfig struct {
+	Name         string // короткое имя в URL, например company
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string // openid добавляется всегда
+}
+
+// Identity - пользователь, подтвержденный провайдером
+type Identity struct {
+	Provider          string // имя провайдера в конфиге
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+type Provider struct {
+	Name        string
+	DisplayName string
+	oauth       oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+}
+
+// NewProvider загружает discovery документ провайдера
+func NewProvider(ctx context.Context, cfg ProviderConfig) (*Provider, error) {
+	discovered, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover oidc provider %s: %w", cfg.Name, err)
+	}
+	scopes := []string{oidc.ScopeOpenID}
+	for _, scope := range cfg.Scopes {
+		if scope != oidc.ScopeOpenID {
+			scopes = append(scopes, scope)
+		}
+	}
+	displayName := cfg.DisplayName
+	if displayName == "" {
+		displayName = cfg.Name
+	}
+	return &Provider{
+		Name:        cfg.Name,
+		DisplayName: displayName,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     discovered.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: discovered.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+// AuthCodeURL - куда отправить пользователя. state и nonce связывают ответ с запросом,
+// verifier - секрет PKCE, который понадобится при обмене кода.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange обменивает код на токены и проверяет ID токен
+func (p *Provider) Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error) {
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchange code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify id token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     bool   `json:"email_verified"`
+		PreferredUsername string `json:"preferred_username"`
+		Name              string `json:"name"`
+	}
+	if err = idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("parse id token claims: %w", err)
+	}
+	return &Identity{
+		Provider:          p.Name,
+		Issuer:            idToken.Issuer,
+		Subject:           idToken.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+		PreferredUsername: claims.PreferredUsername,
+		Name:              claims.Name,
+	}, nil
+}
+
+// GenerateVerifier - новый секрет PKCE
+func GenerateVerifier() string {
+	return oauth2.GenerateVerifier()
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"pictureloader/app_microservice/models"
+	"pictureloader/common/mail"
+	"time"
+)
+
+const minPasswordLength = 8
+
+var (
+	ErrInvalidUserToken     = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrWeakPassword         = fmt.Errorf("password must be at least %d characters", minPasswordLength)
+)
+
+type AccountRepositoryInterface interface {
+	CreateUserToken(ctx context.Context, token *models.UserToken) error
+	UseUserToken(ctx context.Context, tokenHash, purpose string, now time.Time) (int, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserEmail(ctx context.Context, userID int) (string, bool, error)
+	SetEmailVerified(ctx context.Context, userID int) error
+}
+
+// PasswordUpdater меняет пароль и разлогинивает пользователя на всех устройствах
+type PasswordUpdater interface {
+	UpdatePassword(ctx context.Context, userID int, newPassword string) error
+}
+
+type AccountConfig struct {
+	PublicURL      string // адрес фронтенда, на страницы которого ведут ссылки из писем
+	VerifyEmailTTL time.Duration
+	ResetTTL       time.Duration
+}
+
+// AccountService - подтверждение почты и восстановление пароля через письма с одноразовыми токенами
+type AccountService struct {
+	repo   AccountRepositoryInterface
+	users  PasswordUpdater
+	sender mail.Sender
+	cfg    AccountConfig
+}
+
+func NewAccountService(repo AccountRepositoryInterface, users PasswordUpdater, sender mail.Sender, cfg AccountConfig) *AccountService {
+	return &AccountService{repo: repo, users: users, sender: sender, cfg: cfg}
+}
+
+// SendVerification отправляет письмо со ссылкой подтверждения почты
+func (s *AccountService) SendVerification(ctx context.Context, userID int) error {
+	email, verified, err := s.repo.GetUserEmail(ctx, userID)
+	if err != nil {
+		slog.Error("Get user email", "error", err)
+		return err
+	}
+	if verified {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := s.issueToken(ctx, userID, models.TokenPurposeVerifyEmail, s.cfg.VerifyEmailTTL)
+	if err != nil {
+		return err
+	}
+	link := s.link("/verify-email", token)
+	return s.send(ctx, mail.Message{
+		To:      email,
+		Subject: "Подтвердите почту",
+		Text: fmt.Sprintf("Чтобы подтвердить почту, перейдите по ссылке:\n%s\n\nСсылка действует %s. "+
+			"Если вы не регистрировались, просто проигнорируйте письмо.\n", link, s.cfg.VerifyEmailTTL),
+	})
+}
+
+// VerifyEmail подтверждает почту по токену из письма
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.repo.UseUserToken(ctx, hashToken(token), models.TokenPurposeVerifyEmail, time.Now())
+	if err != nil {
+		slog.Error("Use verification token", "error", err)
+		return err
+	}
+	if userID == 0 {
+		return ErrInvalidUserToken
+	}
+	if err = s.repo.SetEmailVerified(ctx, userID); err != nil {
+		slog.Error("Set email verified", "error", err)
+		return err
+	}
+	return nil
+}
+
+// RequestPasswordReset отправляет письмо со ссылкой сброса пароля. Неизвестная почта не считается ошибкой,
+// чтобы по ответу нельзя было узнать, зарегистрирован ли адрес.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		slog.Error("Get user by email", "error", err)
+		return err
+	}
+	if user == nil {
+		slog.Info("Password reset requested for unknown email")
+		return nil
+	}
+
+	token, err := s.issueToken(ctx, user.ID, models.TokenPurposeResetPassword, s.cfg.ResetTTL)
+	if err != nil {
+		return err
+	}
+	link := s.link("/reset-password", token)
+	return s.send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Text: fmt.Sprintf("Для аккаунта %s запрошен сброс пароля. Чтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %s и сработает один раз. Если вы не запрашивали сброс, проигнорируйте письмо.\n",
+			user.Username, link, s.cfg.ResetTTL),
+	})
+}
+
+// ResetPassword задает новый пароль по токену из письма. Все сессии пользователя отзываются,
+// а почта считается подтвержденной - письмо до пользователя дошло.
+func (s *AccountService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if len(newPassword) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	userID, err := s.repo.UseUserToken(ctx, hashToken(token), models.TokenPurposeResetPassword, time.Now())
+	if err != nil {
+		slog.Error("Use reset token", "error", err)
+		return err
+	}
+	if userID == 0 {
+		return ErrInvalidUserToken
+	}
+	if err = s.users.UpdatePassword(ctx, userID, newPassword); err != nil {
+		return err
+	}
+	if err = s.repo.SetEmailVerified(ctx, userID); err != nil {
+		slog.Error("Set email verified", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *AccountService) issueToken(ctx context.Context, userID int, purpose string, ttl time.Duration) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	err = s.repo.CreateUserToken(ctx, &models.UserToken{
+		Hash:      hashToken(token),
+		UserID:    userID,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		slog.Error("Create user token", "error", err, "purpose", purpose)
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *AccountService) link(path, token string) string {
+	return s.cfg.PublicURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func (s *AccountService) send(ctx context.Context, msg mail.Message) error {
+	if err := s.sender.Send(ctx, msg); err != nil {
+		slog.Error("Send mail", "error", err, "subject", msg.Subject)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+const (
+	deletionBatchSize = 10
+	// deletionLease - на сколько задача откладывается, пока ее выполняет одна из реплик
+	deletionLease      = 10 * time.Minute
+	maxDeletionBackoff = time.Hour
+)
+
+type DeletionRepositoryInterface interface {
+	RequestDeletion(ctx context.Context, userID int, now time.Time) (*models.AccountDeletion, error)
+	ClaimDueDeletions(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.AccountDeletion, error)
+	SaveDeletion(ctx context.Context, deletion *models.AccountDeletion) error
+	DeleteUserLikes(ctx context.Context, userID int) ([]int, error)
+	GetAllUserPostIDs(ctx context.Context, userID int) ([]int, error)
+	GetAllUserImageKeys(ctx context.Context, userID int) ([]string, error)
+	DeleteUser(ctx context.Context, userID int) error
+	GetDeletions(ctx context.Context, filter models.DeletionFilter) ([]models.AccountDeletion, error)
+}
+
+type DeletionCache interface {
+	InvalidatePost(ctx context.Context, postID int) (bool, error)
+	InvalidateMostLikedPosts(ctx context.Context) error
+}
+
+// AvatarRemover удаляет аватар пользователя вместе с файлами
+type AvatarRemover interface {
+	DeleteAvatar(ctx context.Context, userID int) error
+}
+
+type DeletionEventPublisher interface {
+	PublishUserDeleted(ctx context.Context, deletion *models.AccountDeletion) error
+}
+
+// AccountDeletionService удаляет аккаунт в фоне: лайки, посты, картинки и аватар вместе с файлами, кеш
+// и самого пользователя, затем сообщает нотификатору событием user.deleted.
+// Пользователь теряет доступ сразу при запросе, данные удаляются задачей, которая переживает рестарты.
+type AccountDeletionService struct {
+	repo     DeletionRepositoryInterface
+	sessions SessionRevoker
+	posts    PostRemover
+	pictures PictureRemover
+	avatars  AvatarRemover
+	cache    DeletionCache
+	events   DeletionEventPublisher
+}
+
+func NewAccountDeletionService(repo DeletionRepositoryInterface, sessions SessionRevoker, posts PostRemover,
+	pictures PictureRemover, avatars AvatarRemover, cache DeletionCache, events DeletionEventPublisher) *AccountDeletionService {
+	return &AccountDeletionService{repo: repo, sessions: sessions, posts: posts, pictures: pictures, avatars: avatars,
+		cache: cache, events: events}
+}
+
+// RequestDeletion ставит аккаунт в очередь на удаление и разлогинивает пользователя везде
+func (s *AccountDeletionService) RequestDeletion(ctx context.Context, userID int) (*models.AccountDeletion, error) {
+	deletion, err := s.repo.RequestDeletion(ctx, userID, time.Now())
+	if err != nil {
+		slog.Error("Request account deletion", "error", err)
+		return nil, err
+	}
+	if deletion == nil {
+		return nil, ErrUserNotFound
+	}
+	if err = s.sessions.RevokeAllSessions(ctx, userID); err != nil {
+		slog.Error("Request account deletion revoke sessions", "error", err)
+		return nil, err
+	}
+	return deletion, nil
+}
+
+func (s *AccountDeletionService) ListDeletions(ctx context.Context, filter models.DeletionFilter) ([]models.AccountDeletion, error) {
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	deletions, err := s.repo.GetDeletions(ctx, filter)
+	if err != nil {
+		slog.Error("Get account deletions", "error", err)
+		return nil, err
+	}
+	return deletions, nil
+}
+
+// ProcessDue выполняет задачи, которым пора, и возвращает, сколько из них завершено
+func (s *AccountDeletionService) ProcessDue(ctx context.Context, now time.Time) (int, error) {
+	deletions, err := s.repo.ClaimDueDeletions(ctx, now, deletionBatchSize, deletionLease)
+	if err != nil {
+		slog.Error("Claim account deletions", "error", err)
+		return 0, err
+	}
+	completed := 0
+	for i := range deletions {
+		if s.process(ctx, &deletions[i]) {
+			completed++
+		}
+	}
+	return completed, nil
+}
+
+// process выполняет шаги задачи начиная с сохраненного. После каждого шага задача сохраняется,
+// при ошибке откладывается с растущей паузой и при следующем запуске продолжит с того же шага.
+func (s *AccountDeletionService) process(ctx context.Context, deletion *models.AccountDeletion) bool {
+	for deletion.Step != "" {
+		if err := s.runStep(ctx, deletion); err != nil {
+			deletion.Attempts++
+			deletion.LastError = err.Error()
+			deletion.NextAttemptAt = time.Now().Add(deletionBackoff(deletion.Attempts))
+			slog.Error("Account deletion step failed", "error", err, "userID", deletion.UserID, "step", deletion.Step)
+			s.save(ctx, deletion)
+			return false
+		}
+		deletion.Step = nextDeletionStep(deletion.Step)
+		if deletion.Step == "" {
+			now := time.Now()
+			deletion.Status = models.DeletionCompleted
+			deletion.CompletedAt = &now
+			deletion.LastError = ""
+		}
+		if !s.save(ctx, deletion) {
+			return false
+		}
+	}
+	slog.Info("Account deleted", "userID", deletion.UserID, "posts", deletion.PostsDeleted,
+		"images", deletion.ImagesDeleted, "likes", deletion.LikesDeleted)
+	return true
+}
+
+func (s *AccountDeletionService) runStep(ctx context.Context, deletion *models.AccountDeletion) error {
+	userID := deletion.UserID
+	switch deletion.Step {
+	case models.DeletionStepLikes:
+		postIDs, err := s.repo.DeleteUserLikes(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, postID := range postIDs {
+			if _, err = s.cache.InvalidatePost(ctx, postID); err != nil {
+				return err
+			}
+		}
+		deletion.LikesDeleted += len(postIDs)
+	case models.DeletionStepPosts:
+		postIDs, err := s.repo.GetAllUserPostIDs(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, postID := range postIDs {
+			if err = s.posts.RemovePost(ctx, postID); err != nil {
+				return err
+			}
+			deletion.PostsDeleted++
+		}
+	case models.DeletionStepImages:
+		imageSKs, err := s.repo.GetAllUserImageKeys(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, imageSK := range imageSKs {
+			if err = s.pictures.RemovePicture(ctx, imageSK); err != nil {
+				return err
+			}
+			deletion.ImagesDeleted++
+		}
+		// в самых популярных постах могли быть лайки, посты или картинки пользователя
+		return s.cache.InvalidateMostLikedPosts(ctx)
+	case models.DeletionStepUser:
+		// пользователь уже удален, если шаг повторяется после сбоя сохранения задачи
+		if err := s.avatars.DeleteAvatar(ctx, userID); err != nil && !errors.Is(err, ErrUserNotFound) {
+			return err
+		}
+		return s.repo.DeleteUser(ctx, userID)
+	case models.DeletionStepEvent:
+		return s.events.PublishUserDeleted(ctx, deletion)
+	default:
+		return fmt.Errorf("unknown account deletion step %q", deletion.Step)
+	}
+	return nil
+}
+
+func (s *AccountDeletionService) save(ctx context.Context, deletion *models.AccountDeletion) bool {
+	if err := s.repo.SaveDeletion(ctx, deletion); err != nil {
+		// задача вернется по истечении lease и повторит шаг, шаги можно повторять
+		slog.Error("Save account deletion", "error", err, "userID", deletion.UserID)
+		return false
+	}
+	return true
+}
+
+func nextDeletionStep(step string) string {
+	for i, s := range models.DeletionSteps {
+		if s == step && i+1 < len(models.DeletionSteps) {
+			return models.DeletionSteps[i+1]
+		}
+	}
+	return ""
+}
+
+// deletionBackoff - пауза перед повтором: 1, 4, 9... минут, но не больше часа
+func deletionBackoff(attempts int) time.Duration {
+	backoff := time.Duration(attempts*attempts) * time.Minute
+	if backoff > maxDeletionBackoff {
+		return maxDeletionBackoff
+	}
+	return backoff
+}
+
+// Run выполняет задачи удаления раз в interval, пока не отменен ctx
+func (s *AccountDeletionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.ProcessDue(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"pictureloader/app_microservice/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultUsersPageSize = 50
+	maxUsersPageSize     = 100
+	maxBanReasonLength   = 500
+)
+
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrInvalidRole    = errors.New("invalid role")
+	ErrSelfModeration = errors.New("cannot moderate yourself")
+	ErrForbiddenUser  = errors.New("not allowed to manage this user")
+)
+
+type AdminRepositoryInterface interface {
+	GetUsers(ctx context.Context, filter models.UserFilter) ([]models.AdminUser, error)
+	GetUserAccess(ctx context.Context, userID int) (*models.UserAccess, error)
+	SetUserBan(ctx context.Context, userID int, bannedAt *time.Time, reason string) (bool, error)
+	SetUserRole(ctx context.Context, userID int, role string) (bool, error)
+	PromoteAdmins(ctx context.Context, userIDs []int) error
+}
+
+// Actor - пользователь, который выполняет действие, с ролью из его токена
+type Actor struct {
+	UserID int
+	Role   string
+}
+
+// AdminService - управление пользователями для модераторов и администраторов.
+// Какие ручки доступны роли, решает middleware, здесь проверяется только, над кем можно действовать.
+type AdminService struct {
+	repo     AdminRepositoryInterface
+	sessions SessionRevoker
+	audit    AuditRecorder
+}
+
+func NewAdminService(repo AdminRepositoryInterface, sessions SessionRevoker, audit AuditRecorder) *AdminService {
+	return &AdminService{repo: repo, sessions: sessions, audit: audit}
+}
+
+// PromoteAdmins выдает роль admin пользователям из конфига, чтобы первого администратора
+// не приходилось назначать через базу
+func (s *AdminService) PromoteAdmins(ctx context.Context, userIDs []int) error {
+	err := s.repo.PromoteAdmins(ctx, userIDs)
+	if err != nil {
+		slog.Error("Promote admins", "error", err)
+	}
+	return err
+}
+
+func (s *AdminService) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.AdminUser, error) {
+	if filter.Role != "" && models.RoleRank(filter.Role) < 0 {
+		return nil, ErrInvalidRole
+	}
+	if filter.Limit <= 0 || filter.Limit > maxUsersPageSize {
+		filter.Limit = defaultUsersPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+	users, err := s.repo.GetUsers(ctx, filter)
+	if err != nil {
+		slog.Error("Get users", "error", err)
+		return nil, err
+	}
+	return users, nil
+}
+
+// BanUser блокирует пользователя и сразу завершает все его сессии
+func (s *AdminService) BanUser(ctx context.Context, actor Actor, userID int, reason string) error {
+	return s.ban(ctx, actor, userID, reason, nil)
+}
+
+// BanForReport блокирует пользователя по жалобе, запись аудита ссылается на жалобу
+func (s *AdminService) BanForReport(ctx context.Context, actor Actor, userID int, reason string, reportID int) error {
+	return s.ban(ctx, actor, userID, reason, &reportID)
+}
+
+func (s *AdminService) ban(ctx context.Context, actor Actor, userID int, reason string, reportID *int) error {
+	if _, err := s.target(ctx, actor, userID); err != nil {
+		return err
+	}
+	reason = strings.TrimSpace(reason)
+	if len(reason) > maxBanReasonLength {
+		reason = reason[:maxBanReasonLength]
+	}
+	now := time.Now()
+	if _, err := s.repo.SetUserBan(ctx, userID, &now, reason); err != nil {
+		slog.Error("Ban user", "error", err)
+		return err
+	}
+	if err := s.sessions.RevokeAllSessions(ctx, userID); err != nil {
+		slog.Error("Ban user revoke sessions", "error", err)
+		return err
+	}
+	recordAudit(ctx, s.audit, actor, models.ModerationBan, models.ReportTargetUser, strconv.Itoa(userID), reportID, reason)
+	return nil
+}
+
+func (s *AdminService) UnbanUser(ctx context.Context, actor Actor, userID int) error {
+	if _, err := s.target(ctx, actor, userID); err != nil {
+		return err
+	}
+	if _, err := s.repo.SetUserBan(ctx, userID, nil, ""); err != nil {
+		slog.Error("Unban user", "error", err)
+		return err
+	}
+	recordAudit(ctx, s.audit, actor, models.AuditUnban, models.ReportTargetUser, strconv.Itoa(userID), nil, "")
+	return nil
+}
+
+// SetRole меняет роль пользователя. Роль зашита в access токен, поэтому при понижении
+// сессии отзываются сразу, а повышение вступает в силу при следующем обмене refresh токена.
+func (s *AdminService) SetRole(ctx context.Context, actor Actor, userID int, role string) error {
+	if models.RoleRank(role) < 0 {
+		return ErrInvalidRole
+	}
+	access, err := s.target(ctx, actor, userID)
+	if err != nil {
+		return err
+	}
+	if _, err = s.repo.SetUserRole(ctx, userID, role); err != nil {
+		slog.Error("Set user role", "error", err)
+		return err
+	}
+	if models.RoleRank(role) < models.RoleRank(access.Role) {
+		if err = s.sessions.RevokeAllSessions(ctx, userID); err != nil {
+			slog.Error("Set user role revoke sessions", "error", err)
+			return err
+		}
+	}
+	recordAudit(ctx, s.audit, actor, models.AuditRole, models.ReportTargetUser, strconv.Itoa(userID), nil,
+		access.Role+" -> "+role)
+	return nil
+}
+
+// target проверяет, что actor может действовать над пользователем: не над собой,
+// а модератор - только над обычными пользователями
+func (s *AdminService) target(ctx context.Context, actor Actor, userID int) (*models.UserAccess, error) {
+	if actor.UserID == userID {
+		return nil, ErrSelfModeration
+	}
+	access, err := s.repo.GetUserAccess(ctx, userID)
+	if err != nil {
+		slog.Error("Get user access", "error", err)
+		return nil, err
+	}
+	if access == nil {
+		return nil, ErrUserNotFound
+	}
+	if actor.Role != models.RoleAdmin && models.RoleRank(access.Role) >= models.RoleRank(actor.Role) {
+		return nil, ErrForbiddenUser
+	}
+	return access, nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"pictureloader/app_microservice/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	maxAPITokensPerUser = 50
+	maxAPITokenDays     = 365
+	// apiTokenTouchInterval - как часто обновляется время последнего использования токена
+	apiTokenTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIToken  = errors.New("invalid api token")
+	ErrAPITokenNotFound = errors.New("api token not found")
+)
+
+// APITokenValidationError - некорректные параметры создаваемого токена
+type APITokenValidationError struct {
+	Reason string
+}
+
+func (e *APITokenValidationError) Error() string {
+	return e.Reason
+}
+
+type APITokenRepositoryInterface interface {
+	CreateAPIToken(ctx context.Context, token *models.APIToken) error
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error)
+	GetAPITokens(ctx context.Context, userID int) ([]models.APIToken, error)
+	TouchAPIToken(ctx context.Context, tokenID int, now time.Time, interval time.Duration) error
+	RevokeAPIToken(ctx context.Context, userID, tokenID int, now time.Time) (bool, error)
+}
+
+type APITokenService struct {
+	repo APITokenRepositoryInterface
+}
+
+func NewAPITokenService(repo APITokenRepositoryInterface) *APITokenService {
+	return &APITokenService{repo: repo}
+}
+
+// CreateToken выпускает персональный токен. Токен целиком возвращается только здесь.
+func (s *APITokenService) CreateToken(ctx context.Context, userID int, req models.APITokenCreate) (*models.APITokenCreated, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, &APITokenValidationError{Reason: "name must be 1-100 characters"}
+	}
+	if len(req.Scopes) == 0 {
+		return nil, &APITokenValidationError{Reason: "at least one scope is required"}
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.Scopes, scope) {
+			return nil, &APITokenValidationError{Reason: fmt.Sprintf("unknown scope %q", scope)}
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPITokenDays {
+		return nil, &APITokenValidationError{Reason: fmt.Sprintf("expires_in_days must be 0-%d", maxAPITokenDays)}
+	}
+
+	existing, err := s.repo.GetAPITokens(ctx, userID)
+	if err != nil {
+		slog.Error("Get api tokens", "error", err)
+		return nil, err
+	}
+	if len(existing) >= maxAPITokensPerUser {
+		return nil, &APITokenValidationError{Reason: "too many api tokens"}
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	raw := models.APITokenPrefix + secret
+	token := models.APIToken{
+		UserID: userID,
+		Name:   name,
+		Hint:   raw[:len(models.APITokenPrefix)+4],
+		Hash:   hashToken(raw),
+		Scopes: scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+	if err = s.repo.CreateAPIToken(ctx, &token); err != nil {
+		slog.Error("Create api token", "error", err)
+		return nil, err
+	}
+	return &models.APITokenCreated{APIToken: token, Token: raw}, nil
+}
+
+// Authenticate находит действующий токен по его значению и отмечает использование
+func (s *APITokenService) Authenticate(ctx context.Context, raw string) (*models.APIToken, error) {
+	token, err := s.repo.GetAPITokenByHash(ctx, hashToken(raw))
+	if err != nil {
+		slog.Error("Get api token", "error", err)
+		return nil, err
+	}
+	now := time.Now()
+	if token == nil || (token.ExpiresAt != nil && !token.ExpiresAt.After(now)) {
+		return nil, ErrInvalidAPIToken
+	}
+	// ошибка обновления статистики не должна ломать запрос
+	if err = s.repo.TouchAPIToken(ctx, token.ID, now, apiTokenTouchInterval); err != nil {
+		slog.Warn("Touch api token", "error", err, "tokenID", token.ID)
+	}
+	return token, nil
+}
+
+func (s *APITokenService) ListTokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	tokens, err := s.repo.GetAPITokens(ctx, userID)
+	if err != nil {
+		slog.Error("Get api tokens", "error", err)
+	}
+	return tokens, err
+}
+
+func (s *APITokenService) RevokeToken(ctx context.Context, userID, tokenID int) error {
+	revoked, err := s.repo.RevokeAPIToken(ctx, userID, tokenID, time.Now())
+	if err != nil {
+		slog.Error("Revoke api token", "error", err)
+		return err
+	}
+	if !revoked {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
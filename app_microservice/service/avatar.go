@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"pictureloader/app_microservice/image_storage"
+	"pictureloader/app_microservice/models"
+	"strconv"
+)
+
+const (
+	// MaxAvatarUploadSize - наибольший размер загружаемого файла аватара
+	MaxAvatarUploadSize = 10 << 20
+	// maxAvatarSourceSide и maxAvatarSourcePixels защищают от картинок, которые раскрываются в гигабайты памяти
+	maxAvatarSourceSide   = 8000
+	maxAvatarSourcePixels = 40_000_000
+)
+
+var ErrAvatarSourceNotFound = errors.New("picture not found")
+
+// AvatarValidationError - файл не картинка или неверная область обрезки
+type AvatarValidationError struct {
+	Reason string
+}
+
+func (e *AvatarValidationError) Error() string {
+	return e.Reason
+}
+
+type AvatarRepositoryInterface interface {
+	IsAvatarSource(ctx context.Context, userID int, imageSK string) (bool, error)
+	SetAvatar(ctx context.Context, userID int, key string) (string, bool, error)
+}
+
+// AvatarService делает из картинки квадратные аватары всех размеров из models.AvatarSizes.
+// Аватары хранятся под префиксом models.AvatarPrefix отдельно от картинок пользователя,
+// у каждой версии свой ключ, поэтому закешированные ссылки на старый аватар не показывают новый.
+type AvatarService struct {
+	repo    AvatarRepositoryInterface
+	storage image_storage.ImageStorage
+}
+
+func NewAvatarService(repo AvatarRepositoryInterface, storage image_storage.ImageStorage) *AvatarService {
+	return &AvatarService{repo: repo, storage: storage}
+}
+
+// UploadAvatar делает аватар из загруженного файла, crop nil - квадрат по центру
+func (s *AvatarService) UploadAvatar(ctx context.Context, userID int, payload io.Reader, crop *models.AvatarCrop) (models.AvatarURLs, error) {
+	data, err := io.ReadAll(io.LimitReader(payload, MaxAvatarUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxAvatarUploadSize {
+		return nil, &AvatarValidationError{Reason: "file must be at most 10 MB"}
+	}
+	return s.setAvatar(ctx, userID, data, crop)
+}
+
+// SetAvatarFromPicture делает аватар из картинки, которую пользователь уже загрузил
+func (s *AvatarService) SetAvatarFromPicture(ctx context.Context, userID int, req models.AvatarFromPicture) (models.AvatarURLs, error) {
+	ok, err := s.repo.IsAvatarSource(ctx, userID, req.PictureSK)
+	if err != nil {
+		slog.Error("Check avatar source", "error", err)
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrAvatarSourceNotFound
+	}
+	file, err := s.storage.GetFile(ctx, req.PictureSK)
+	if errors.Is(err, image_storage.ErrFileNotFound) {
+		return nil, ErrAvatarSourceNotFound
+	}
+	if err != nil {
+		slog.Error("Get avatar source", "error", err)
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		slog.Error("Read avatar source", "error", err)
+		return nil, err
+	}
+	return s.setAvatar(ctx, userID, data, req.Crop)
+}
+
+// DeleteAvatar убирает аватар пользователя и удаляет его файлы
+func (s *AvatarService) DeleteAvatar(ctx context.Context, userID int) error {
+	previous, found, err := s.repo.SetAvatar(ctx, userID, "")
+	if err != nil {
+		slog.Error("Delete avatar", "error", err)
+		return err
+	}
+	if !found {
+		return ErrUserNotFound
+	}
+	return s.removeFiles(ctx, previous)
+}
+
+func (s *AvatarService) setAvatar(ctx context.Context, userID int, data []byte, crop *models.AvatarCrop) (models.AvatarURLs, error) {
+	files, err := renderAvatars(data, crop)
+	if err != nil {
+		return nil, err
+	}
+	key, err := newAvatarKey(userID)
+	if err != nil {
+		return nil, err
+	}
+	for size, file := range files {
+		err = s.storage.PutFile(ctx, models.AvatarFileKey(key, size), bytes.NewReader(file), int64(len(file)), "image/png")
+		if err != nil {
+			slog.Error("Upload avatar", "error", err)
+			s.removeFiles(ctx, key)
+			return nil, err
+		}
+	}
+	previous, found, err := s.repo.SetAvatar(ctx, userID, key)
+	if err == nil && !found {
+		err = ErrUserNotFound
+	}
+	if err != nil {
+		slog.Error("Set avatar", "error", err)
+		s.removeFiles(ctx, key)
+		return nil, err
+	}
+	// старый аватар больше никому не нужен, ошибка удаления не отменяет замену
+	s.removeFiles(ctx, previous)
+	return avatarURLs(ctx, s.storage, key), nil
+}
+
+// removeFiles удаляет файлы аватара. Ключи картинок, которые раньше ставились аватаром напрямую,
+// не трогаются: это картинки пользователя.
+func (s *AvatarService) removeFiles(ctx context.Context, key string) error {
+	if !models.IsAvatarKey(key) {
+		return nil
+	}
+	var result error
+	for _, size := range models.AvatarSizes {
+		if err := s.storage.DeleteFileByURL(ctx, models.AvatarFileKey(key, size)); err != nil {
+			slog.Error("Delete avatar file", "error", err, "key", key, "size", size)
+			result = err
+		}
+	}
+	return result
+}
+
+func newAvatarKey(userID int) (string, error) {
+	version := make([]byte, 8)
+	if _, err := rand.Read(version); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d/%s", models.AvatarPrefix, userID, hex.EncodeToString(version)), nil
+}
+
+// avatarURLs возвращает ссылки на аватар по размерам. Для ключа картинки, поставленной аватаром
+// до появления размеров, все размеры ведут на саму картинку.
+func avatarURLs(ctx context.Context, storage UserStorageManager, key string) models.AvatarURLs {
+	if key == "" {
+		return nil
+	}
+	urls := models.AvatarURLs{}
+	for _, size := range models.AvatarSizes {
+		fileKey := key
+		if models.IsAvatarKey(key) {
+			fileKey = models.AvatarFileKey(key, size)
+		}
+		fileURL, err := storage.GetFileURL(ctx, fileKey)
+		if err != nil {
+			slog.Error("Get avatar URL", "error", err, "key", fileKey)
+			return nil
+		}
+		urls[strconv.Itoa(size)] = fileURL
+	}
+	return urls
+}
+
+// largestAvatar - ссылка на самый большой размер аватара
+func largestAvatar(urls models.AvatarURLs) string {
+	return urls[strconv.Itoa(models.AvatarSizes[len(models.AvatarSizes)-1])]
+}
+
+// renderAvatars обрезает картинку и возвращает PNG файлы аватара по размерам
+func renderAvatars(data []byte, crop *models.AvatarCrop) (map[int][]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, &AvatarValidationError{Reason: "file must be a PNG, JPEG or GIF image"}
+	}
+	if config.Width > maxAvatarSourceSide || config.Height > maxAvatarSourceSide ||
+		config.Width*config.Height > maxAvatarSourcePixels {
+		return nil, &AvatarValidationError{Reason: "image must be at most 8000 pixels on each side"}
+	}
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, &AvatarValidationError{Reason: "file must be a PNG, JPEG or GIF image"}
+	}
+	area, err := cropArea(source.Bounds(), crop)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[int][]byte{}
+	// большие размеры уменьшаются из исходника, меньшие - из уже уменьшенного, так быстрее
+	var previous image.Image = source
+	previousArea := area
+	for i := len(models.AvatarSizes) - 1; i >= 0; i-- {
+		size := models.AvatarSizes[i]
+		avatar := resizeSquare(previous, previousArea, size)
+		var buf bytes.Buffer
+		if err = png.Encode(&buf, avatar); err != nil {
+			return nil, err
+		}
+		files[size] = buf.Bytes()
+		previous, previousArea = avatar, avatar.Bounds()
+	}
+	return files, nil
+}
+
+// cropArea проверяет область обрезки и делает ее квадратной: из прямоугольника берется
+// наибольший квадрат по центру
+func cropArea(bounds image.Rectangle, crop *models.AvatarCrop) (image.Rectangle, error) {
+	area := bounds
+	if crop != nil && (crop.Width != 0 || crop.Height != 0) {
+		area = image.Rect(crop.X, crop.Y, crop.X+crop.Width, crop.Y+crop.Height).Add(bounds.Min)
+		if crop.X < 0 || crop.Y < 0 || crop.Width <= 0 || crop.Height <= 0 || !area.In(bounds) {
+			return image.Rectangle{}, &AvatarValidationError{
+				Reason: fmt.Sprintf("crop must be inside the %dx%d image", bounds.Dx(), bounds.Dy())}
+		}
+	}
+	side := min(area.Dx(), area.Dy())
+	if side < models.AvatarSizes[0] {
+		return image.Rectangle{}, &AvatarValidationError{
+			Reason: fmt.Sprintf("crop must be at least %d pixels on each side", models.AvatarSizes[0])}
+	}
+	x := area.Min.X + (area.Dx()-side)/2
+	y := area.Min.Y + (area.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side), nil
+}
+
+// resizeSquare масштабирует квадрат area картинки в квадрат size x size, усредняя пиксели,
+// которые попадают в каждый пиксель результата
+func resizeSquare(source image.Image, area image.Rectangle, size int) *image.NRGBA {
+	result := image.NewNRGBA(image.Rect(0, 0, size, size))
+	side := area.Dx()
+	for y := 0; y < size; y++ {
+		y0, y1 := scaleSpan(y, side, size)
+		for x := 0; x < size; x++ {
+			x0, x1 := scaleSpan(x, side, size)
+			var r, g, b, a, n uint64
+			for sy := area.Min.Y + y0; sy < area.Min.Y+y1; sy++ {
+				for sx := area.Min.X + x0; sx < area.Min.X+x1; sx++ {
+					// RGBA отдает цвета, умноженные на альфу, поэтому прозрачные края не темнеют
+					pr, pg, pb, pa := source.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			result.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return result
+}
+
+// scaleSpan - пиксели исходника [from, to), которые попадают в пиксель i результата.
+// При увеличении в пиксель попадает один ближайший пиксель исходника.
+func scaleSpan(i, side, size int) (int, int) {
+	from := i * side / size
+	to := (i + 1) * side / size
+	if to <= from {
+		to = from + 1
+	}
+	return from, to
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"pictureloader/app_microservice/image_storage"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+const (
+	exportBatchSize = 5
+	// exportLease - на сколько задача откладывается, пока архив собирает одна из реплик
+	exportLease       = 30 * time.Minute
+	maxExportAttempts = 5
+)
+
+type ExportRepositoryInterface interface {
+	CreateExport(ctx context.Context, export *models.DataExport) error
+	GetActiveExport(ctx context.Context, userID int, now time.Time) (*models.DataExport, error)
+	GetUserExports(ctx context.Context, userID int) ([]models.DataExport, error)
+	ClaimDueExports(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.DataExport, error)
+	SaveExport(ctx context.Context, export *models.DataExport) error
+	GetExpiredExports(ctx context.Context, now time.Time, limit int) ([]models.DataExport, error)
+}
+
+type ExportProfileSource interface {
+	GetUserByID(ctx context.Context, id int) (*models.UserProfile, error)
+}
+
+type ExportPostSource interface {
+	ExportUserPosts(ctx context.Context, userID int) ([]models.ExportPost, error)
+	ExportLikesGiven(ctx context.Context, userID int) ([]models.ExportLike, error)
+	ExportLikesReceived(ctx context.Context, userID int) ([]models.ExportLike, error)
+}
+
+type ExportImageSource interface {
+	ExportUserImages(ctx context.Context, userID int) ([]models.ExportImage, error)
+}
+
+// NotificationsSource отдает уведомления пользователя из нотификатора
+type NotificationsSource interface {
+	GetNotifications(ctx context.Context, userID int) (json.RawMessage, error)
+}
+
+type ExportEventPublisher interface {
+	PublishExportReady(ctx context.Context, export *models.DataExport) error
+}
+
+// ExportService собирает в фоне zip архив со всеми данными пользователя: JSON файлы профиля,
+// постов, лайков и уведомлений и оригиналы картинок. Архив хранится в бакете до истечения ttl.
+type ExportService struct {
+	repo          ExportRepositoryInterface
+	users         ExportProfileSource
+	posts         ExportPostSource
+	images        ExportImageSource
+	storage       image_storage.ImageStorage
+	notifications NotificationsSource
+	events        ExportEventPublisher
+	ttl           time.Duration
+}
+
+func NewExportService(repo ExportRepositoryInterface, users ExportProfileSource, posts ExportPostSource,
+	images ExportImageSource, storage image_storage.ImageStorage, notifications NotificationsSource,
+	events ExportEventPublisher, ttl time.Duration) *ExportService {
+	return &ExportService{repo: repo, users: users, posts: posts, images: images, storage: storage,
+		notifications: notifications, events: events, ttl: ttl}
+}
+
+// RequestExport ставит выгрузку в очередь. Пока предыдущая собирается или доступна, возвращается она.
+func (s *ExportService) RequestExport(ctx context.Context, userID int) (*models.DataExport, error) {
+	now := time.Now()
+	export, err := s.repo.GetActiveExport(ctx, userID, now)
+	if err != nil {
+		slog.Error("Get active export", "error", err)
+		return nil, err
+	}
+	if export != nil {
+		s.attachDownloadURL(ctx, export, now)
+		return export, nil
+	}
+	export = &models.DataExport{UserID: userID, Status: models.ExportPending, NextAttemptAt: now, RequestedAt: now}
+	if err = s.repo.CreateExport(ctx, export); err != nil {
+		slog.Error("Create export", "error", err)
+		return nil, err
+	}
+	return export, nil
+}
+
+// ListExports возвращает выгрузки пользователя, у доступных - со ссылкой на скачивание
+func (s *ExportService) ListExports(ctx context.Context, userID int) ([]models.DataExport, error) {
+	exports, err := s.repo.GetUserExports(ctx, userID)
+	if err != nil {
+		slog.Error("Get user exports", "error", err)
+		return nil, err
+	}
+	now := time.Now()
+	for i := range exports {
+		s.attachDownloadURL(ctx, &exports[i], now)
+	}
+	return exports, nil
+}
+
+// attachDownloadURL выдает ссылку готовой выгрузке, ссылка истекает вместе с архивом
+func (s *ExportService) attachDownloadURL(ctx context.Context, export *models.DataExport, now time.Time) {
+	if export.Status != models.ExportReady || export.ExpiresAt == nil || !export.ExpiresAt.After(now) {
+		return
+	}
+	link, err := s.storage.GetDownloadURL(ctx, export.StorageKey, exportFilename(export), export.ExpiresAt.Sub(now))
+	if err != nil {
+		slog.Error("Get export download URL", "error", err, "exportID", export.ID)
+		return
+	}
+	export.DownloadURL = link
+}
+
+// ProcessDue собирает архивы выгрузок, которым пора, и возвращает, сколько из них готово
+func (s *ExportService) ProcessDue(ctx context.Context, now time.Time) (int, error) {
+	exports, err := s.repo.ClaimDueExports(ctx, now, exportBatchSize, exportLease)
+	if err != nil {
+		slog.Error("Claim exports", "error", err)
+		return 0, err
+	}
+	ready := 0
+	for i := range exports {
+		if s.process(ctx, &exports[i]) {
+			ready++
+		}
+	}
+	return ready, nil
+}
+
+func (s *ExportService) process(ctx context.Context, export *models.DataExport) bool {
+	size, err := s.build(ctx, export)
+	if err != nil {
+		export.Attempts++
+		export.LastError = err.Error()
+		export.NextAttemptAt = time.Now().Add(time.Duration(export.Attempts*export.Attempts) * time.Minute)
+		if export.Attempts >= maxExportAttempts {
+			export.Status = models.ExportFailed
+		}
+		slog.Error("Build export", "error", err, "exportID", export.ID, "attempts", export.Attempts)
+		s.save(ctx, export)
+		return false
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.ttl)
+	export.Status = models.ExportReady
+	export.Size = size
+	export.ReadyAt = &now
+	export.ExpiresAt = &expiresAt
+	export.LastError = ""
+	if !s.save(ctx, export) {
+		return false
+	}
+	s.attachDownloadURL(ctx, export, now)
+	if err = s.events.PublishExportReady(ctx, export); err != nil {
+		// ссылка все равно доступна в списке выгрузок
+		slog.Error("Export ready", "broker error", err)
+	}
+	return true
+}
+
+func (s *ExportService) save(ctx context.Context, export *models.DataExport) bool {
+	if err := s.repo.SaveExport(ctx, export); err != nil {
+		slog.Error("Save export", "error", err, "exportID", export.ID)
+		return false
+	}
+	return true
+}
+
+// build собирает архив во временном файле и загружает его в хранилище, возвращает размер архива.
+// Ключ архива не зависит от попытки, повторная сборка перезаписывает тот же объект.
+func (s *ExportService) build(ctx context.Context, export *models.DataExport) (int64, error) {
+	file, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	if err = s.writeArchive(ctx, archive, export.UserID); err != nil {
+		return 0, err
+	}
+	if err = archive.Close(); err != nil {
+		return 0, err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	export.StorageKey = fmt.Sprintf("exports/%d/%d.zip", export.UserID, export.ID)
+	if err = s.storage.PutFile(ctx, export.StorageKey, file, size, "application/zip"); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+func (s *ExportService) writeArchive(ctx context.Context, archive *zip.Writer, userID int) error {
+	profile, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("profile: %w", err)
+	}
+	posts, err := s.posts.ExportUserPosts(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("posts: %w", err)
+	}
+	likesGiven, err := s.posts.ExportLikesGiven(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("likes given: %w", err)
+	}
+	likesReceived, err := s.posts.ExportLikesReceived(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("likes received: %w", err)
+	}
+	images, err := s.images.ExportUserImages(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("images: %w", err)
+	}
+	notifications, err := s.notifications.GetNotifications(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("notifications: %w", err)
+	}
+
+	for i := range images {
+		if err = s.writeImage(ctx, archive, &images[i]); err != nil {
+			return fmt.Errorf("image %s: %w", images[i].StorageKey, err)
+		}
+	}
+	manifests := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", profile},
+		{"posts.json", posts},
+		{"images.json", images},
+		{"likes_given.json", likesGiven},
+		{"likes_received.json", likesReceived},
+		{"notifications.json", notifications},
+	}
+	for _, manifest := range manifests {
+		if err = writeJSON(archive, manifest.name, manifest.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeImage копирует оригинал картинки в архив. Картинка, файла которой нет в хранилище,
+// остается в images.json без пути к файлу.
+func (s *ExportService) writeImage(ctx context.Context, archive *zip.Writer, image *models.ExportImage) error {
+	original, err := s.storage.GetFile(ctx, image.StorageKey)
+	if errors.Is(err, image_storage.ErrFileNotFound) {
+		slog.Info("Export image file not found", "storageKey", image.StorageKey)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer original.Close()
+	// картинки загружаются как image/png
+	image.File = "images/" + image.StorageKey + ".png"
+	entry, err := archive.Create(image.File)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, original)
+	return err
+}
+
+func writeJSON(archive *zip.Writer, name string, data any) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+func exportFilename(export *models.DataExport) string {
+	return fmt.Sprintf("pictureloader-export-%s.zip", export.RequestedAt.Format("2006-01-02"))
+}
+
+// Cleanup удаляет из хранилища истекшие архивы и архивы удаленных пользователей
+func (s *ExportService) Cleanup(ctx context.Context, now time.Time) (int, error) {
+	exports, err := s.repo.GetExpiredExports(ctx, now, exportBatchSize*10)
+	if err != nil {
+		slog.Error("Get expired exports", "error", err)
+		return 0, err
+	}
+	removed := 0
+	for i := range exports {
+		export := &exports[i]
+		if err = s.storage.DeleteFileByURL(ctx, export.StorageKey); err != nil {
+			slog.Error("Delete export archive", "error", err, "exportID", export.ID)
+			continue
+		}
+		export.Status = models.ExportExpired
+		export.StorageKey = ""
+		if s.save(ctx, export) {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Run собирает и чистит выгрузки раз в interval, пока не отменен ctx
+func (s *ExportService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		s.ProcessDue(ctx, now)
+		if removed, err := s.Cleanup(ctx, now); err == nil && removed > 0 {
+			slog.Info("Expired exports removed", "removed", removed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"log/slog"
 	"pictureloader/app_microservice/database/postgres"
@@ -18,6 +17,7 @@ type ImageManager interface {
 	GetUserImagesID(ctx context.Context, userID int) ([]string, error)
 	GetImageDescription(ctx context.Context, imageURL string) (string, error)
 	DeleteImage(ctx context.Context, imageID string) error
+	PurgeImage(ctx context.Context, imageSK string) error
 	IsOwnerOfPicture(ctx context.Context, userID int, imageSK string) error
 	GetImageLinkedPost(ctx context.Context, imageSK string) (int, error)
 }
@@ -26,14 +26,20 @@ type Cacher interface {
 	InvalidatePost(ctx context.Context, postID int) (bool, error)
 }
 
+type ImageEventPublisher interface {
+	PublishImageUploaded(ctx context.Context, userID int, storageKey, description string) error
+}
+
 type PictureLoader struct {
 	storage  image_storage.ImageStorage
 	database ImageManager
 	cache    Cacher
+	events   ImageEventPublisher
 }
 
-func NewPictureLoader(storage image_storage.ImageStorage, database *postgres.ImageRepository, cache Cacher) *PictureLoader {
-	return &PictureLoader{storage, database, cache}
+func NewPictureLoader(storage image_storage.ImageStorage, database *postgres.ImageRepository, cache Cacher,
+	events ImageEventPublisher) *PictureLoader {
+	return &PictureLoader{storage, database, cache, events}
 }
 
 func GenerateSK(desc string) string {
@@ -63,20 +69,26 @@ func (p *PictureLoader) Upload(ctx context.Context, img models.ImageUnit, userID
 		slog.Error("Database upload error", "error", err)
 		return "", fmt.Errorf("failed to upload image to database: %w", err)
 	}
+
+	err = p.events.PublishImageUploaded(ctx, userID, storageKey, description)
+	if err != nil {
+		slog.Error("Image upload", "broker error", err)
+	}
 	return imgName, nil
 }
 
 func (p *PictureLoader) Download(ctx context.Context, imgURL string) (string, string, error) {
-	img, err := p.storage.GetFileURL(ctx, imgURL)
-	if err != nil {
-		slog.Error("S3 error downloading file", "error", err)
-		return "", "", fmt.Errorf("failed to get file StorageKey from S3: %w", err)
-	}
+	// описание читается первым: на скрытую модератором картинку ссылка не выдается
 	description, err := p.database.GetImageDescription(ctx, imgURL)
 	if err != nil {
 		slog.Error("Database download description error", "error", err)
 		return "", "", fmt.Errorf("failed to get image description: %w", err)
 	}
+	img, err := p.storage.GetFileURL(ctx, imgURL)
+	if err != nil {
+		slog.Error("S3 error downloading file", "error", err)
+		return "", "", fmt.Errorf("failed to get file StorageKey from S3: %w", err)
+	}
 	return img, description, nil
 }
 
@@ -94,38 +106,55 @@ func (p *PictureLoader) GetAllUserPictures(ctx context.Context, userID int) ([]s
 	return imageURLS, err
 }
 
+// Delete переносит картинку владельца в корзину, файл в хранилище остается до purge
 func (p *PictureLoader) Delete(ctx context.Context, userID int, imgSK string) error {
 	err := p.database.IsOwnerOfPicture(ctx, userID, imgSK)
 	if err != nil {
 		slog.Error("Database delete error", "error", err)
 		return err
 	}
-
-	postID, err := p.database.GetImageLinkedPost(ctx, imgSK)
-	if err != nil {
-		slog.Error("Database get image linked post error", "error", err)
+	if err = p.invalidateLinkedPost(ctx, imgSK); err != nil {
 		return err
 	}
-
-	result, err := p.cache.InvalidatePost(ctx, postID)
+	err = p.database.DeleteImage(ctx, imgSK)
 	if err != nil {
-		slog.Error("Cache delete error", "error", err)
+		slog.Info("Database delete error", "error", err)
 		return err
 	}
-	if !result {
-		slog.Error("Cache delete error: no such key")
-		return errors.New("no such key")
+	return nil
+}
+
+// RemovePicture удаляет любую картинку навсегда без проверки владельца, для модераторов и purge корзины
+func (p *PictureLoader) RemovePicture(ctx context.Context, imgSK string) error {
+	if err := p.invalidateLinkedPost(ctx, imgSK); err != nil {
+		return err
 	}
 
-	err = p.storage.DeleteFileByURL(ctx, imgSK)
+	err := p.storage.DeleteFileByURL(ctx, imgSK)
 	if err != nil {
 		slog.Info("Storage delete error", "error", err)
 		return err
 	}
-	err = p.database.DeleteImage(ctx, imgSK)
+	err = p.database.PurgeImage(ctx, imgSK)
 	if err != nil {
 		slog.Info("Database delete error", "error", err)
 		return err
 	}
 	return nil
 }
+
+func (p *PictureLoader) invalidateLinkedPost(ctx context.Context, imgSK string) error {
+	postID, err := p.database.GetImageLinkedPost(ctx, imgSK)
+	if err != nil {
+		slog.Error("Database get image linked post error", "error", err)
+		return err
+	}
+
+	// пост мог быть не закеширован или картинка не входит ни в один пост, это не ошибка
+	_, err = p.cache.InvalidatePost(ctx, postID)
+	if err != nil {
+		slog.Error("Cache delete error", "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"pictureloader/app_microservice/models"
+	"strings"
+	"time"
+)
+
+const securityEventsLimit = 100
+
+// LoginAttemptStore считает неудачные входы и хранит блокировки
+type LoginAttemptStore interface {
+	LoginBlockedFor(ctx context.Context, keys ...string) (time.Duration, error)
+	RegisterLoginFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	LockLogin(ctx context.Context, key string, ttl time.Duration) error
+	ResetLoginFailures(ctx context.Context, key string) error
+}
+
+// LoginBlockedError - вход заблокирован после слишком многих неудач
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return "too many failed login attempts, try again later"
+}
+
+type SecurityRepositoryInterface interface {
+	CreateSecurityEvent(ctx context.Context, event *models.SecurityEvent) error
+	GetSecurityEvents(ctx context.Context, username string, limit int) ([]models.SecurityEvent, error)
+}
+
+type LoginGuardConfig struct {
+	MaxAccountFailures int           // неудач подряд по одному имени до блокировки
+	MaxIPFailures      int           // неудач с одного IP до блокировки
+	Window             time.Duration // за какое время считаются неудачи
+	Lockout            time.Duration // первая блокировка, каждая следующая вдвое длиннее
+	MaxLockout         time.Duration
+}
+
+// LoginGuard защищает вход от перебора паролей: неудачи считаются по имени пользователя и по IP,
+// после порога вход блокируется на растущее время. Имя учитывается, даже если такого пользователя нет,
+// чтобы по поведению нельзя было понять, существует ли аккаунт.
+type LoginGuard struct {
+	store  LoginAttemptStore
+	events SecurityRepositoryInterface
+	cfg    LoginGuardConfig
+}
+
+func NewLoginGuard(store LoginAttemptStore, events SecurityRepositoryInterface, cfg LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{store: store, events: events, cfg: cfg}
+}
+
+func accountKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// BlockedFor возвращает, сколько еще ждать до следующей попытки, 0 - вход разрешен
+func (g *LoginGuard) BlockedFor(ctx context.Context, username string, device Device) (time.Duration, error) {
+	blocked, err := g.store.LoginBlockedFor(ctx, accountKey(username), ipKey(device.IP))
+	if err != nil {
+		slog.Error("Check login lock", "error", err)
+	}
+	return blocked, err
+}
+
+// Fail учитывает неудачный вход и при превышении порога блокирует имя или IP
+func (g *LoginGuard) Fail(ctx context.Context, username string, device Device) error {
+	g.record(ctx, models.SecurityLoginFailed, username, device, "")
+
+	failures, err := g.store.RegisterLoginFailure(ctx, accountKey(username), g.cfg.Window)
+	if err != nil {
+		slog.Error("Register login failure", "error", err)
+		return err
+	}
+	if lockout := g.lockout(failures, g.cfg.MaxAccountFailures); lockout > 0 {
+		if err = g.store.LockLogin(ctx, accountKey(username), lockout); err != nil {
+			slog.Error("Lock login", "error", err)
+			return err
+		}
+		g.record(ctx, models.SecurityLockout, username, device, fmt.Sprintf("account locked for %s after %d failures", lockout, failures))
+	}
+
+	failures, err = g.store.RegisterLoginFailure(ctx, ipKey(device.IP), g.cfg.Window)
+	if err != nil {
+		slog.Error("Register login failure", "error", err)
+		return err
+	}
+	if lockout := g.lockout(failures, g.cfg.MaxIPFailures); lockout > 0 {
+		if err = g.store.LockLogin(ctx, ipKey(device.IP), lockout); err != nil {
+			slog.Error("Lock login", "error", err)
+			return err
+		}
+		g.record(ctx, models.SecurityLockout, username, device, fmt.Sprintf("ip locked for %s after %d failures", lockout, failures))
+	}
+	return nil
+}
+
+// Succeed обнуляет счетчик неудач по имени. Счетчик IP не сбрасывается: иначе перебор по многим
+// аккаунтам можно разбавлять входами в свой.
+func (g *LoginGuard) Succeed(ctx context.Context, username string) {
+	if err := g.store.ResetLoginFailures(ctx, accountKey(username)); err != nil {
+		slog.Error("Reset login failures", "error", err)
+	}
+}
+
+// Unlock снимает блокировку аккаунта вручную, а с ней и блокировки IP, с которых на аккаунт
+// недавно не удавалось войти: иначе владелец, заблокированный по IP, все равно не войдет
+func (g *LoginGuard) Unlock(ctx context.Context, username string, admin string, device Device) error {
+	if err := g.store.ResetLoginFailures(ctx, accountKey(username)); err != nil {
+		slog.Error("Reset login failures", "error", err)
+		return err
+	}
+	events, err := g.events.GetSecurityEvents(ctx, username, securityEventsLimit)
+	if err != nil {
+		slog.Error("Get security events", "error", err)
+		return err
+	}
+	// блокировка IP живет не дольше окна подсчета неудач или максимальной блокировки
+	since := time.Now().Add(-max(g.cfg.Window, g.cfg.MaxLockout))
+	cleared := map[string]bool{}
+	for _, event := range events {
+		if event.Type != models.SecurityLoginFailed || event.IP == "" || cleared[event.IP] || event.CreatedAt.Before(since) {
+			continue
+		}
+		if err = g.store.ResetLoginFailures(ctx, ipKey(event.IP)); err != nil {
+			slog.Error("Reset login failures", "error", err)
+			return err
+		}
+		cleared[event.IP] = true
+	}
+	g.record(ctx, models.SecurityUnlock, username, device, "unlocked by "+admin)
+	return nil
+}
+
+func (g *LoginGuard) SecurityEvents(ctx context.Context, username string) ([]models.SecurityEvent, error) {
+	events, err := g.events.GetSecurityEvents(ctx, username, securityEventsLimit)
+	if err != nil {
+		slog.Error("Get security events", "error", err)
+	}
+	return events, err
+}
+
+// lockout - длительность блокировки после failures неудач: Lockout на пороге, дальше вдвое больше
+// за каждую следующую неудачу, но не больше MaxLockout
+func (g *LoginGuard) lockout(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	lockout := g.cfg.Lockout
+	for i := threshold; i < failures && lockout < g.cfg.MaxLockout; i++ {
+		lockout *= 2
+	}
+	return min(lockout, g.cfg.MaxLockout)
+}
+
+// record пишет событие в журнал. Ошибка записи не должна менять ответ на вход.
+func (g *LoginGuard) record(ctx context.Context, eventType, username string, device Device, details string) {
+	slog.Warn("Security event", "type", eventType, "username", username, "ip", device.IP, "details", details)
+	err := g.events.CreateSecurityEvent(ctx, &models.SecurityEvent{
+		Type:      eventType,
+		Username:  username,
+		IP:        device.IP,
+		UserAgent: device.UserAgent,
+		Details:   details,
+	})
+	if err != nil {
+		slog.Error("Create security event", "error", err)
+	}
+}
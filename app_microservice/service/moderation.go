@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"pictureloader/app_microservice/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultReportsPageSize = 50
+	maxReportsPageSize     = 100
+	maxReportDetailsLength = 1000
+)
+
+var (
+	ErrReportTargetNotFound    = errors.New("reported content not found")
+	ErrDuplicateReport         = errors.New("you have already reported this")
+	ErrReportNotFound          = errors.New("report not found")
+	ErrReportResolved          = errors.New("report is already resolved")
+	ErrInvalidModerationAction = errors.New("action is not applicable to this content")
+)
+
+// ReportValidationError - некорректная жалоба
+type ReportValidationError struct {
+	Reason string
+}
+
+func (e *ReportValidationError) Error() string {
+	return e.Reason
+}
+
+// AuditRecorder пишет журнал действий модераторов и администраторов
+type AuditRecorder interface {
+	CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+}
+
+type ModerationRepositoryInterface interface {
+	AuditRecorder
+	CreateReport(ctx context.Context, report *models.Report) error
+	HasOpenReport(ctx context.Context, reporterID int, targetType, targetID string) (bool, error)
+	GetReports(ctx context.Context, filter models.ReportFilter) ([]models.Report, error)
+	GetReport(ctx context.Context, reportID int) (*models.Report, error)
+	ResolveReports(ctx context.Context, targetType, targetID, status, resolution string, resolvedBy int, now time.Time) ([]int, error)
+	GetReportTarget(ctx context.Context, targetType, targetID string) (*models.ReportTarget, error)
+	SetPostHidden(ctx context.Context, postID int, hiddenAt *time.Time) (bool, error)
+	SetImageHidden(ctx context.Context, imageSK string, hiddenAt *time.Time) ([]int, error)
+	GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+type PostRemover interface {
+	RemovePost(ctx context.Context, postID int) error
+}
+
+type PictureRemover interface {
+	RemovePicture(ctx context.Context, imgSK string) error
+}
+
+// UserBanner блокирует пользователя по жалобе
+type UserBanner interface {
+	BanForReport(ctx context.Context, actor Actor, userID int, reason string, reportID int) error
+}
+
+// ModerationCache сбрасывает кеш, в котором может остаться скрытый контент
+type ModerationCache interface {
+	InvalidatePost(ctx context.Context, postID int) (bool, error)
+	InvalidateMostLikedPosts(ctx context.Context) error
+}
+
+type ModerationEventPublisher interface {
+	PublishModerationDecided(ctx context.Context, decision *models.ModerationDecision) error
+}
+
+// ModerationService - жалобы пользователей и очередь модерации. Решение по жалобе применяется
+// ко всему объекту и закрывает все открытые жалобы на него.
+type ModerationService struct {
+	repo     ModerationRepositoryInterface
+	posts    PostRemover
+	pictures PictureRemover
+	bans     UserBanner
+	cache    ModerationCache
+	events   ModerationEventPublisher
+}
+
+func NewModerationService(repo ModerationRepositoryInterface, posts PostRemover, pictures PictureRemover,
+	bans UserBanner, cache ModerationCache, events ModerationEventPublisher) *ModerationService {
+	return &ModerationService{repo: repo, posts: posts, pictures: pictures, bans: bans, cache: cache, events: events}
+}
+
+// Report принимает жалобу пользователя. На свой контент и повторно на тот же объект жаловаться нельзя.
+func (s *ModerationService) Report(ctx context.Context, reporterID int, req models.ReportCreate) (*models.Report, error) {
+	req.TargetID = strings.TrimSpace(req.TargetID)
+	req.Details = strings.TrimSpace(req.Details)
+	if !slices.Contains(models.ReportTargets, req.TargetType) {
+		return nil, &ReportValidationError{Reason: "target_type must be one of " + strings.Join(models.ReportTargets, ", ")}
+	}
+	if req.TargetID == "" {
+		return nil, &ReportValidationError{Reason: "target_id is required"}
+	}
+	if !slices.Contains(models.ReportReasons, req.Reason) {
+		return nil, &ReportValidationError{Reason: "reason must be one of " + strings.Join(models.ReportReasons, ", ")}
+	}
+	if len(req.Details) > maxReportDetailsLength {
+		return nil, &ReportValidationError{Reason: "details must be at most 1000 characters"}
+	}
+
+	target, err := s.repo.GetReportTarget(ctx, req.TargetType, req.TargetID)
+	if err != nil {
+		slog.Error("Get report target", "error", err)
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrReportTargetNotFound
+	}
+	if target.OwnerID == reporterID {
+		return nil, &ReportValidationError{Reason: "you can't report your own content"}
+	}
+	duplicate, err := s.repo.HasOpenReport(ctx, reporterID, req.TargetType, req.TargetID)
+	if err != nil {
+		slog.Error("Check open report", "error", err)
+		return nil, err
+	}
+	if duplicate {
+		return nil, ErrDuplicateReport
+	}
+
+	report := &models.Report{
+		ReporterID: reporterID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		OwnerID:    target.OwnerID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+		Status:     models.ReportOpen,
+	}
+	if err = s.repo.CreateReport(ctx, report); err != nil {
+		slog.Error("Create report", "error", err)
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *ModerationService) ListReports(ctx context.Context, filter models.ReportFilter) ([]models.Report, error) {
+	if filter.Status != "" && !slices.Contains(models.ReportStatuses, filter.Status) {
+		return nil, &ReportValidationError{Reason: "status must be one of " + strings.Join(models.ReportStatuses, ", ")}
+	}
+	if filter.TargetType != "" && !slices.Contains(models.ReportTargets, filter.TargetType) {
+		return nil, &ReportValidationError{Reason: "target_type must be one of " + strings.Join(models.ReportTargets, ", ")}
+	}
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	reports, err := s.repo.GetReports(ctx, filter)
+	if err != nil {
+		slog.Error("Get reports", "error", err)
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (s *ModerationService) GetReport(ctx context.Context, reportID int) (*models.Report, error) {
+	report, err := s.repo.GetReport(ctx, reportID)
+	if err != nil {
+		slog.Error("Get report", "error", err)
+		return nil, err
+	}
+	if report == nil {
+		return nil, ErrReportNotFound
+	}
+	return report, nil
+}
+
+// Resolve применяет решение модератора по жалобе
+func (s *ModerationService) Resolve(ctx context.Context, actor Actor, reportID int, req models.ReportResolve) error {
+	report, err := s.GetReport(ctx, reportID)
+	if err != nil {
+		return err
+	}
+	if report.Status != models.ReportOpen {
+		return ErrReportResolved
+	}
+	return s.decide(ctx, actor, report.TargetType, report.TargetID, req.Action, strings.TrimSpace(req.Note), report)
+}
+
+// RemoveContent удаляет пост или картинку без жалобы, открытые жалобы на них закрываются
+func (s *ModerationService) RemoveContent(ctx context.Context, actor Actor, targetType, targetID string) error {
+	return s.decide(ctx, actor, targetType, targetID, models.ModerationRemove, "", nil)
+}
+
+// RestoreContent возвращает скрытый пост или картинку без жалобы, открытые жалобы на них отклоняются
+func (s *ModerationService) RestoreContent(ctx context.Context, actor Actor, targetType, targetID string) error {
+	return s.decide(ctx, actor, targetType, targetID, models.ModerationUnhide, "", nil)
+}
+
+func (s *ModerationService) AuditLog(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	entries, err := s.repo.GetAuditEntries(ctx, filter)
+	if err != nil {
+		slog.Error("Get audit entries", "error", err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// decide применяет действие к объекту, закрывает жалобы на него, пишет аудит
+// и сообщает владельцу и пожаловавшимся. report - жалоба, из очереди которой принято решение.
+func (s *ModerationService) decide(ctx context.Context, actor Actor, targetType, targetID, action, note string,
+	report *models.Report) error {
+	if !actionApplies(targetType, action) || (action == models.ModerationBan && report == nil) {
+		return ErrInvalidModerationAction
+	}
+	target, err := s.repo.GetReportTarget(ctx, targetType, targetID)
+	if err != nil {
+		slog.Error("Get report target", "error", err)
+		return err
+	}
+	// отклонить жалобу можно и на уже удаленный контент
+	if target == nil && action != models.ModerationDismiss {
+		return ErrReportTargetNotFound
+	}
+
+	now := time.Now()
+	decision := &models.ModerationDecision{TargetType: targetType, TargetID: targetID, Action: action, DecidedAt: now}
+	if target != nil {
+		decision.OwnerID, decision.TargetName = target.OwnerID, target.Name
+	} else {
+		decision.OwnerID = report.OwnerID
+	}
+
+	switch action {
+	case models.ModerationHide:
+		err = s.setHidden(ctx, targetType, targetID, &now)
+	case models.ModerationUnhide:
+		err = s.setHidden(ctx, targetType, targetID, nil)
+	case models.ModerationRemove:
+		err = s.remove(ctx, targetType, targetID)
+	case models.ModerationBan:
+		// блокировка пишет аудит сама, со ссылкой на жалобу
+		err = s.bans.BanForReport(ctx, actor, target.OwnerID, note, report.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// вернуть скрытое - значит признать, что нарушения не было
+	status := models.ReportActioned
+	if action == models.ModerationDismiss || action == models.ModerationUnhide {
+		status = models.ReportDismissed
+	}
+	decision.Reporters, err = s.repo.ResolveReports(ctx, targetType, targetID, status, action, actor.UserID, now)
+	if err != nil {
+		slog.Error("Resolve reports", "error", err)
+		return err
+	}
+
+	if action != models.ModerationBan {
+		var reportID *int
+		if report != nil {
+			reportID = &report.ID
+		}
+		recordAudit(ctx, s.repo, actor, action, targetType, targetID, reportID, note)
+	}
+
+	if err = s.events.PublishModerationDecided(ctx, decision); err != nil {
+		slog.Error("Moderation decision", "broker error", err)
+	}
+	return nil
+}
+
+// setHidden скрывает контент с момента hiddenAt, nil возвращает его в ленты
+func (s *ModerationService) setHidden(ctx context.Context, targetType, targetID string, hiddenAt *time.Time) error {
+	var postIDs []int
+	switch targetType {
+	case models.ReportTargetPost:
+		postID, _ := strconv.Atoi(targetID)
+		ok, err := s.repo.SetPostHidden(ctx, postID, hiddenAt)
+		if err != nil {
+			slog.Error("Set post hidden", "error", err)
+			return err
+		}
+		if !ok {
+			return ErrReportTargetNotFound
+		}
+		postIDs = []int{postID}
+	case models.ReportTargetImage:
+		var err error
+		postIDs, err = s.repo.SetImageHidden(ctx, targetID, hiddenAt)
+		if err != nil {
+			slog.Error("Set image hidden", "error", err)
+			return err
+		}
+		if postIDs == nil {
+			return ErrReportTargetNotFound
+		}
+	}
+
+	// кеш постов и популярного должен сразу увидеть изменение
+	for _, postID := range postIDs {
+		if _, err := s.cache.InvalidatePost(ctx, postID); err != nil {
+			slog.Error("Moderation invalidate post", "error", err, "postID", postID)
+		}
+	}
+	if err := s.cache.InvalidateMostLikedPosts(ctx); err != nil {
+		slog.Error("Moderation invalidate most liked posts", "error", err)
+	}
+	return nil
+}
+
+func (s *ModerationService) remove(ctx context.Context, targetType, targetID string) error {
+	if targetType == models.ReportTargetPost {
+		postID, _ := strconv.Atoi(targetID)
+		return s.posts.RemovePost(ctx, postID)
+	}
+	return s.pictures.RemovePicture(ctx, targetID)
+}
+
+// actionApplies - контент можно скрыть, вернуть или удалить, пользователя - заблокировать
+func actionApplies(targetType, action string) bool {
+	switch action {
+	case models.ModerationDismiss:
+		return slices.Contains(models.ReportTargets, targetType)
+	case models.ModerationHide, models.ModerationUnhide, models.ModerationRemove:
+		return targetType == models.ReportTargetPost || targetType == models.ReportTargetImage
+	case models.ModerationBan:
+		return targetType == models.ReportTargetUser
+	}
+	return false
+}
+
+// recordAudit пишет запись аудита. Действие уже выполнено, поэтому ошибка записи только логируется.
+func recordAudit(ctx context.Context, audit AuditRecorder, actor Actor, action, targetType, targetID string,
+	reportID *int, details string) {
+	entry := &models.AuditEntry{
+		ActorID:    actor.UserID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		ReportID:   reportID,
+		Details:    details,
+	}
+	if err := audit.CreateAuditEntry(ctx, entry); err != nil {
+		slog.Error("Create audit entry", "error", err, "action", action, "actorID", actor.UserID)
+	}
+}
+
+func page(limit, offset int) (int, int) {
+	if limit <= 0 || limit > maxReportsPageSize {
+		limit = defaultReportsPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/safety/oidcauth"
+	"strings"
+	"time"
+)
+
+// oidcStateTTL - сколько пользователь может пробыть на стороне провайдера
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownProvider    = errors.New("unknown identity provider")
+	ErrInvalidOIDCState   = errors.New("sign-in request is invalid or expired")
+	ErrIdentityLinked     = errors.New("this external account is linked to another user")
+	ErrOIDCEmailTaken     = errors.New("an account with this email already exists, sign in with password and link the provider in the profile")
+	ErrOIDCEmailMissing   = errors.New("identity provider did not return an email")
+	ErrLastLoginMethod    = errors.New("cannot unlink the only way to sign in, set a password first")
+	ErrIdentityNotFound   = errors.New("linked account not found")
+	ErrOIDCAuthentication = errors.New("identity provider authentication failed")
+	ErrPendingDeletion    = errors.New("account is scheduled for deletion")
+)
+
+type OIDCProvider interface {
+	AuthCodeURL(state, nonce, verifier string) string
+	Exchange(ctx context.Context, code, nonce, verifier string) (*oidcauth.Identity, error)
+}
+
+type IdentityRepositoryInterface interface {
+	GetIdentity(ctx context.Context, issuer, subject string) (*models.ExternalIdentity, error)
+	CreateIdentity(ctx context.Context, identity *models.ExternalIdentity) error
+	CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.ExternalIdentity) error
+	GetIdentities(ctx context.Context, userID int) ([]models.ExternalIdentity, error)
+	DeleteIdentity(ctx context.Context, userID, identityID int) (bool, error)
+	HasPassword(ctx context.Context, userID int) (bool, error)
+	UsernameTaken(ctx context.Context, username string) (bool, error)
+	EmailTaken(ctx context.Context, email string) (bool, error)
+	GetUserAccess(ctx context.Context, userID int) (*models.UserAccess, error)
+}
+
+// OIDCStateStore - одноразовое хранилище параметров входа между редиректами
+type OIDCStateStore interface {
+	SaveOIDCState(ctx context.Context, state, value string, ttl time.Duration) error
+	TakeOIDCState(ctx context.Context, state string) (string, error)
+}
+
+// oidcState - то, что нужно для проверки ответа провайдера. LinkUserID не 0, если пользователь
+// не входит, а привязывает провайдера к своему аккаунту.
+type oidcState struct {
+	Provider   string `json:"provider"`
+	Nonce      string `json:"nonce"`
+	Verifier   string `json:"verifier"`
+	LinkUserID int    `json:"link_user_id,omitempty"`
+}
+
+type OIDCService struct {
+	providers map[string]OIDCProvider
+	infos     []models.OIDCProviderInfo
+	repo      IdentityRepositoryInterface
+	states    OIDCStateStore
+}
+
+func NewOIDCService(repo IdentityRepositoryInterface, states OIDCStateStore) *OIDCService {
+	return &OIDCService{providers: map[string]OIDCProvider{}, repo: repo, states: states}
+}
+
+// AddProvider подключает провайдера под именем info.Name
+func (s *OIDCService) AddProvider(info models.OIDCProviderInfo, provider OIDCProvider) {
+	s.providers[info.Name] = provider
+	s.infos = append(s.infos, info)
+}
+
+func (s *OIDCService) Providers() []models.OIDCProviderInfo {
+	return append([]models.OIDCProviderInfo{}, s.infos...)
+}
+
+// Begin начинает вход (linkUserID = 0) или привязку и возвращает адрес провайдера и state.
+// state нужно сохранить и в браузере, чтобы ответ провайдера нельзя было подсунуть чужому пользователю.
+func (s *OIDCService) Begin(ctx context.Context, providerName string, linkUserID int) (string, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+	state, err := randomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	saved := oidcState{Provider: providerName, Nonce: nonce, Verifier: oidcauth.GenerateVerifier(), LinkUserID: linkUserID}
+	value, err := json.Marshal(saved)
+	if err != nil {
+		return "", "", err
+	}
+	if err = s.states.SaveOIDCState(ctx, hashToken(state), string(value), oidcStateTTL); err != nil {
+		slog.Error("Save oidc state", "error", err)
+		return "", "", err
+	}
+	return provider.AuthCodeURL(state, nonce, saved.Verifier), state, nil
+}
+
+// Complete обрабатывает возврат от провайдера и возвращает пользователя, которому нужно начать сессию.
+// linking = true - это была привязка провайдера к уже вошедшему пользователю, новая сессия не нужна.
+func (s *OIDCService) Complete(ctx context.Context, state, code string) (userID int, linking bool, err error) {
+	value, err := s.states.TakeOIDCState(ctx, hashToken(state))
+	if err != nil {
+		slog.Error("Take oidc state", "error", err)
+		return 0, false, err
+	}
+	if value == "" {
+		return 0, false, ErrInvalidOIDCState
+	}
+	var saved oidcState
+	if err = json.Unmarshal([]byte(value), &saved); err != nil {
+		return 0, false, ErrInvalidOIDCState
+	}
+	provider, ok := s.providers[saved.Provider]
+	if !ok {
+		return 0, false, ErrUnknownProvider
+	}
+
+	identity, err := provider.Exchange(ctx, code, saved.Nonce, saved.Verifier)
+	if err != nil {
+		slog.Warn("OIDC exchange failed", "error", err, "provider", saved.Provider)
+		return 0, false, ErrOIDCAuthentication
+	}
+
+	existing, err := s.repo.GetIdentity(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		slog.Error("Get identity", "error", err)
+		return 0, false, err
+	}
+	if saved.LinkUserID != 0 {
+		return saved.LinkUserID, true, s.link(ctx, saved.LinkUserID, existing, identity)
+	}
+	if existing != nil {
+		return existing.UserID, false, s.checkAccess(ctx, existing.UserID)
+	}
+	userID, err = s.provision(ctx, identity)
+	return userID, false, err
+}
+
+// checkAccess не пускает через провайдера заблокированных и удаляющих аккаунт, как и вход по паролю
+func (s *OIDCService) checkAccess(ctx context.Context, userID int) error {
+	access, err := s.repo.GetUserAccess(ctx, userID)
+	if err != nil {
+		slog.Error("Get user access", "error", err)
+		return err
+	}
+	switch {
+	case access == nil:
+		return ErrIdentityNotFound
+	case access.DeletionRequestedAt != nil:
+		return ErrPendingDeletion
+	case access.BannedAt != nil:
+		return ErrUserBanned
+	}
+	return nil
+}
+
+func (s *OIDCService) link(ctx context.Context, userID int, existing *models.ExternalIdentity, identity *oidcauth.Identity) error {
+	if existing != nil {
+		if existing.UserID != userID {
+			return ErrIdentityLinked
+		}
+		return nil
+	}
+	err := s.repo.CreateIdentity(ctx, &models.ExternalIdentity{
+		UserID:   userID,
+		Provider: identity.Provider,
+		Issuer:   identity.Issuer,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		slog.Error("Create identity", "error", err)
+	}
+	return err
+}
+
+// provision создает пользователя при первом входе через провайдера. Существующий аккаунт с той же
+// почтой автоматически не привязывается: провайдер мог выдать чужой адрес.
+func (s *OIDCService) provision(ctx context.Context, identity *oidcauth.Identity) (int, error) {
+	if identity.Email == "" {
+		return 0, ErrOIDCEmailMissing
+	}
+	taken, err := s.repo.EmailTaken(ctx, identity.Email)
+	if err != nil {
+		slog.Error("Check email", "error", err)
+		return 0, err
+	}
+	if taken {
+		return 0, ErrOIDCEmailTaken
+	}
+	username, err := s.freeUsername(ctx, identity)
+	if err != nil {
+		return 0, err
+	}
+
+	// без пароля: войти по паролю можно будет только после сброса
+	user := &models.User{Username: username, Email: identity.Email, EmailVerified: identity.EmailVerified}
+	err = s.repo.CreateUserWithIdentity(ctx, user, &models.ExternalIdentity{
+		Provider: identity.Provider,
+		Issuer:   identity.Issuer,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		slog.Error("Create user with identity", "error", err)
+		return 0, err
+	}
+	slog.Info("User provisioned from identity provider", "userID", user.ID, "provider", identity.Provider)
+	return user.ID, nil
+}
+
+// freeUsername подбирает свободное имя из preferred_username, почты или имени
+func (s *OIDCService) freeUsername(ctx context.Context, identity *oidcauth.Identity) (string, error) {
+	localPart, _, _ := strings.Cut(identity.Email, "@")
+	base := "user"
+	for _, candidate := range []string{identity.PreferredUsername, localPart, identity.Name} {
+		if sanitized := sanitizeUsername(candidate); sanitized != "" {
+			base = sanitized
+			break
+		}
+	}
+	for i := 1; i <= 20; i++ {
+		username := base
+		if i > 1 {
+			username = fmt.Sprintf("%s%d", base, i)
+		}
+		taken, err := s.repo.UsernameTaken(ctx, username)
+		if err != nil {
+			slog.Error("Check username", "error", err)
+			return "", err
+		}
+		if !taken {
+			return username, nil
+		}
+	}
+	suffix, err := randomBytes(4)
+	if err != nil {
+		return "", err
+	}
+	return base + "_" + hex.EncodeToString(suffix), nil
+}
+
+func sanitizeUsername(value string) string {
+	var result strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(value)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			result.WriteRune(r)
+		case r == ' ':
+			result.WriteRune('_')
+		}
+		if result.Len() >= 30 {
+			break
+		}
+	}
+	return strings.Trim(result.String(), "_.-")
+}
+
+func (s *OIDCService) ListIdentities(ctx context.Context, userID int) ([]models.ExternalIdentity, error) {
+	identities, err := s.repo.GetIdentities(ctx, userID)
+	if err != nil {
+		slog.Error("Get identities", "error", err)
+	}
+	return identities, err
+}
+
+// Unlink отвязывает провайдера, если у пользователя остается другой способ входа
+func (s *OIDCService) Unlink(ctx context.Context, userID, identityID int) error {
+	identities, err := s.repo.GetIdentities(ctx, userID)
+	if err != nil {
+		slog.Error("Get identities", "error", err)
+		return err
+	}
+	hasPassword, err := s.repo.HasPassword(ctx, userID)
+	if err != nil {
+		slog.Error("Check password", "error", err)
+		return err
+	}
+	if !hasPassword && len(identities) <= 1 {
+		return ErrLastLoginMethod
+	}
+	deleted, err := s.repo.DeleteIdentity(ctx, userID, identityID)
+	if err != nil {
+		slog.Error("Delete identity", "error", err)
+		return err
+	}
+	if !deleted {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"github.com/redis/go-redis/v9"
 	"log/slog"
-	"pictureloader/app_microservice/broker"
 	"pictureloader/app_microservice/image_storage"
 	"pictureloader/app_microservice/models"
 	"unicode/utf8"
@@ -17,11 +16,13 @@ type PostRepositoryInterface interface {
 	CreatePostAndImage(ctx context.Context, postID int, imageSK string) error
 	GetUserPostIDs(ctx context.Context, userID int) ([]int, error)
 	DeletePostByID(ctx context.Context, albumID int) error
+	PurgePost(ctx context.Context, postID int) error
 	DeletePostImage(ctx context.Context, postID int, imageSK string) error
 	IsOwnerOfPost(ctx context.Context, userID int, albumID int) error
-	LikePost(ctx context.Context, postID, userID int) error
+	LikePost(ctx context.Context, postID, userID int) (*models.Like, error)
+	UnlikePost(ctx context.Context, postID, userID int) error
 	GetMostLikedPosts(ctx context.Context) ([]models.PostUnit, error)
-	GetPostOwner(ctx context.Context, postID int) (int, error)
+	GetPostByID(ctx context.Context, postID int) (*models.Post, error)
 	GetPost(ctx context.Context, postID int) (models.PostUnit, error)
 }
 
@@ -33,20 +34,25 @@ type AlbumCacher interface {
 	SetPost(ctx context.Context, postID int, resultJSON string) (bool, error)
 }
 
+type EventPublisher interface {
+	PublishNewLike(ctx context.Context, post *models.Post, like *models.Like) error
+	PublishPostCreated(ctx context.Context, post *models.Post) error
+}
+
 type PostService struct {
 	database PostRepositoryInterface
 	storage  image_storage.ImageStorage
 	cache    AlbumCacher
-	broker   broker.RabbitBroker
+	events   EventPublisher
 }
 
 func NewPostService(database PostRepositoryInterface, storage image_storage.ImageStorage,
-	cacher AlbumCacher, broker broker.RabbitBroker) *PostService {
+	cacher AlbumCacher, events EventPublisher) *PostService {
 	return &PostService{
 		database: database,
 		storage:  storage,
 		cache:    cacher,
-		broker:   broker,
+		events:   events,
 	}
 }
 
@@ -59,6 +65,11 @@ func (als *PostService) CreatePost(ctx context.Context, post *models.Post) error
 		slog.Error("Create post", "error", err)
 		return err
 	}
+
+	err = als.events.PublishPostCreated(ctx, post)
+	if err != nil {
+		slog.Error("Create post", "broker error", err)
+	}
 	return nil
 }
 
@@ -151,29 +162,38 @@ func (als *PostService) AppendImageToPost(ctx context.Context, postID int, image
 	return nil
 }
 
+// DeletePost переносит пост владельца в корзину, откуда его можно восстановить до purge
 func (als *PostService) DeletePost(ctx context.Context, postID int, userID int) error {
 	err := als.database.IsOwnerOfPost(ctx, userID, postID)
 	if err != nil {
 		slog.Error("Database delete error", "error", err)
 		return err
 	}
-
 	err = als.database.DeletePostByID(ctx, postID)
 	if err != nil {
 		slog.Error("Delete post", "error", err)
 		return err
 	}
+	return als.invalidateDeleted(ctx, postID)
+}
 
-	ok, err := als.cache.InvalidatePost(ctx, postID)
+// RemovePost удаляет любой пост навсегда без проверки владельца, для модераторов и purge корзины
+func (als *PostService) RemovePost(ctx context.Context, postID int) error {
+	err := als.database.PurgePost(ctx, postID)
 	if err != nil {
-		slog.Error("Delete post", "error", err)
+		slog.Error("Purge post", "error", err)
 		return err
 	}
-	if ok == false {
-		slog.Info("No such post", "postID", postID)
-		return errors.New("no such post")
-	}
+	return als.invalidateDeleted(ctx, postID)
+}
 
+func (als *PostService) invalidateDeleted(ctx context.Context, postID int) error {
+	// пост уже удален, отсутствие его в кеше не ошибка
+	_, err := als.cache.InvalidatePost(ctx, postID)
+	if err != nil {
+		slog.Error("Delete post", "error", err)
+		return err
+	}
 	return nil
 }
 
@@ -214,19 +234,19 @@ func (als *PostService) LikePost(ctx context.Context, postID, userID int) error
 		slog.Error("No such post (cache)", "postID", postID)
 	}
 
-	postOwnerID, err := als.database.GetPostOwner(ctx, postID)
+	post, err := als.database.GetPostByID(ctx, postID)
 	if err != nil {
-		slog.Error("Get post owner", "error", err)
+		slog.Error("Get post", "error", err)
 		return err
 	}
 
-	err = als.database.LikePost(ctx, postID, userID)
+	like, err := als.database.LikePost(ctx, postID, userID)
 	if err != nil {
 		slog.Error("Like post", "error", err)
 		return err
 	}
 
-	err = als.broker.PublishNewLike(postID, userID, postOwnerID)
+	err = als.events.PublishNewLike(ctx, post, like)
 	if err != nil {
 		slog.Error("Like post", "broker error", err)
 	}
@@ -234,6 +254,20 @@ func (als *PostService) LikePost(ctx context.Context, postID, userID int) error
 	return nil
 }
 
+func (als *PostService) UnlikePost(ctx context.Context, postID, userID int) error {
+	err := als.database.UnlikePost(ctx, postID, userID)
+	if err != nil {
+		slog.Error("Unlike post", "error", err)
+		return err
+	}
+
+	_, err = als.cache.InvalidatePost(ctx, postID)
+	if err != nil {
+		slog.Error("Unlike post", "error", err)
+	}
+	return nil
+}
+
 func (als *PostService) GetMostLikedPosts(ctx context.Context) ([]models.PostUnit, error) {
 	//пограничный случай если imageDesc у картинок одинаковые в одном посте, нужно будет что-то придумать todo
 	posts, err := als.cache.GetMostLikedPosts(ctx)
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"pictureloader/app_microservice/models"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	recentPostsCount = 12
+	maxBioLength     = 300
+	maxProfileLinks  = 5
+	maxLinkLength    = 200
+)
+
+var ErrSelfFollow = errors.New("cannot follow yourself")
+
+// ProfileValidationError - некорректное изменение профиля
+type ProfileValidationError struct {
+	Reason string
+}
+
+func (e *ProfileValidationError) Error() string {
+	return e.Reason
+}
+
+type ProfileRepositoryInterface interface {
+	GetPublicProfile(ctx context.Context, username string) (*models.PublicProfile, error)
+	GetRecentPosts(ctx context.Context, userID int, limit int) ([]models.ProfilePost, error)
+	UpdateProfile(ctx context.Context, userID int, update models.ProfileUpdate) error
+	GetActiveUserID(ctx context.Context, username string) (int, error)
+	Follow(ctx context.Context, followerID, followeeID int) error
+	Unfollow(ctx context.Context, followerID, followeeID int) error
+}
+
+// ProfileService - публичные профили пользователей и подписки
+type ProfileService struct {
+	repo    ProfileRepositoryInterface
+	storage UserStorageManager
+}
+
+func NewProfileService(repo ProfileRepositoryInterface, storage UserStorageManager) *ProfileService {
+	return &ProfileService{repo: repo, storage: storage}
+}
+
+// GetPublicProfile возвращает профиль по имени пользователя со ссылками на аватар и обложки постов
+func (s *ProfileService) GetPublicProfile(ctx context.Context, username string) (*models.PublicProfile, error) {
+	profile, err := s.repo.GetPublicProfile(ctx, username)
+	if err != nil {
+		slog.Error("Get public profile", "error", err)
+		return nil, err
+	}
+	if profile == nil {
+		return nil, ErrUserNotFound
+	}
+	profile.RecentPosts, err = s.repo.GetRecentPosts(ctx, profile.UserID, recentPostsCount)
+	if err != nil {
+		slog.Error("Get recent posts", "error", err)
+		return nil, err
+	}
+	if profile.Links == nil {
+		profile.Links = []string{}
+	}
+	// без картинки профиль все равно полезен, поэтому ошибки хранилища только логируются
+	if profile.Avatar != "" {
+		profile.Avatars = avatarURLs(ctx, s.storage, profile.Avatar)
+		profile.Avatar = largestAvatar(profile.Avatars)
+	}
+	for i := range profile.RecentPosts {
+		profile.RecentPosts[i].Cover = s.fileURL(ctx, profile.RecentPosts[i].Cover)
+	}
+	return profile, nil
+}
+
+func (s *ProfileService) fileURL(ctx context.Context, key string) string {
+	if key == "" {
+		return ""
+	}
+	fileURL, err := s.storage.GetFileURL(ctx, key)
+	if err != nil {
+		slog.Error("Get profile file URL", "error", err, "key", key)
+		return ""
+	}
+	return fileURL
+}
+
+// UpdateProfile проверяет и сохраняет описание и ссылки профиля
+func (s *ProfileService) UpdateProfile(ctx context.Context, userID int, update models.ProfileUpdate) error {
+	if update.Bio != nil {
+		bio := strings.TrimSpace(*update.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			return &ProfileValidationError{Reason: "bio must be at most 300 characters"}
+		}
+		update.Bio = &bio
+	}
+	if update.Links != nil {
+		links, err := normalizeLinks(*update.Links)
+		if err != nil {
+			return err
+		}
+		update.Links = &links
+	}
+	if err := s.repo.UpdateProfile(ctx, userID, update); err != nil {
+		slog.Error("Update profile", "error", err)
+		return err
+	}
+	return nil
+}
+
+// normalizeLinks оставляет только абсолютные http и https ссылки без повторов
+func normalizeLinks(links []string) ([]string, error) {
+	if len(links) > maxProfileLinks {
+		return nil, &ProfileValidationError{Reason: "at most 5 links are allowed"}
+	}
+	result := make([]string, 0, len(links))
+	for _, link := range links {
+		link = strings.TrimSpace(link)
+		if len(link) > maxLinkLength {
+			return nil, &ProfileValidationError{Reason: "links must be at most 200 characters"}
+		}
+		parsed, err := url.Parse(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, &ProfileValidationError{Reason: "invalid link: " + link}
+		}
+		// ссылки с логином и паролем в адресе выглядят как фишинг
+		if parsed.User != nil {
+			return nil, &ProfileValidationError{Reason: "invalid link: " + link}
+		}
+		link = parsed.String()
+		if !slices.Contains(result, link) {
+			result = append(result, link)
+		}
+	}
+	return result, nil
+}
+
+// Follow подписывает пользователя на автора профиля username
+func (s *ProfileService) Follow(ctx context.Context, userID int, username string) error {
+	followeeID, err := s.followee(ctx, userID, username)
+	if err != nil {
+		return err
+	}
+	if err = s.repo.Follow(ctx, userID, followeeID); err != nil {
+		slog.Error("Follow", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *ProfileService) Unfollow(ctx context.Context, userID int, username string) error {
+	followeeID, err := s.followee(ctx, userID, username)
+	if err != nil {
+		return err
+	}
+	if err = s.repo.Unfollow(ctx, userID, followeeID); err != nil {
+		slog.Error("Unfollow", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *ProfileService) followee(ctx context.Context, userID int, username string) (int, error) {
+	followeeID, err := s.repo.GetActiveUserID(ctx, username)
+	if err != nil {
+		slog.Error("Get active user ID", "error", err)
+		return 0, err
+	}
+	if followeeID == 0 {
+		return 0, ErrUserNotFound
+	}
+	if followeeID == userID {
+		return 0, ErrSelfFollow
+	}
+	return followeeID, nil
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrUserBanned          = errors.New("user is banned")
+)
+
+type SessionRepositoryInterface interface {
+	CreateSession(ctx context.Context, session *models.Session, tokenHash string) error
+	UseRefreshToken(ctx context.Context, tokenHash string, now time.Time) (*models.RefreshToken, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, session *models.Session, tokenHash string) error
+	GetActiveSessions(ctx context.Context, userID int, now time.Time) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID int, sessionID string, now time.Time) (bool, error)
+	RevokeAllSessions(ctx context.Context, userID int, now time.Time) ([]string, error)
+	GetUserAccess(ctx context.Context, userID int) (*models.UserAccess, error)
+}
+
+// SessionDenylist сразу отключает access токены отозванных сессий
+type SessionDenylist interface {
+	RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error
+}
+
+type AccessTokenIssuer interface {
+	GenerateToken(userID int, sessionID string, role string) (string, error)
+	AccessTTL() time.Duration
+}
+
+// IssuedTokens - пара токенов, выданная при входе или обмене refresh токена
+type IssuedTokens struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// Device - откуда пришел запрос, сохраняется в сессии
+type Device struct {
+	UserAgent string
+	IP        string
+}
+
+type SessionService struct {
+	repo       SessionRepositoryInterface
+	denylist   SessionDenylist
+	tokens     AccessTokenIssuer
+	refreshTTL time.Duration
+}
+
+func NewSessionService(repo SessionRepositoryInterface, denylist SessionDenylist, tokens AccessTokenIssuer,
+	refreshTTL time.Duration) *SessionService {
+	return &SessionService{repo: repo, denylist: denylist, tokens: tokens, refreshTTL: refreshTTL}
+}
+
+// StartSession создает сессию при входе и выдает первую пару токенов
+func (s *SessionService) StartSession(ctx context.Context, userID int, device Device) (IssuedTokens, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	now := time.Now()
+	access, err := s.userAccess(ctx, userID)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+	session := &models.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		UserAgent:  device.UserAgent,
+		IP:         device.IP,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.refreshTTL),
+	}
+	err = s.repo.CreateSession(ctx, session, hashToken(refreshToken))
+	if err != nil {
+		slog.Error("Create session", "error", err)
+		return IssuedTokens{}, err
+	}
+	return s.issue(session, access.Role, refreshToken, now)
+}
+
+// Refresh обменивает refresh токен на новую пару. Повторное предъявление уже обмененного токена
+// значит, что токен украден, поэтому вся сессия отзывается.
+func (s *SessionService) Refresh(ctx context.Context, refreshToken string, device Device) (IssuedTokens, error) {
+	now := time.Now()
+	token, err := s.repo.UseRefreshToken(ctx, hashToken(refreshToken), now)
+	if err != nil {
+		slog.Error("Use refresh token", "error", err)
+		return IssuedTokens{}, err
+	}
+	if token == nil {
+		return IssuedTokens{}, ErrInvalidRefreshToken
+	}
+
+	session := token.Session
+	if session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+		return IssuedTokens{}, ErrInvalidRefreshToken
+	}
+	if token.UsedAt != nil {
+		slog.Warn("Refresh token reuse detected, revoking session", "sessionID", session.ID, "userID", session.UserID)
+		if err = s.revoke(ctx, session.UserID, session.ID, now); err != nil {
+			return IssuedTokens{}, err
+		}
+		return IssuedTokens{}, ErrInvalidRefreshToken
+	}
+
+	// роль читается заново при каждом обмене, поэтому ее изменение доходит до токена за время жизни access токена
+	access, err := s.userAccess(ctx, session.UserID)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+	newToken, err := randomToken(32)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+	session.UserAgent = device.UserAgent
+	session.IP = device.IP
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(s.refreshTTL)
+	err = s.repo.RotateRefreshToken(ctx, &session, hashToken(newToken))
+	if err != nil {
+		slog.Error("Rotate refresh token", "error", err)
+		return IssuedTokens{}, err
+	}
+	return s.issue(&session, access.Role, newToken, now)
+}
+
+// EndSession отзывает сессию, которой принадлежит refresh токен (выход с устройства)
+func (s *SessionService) EndSession(ctx context.Context, refreshToken string) error {
+	token, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		slog.Error("Get refresh token", "error", err)
+		return err
+	}
+	if token == nil {
+		return ErrInvalidRefreshToken
+	}
+	return s.revoke(ctx, token.Session.UserID, token.SessionID, time.Now())
+}
+
+// ListSessions возвращает активные сессии пользователя, currentSessionID помечается текущей
+func (s *SessionService) ListSessions(ctx context.Context, userID int, currentSessionID string) ([]models.Session, error) {
+	sessions, err := s.repo.GetActiveSessions(ctx, userID, time.Now())
+	if err != nil {
+		slog.Error("Get sessions", "error", err)
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+func (s *SessionService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	return s.revoke(ctx, userID, sessionID, time.Now())
+}
+
+// RevokeAllSessions разлогинивает пользователя на всех устройствах
+func (s *SessionService) RevokeAllSessions(ctx context.Context, userID int) error {
+	sessionIDs, err := s.repo.RevokeAllSessions(ctx, userID, time.Now())
+	if err != nil {
+		slog.Error("Revoke all sessions", "error", err)
+		return err
+	}
+	for _, sessionID := range sessionIDs {
+		if err = s.denylist.RevokeSession(ctx, sessionID, s.tokens.AccessTTL()); err != nil {
+			slog.Error("Deny session", "error", err, "sessionID", sessionID)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SessionService) revoke(ctx context.Context, userID int, sessionID string, now time.Time) error {
+	revoked, err := s.repo.RevokeSession(ctx, userID, sessionID, now)
+	if err != nil {
+		slog.Error("Revoke session", "error", err)
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	err = s.denylist.RevokeSession(ctx, sessionID, s.tokens.AccessTTL())
+	if err != nil {
+		slog.Error("Deny session", "error", err, "sessionID", sessionID)
+	}
+	return err
+}
+
+// userAccess возвращает роль пользователя, заблокированным сессия не выдается
+func (s *SessionService) userAccess(ctx context.Context, userID int) (*models.UserAccess, error) {
+	access, err := s.repo.GetUserAccess(ctx, userID)
+	if err != nil {
+		slog.Error("Get user access", "error", err)
+		return nil, err
+	}
+	if access == nil || access.DeletionRequestedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	if access.BannedAt != nil {
+		return nil, ErrUserBanned
+	}
+	return access, nil
+}
+
+func (s *SessionService) issue(session *models.Session, role string, refreshToken string, now time.Time) (IssuedTokens, error) {
+	accessToken, err := s.tokens.GenerateToken(session.UserID, session.ID, role)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+	return IssuedTokens{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  now.Add(s.tokens.AccessTTL()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+func randomToken(size int) (string, error) {
+	buf, err := randomBytes(size)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func randomBytes(size int) ([]byte, error) {
+	buf := make([]byte, size)
+	_, err := rand.Read(buf)
+	return buf, err
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+// purgeBatchSize - сколько постов и картинок удаляется навсегда за один проход purge
+const purgeBatchSize = 100
+
+var ErrTrashItemNotFound = errors.New("item not found in trash")
+
+type TrashRepositoryInterface interface {
+	GetTrash(ctx context.Context, userID int) ([]models.TrashItem, error)
+	RestorePost(ctx context.Context, userID, postID int) (bool, error)
+	RestoreImage(ctx context.Context, userID int, imageSK string) ([]int, error)
+	GetExpiredPosts(ctx context.Context, before time.Time, limit int) ([]int, error)
+	GetExpiredImages(ctx context.Context, before time.Time, limit int) ([]string, error)
+}
+
+// TrashService - корзина удаленных постов и картинок. Через retention после удаления
+// purge удаляет их навсегда, вместе с файлами в хранилище.
+type TrashService struct {
+	repo      TrashRepositoryInterface
+	posts     PostRemover
+	pictures  PictureRemover
+	cache     Cacher
+	retention time.Duration
+}
+
+func NewTrashService(repo TrashRepositoryInterface, posts PostRemover, pictures PictureRemover, cache Cacher,
+	retention time.Duration) *TrashService {
+	return &TrashService{repo: repo, posts: posts, pictures: pictures, cache: cache, retention: retention}
+}
+
+func (s *TrashService) ListTrash(ctx context.Context, userID int) ([]models.TrashItem, error) {
+	items, err := s.repo.GetTrash(ctx, userID)
+	if err != nil {
+		slog.Error("Get trash", "error", err)
+		return nil, err
+	}
+	for i := range items {
+		items[i].PurgeAt = items[i].DeletedAt.Add(s.retention)
+	}
+	return items, nil
+}
+
+func (s *TrashService) RestorePost(ctx context.Context, userID, postID int) error {
+	ok, err := s.repo.RestorePost(ctx, userID, postID)
+	if err != nil {
+		slog.Error("Restore post", "error", err)
+		return err
+	}
+	if !ok {
+		return ErrTrashItemNotFound
+	}
+	s.invalidatePost(ctx, postID)
+	return nil
+}
+
+func (s *TrashService) RestorePicture(ctx context.Context, userID int, imageSK string) error {
+	postIDs, err := s.repo.RestoreImage(ctx, userID, imageSK)
+	if err != nil {
+		slog.Error("Restore picture", "error", err)
+		return err
+	}
+	if postIDs == nil {
+		return ErrTrashItemNotFound
+	}
+	// посты с этой картинкой могли закешироваться, пока она была в корзине
+	for _, postID := range postIDs {
+		s.invalidatePost(ctx, postID)
+	}
+	return nil
+}
+
+func (s *TrashService) invalidatePost(ctx context.Context, postID int) {
+	if _, err := s.cache.InvalidatePost(ctx, postID); err != nil {
+		slog.Error("Restore invalidate post", "error", err, "postID", postID)
+	}
+}
+
+// Purge удаляет навсегда все, что пролежало в корзине дольше retention, и возвращает,
+// сколько удалено. Ошибка одного элемента не останавливает остальные, он попадет в следующий проход.
+func (s *TrashService) Purge(ctx context.Context, now time.Time) (int, error) {
+	before := now.Add(-s.retention)
+	purged := 0
+	postIDs, err := s.repo.GetExpiredPosts(ctx, before, purgeBatchSize)
+	if err != nil {
+		slog.Error("Get expired posts", "error", err)
+		return purged, err
+	}
+	for _, postID := range postIDs {
+		if s.posts.RemovePost(ctx, postID) == nil {
+			purged++
+		}
+	}
+	imageSKs, err := s.repo.GetExpiredImages(ctx, before, purgeBatchSize)
+	if err != nil {
+		slog.Error("Get expired images", "error", err)
+		return purged, err
+	}
+	for _, imageSK := range imageSKs {
+		if s.pictures.RemovePicture(ctx, imageSK) == nil {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// RunPurge чистит корзину раз в interval, пока не отменен ctx
+func (s *TrashService) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if purged, err := s.Purge(ctx, time.Now()); err == nil && purged > 0 {
+			slog.Info("Trash purged", "removed", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
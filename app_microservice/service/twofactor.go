@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"math/big"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/safety/totp"
+	"strings"
+	"time"
+)
+
+const (
+	totpIssuer         = "Imgur 2.0"
+	recoveryCodesCount = 10
+	// loginChallengeTTL - сколько есть времени ввести код после пароля
+	loginChallengeTTL = 5 * time.Minute
+	// maxChallengeAttempts - после стольких неверных кодов нужно снова вводить пароль
+	maxChallengeAttempts = 5
+)
+
+var (
+	ErrTwoFactorEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotStarted = errors.New("two-factor enrolment is not started")
+	ErrInvalidTwoFactor    = errors.New("invalid two-factor code")
+	ErrInvalidPassword     = errors.New("invalid password")
+	ErrInvalidChallenge    = errors.New("login challenge is invalid or expired")
+)
+
+type TwoFactorRepositoryInterface interface {
+	GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error)
+	SavePendingTwoFactor(ctx context.Context, userID int, secret string) error
+	EnableTwoFactor(ctx context.Context, userID int, step int64, codeHashes []string, now time.Time) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	DisableTwoFactor(ctx context.Context, userID int) error
+	UseStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string, now time.Time) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID int) (int, error)
+	GetCredentials(ctx context.Context, userID int) (*models.User, error)
+}
+
+// LoginChallengeStore хранит челленджи входа между проверкой пароля и вводом кода
+type LoginChallengeStore interface {
+	SaveLoginChallenge(ctx context.Context, challengeHash string, userID int, ttl time.Duration) error
+	GetLoginChallenge(ctx context.Context, challengeHash string) (int, error)
+	FailLoginChallenge(ctx context.Context, challengeHash string, ttl time.Duration) (int, error)
+	DeleteLoginChallenge(ctx context.Context, challengeHash string) error
+}
+
+// LoginLimiter считает неверные пароли и коды по имени пользователя, реализуется LoginGuard
+type LoginLimiter interface {
+	BlockedFor(ctx context.Context, username string, device Device) (time.Duration, error)
+	Fail(ctx context.Context, username string, device Device) error
+	Succeed(ctx context.Context, username string)
+}
+
+type TwoFactorService struct {
+	repo       TwoFactorRepositoryInterface
+	challenges LoginChallengeStore
+	guard      LoginLimiter
+}
+
+func NewTwoFactorService(repo TwoFactorRepositoryInterface, challenges LoginChallengeStore, guard LoginLimiter) *TwoFactorService {
+	return &TwoFactorService{repo: repo, challenges: challenges, guard: guard}
+}
+
+// Enroll создает новый секрет. 2FA включится только после подтверждения первым кодом.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID int) (*models.TwoFactorEnrollment, error) {
+	current, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		slog.Error("Get two factor", "error", err)
+		return nil, err
+	}
+	if current != nil && current.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	user, err := s.repo.GetCredentials(ctx, userID)
+	if err != nil {
+		slog.Error("Get credentials", "error", err)
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err = s.repo.SavePendingTwoFactor(ctx, userID, secret); err != nil {
+		slog.Error("Save pending two factor", "error", err)
+		return nil, err
+	}
+	return &models.TwoFactorEnrollment{Secret: secret, OTPAuthURI: totp.URI(totpIssuer, user.Username, secret)}, nil
+}
+
+// Confirm включает 2FA, если код из приложения верный, и выдает коды восстановления
+func (s *TwoFactorService) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	current, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		slog.Error("Get two factor", "error", err)
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrTwoFactorNotStarted
+	}
+	if current.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	now := time.Now()
+	step, ok := totp.Validate(current.Secret, code, now)
+	if !ok {
+		return nil, ErrInvalidTwoFactor
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = s.repo.EnableTwoFactor(ctx, userID, step, hashes, now); err != nil {
+		slog.Error("Enable two factor", "error", err)
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Enabled - нужен ли пользователю второй шаг при входе
+func (s *TwoFactorService) Enabled(ctx context.Context, userID int) (bool, error) {
+	current, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		slog.Error("Get two factor", "error", err)
+		return false, err
+	}
+	return current != nil && current.Enabled, nil
+}
+
+// Status - включена ли 2FA и сколько осталось кодов восстановления
+func (s *TwoFactorService) Status(ctx context.Context, userID int) (*models.TwoFactorStatus, error) {
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil || !enabled {
+		return &models.TwoFactorStatus{}, err
+	}
+	left, err := s.repo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		slog.Error("Count recovery codes", "error", err)
+		return nil, err
+	}
+	return &models.TwoFactorStatus{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+// StartChallenge вызывается после верного пароля и выдает короткоживущий токен для второго шага
+func (s *TwoFactorService) StartChallenge(ctx context.Context, userID int) (string, error) {
+	challenge, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err = s.challenges.SaveLoginChallenge(ctx, hashToken(challenge), userID, loginChallengeTTL); err != nil {
+		slog.Error("Save login challenge", "error", err)
+		return "", err
+	}
+	return challenge, nil
+}
+
+// CompleteChallenge проверяет код второго шага и возвращает пользователя, для которого можно начать сессию.
+// Челлендж одноразовый и сгорает после нескольких неверных кодов. Неверные коды считаются в LoginGuard
+// вместе с неверными паролями, иначе перебор кода продолжался бы с новым челленджем после каждого входа.
+func (s *TwoFactorService) CompleteChallenge(ctx context.Context, challenge, code string, device Device) (int, error) {
+	challengeHash := hashToken(challenge)
+	userID, err := s.challenges.GetLoginChallenge(ctx, challengeHash)
+	if err != nil {
+		slog.Error("Get login challenge", "error", err)
+		return 0, err
+	}
+	if userID == 0 {
+		return 0, ErrInvalidChallenge
+	}
+	user, err := s.repo.GetCredentials(ctx, userID)
+	if err != nil {
+		slog.Error("Get credentials", "error", err)
+		return 0, err
+	}
+	if err = s.checkBlocked(ctx, user.Username, device); err != nil {
+		return 0, err
+	}
+
+	err = s.verifyCode(ctx, userID, code)
+	if errors.Is(err, ErrInvalidTwoFactor) {
+		if failErr := s.guard.Fail(ctx, user.Username, device); failErr != nil {
+			return 0, failErr
+		}
+		attempts, failErr := s.challenges.FailLoginChallenge(ctx, challengeHash, loginChallengeTTL)
+		if failErr != nil {
+			slog.Error("Fail login challenge", "error", failErr)
+			return 0, failErr
+		}
+		if attempts >= maxChallengeAttempts {
+			slog.Warn("Too many invalid two-factor codes, dropping challenge", "userID", userID)
+			s.dropChallenge(ctx, challengeHash)
+			return 0, ErrInvalidChallenge
+		}
+		return 0, err
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.guard.Succeed(ctx, user.Username)
+	s.dropChallenge(ctx, challengeHash)
+	return userID, nil
+}
+
+// checkBlocked возвращает LoginBlockedError, если вход под этим именем или с этого IP заблокирован
+func (s *TwoFactorService) checkBlocked(ctx context.Context, username string, device Device) error {
+	blocked, err := s.guard.BlockedFor(ctx, username, device)
+	if err != nil {
+		return err
+	}
+	if blocked > 0 {
+		return &LoginBlockedError{RetryAfter: blocked}
+	}
+	return nil
+}
+
+// Disable выключает 2FA после повторной проверки пароля и кода
+func (s *TwoFactorService) Disable(ctx context.Context, userID int, reauth models.TwoFactorReauth, device Device) error {
+	if err := s.reauthenticate(ctx, userID, reauth, device); err != nil {
+		return err
+	}
+	if err := s.repo.DisableTwoFactor(ctx, userID); err != nil {
+		slog.Error("Disable two factor", "error", err)
+		return err
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes выпускает новые коды восстановления после повторной проверки пароля и кода
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, reauth models.TwoFactorReauth,
+	device Device) ([]string, error) {
+	if err := s.reauthenticate(ctx, userID, reauth, device); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		slog.Error("Replace recovery codes", "error", err)
+		return nil, err
+	}
+	return codes, nil
+}
+
+// reauthenticate повторно проверяет пароль и код. Неудачи считаются в LoginGuard так же, как на входе:
+// с украденной сессией пароль здесь иначе можно было бы перебирать без ограничений.
+func (s *TwoFactorService) reauthenticate(ctx context.Context, userID int, reauth models.TwoFactorReauth, device Device) error {
+	user, err := s.repo.GetCredentials(ctx, userID)
+	if err != nil {
+		slog.Error("Get credentials", "error", err)
+		return err
+	}
+	if err = s.checkBlocked(ctx, user.Username, device); err != nil {
+		return err
+	}
+	err = s.verifyReauth(ctx, user, reauth)
+	if errors.Is(err, ErrInvalidPassword) || errors.Is(err, ErrInvalidTwoFactor) {
+		if failErr := s.guard.Fail(ctx, user.Username, device); failErr != nil {
+			return failErr
+		}
+	}
+	return err
+}
+
+func (s *TwoFactorService) verifyReauth(ctx context.Context, user *models.User, reauth models.TwoFactorReauth) error {
+	if user.Password == "" {
+		// у пользователей, созданных через OIDC, пароля нет: вместо него нужен код из приложения,
+		// одного кода восстановления для отключения 2FA мало
+		return s.verifyAppCode(ctx, user.ID, reauth.Code)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(reauth.Password)) != nil {
+		return ErrInvalidPassword
+	}
+	return s.verifyCode(ctx, user.ID, reauth.Code)
+}
+
+// verifyAppCode принимает только код из приложения
+func (s *TwoFactorService) verifyAppCode(ctx context.Context, userID int, code string) error {
+	current, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		slog.Error("Get two factor", "error", err)
+		return err
+	}
+	if current == nil || !current.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+	step, ok := totp.Validate(current.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactor
+	}
+	return s.useStep(ctx, userID, step)
+}
+
+func (s *TwoFactorService) useStep(ctx context.Context, userID int, step int64) error {
+	fresh, err := s.repo.UseStep(ctx, userID, step)
+	if err != nil {
+		slog.Error("Use totp step", "error", err)
+		return err
+	}
+	if !fresh {
+		return ErrInvalidTwoFactor
+	}
+	return nil
+}
+
+// verifyCode принимает код из приложения или код восстановления
+func (s *TwoFactorService) verifyCode(ctx context.Context, userID int, code string) error {
+	current, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		slog.Error("Get two factor", "error", err)
+		return err
+	}
+	if current == nil || !current.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	now := time.Now()
+	if step, ok := totp.Validate(current.Secret, code, now); ok {
+		return s.useStep(ctx, userID, step)
+	}
+
+	used, err := s.repo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)), now)
+	if err != nil {
+		slog.Error("Use recovery code", "error", err)
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactor
+	}
+	slog.Info("Recovery code used", "userID", userID)
+	return nil
+}
+
+func (s *TwoFactorService) dropChallenge(ctx context.Context, challengeHash string) {
+	if err := s.challenges.DeleteLoginChallenge(ctx, challengeHash); err != nil {
+		slog.Error("Delete login challenge", "error", err)
+	}
+}
+
+// recoveryAlphabet без похожих друг на друга символов
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// newRecoveryCodes возвращает коды вида xxxxx-xxxxx и их хеши для базы
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+	alphabetSize := big.NewInt(int64(len(recoveryAlphabet)))
+	for i := range codes {
+		var code strings.Builder
+		for j := 0; j < 10; j++ {
+			if j == 5 {
+				code.WriteByte('-')
+			}
+			// rand.Int выбирает равномерно, в отличие от остатка от деления случайного байта
+			index, err := rand.Int(rand.Reader, alphabetSize)
+			if err != nil {
+				return nil, nil, err
+			}
+			code.WriteByte(recoveryAlphabet[index.Int64()])
+		}
+		codes[i] = code.String()
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
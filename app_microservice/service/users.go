@@ -6,31 +6,37 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"log"
 	"log/slog"
+	netmail "net/mail"
 	"pictureloader/app_microservice/models"
 	"strings"
+	"time"
 )
 
 type UserRepositoryInterface interface {
 	CreateNewUser(ctx context.Context, user *models.User) error
 	GetUserByID(ctx context.Context, id int) (*models.UserProfile, error)
-	DeleteUserByID(ctx context.Context, id int) error
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
 	ChangeUsernameByID(ctx context.Context, userID int, newUsername string) error
 	UpdatePasswordByID(ctx context.Context, userID int, newPassword string) error
-	UploadProfilePicture(ctx context.Context, userID int, imageSK string) error
 }
 
 type UserStorageManager interface {
 	GetFileURL(context.Context, string) (string, error)
 }
 
+// SessionRevoker разлогинивает пользователя на всех устройствах
+type SessionRevoker interface {
+	RevokeAllSessions(ctx context.Context, userID int) error
+}
+
 type UserService struct {
 	database UserRepositoryInterface
 	storage  UserStorageManager
+	sessions SessionRevoker
 }
 
-func NewUserService(database UserRepositoryInterface, storage UserStorageManager) *UserService {
-	return &UserService{database, storage}
+func NewUserService(database UserRepositoryInterface, storage UserStorageManager, sessions SessionRevoker) *UserService {
+	return &UserService{database, storage, sessions}
 }
 
 func (u *UserService) RegisterUser(ctx context.Context, user *models.User) error {
@@ -38,23 +44,48 @@ func (u *UserService) RegisterUser(ctx context.Context, user *models.User) error
 		return errors.New("invalid username")
 	}
 
-	if user.Email == "" || strings.Contains(user.Email, " ") || !strings.Contains(user.Email, "@") {
+	// адрес должен быть голым user@host, без имени и угловых скобок
+	address, err := netmail.ParseAddress(user.Email)
+	if err != nil || address.Address != user.Email {
 		return errors.New("invalid email")
 	}
+	if len(user.Password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	// роль и блокировку нельзя задать себе при регистрации
+	user.EmailVerified = false
+	user.Role = models.RoleUser
+	user.BannedAt = nil
+	user.BanReason = ""
+	// публичный профиль заполняется отдельной ручкой с проверкой
+	user.Bio = ""
+	user.Links = nil
+	user.CreatedAt = time.Time{}
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	user.Password = string(hashedPassword)
 	return u.database.CreateNewUser(ctx, user)
 }
 
+// dummyPasswordHash сравнивается с паролем, когда пользователя нет: без bcrypt ответ приходил бы
+// заметно быстрее и выдавал бы, существует ли имя
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password for timing"), bcrypt.DefaultCost)
+
 func (u *UserService) LoginUser(ctx context.Context, userLogin *models.UserLogin) (bool, int) {
 	user, err := u.database.GetUserByUsername(ctx, userLogin.Username)
 	if err != nil {
 		slog.Error("Login user (get user by username) error", "error", err)
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(userLogin.Password))
 		return false, -1
 	}
 	if user == nil {
-		slog.Info("LoginUser: User not found", "error", err)
+		slog.Info("LoginUser: User not found")
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(userLogin.Password))
+		return false, -1
+	}
+	// пользователи, созданные через OIDC провайдера, не имеют пароля
+	if user.Password == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(userLogin.Password))
 		return false, -1
 	}
 
@@ -68,15 +99,6 @@ func (u *UserService) LoginUser(ctx context.Context, userLogin *models.UserLogin
 	return true, user.ID
 }
 
-func (u *UserService) DeleteUserByID(ctx context.Context, userID int) error {
-	err := u.database.DeleteUserByID(ctx, userID)
-	if err != nil {
-		slog.Error("DeleteUserByID error", "error", err)
-		return err
-	}
-	return nil
-}
-
 func (u *UserService) UpdateUsername(ctx context.Context, userID int, username string) error {
 	err := u.database.ChangeUsernameByID(ctx, userID, username)
 	if err != nil {
@@ -87,6 +109,9 @@ func (u *UserService) UpdateUsername(ctx context.Context, userID int, username s
 }
 
 func (u *UserService) UpdatePassword(ctx context.Context, userID int, newPassword string) error {
+	if len(newPassword) < minPasswordLength {
+		return ErrWeakPassword
+	}
 	hashedPass, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		slog.Error("UpdatePassword error", "error", err)
@@ -98,6 +123,13 @@ func (u *UserService) UpdatePassword(ctx context.Context, userID int, newPasswor
 		slog.Error("UpdateUsername error", "error", err)
 		return err
 	}
+
+	// после смены пароля старые сессии могли остаться у того, кто его узнал
+	err = u.sessions.RevokeAllSessions(ctx, userID)
+	if err != nil {
+		slog.Error("UpdatePassword revoke sessions error", "error", err)
+		return err
+	}
 	return nil
 }
 
@@ -107,26 +139,14 @@ func (u *UserService) GetUserByID(ctx context.Context, userID int) (*models.User
 		slog.Error("GetUserByID error", "error", err)
 		return nil, err
 	}
-	if user.ProfilePicture != "" {
-		user.ProfilePicture, err = u.storage.GetFileURL(ctx, user.ProfilePicture)
-		if err != nil {
-			slog.Error("GetUserByID error", "error", err)
-			return nil, err
-		}
-	}
-
 	if user == nil {
 		slog.Info("GetUserByID: User not found", "error", errors.New("user not found"))
 		return nil, errors.New("user not found")
 	}
-	return user, nil
-}
 
-func (u *UserService) UploadProfilePicture(ctx context.Context, userID int, imageSK string) error {
-	err := u.database.UploadProfilePicture(ctx, userID, imageSK)
-	if err != nil {
-		slog.Error("UploadProfilePicture error", "error", err)
-		return err
+	if user.ProfilePicture != "" {
+		user.Avatars = avatarURLs(ctx, u.storage, user.ProfilePicture)
+		user.ProfilePicture = largestAvatar(user.Avatars)
 	}
-	return nil
+	return user, nil
 }
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	postgres2 "pictureloader/app_microservice/database/postgres"
+	"pictureloader/app_microservice/models"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	conn, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	return db, sqlMock
+}
+
+func TestSessionRepository_RotateRefreshToken_ExtendsSession(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+	repo := postgres2.NewSessionRepository(db)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	session := &models.Session{ID: "s1", UserID: 3, UserAgent: "curl", IP: "10.0.0.1", LastUsedAt: now,
+		ExpiresAt: now.Add(24 * time.Hour)}
+
+	sqlMock.ExpectBegin()
+	// срок сессии сохраняется вместе с новым токеном, иначе refresh кука переживет сессию
+	sqlMock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "sessions" SET "expires_at"=$1,"ip"=$2,"last_used_at"=$3,"user_agent"=$4 WHERE id = $5`)).
+		WithArgs(session.ExpiresAt, "10.0.0.1", now, "curl", "s1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "refresh_tokens"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	err := repo.RotateRefreshToken(context.Background(), session, "hash")
+
+	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
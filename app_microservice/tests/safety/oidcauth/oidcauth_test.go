@@ -0,0 +1,97 @@
+package oidcauth
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"pictureloader/app_microservice/mockidp"
+	"pictureloader/app_microservice/safety/oidcauth"
+	"testing"
+)
+
+const redirectURL = "http://app.local/users/oidc/callback"
+
+// startIdP поднимает mock IdP на локальном адресе, issuer должен совпадать с адресом сервера
+func startIdP(t *testing.T) string {
+	server := httptest.NewUnstartedServer(nil)
+	issuer := "http://" + server.Listener.Addr().String()
+	idp, err := mockidp.New(mockidp.Config{
+		Issuer:       issuer,
+		ClientID:     "pictureloader",
+		ClientSecret: "secret",
+		RedirectURLs: []string{redirectURL},
+		Users: []mockidp.User{
+			{Subject: "1001", Email: "alice@example.com", EmailVerified: true, PreferredUsername: "alice"},
+			{Subject: "1002", Email: "bob@example.com", PreferredUsername: "bob"},
+		},
+	})
+	require.NoError(t, err)
+	server.Config.Handler = idp
+	server.Start()
+	t.Cleanup(server.Close)
+	return issuer
+}
+
+// authorize проходит шаг авторизации и возвращает параметры редиректа обратно в приложение
+func authorize(t *testing.T, authURL string) url.Values {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query()
+}
+
+func newProvider(t *testing.T, issuer string) *oidcauth.Provider {
+	provider, err := oidcauth.NewProvider(context.Background(), oidcauth.ProviderConfig{
+		Name:         "mock",
+		Issuer:       issuer,
+		ClientID:     "pictureloader",
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"email", "profile"},
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestProvider_AuthorizationCodeWithPKCE(t *testing.T) {
+	issuer := startIdP(t)
+	provider := newProvider(t, issuer)
+	verifier := oidcauth.GenerateVerifier()
+
+	params := authorize(t, provider.AuthCodeURL("state-1", "nonce-1", verifier)+"&login_hint=bob")
+	assert.Equal(t, "state-1", params.Get("state"))
+
+	identity, err := provider.Exchange(context.Background(), params.Get("code"), "nonce-1", verifier)
+
+	require.NoError(t, err)
+	assert.Equal(t, "mock", identity.Provider)
+	assert.Equal(t, issuer, identity.Issuer)
+	assert.Equal(t, "1002", identity.Subject)
+	assert.Equal(t, "bob@example.com", identity.Email)
+	assert.False(t, identity.EmailVerified)
+	assert.Equal(t, "bob", identity.PreferredUsername)
+}
+
+func TestProvider_Exchange_RejectsWrongVerifierAndNonce(t *testing.T) {
+	provider := newProvider(t, startIdP(t))
+	verifier := oidcauth.GenerateVerifier()
+
+	params := authorize(t, provider.AuthCodeURL("state", "nonce", verifier))
+	_, err := provider.Exchange(context.Background(), params.Get("code"), "nonce", oidcauth.GenerateVerifier())
+	assert.Error(t, err)
+
+	params = authorize(t, provider.AuthCodeURL("state", "nonce", verifier))
+	_, err = provider.Exchange(context.Background(), params.Get("code"), "other-nonce", verifier)
+	assert.Error(t, err)
+
+	// код одноразовый
+	_, err = provider.Exchange(context.Background(), params.Get("code"), "nonce", verifier)
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package account
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type MockAccountRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountRepository) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	return m.Called(ctx, token).Error(0)
+}
+
+func (m *MockAccountRepository) UseUserToken(ctx context.Context, tokenHash, purpose string, now time.Time) (int, error) {
+	args := m.Called(ctx, tokenHash, purpose, now)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAccountRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(ctx, email)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockAccountRepository) GetUserEmail(ctx context.Context, userID int) (string, bool, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockAccountRepository) SetEmailVerified(ctx context.Context, userID int) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+////////////////////
+
+type MockPasswordUpdater struct {
+	mock.Mock
+}
+
+func (m *MockPasswordUpdater) UpdatePassword(ctx context.Context, userID int, newPassword string) error {
+	return m.Called(ctx, userID, newPassword).Error(0)
+}
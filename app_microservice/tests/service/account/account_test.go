@@ -0,0 +1,85 @@
+package account
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"pictureloader/common/mail"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func setupTest() (*service.AccountService, *MockAccountRepository, *MockPasswordUpdater, *mail.MemorySender) {
+	repo := new(MockAccountRepository)
+	users := new(MockPasswordUpdater)
+	sender := mail.NewMemorySender()
+	accountService := service.NewAccountService(repo, users, sender, service.AccountConfig{
+		PublicURL:      "http://front",
+		VerifyEmailTTL: time.Hour,
+		ResetTTL:       time.Hour,
+	})
+	return accountService, repo, users, sender
+}
+
+// tokenFromMail достает токен из ссылки в письме
+func tokenFromMail(t *testing.T, msg mail.Message) string {
+	link := regexp.MustCompile(`http://front/\S+`).FindString(msg.Text)
+	require.NotEmpty(t, link)
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	return parsed.Query().Get("token")
+}
+
+func TestAccountService_PasswordReset(t *testing.T) {
+	ctx := context.Background()
+	accountService, repo, users, sender := setupTest()
+
+	var stored *models.UserToken
+	repo.On("GetUserByEmail", ctx, "vaflya@gmail.com").Return(&models.User{ID: 4, Username: "vaflya", Email: "vaflya@gmail.com"}, nil)
+	repo.On("CreateUserToken", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*models.UserToken)
+	}).Return(nil)
+
+	require.NoError(t, accountService.RequestPasswordReset(ctx, "vaflya@gmail.com"))
+	require.Len(t, sender.Sent(), 1)
+	token := tokenFromMail(t, sender.Sent()[0])
+	assert.Equal(t, models.TokenPurposeResetPassword, stored.Purpose)
+	sum := sha256.Sum256([]byte(token))
+	assert.Equal(t, hex.EncodeToString(sum[:]), stored.Hash)
+
+	repo.On("UseUserToken", ctx, stored.Hash, models.TokenPurposeResetPassword, mock.Anything).Return(4, nil)
+	repo.On("SetEmailVerified", ctx, 4).Return(nil)
+	users.On("UpdatePassword", ctx, 4, "new-password").Return(nil)
+
+	assert.NoError(t, accountService.ResetPassword(ctx, token, "new-password"))
+	users.AssertExpectations(t)
+}
+
+func TestAccountService_ResetPassword_UsedToken(t *testing.T) {
+	ctx := context.Background()
+	accountService, repo, users, _ := setupTest()
+
+	repo.On("UseUserToken", ctx, mock.Anything, models.TokenPurposeResetPassword, mock.Anything).Return(0, nil)
+
+	err := accountService.ResetPassword(ctx, "already-used", "new-password")
+
+	assert.ErrorIs(t, err, service.ErrInvalidUserToken)
+	users.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAccountService_RequestPasswordReset_UnknownEmail(t *testing.T) {
+	ctx := context.Background()
+	accountService, repo, _, sender := setupTest()
+
+	repo.On("GetUserByEmail", ctx, "nobody@gmail.com").Return((*models.User)(nil), nil)
+
+	assert.NoError(t, accountService.RequestPasswordReset(ctx, "nobody@gmail.com"))
+	assert.Empty(t, sender.Sent())
+}
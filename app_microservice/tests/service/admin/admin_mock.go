@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type MockAdminRepository struct {
+	mock.Mock
+}
+
+func (m *MockAdminRepository) GetUsers(ctx context.Context, filter models.UserFilter) ([]models.AdminUser, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.AdminUser), args.Error(1)
+}
+
+func (m *MockAdminRepository) GetUserAccess(ctx context.Context, userID int) (*models.UserAccess, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.UserAccess), args.Error(1)
+}
+
+func (m *MockAdminRepository) SetUserBan(ctx context.Context, userID int, bannedAt *time.Time, reason string) (bool, error) {
+	args := m.Called(ctx, userID, bannedAt, reason)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAdminRepository) SetUserRole(ctx context.Context, userID int, role string) (bool, error) {
+	args := m.Called(ctx, userID, role)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAdminRepository) PromoteAdmins(ctx context.Context, userIDs []int) error {
+	return m.Called(ctx, userIDs).Error(0)
+}
+
+////////////////////
+
+type MockSessionRevoker struct {
+	mock.Mock
+}
+
+func (m *MockSessionRevoker) RevokeAllSessions(ctx context.Context, userID int) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+////////////////////
+
+type MockAuditRecorder struct {
+	mock.Mock
+}
+
+func (m *MockAuditRecorder) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	return m.Called(ctx, entry).Error(0)
+}
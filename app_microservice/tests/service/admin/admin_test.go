@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"testing"
+)
+
+func setupTest() (*service.AdminService, *MockAdminRepository, *MockSessionRevoker) {
+	repo := new(MockAdminRepository)
+	sessions := new(MockSessionRevoker)
+	audit := new(MockAuditRecorder)
+	audit.On("CreateAuditEntry", mock.Anything, mock.Anything).Return(nil)
+	return service.NewAdminService(repo, sessions, audit), repo, sessions
+}
+
+func TestAdminService_BanUser_RevokesSessions(t *testing.T) {
+	ctx := context.Background()
+	adminService, repo, sessions := setupTest()
+
+	repo.On("GetUserAccess", ctx, 7).Return(&models.UserAccess{Role: models.RoleUser}, nil)
+	repo.On("SetUserBan", ctx, 7, mock.AnythingOfType("*time.Time"), "spam").Return(true, nil)
+	sessions.On("RevokeAllSessions", ctx, 7).Return(nil)
+
+	err := adminService.BanUser(ctx, service.Actor{UserID: 1, Role: models.RoleModerator}, 7, "  spam ")
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	sessions.AssertExpectations(t)
+}
+
+func TestAdminService_BanUser_ModeratorCannotBanModerator(t *testing.T) {
+	ctx := context.Background()
+	adminService, repo, sessions := setupTest()
+
+	repo.On("GetUserAccess", ctx, 7).Return(&models.UserAccess{Role: models.RoleModerator}, nil)
+
+	err := adminService.BanUser(ctx, service.Actor{UserID: 1, Role: models.RoleModerator}, 7, "")
+
+	assert.ErrorIs(t, err, service.ErrForbiddenUser)
+	repo.AssertNotCalled(t, "SetUserBan")
+	sessions.AssertNotCalled(t, "RevokeAllSessions")
+}
+
+func TestAdminService_BanUser_Self(t *testing.T) {
+	adminService, repo, _ := setupTest()
+
+	err := adminService.BanUser(context.Background(), service.Actor{UserID: 1, Role: models.RoleAdmin}, 1, "")
+
+	assert.ErrorIs(t, err, service.ErrSelfModeration)
+	repo.AssertNotCalled(t, "GetUserAccess")
+}
+
+func TestAdminService_BanUser_NotFound(t *testing.T) {
+	ctx := context.Background()
+	adminService, repo, _ := setupTest()
+
+	repo.On("GetUserAccess", ctx, 7).Return((*models.UserAccess)(nil), nil)
+
+	err := adminService.BanUser(ctx, service.Actor{UserID: 1, Role: models.RoleAdmin}, 7, "")
+
+	assert.ErrorIs(t, err, service.ErrUserNotFound)
+}
+
+func TestAdminService_SetRole_DemotionRevokesSessions(t *testing.T) {
+	ctx := context.Background()
+	adminService, repo, sessions := setupTest()
+
+	repo.On("GetUserAccess", ctx, 7).Return(&models.UserAccess{Role: models.RoleModerator}, nil)
+	repo.On("SetUserRole", ctx, 7, models.RoleUser).Return(true, nil)
+	sessions.On("RevokeAllSessions", ctx, 7).Return(nil)
+
+	err := adminService.SetRole(ctx, service.Actor{UserID: 1, Role: models.RoleAdmin}, 7, models.RoleUser)
+
+	assert.NoError(t, err)
+	sessions.AssertExpectations(t)
+}
+
+func TestAdminService_SetRole_PromotionKeepsSessions(t *testing.T) {
+	ctx := context.Background()
+	adminService, repo, sessions := setupTest()
+
+	repo.On("GetUserAccess", ctx, 7).Return(&models.UserAccess{Role: models.RoleUser}, nil)
+	repo.On("SetUserRole", ctx, 7, models.RoleModerator).Return(true, nil)
+
+	err := adminService.SetRole(ctx, service.Actor{UserID: 1, Role: models.RoleAdmin}, 7, models.RoleModerator)
+
+	assert.NoError(t, err)
+	sessions.AssertNotCalled(t, "RevokeAllSessions")
+}
+
+func TestAdminService_SetRole_Invalid(t *testing.T) {
+	adminService, repo, _ := setupTest()
+
+	err := adminService.SetRole(context.Background(), service.Actor{UserID: 1, Role: models.RoleAdmin}, 7, "root")
+
+	assert.ErrorIs(t, err, service.ErrInvalidRole)
+	repo.AssertNotCalled(t, "SetUserRole")
+}
+
+func TestRoleCan(t *testing.T) {
+	assert.True(t, models.RoleCan(models.RoleAdmin, models.PermManageRoles))
+	assert.True(t, models.RoleCan(models.RoleModerator, models.PermModerateContent))
+	assert.False(t, models.RoleCan(models.RoleModerator, models.PermManageRoles))
+	assert.False(t, models.RoleCan(models.RoleUser, models.PermViewUsers))
+	assert.False(t, models.RoleCan("", models.PermViewUsers))
+}
@@ -3,6 +3,7 @@ package albums
 import (
 	"context"
 	"github.com/stretchr/testify/mock"
+	"io"
 	"pictureloader/app_microservice/models"
 	"time"
 )
@@ -11,31 +12,33 @@ type MockAlbumRepository struct {
 	mock.Mock
 }
 
-func (m *MockAlbumRepository) CreateAlbum(ctx context.Context, album *models.Post) error {
+func (m *MockAlbumRepository) CreatePost(ctx context.Context, album *models.Post) error {
 	args := m.Called(ctx, album)
 	return args.Error(0)
 }
 
-func (m *MockAlbumRepository) CreateAlbumAndImage(ctx context.Context, albumImage *models.PostImage) error {
-	args := m.Called(ctx, albumImage)
+func (m *MockAlbumRepository) CreatePostAndImage(ctx context.Context, postID int, imageSK string) error {
+	args := m.Called(ctx, postID, imageSK)
 	return args.Error(0)
 }
 
-func (m *MockAlbumRepository) GetAlbumData(ctx context.Context, albumID int) (string, map[string]string, error) {
-	args := m.Called(ctx, albumID)
-	return args.String(0), args.Get(1).(map[string]string), args.Error(2)
-}
-func (m *MockAlbumRepository) GetUserAlbumIDs(ctx context.Context, userID int) ([]int, error) {
+func (m *MockAlbumRepository) GetUserPostIDs(ctx context.Context, userID int) ([]int, error) {
 	args := m.Called(ctx, userID)
 	return args.Get(0).([]int), args.Error(1)
 }
 
-func (m *MockAlbumRepository) DeleteAlbumByID(ctx context.Context, albumID int) error {
+func (m *MockAlbumRepository) DeletePostByID(ctx context.Context, albumID int) error {
 	args := m.Called(ctx, albumID)
 	return args.Error(0)
 }
-func (m *MockAlbumRepository) DeletePostImage(ctx context.Context, albumID int, imageID int) error {
-	args := m.Called(ctx, albumID, imageID)
+
+func (m *MockAlbumRepository) PurgePost(ctx context.Context, postID int) error {
+	args := m.Called(ctx, postID)
+	return args.Error(0)
+}
+
+func (m *MockAlbumRepository) DeletePostImage(ctx context.Context, postID int, imageSK string) error {
+	args := m.Called(ctx, postID, imageSK)
 	return args.Error(0)
 }
 
@@ -44,15 +47,29 @@ func (m *MockAlbumRepository) IsOwnerOfPost(ctx context.Context, userID int, alb
 	return args.Error(0)
 }
 
-////////////////////
+func (m *MockAlbumRepository) LikePost(ctx context.Context, postID, userID int) (*models.Like, error) {
+	args := m.Called(ctx, postID, userID)
+	return args.Get(0).(*models.Like), args.Error(1)
+}
 
-type MockImageWorker struct {
-	mock.Mock
+func (m *MockAlbumRepository) UnlikePost(ctx context.Context, postID, userID int) error {
+	args := m.Called(ctx, postID, userID)
+	return args.Error(0)
+}
+
+func (m *MockAlbumRepository) GetMostLikedPosts(ctx context.Context) ([]models.PostUnit, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.PostUnit), args.Error(1)
 }
 
-func (m *MockImageWorker) GetImageIDBySK(ctx context.Context, imageSK string) (int, error) {
-	args := m.Called(ctx, imageSK)
-	return args.Int(0), args.Error(1)
+func (m *MockAlbumRepository) GetPostByID(ctx context.Context, postID int) (*models.Post, error) {
+	args := m.Called(ctx, postID)
+	return args.Get(0).(*models.Post), args.Error(1)
+}
+
+func (m *MockAlbumRepository) GetPost(ctx context.Context, postID int) (models.PostUnit, error) {
+	args := m.Called(ctx, postID)
+	return args.Get(0).(models.PostUnit), args.Error(1)
 }
 
 ////////////////////
@@ -86,22 +103,48 @@ func (m *MockImageStorage) DeleteFileByURL(ctx context.Context, imageURL string)
 	return args.Error(0)
 }
 
+func (m *MockImageStorage) GetFile(ctx context.Context, key string) (io.ReadCloser, error) {
+	args := m.Called(ctx, key)
+	file, _ := args.Get(0).(io.ReadCloser)
+	return file, args.Error(1)
+}
+
+func (m *MockImageStorage) PutFile(ctx context.Context, key string, payload io.Reader, size int64, contentType string) error {
+	return m.Called(ctx, key, payload, size, contentType).Error(0)
+}
+
+func (m *MockImageStorage) GetDownloadURL(ctx context.Context, key, filename string, ttl time.Duration) (string, error) {
+	args := m.Called(ctx, key, filename, ttl)
+	return args.String(0), args.Error(1)
+}
+
 ////////////////////
 
 type MockCacher struct {
 	mock.Mock
 }
 
-func (m *MockCacher) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	args := m.Called(ctx, key, value, expiration)
+func (m *MockCacher) InvalidatePost(ctx context.Context, postID int) (bool, error) {
+	args := m.Called(ctx, postID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCacher) SetMostLikedPosts(ctx context.Context, posts []models.PostUnit) error {
+	args := m.Called(ctx, posts)
 	return args.Error(0)
 }
-func (m *MockCacher) Get(ctx context.Context, key string) (string, error) {
-	args := m.Called(ctx, key)
-	return args.String(0), args.Error(1)
+
+func (m *MockCacher) GetMostLikedPosts(ctx context.Context) ([]models.PostUnit, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.PostUnit), args.Error(1)
 }
 
-func (m *MockCacher) Delete(ctx context.Context, key string) error {
-	args := m.Called(ctx, key)
-	return args.Error(0)
+func (m *MockCacher) GetPost(ctx context.Context, postID int) (models.PostUnit, bool, error) {
+	args := m.Called(ctx, postID)
+	return args.Get(0).(models.PostUnit), args.Bool(1), args.Error(2)
+}
+
+func (m *MockCacher) SetPost(ctx context.Context, postID int, resultJSON string) (bool, error) {
+	args := m.Called(ctx, postID, resultJSON)
+	return args.Bool(0), args.Error(1)
 }
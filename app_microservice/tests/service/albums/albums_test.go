@@ -2,37 +2,51 @@ package albums
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
+	"pictureloader/app_microservice/broker"
 	"pictureloader/app_microservice/models"
 	"pictureloader/app_microservice/service"
+	"pictureloader/common/eventbus"
+	"pictureloader/common/events"
 	"testing"
+	"time"
 )
 
-type mockAlbumService struct {
-}
-
-func setupTest() (*service.PostService, *MockAlbumRepository, *MockImageWorker, *MockImageStorage, *MockCacher) {
+func setupTest() (*service.PostService, *MockAlbumRepository, *MockImageStorage, *MockCacher, *eventbus.MemoryBus) {
 	mockAlbumRepo := new(MockAlbumRepository)
-	mockImageWorker := new(MockImageWorker)
 	mockImageStorage := new(MockImageStorage)
 	mockCacher := new(MockCacher)
+	bus := eventbus.NewMemoryBus()
 
-	albumService := service.NewPostService(mockAlbumRepo, mockImageStorage, mockImageWorker, mockCacher)
+	albumService := service.NewPostService(mockAlbumRepo, mockImageStorage, mockCacher, broker.NewPublisher(bus))
 
-	return albumService, mockAlbumRepo, mockImageWorker, mockImageStorage, mockCacher
+	return albumService, mockAlbumRepo, mockImageStorage, mockCacher, bus
 }
 
 func TestAlbumService_CreateAlbum(t *testing.T) {
 	ctx := context.Background()
-	albumService, mockAlbumRepo, _, _, _ := setupTest()
+	albumService, mockAlbumRepo, _, _, bus := setupTest()
 
 	album := &models.Post{ID: 1, Name: "Test Post", UserID: 1}
 
 	mockAlbumRepo.On("CreatePost", ctx, album).Return(nil)
 
-	err := albumService.CreatePost(ctx, album)
+	var received []events.PostCreated
+	err := bus.Subscribe(ctx, events.TopicPostCreated, "webhooks", func(ctx context.Context, payload []byte) error {
+		var event events.PostCreated
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return err
+		}
+		received = append(received, event)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	err = albumService.CreatePost(ctx, album)
 
 	assert.NoError(t, err)
+	assert.Equal(t, []events.PostCreated{{EventID: "post-1", PostID: 1, UserID: 1, Name: "Test Post"}}, received)
 	mockAlbumRepo.AssertExpectations(t)
 }
 
@@ -45,8 +59,50 @@ func TestAlbumService_CreateAlbum_Error(t *testing.T) {
 	err := albumService.CreatePost(ctx, album)
 
 	assert.Error(t, err)
-	assert.Equal(t, "invalid album name", err.Error())
+	assert.Equal(t, "invalid post name", err.Error())
 
 	mockAlbumRepo.AssertExpectations(t)
 	mockAlbumRepo.AssertNotCalled(t, "CreatePost")
 }
+
+func TestAlbumService_LikePost_PublishesEvent(t *testing.T) {
+	ctx := context.Background()
+	albumService, mockAlbumRepo, _, mockCacher, bus := setupTest()
+
+	var received []events.NewLike
+	err := bus.Subscribe(ctx, events.TopicNewLike, "notifications", func(ctx context.Context, payload []byte) error {
+		var event events.NewLike
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return err
+		}
+		received = append(received, event)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	mockCacher.On("InvalidatePost", ctx, 7).Return(true, nil)
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockAlbumRepo.On("GetPostByID", ctx, 7).Return(&models.Post{ID: 7, Name: "Sunset", UserID: 2, CreatedAt: createdAt}, nil)
+	likedAt := time.Date(2024, 2, 3, 4, 5, 6, 7000, time.UTC)
+	mockAlbumRepo.On("LikePost", ctx, 7, 5).Return(&models.Like{
+		PostID:    7,
+		UserID:    5,
+		CreatedAt: likedAt,
+		User:      models.User{ID: 5, Username: "alice"},
+	}, nil)
+
+	err = albumService.LikePost(ctx, 7, 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []events.NewLike{{
+		EventID:       "like-7-5-1706933106000007",
+		PostID:        7,
+		Liker:         5,
+		Liked:         2,
+		LikerName:     "alice",
+		PostName:      "Sunset",
+		PostCreatedAt: createdAt,
+	}}, received)
+	mockAlbumRepo.AssertExpectations(t)
+	mockCacher.AssertExpectations(t)
+}
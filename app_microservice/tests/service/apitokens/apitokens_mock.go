@@ -0,0 +1,35 @@
+package apitokens
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type MockAPITokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPITokenRepository) CreateAPIToken(ctx context.Context, token *models.APIToken) error {
+	return m.Called(ctx, token).Error(0)
+}
+
+func (m *MockAPITokenRepository) GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*models.APIToken), args.Error(1)
+}
+
+func (m *MockAPITokenRepository) GetAPITokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.APIToken), args.Error(1)
+}
+
+func (m *MockAPITokenRepository) TouchAPIToken(ctx context.Context, tokenID int, now time.Time, interval time.Duration) error {
+	return m.Called(ctx, tokenID, now, interval).Error(0)
+}
+
+func (m *MockAPITokenRepository) RevokeAPIToken(ctx context.Context, userID, tokenID int, now time.Time) (bool, error) {
+	args := m.Called(ctx, userID, tokenID, now)
+	return args.Bool(0), args.Error(1)
+}
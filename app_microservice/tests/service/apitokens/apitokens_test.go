@@ -0,0 +1,62 @@
+package apitokens
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPITokenService_CreateToken_StoresOnlyHash(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAPITokenRepository)
+	tokenService := service.NewAPITokenService(repo)
+
+	var stored *models.APIToken
+	repo.On("GetAPITokens", ctx, 5).Return([]models.APIToken{}, nil)
+	repo.On("CreateAPIToken", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*models.APIToken)
+	}).Return(nil)
+
+	created, err := tokenService.CreateToken(ctx, 5, models.APITokenCreate{
+		Name:          "sharex",
+		Scopes:        []string{models.ScopeImagesWrite, models.ScopeImagesWrite},
+		ExpiresInDays: 30,
+	})
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Token, models.APITokenPrefix))
+	assert.NotEqual(t, created.Token, stored.Hash)
+	assert.NotContains(t, stored.Hash, created.Token)
+	assert.Equal(t, []string{models.ScopeImagesWrite}, stored.Scopes)
+	assert.NotNil(t, stored.ExpiresAt)
+}
+
+func TestAPITokenService_CreateToken_UnknownScope(t *testing.T) {
+	tokenService := service.NewAPITokenService(new(MockAPITokenRepository))
+
+	_, err := tokenService.CreateToken(context.Background(), 5, models.APITokenCreate{Name: "ci", Scopes: []string{"admin"}})
+
+	var validationErr *service.APITokenValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
+
+func TestAPITokenService_Authenticate_RejectsExpired(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAPITokenRepository)
+	tokenService := service.NewAPITokenService(repo)
+
+	expiredAt := time.Now().Add(-time.Minute)
+	repo.On("GetAPITokenByHash", ctx, mock.Anything).Return(&models.APIToken{ID: 1, UserID: 5, ExpiresAt: &expiredAt}, nil)
+
+	token, err := tokenService.Authenticate(ctx, "pl_expired")
+
+	assert.ErrorIs(t, err, service.ErrInvalidAPIToken)
+	assert.Nil(t, token)
+	repo.AssertNotCalled(t, "TouchAPIToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
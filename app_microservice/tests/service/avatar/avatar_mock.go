@@ -0,0 +1,77 @@
+package avatar
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type MockAvatarRepository struct {
+	mock.Mock
+}
+
+func (m *MockAvatarRepository) IsAvatarSource(ctx context.Context, userID int, imageSK string) (bool, error) {
+	args := m.Called(ctx, userID, imageSK)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAvatarRepository) SetAvatar(ctx context.Context, userID int, key string) (string, bool, error) {
+	args := m.Called(ctx, userID, key)
+	return args.String(0), args.Bool(1), args.Error(2)
+}
+
+////////////////////
+
+type MockImageStorage struct {
+	mock.Mock
+	// Uploaded - загруженные через PutFile файлы по ключам
+	Uploaded map[string][]byte
+}
+
+func (m *MockImageStorage) Connect() error {
+	return m.Called().Error(0)
+}
+
+func (m *MockImageStorage) UploadFile(ctx context.Context, image models.ImageUnit, sk string) (string, error) {
+	args := m.Called(ctx, image, sk)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockImageStorage) GetFileURL(ctx context.Context, imageURL string) (string, error) {
+	args := m.Called(ctx, imageURL)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockImageStorage) GetFileURLS(ctx context.Context, imageURLS []string) ([]string, error) {
+	args := m.Called(ctx, imageURLS)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockImageStorage) DeleteFileByURL(ctx context.Context, imageURL string) error {
+	return m.Called(ctx, imageURL).Error(0)
+}
+
+func (m *MockImageStorage) GetFile(ctx context.Context, key string) (io.ReadCloser, error) {
+	args := m.Called(ctx, key)
+	file, _ := args.Get(0).(io.ReadCloser)
+	return file, args.Error(1)
+}
+
+func (m *MockImageStorage) PutFile(ctx context.Context, key string, payload io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+	if m.Uploaded == nil {
+		m.Uploaded = map[string][]byte{}
+	}
+	m.Uploaded[key] = data
+	return m.Called(ctx, key, size, contentType).Error(0)
+}
+
+func (m *MockImageStorage) GetDownloadURL(ctx context.Context, key, filename string, ttl time.Duration) (string, error) {
+	args := m.Called(ctx, key, filename, ttl)
+	return args.String(0), args.Error(1)
+}
@@ -0,0 +1,121 @@
+package avatar
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"image"
+	"image/color"
+	"image/png"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"strings"
+	"testing"
+)
+
+func setupTest() (*service.AvatarService, *MockAvatarRepository, *MockImageStorage) {
+	repo := new(MockAvatarRepository)
+	storage := new(MockImageStorage)
+	return service.NewAvatarService(repo, storage), repo, storage
+}
+
+// testPNG - картинка width x height, левая половина красная, правая синяя
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestAvatarService_UploadAvatar(t *testing.T) {
+	ctx := context.Background()
+	avatarService, repo, storage := setupTest()
+
+	storage.On("PutFile", ctx, mock.Anything, mock.Anything, "image/png").Return(nil)
+	storage.On("GetFileURL", ctx, mock.Anything).Return("http://minio/avatar", nil)
+	storage.On("DeleteFileByURL", ctx, mock.Anything).Return(nil)
+	repo.On("SetAvatar", ctx, 3, mock.Anything).Return("avatars/3/old", true, nil)
+
+	// обрезается левая, красная половина картинки
+	urls, err := avatarService.UploadAvatar(ctx, 3, bytes.NewReader(testPNG(t, 200, 100)),
+		&models.AvatarCrop{X: 0, Y: 0, Width: 100, Height: 100})
+
+	assert.NoError(t, err)
+	assert.Len(t, urls, len(models.AvatarSizes))
+	key := repo.Calls[0].Arguments.String(2)
+	assert.True(t, strings.HasPrefix(key, "avatars/3/"))
+	for _, size := range models.AvatarSizes {
+		file, ok := storage.Uploaded[models.AvatarFileKey(key, size)]
+		assert.True(t, ok)
+		avatar, err := png.Decode(bytes.NewReader(file))
+		assert.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, size, size), avatar.Bounds())
+		r, _, b, _ := avatar.At(size-1, size-1).RGBA()
+		assert.Equal(t, uint32(0xffff), r)
+		assert.Zero(t, b)
+	}
+	for _, size := range models.AvatarSizes {
+		storage.AssertCalled(t, "DeleteFileByURL", ctx, models.AvatarFileKey("avatars/3/old", size))
+	}
+}
+
+func TestAvatarService_UploadAvatar_KeepsLegacyPicture(t *testing.T) {
+	ctx := context.Background()
+	avatarService, repo, storage := setupTest()
+
+	storage.On("PutFile", ctx, mock.Anything, mock.Anything, "image/png").Return(nil)
+	storage.On("GetFileURL", ctx, mock.Anything).Return("http://minio/avatar", nil)
+	// раньше аватаром ставилась сама картинка пользователя, ее удалять нельзя
+	repo.On("SetAvatar", ctx, 3, mock.Anything).Return("cat1234", true, nil)
+
+	_, err := avatarService.UploadAvatar(ctx, 3, bytes.NewReader(testPNG(t, 64, 64)), nil)
+
+	assert.NoError(t, err)
+	storage.AssertNotCalled(t, "DeleteFileByURL")
+}
+
+func TestAvatarService_UploadAvatar_InvalidCrop(t *testing.T) {
+	ctx := context.Background()
+	avatarService, repo, storage := setupTest()
+
+	crops := []*models.AvatarCrop{
+		{X: 150, Y: 0, Width: 100, Height: 100},
+		{X: -1, Y: 0, Width: 50, Height: 50},
+		{X: 0, Y: 0, Width: 16, Height: 16},
+	}
+	for _, crop := range crops {
+		_, err := avatarService.UploadAvatar(ctx, 3, bytes.NewReader(testPNG(t, 200, 100)), crop)
+
+		var validationErr *service.AvatarValidationError
+		assert.ErrorAs(t, err, &validationErr)
+	}
+	_, err := avatarService.UploadAvatar(ctx, 3, strings.NewReader("not an image"), nil)
+	var validationErr *service.AvatarValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	storage.AssertNotCalled(t, "PutFile")
+	repo.AssertNotCalled(t, "SetAvatar")
+}
+
+func TestAvatarService_SetAvatarFromPicture_NotOwner(t *testing.T) {
+	ctx := context.Background()
+	avatarService, repo, storage := setupTest()
+
+	repo.On("IsAvatarSource", ctx, 3, "dog5678").Return(false, nil)
+
+	_, err := avatarService.SetAvatarFromPicture(ctx, 3, models.AvatarFromPicture{PictureSK: "dog5678"})
+
+	assert.ErrorIs(t, err, service.ErrAvatarSourceNotFound)
+	storage.AssertNotCalled(t, "GetFile")
+	repo.AssertNotCalled(t, "SetAvatar")
+}
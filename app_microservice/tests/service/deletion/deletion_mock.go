@@ -0,0 +1,105 @@
+package deletion
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type MockDeletionRepository struct {
+	mock.Mock
+}
+
+func (m *MockDeletionRepository) RequestDeletion(ctx context.Context, userID int, now time.Time) (*models.AccountDeletion, error) {
+	args := m.Called(ctx, userID, now)
+	deletion, _ := args.Get(0).(*models.AccountDeletion)
+	return deletion, args.Error(1)
+}
+
+func (m *MockDeletionRepository) ClaimDueDeletions(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.AccountDeletion, error) {
+	args := m.Called(ctx, now, limit, lease)
+	return args.Get(0).([]models.AccountDeletion), args.Error(1)
+}
+
+func (m *MockDeletionRepository) SaveDeletion(ctx context.Context, deletion *models.AccountDeletion) error {
+	// копия, чтобы проверять состояние задачи на момент каждого сохранения
+	return m.Called(ctx, *deletion).Error(0)
+}
+
+func (m *MockDeletionRepository) DeleteUserLikes(ctx context.Context, userID int) ([]int, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockDeletionRepository) GetAllUserPostIDs(ctx context.Context, userID int) ([]int, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockDeletionRepository) GetAllUserImageKeys(ctx context.Context, userID int) ([]string, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockDeletionRepository) DeleteUser(ctx context.Context, userID int) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func (m *MockDeletionRepository) GetDeletions(ctx context.Context, filter models.DeletionFilter) ([]models.AccountDeletion, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.AccountDeletion), args.Error(1)
+}
+
+////////////////////
+
+type MockSessionRevoker struct {
+	mock.Mock
+}
+
+func (m *MockSessionRevoker) RevokeAllSessions(ctx context.Context, userID int) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+////////////////////
+
+type MockContentRemover struct {
+	mock.Mock
+}
+
+func (m *MockContentRemover) RemovePost(ctx context.Context, postID int) error {
+	return m.Called(ctx, postID).Error(0)
+}
+
+func (m *MockContentRemover) RemovePicture(ctx context.Context, imgSK string) error {
+	return m.Called(ctx, imgSK).Error(0)
+}
+
+func (m *MockContentRemover) DeleteAvatar(ctx context.Context, userID int) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+////////////////////
+
+type MockCache struct {
+	mock.Mock
+}
+
+func (m *MockCache) InvalidatePost(ctx context.Context, postID int) (bool, error) {
+	args := m.Called(ctx, postID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCache) InvalidateMostLikedPosts(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+////////////////////
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) PublishUserDeleted(ctx context.Context, deletion *models.AccountDeletion) error {
+	return m.Called(ctx, deletion).Error(0)
+}
@@ -0,0 +1,101 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"testing"
+	"time"
+)
+
+type mocks struct {
+	repo      *MockDeletionRepository
+	sessions  *MockSessionRevoker
+	content   *MockContentRemover
+	cache     *MockCache
+	publisher *MockPublisher
+}
+
+func setupTest() (*service.AccountDeletionService, mocks) {
+	m := mocks{
+		repo:      new(MockDeletionRepository),
+		sessions:  new(MockSessionRevoker),
+		content:   new(MockContentRemover),
+		cache:     new(MockCache),
+		publisher: new(MockPublisher),
+	}
+	return service.NewAccountDeletionService(m.repo, m.sessions, m.content, m.content, m.content, m.cache, m.publisher), m
+}
+
+func TestAccountDeletionService_RequestDeletion_UserNotFound(t *testing.T) {
+	ctx := context.Background()
+	deletionService, m := setupTest()
+
+	m.repo.On("RequestDeletion", ctx, 3, mock.Anything).Return(nil, nil)
+
+	_, err := deletionService.RequestDeletion(ctx, 3)
+
+	assert.ErrorIs(t, err, service.ErrUserNotFound)
+	m.sessions.AssertNotCalled(t, "RevokeAllSessions")
+}
+
+func TestAccountDeletionService_ProcessDue(t *testing.T) {
+	ctx := context.Background()
+	deletionService, m := setupTest()
+
+	now := time.Now()
+	job := models.AccountDeletion{ID: 1, UserID: 3, Status: models.DeletionPending, Step: models.DeletionStepLikes}
+	m.repo.On("ClaimDueDeletions", ctx, now, mock.Anything, mock.Anything).Return([]models.AccountDeletion{job}, nil)
+	m.repo.On("DeleteUserLikes", ctx, 3).Return([]int{8}, nil)
+	m.repo.On("GetAllUserPostIDs", ctx, 3).Return([]int{4, 5}, nil)
+	m.repo.On("GetAllUserImageKeys", ctx, 3).Return([]string{"cat1234"}, nil)
+	m.repo.On("DeleteUser", ctx, 3).Return(nil)
+	m.repo.On("SaveDeletion", ctx, mock.Anything).Return(nil)
+	m.cache.On("InvalidatePost", ctx, 8).Return(true, nil)
+	m.cache.On("InvalidateMostLikedPosts", ctx).Return(nil)
+	m.content.On("RemovePost", ctx, mock.Anything).Return(nil)
+	m.content.On("RemovePicture", ctx, "cat1234").Return(nil)
+	m.content.On("DeleteAvatar", ctx, 3).Return(nil)
+	m.publisher.On("PublishUserDeleted", ctx, mock.Anything).Return(nil)
+
+	completed, err := deletionService.ProcessDue(ctx, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, completed)
+	m.repo.AssertNumberOfCalls(t, "SaveDeletion", len(models.DeletionSteps))
+	final := m.repo.Calls[len(m.repo.Calls)-1].Arguments.Get(1).(models.AccountDeletion)
+	assert.Equal(t, models.DeletionCompleted, final.Status)
+	assert.Empty(t, final.Step)
+	assert.NotNil(t, final.CompletedAt)
+	assert.Equal(t, 1, final.LikesDeleted)
+	assert.Equal(t, 2, final.PostsDeleted)
+	assert.Equal(t, 1, final.ImagesDeleted)
+}
+
+func TestAccountDeletionService_ProcessDue_ResumesAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	deletionService, m := setupTest()
+
+	now := time.Now()
+	// лайки и посты уже удалены прошлым запуском
+	job := models.AccountDeletion{ID: 1, UserID: 3, Status: models.DeletionPending, Step: models.DeletionStepImages}
+	m.repo.On("ClaimDueDeletions", ctx, now, mock.Anything, mock.Anything).Return([]models.AccountDeletion{job}, nil)
+	m.repo.On("GetAllUserImageKeys", ctx, 3).Return([]string{"cat1234"}, nil)
+	m.content.On("RemovePicture", ctx, "cat1234").Return(errors.New("storage is down"))
+	m.repo.On("SaveDeletion", ctx, mock.Anything).Return(nil)
+
+	completed, err := deletionService.ProcessDue(ctx, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, completed)
+	m.repo.AssertNotCalled(t, "DeleteUserLikes", ctx, 3)
+	m.repo.AssertNotCalled(t, "DeleteUser", ctx, 3)
+	saved := m.repo.Calls[len(m.repo.Calls)-1].Arguments.Get(1).(models.AccountDeletion)
+	assert.Equal(t, models.DeletionStepImages, saved.Step)
+	assert.Equal(t, 1, saved.Attempts)
+	assert.Equal(t, "storage is down", saved.LastError)
+	assert.True(t, saved.NextAttemptAt.After(now))
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type MockExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockExportRepository) CreateExport(ctx context.Context, export *models.DataExport) error {
+	return m.Called(ctx, export).Error(0)
+}
+
+func (m *MockExportRepository) GetActiveExport(ctx context.Context, userID int, now time.Time) (*models.DataExport, error) {
+	args := m.Called(ctx, userID, now)
+	export, _ := args.Get(0).(*models.DataExport)
+	return export, args.Error(1)
+}
+
+func (m *MockExportRepository) GetUserExports(ctx context.Context, userID int) ([]models.DataExport, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.DataExport), args.Error(1)
+}
+
+func (m *MockExportRepository) ClaimDueExports(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.DataExport, error) {
+	args := m.Called(ctx, now, limit, lease)
+	return args.Get(0).([]models.DataExport), args.Error(1)
+}
+
+func (m *MockExportRepository) SaveExport(ctx context.Context, export *models.DataExport) error {
+	// копия, чтобы проверять состояние выгрузки на момент сохранения
+	return m.Called(ctx, *export).Error(0)
+}
+
+func (m *MockExportRepository) GetExpiredExports(ctx context.Context, now time.Time, limit int) ([]models.DataExport, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]models.DataExport), args.Error(1)
+}
+
+////////////////////
+
+type MockSources struct {
+	mock.Mock
+}
+
+func (m *MockSources) GetUserByID(ctx context.Context, id int) (*models.UserProfile, error) {
+	args := m.Called(ctx, id)
+	profile, _ := args.Get(0).(*models.UserProfile)
+	return profile, args.Error(1)
+}
+
+func (m *MockSources) ExportUserPosts(ctx context.Context, userID int) ([]models.ExportPost, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.ExportPost), args.Error(1)
+}
+
+func (m *MockSources) ExportLikesGiven(ctx context.Context, userID int) ([]models.ExportLike, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.ExportLike), args.Error(1)
+}
+
+func (m *MockSources) ExportLikesReceived(ctx context.Context, userID int) ([]models.ExportLike, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.ExportLike), args.Error(1)
+}
+
+func (m *MockSources) ExportUserImages(ctx context.Context, userID int) ([]models.ExportImage, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.ExportImage), args.Error(1)
+}
+
+func (m *MockSources) GetNotifications(ctx context.Context, userID int) (json.RawMessage, error) {
+	args := m.Called(ctx, userID)
+	notifications, _ := args.Get(0).(json.RawMessage)
+	return notifications, args.Error(1)
+}
+
+////////////////////
+
+type MockImageStorage struct {
+	mock.Mock
+	// Uploaded - содержимое последнего загруженного через PutFile файла
+	Uploaded []byte
+}
+
+func (m *MockImageStorage) Connect() error {
+	return m.Called().Error(0)
+}
+
+func (m *MockImageStorage) UploadFile(ctx context.Context, image models.ImageUnit, sk string) (string, error) {
+	args := m.Called(ctx, image, sk)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockImageStorage) GetFileURL(ctx context.Context, imageURL string) (string, error) {
+	args := m.Called(ctx, imageURL)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockImageStorage) GetFileURLS(ctx context.Context, imageURLS []string) ([]string, error) {
+	args := m.Called(ctx, imageURLS)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockImageStorage) DeleteFileByURL(ctx context.Context, imageURL string) error {
+	return m.Called(ctx, imageURL).Error(0)
+}
+
+func (m *MockImageStorage) GetFile(ctx context.Context, key string) (io.ReadCloser, error) {
+	args := m.Called(ctx, key)
+	file, _ := args.Get(0).(io.ReadCloser)
+	return file, args.Error(1)
+}
+
+func (m *MockImageStorage) PutFile(ctx context.Context, key string, payload io.Reader, size int64, contentType string) error {
+	var err error
+	if m.Uploaded, err = io.ReadAll(payload); err != nil {
+		return err
+	}
+	return m.Called(ctx, key, size, contentType).Error(0)
+}
+
+func (m *MockImageStorage) GetDownloadURL(ctx context.Context, key, filename string, ttl time.Duration) (string, error) {
+	args := m.Called(ctx, key, filename, ttl)
+	return args.String(0), args.Error(1)
+}
+
+////////////////////
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) PublishExportReady(ctx context.Context, export *models.DataExport) error {
+	return m.Called(ctx, *export).Error(0)
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"pictureloader/app_microservice/image_storage"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"testing"
+	"time"
+)
+
+type mocks struct {
+	repo      *MockExportRepository
+	sources   *MockSources
+	storage   *MockImageStorage
+	publisher *MockPublisher
+}
+
+func setupTest() (*service.ExportService, mocks) {
+	m := mocks{
+		repo:      new(MockExportRepository),
+		sources:   new(MockSources),
+		storage:   new(MockImageStorage),
+		publisher: new(MockPublisher),
+	}
+	return service.NewExportService(m.repo, m.sources, m.sources, m.sources, m.storage, m.sources, m.publisher,
+		48*time.Hour), m
+}
+
+func TestExportService_RequestExport_ReturnsActive(t *testing.T) {
+	ctx := context.Background()
+	exportService, m := setupTest()
+
+	active := &models.DataExport{ID: 2, UserID: 3, Status: models.ExportPending}
+	m.repo.On("GetActiveExport", ctx, 3, mock.Anything).Return(active, nil)
+
+	export, err := exportService.RequestExport(ctx, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, export.ID)
+	m.repo.AssertNotCalled(t, "CreateExport")
+}
+
+func TestExportService_ProcessDue(t *testing.T) {
+	ctx := context.Background()
+	exportService, m := setupTest()
+
+	now := time.Now()
+	job := models.DataExport{ID: 7, UserID: 3, Status: models.ExportPending, RequestedAt: now}
+	m.repo.On("ClaimDueExports", ctx, now, mock.Anything, mock.Anything).Return([]models.DataExport{job}, nil)
+	m.sources.On("GetUserByID", ctx, 3).Return(&models.UserProfile{Username: "cat"}, nil)
+	m.sources.On("ExportUserPosts", ctx, 3).Return([]models.ExportPost{{ID: 4, Images: []string{"cat1234"}}}, nil)
+	m.sources.On("ExportLikesGiven", ctx, 3).Return([]models.ExportLike{}, nil)
+	m.sources.On("ExportLikesReceived", ctx, 3).Return([]models.ExportLike{{PostID: 4, UserID: 5}}, nil)
+	m.sources.On("ExportUserImages", ctx, 3).Return([]models.ExportImage{{StorageKey: "cat1234"}, {StorageKey: "lost5678"}}, nil)
+	m.sources.On("GetNotifications", ctx, 3).Return(json.RawMessage(`[]`), nil)
+	m.storage.On("GetFile", ctx, "cat1234").Return(io.NopCloser(bytes.NewReader([]byte("png"))), nil)
+	m.storage.On("GetFile", ctx, "lost5678").Return(nil, image_storage.ErrFileNotFound)
+	m.storage.On("PutFile", ctx, "exports/3/7.zip", mock.Anything, "application/zip").Return(nil)
+	m.storage.On("GetDownloadURL", ctx, "exports/3/7.zip", mock.Anything, mock.Anything).Return("http://minio/exports/3/7.zip", nil)
+	m.repo.On("SaveExport", ctx, mock.Anything).Return(nil)
+	m.publisher.On("PublishExportReady", ctx, mock.Anything).Return(nil)
+
+	ready, err := exportService.ProcessDue(ctx, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ready)
+	saved := m.repo.Calls[len(m.repo.Calls)-1].Arguments.Get(1).(models.DataExport)
+	assert.Equal(t, models.ExportReady, saved.Status)
+	assert.Equal(t, int64(len(m.storage.Uploaded)), saved.Size)
+	assert.NotNil(t, saved.ExpiresAt)
+	published := m.publisher.Calls[0].Arguments.Get(1).(models.DataExport)
+	assert.Equal(t, "http://minio/exports/3/7.zip", published.DownloadURL)
+
+	archive, err := zip.NewReader(bytes.NewReader(m.storage.Uploaded), int64(len(m.storage.Uploaded)))
+	assert.NoError(t, err)
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	for _, name := range []string{"profile.json", "posts.json", "images.json", "likes_given.json",
+		"likes_received.json", "notifications.json", "images/cat1234.png"} {
+		assert.Contains(t, files, name)
+	}
+	assert.NotContains(t, files, "images/lost5678.png")
+
+	reader, err := files["images.json"].Open()
+	assert.NoError(t, err)
+	var images []models.ExportImage
+	assert.NoError(t, json.NewDecoder(reader).Decode(&images))
+	assert.Equal(t, "images/cat1234.png", images[0].File)
+	assert.Empty(t, images[1].File)
+}
+
+func TestExportService_ProcessDue_FailureBacksOff(t *testing.T) {
+	ctx := context.Background()
+	exportService, m := setupTest()
+
+	now := time.Now()
+	job := models.DataExport{ID: 7, UserID: 3, Status: models.ExportPending, Attempts: 4}
+	m.repo.On("ClaimDueExports", ctx, now, mock.Anything, mock.Anything).Return([]models.DataExport{job}, nil)
+	m.sources.On("GetUserByID", ctx, 3).Return(nil, errors.New("db is down"))
+	m.repo.On("SaveExport", ctx, mock.Anything).Return(nil)
+
+	ready, err := exportService.ProcessDue(ctx, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ready)
+	saved := m.repo.Calls[len(m.repo.Calls)-1].Arguments.Get(1).(models.DataExport)
+	assert.Equal(t, models.ExportFailed, saved.Status)
+	assert.Equal(t, 5, saved.Attempts)
+	assert.Contains(t, saved.LastError, "db is down")
+	m.storage.AssertNotCalled(t, "PutFile")
+	m.publisher.AssertNotCalled(t, "PublishExportReady")
+}
+
+func TestExportService_Cleanup(t *testing.T) {
+	ctx := context.Background()
+	exportService, m := setupTest()
+
+	now := time.Now()
+	expired := models.DataExport{ID: 7, UserID: 3, Status: models.ExportReady, StorageKey: "exports/3/7.zip"}
+	m.repo.On("GetExpiredExports", ctx, now, mock.Anything).Return([]models.DataExport{expired}, nil)
+	m.storage.On("DeleteFileByURL", ctx, "exports/3/7.zip").Return(nil)
+	m.repo.On("SaveExport", ctx, mock.Anything).Return(nil)
+
+	removed, err := exportService.Cleanup(ctx, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	saved := m.repo.Calls[len(m.repo.Calls)-1].Arguments.Get(1).(models.DataExport)
+	assert.Equal(t, models.ExportExpired, saved.Status)
+	assert.Empty(t, saved.StorageKey)
+}
@@ -0,0 +1,45 @@
+package loginguard
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type MockAttemptStore struct {
+	mock.Mock
+}
+
+func (m *MockAttemptStore) LoginBlockedFor(ctx context.Context, keys ...string) (time.Duration, error) {
+	args := m.Called(ctx, keys)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *MockAttemptStore) RegisterLoginFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	args := m.Called(ctx, key, window)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAttemptStore) LockLogin(ctx context.Context, key string, ttl time.Duration) error {
+	return m.Called(ctx, key, ttl).Error(0)
+}
+
+func (m *MockAttemptStore) ResetLoginFailures(ctx context.Context, key string) error {
+	return m.Called(ctx, key).Error(0)
+}
+
+////////////////////
+
+type MockSecurityRepository struct {
+	mock.Mock
+}
+
+func (m *MockSecurityRepository) CreateSecurityEvent(ctx context.Context, event *models.SecurityEvent) error {
+	return m.Called(ctx, event).Error(0)
+}
+
+func (m *MockSecurityRepository) GetSecurityEvents(ctx context.Context, username string, limit int) ([]models.SecurityEvent, error) {
+	args := m.Called(ctx, username, limit)
+	return args.Get(0).([]models.SecurityEvent), args.Error(1)
+}
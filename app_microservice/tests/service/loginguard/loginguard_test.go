@@ -0,0 +1,89 @@
+package loginguard
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"testing"
+	"time"
+)
+
+func setupTest() (*service.LoginGuard, *MockAttemptStore, *MockSecurityRepository) {
+	store := new(MockAttemptStore)
+	events := new(MockSecurityRepository)
+	guard := service.NewLoginGuard(store, events, service.LoginGuardConfig{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      20,
+		Window:             time.Hour,
+		Lockout:            time.Minute,
+		MaxLockout:         time.Hour,
+	})
+	return guard, store, events
+}
+
+func TestLoginGuard_Fail_ExponentialLockout(t *testing.T) {
+	tests := []struct {
+		failures int
+		lockout  time.Duration
+	}{
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{8, 8 * time.Minute},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		guard, store, events := setupTest()
+		device := service.Device{IP: "10.0.0.1"}
+
+		events.On("CreateSecurityEvent", ctx, mock.Anything).Return(nil)
+		store.On("RegisterLoginFailure", ctx, "user:vaflya", time.Hour).Return(tt.failures, nil)
+		store.On("RegisterLoginFailure", ctx, "ip:10.0.0.1", time.Hour).Return(1, nil)
+		store.On("LockLogin", ctx, "user:vaflya", tt.lockout).Return(nil)
+
+		assert.NoError(t, guard.Fail(ctx, "Vaflya", device))
+		store.AssertCalled(t, "LockLogin", ctx, "user:vaflya", tt.lockout)
+		events.AssertCalled(t, "CreateSecurityEvent", ctx, mock.MatchedBy(func(e *models.SecurityEvent) bool {
+			return e.Type == models.SecurityLockout
+		}))
+	}
+}
+
+func TestLoginGuard_Fail_BelowThreshold(t *testing.T) {
+	ctx := context.Background()
+	guard, store, events := setupTest()
+
+	events.On("CreateSecurityEvent", ctx, mock.Anything).Return(nil)
+	store.On("RegisterLoginFailure", ctx, mock.Anything, time.Hour).Return(2, nil)
+
+	assert.NoError(t, guard.Fail(ctx, "nobody", service.Device{IP: "10.0.0.1"}))
+	store.AssertNotCalled(t, "LockLogin", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoginGuard_Unlock(t *testing.T) {
+	ctx := context.Background()
+	guard, store, events := setupTest()
+
+	now := time.Now()
+	events.On("GetSecurityEvents", ctx, "vaflya", 100).Return([]models.SecurityEvent{
+		{Type: models.SecurityLockout, IP: "10.0.0.1", CreatedAt: now},
+		{Type: models.SecurityLoginFailed, IP: "10.0.0.1", CreatedAt: now},
+		{Type: models.SecurityLoginFailed, IP: "10.0.0.1", CreatedAt: now.Add(-time.Minute)},
+		{Type: models.SecurityLoginFailed, IP: "10.0.0.2", CreatedAt: now.Add(-10 * time.Minute)},
+		// неудача старше окна и максимальной блокировки уже ничего не блокирует
+		{Type: models.SecurityLoginFailed, IP: "10.0.0.3", CreatedAt: now.Add(-2 * time.Hour)},
+	}, nil)
+	events.On("CreateSecurityEvent", ctx, mock.Anything).Return(nil)
+	store.On("ResetLoginFailures", ctx, mock.Anything).Return(nil)
+
+	assert.NoError(t, guard.Unlock(ctx, "vaflya", "admin 1", service.Device{IP: "192.168.0.9"}))
+
+	store.AssertCalled(t, "ResetLoginFailures", ctx, "user:vaflya")
+	store.AssertCalled(t, "ResetLoginFailures", ctx, "ip:10.0.0.1")
+	store.AssertCalled(t, "ResetLoginFailures", ctx, "ip:10.0.0.2")
+	store.AssertNotCalled(t, "ResetLoginFailures", ctx, "ip:10.0.0.3")
+	store.AssertNotCalled(t, "ResetLoginFailures", ctx, "ip:192.168.0.9")
+	store.AssertNumberOfCalls(t, "ResetLoginFailures", 3)
+}
@@ -0,0 +1,111 @@
+package moderation
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"time"
+)
+
+type MockModerationRepository struct {
+	mock.Mock
+}
+
+func (m *MockModerationRepository) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	return m.Called(ctx, entry).Error(0)
+}
+
+func (m *MockModerationRepository) CreateReport(ctx context.Context, report *models.Report) error {
+	return m.Called(ctx, report).Error(0)
+}
+
+func (m *MockModerationRepository) HasOpenReport(ctx context.Context, reporterID int, targetType, targetID string) (bool, error) {
+	args := m.Called(ctx, reporterID, targetType, targetID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockModerationRepository) GetReports(ctx context.Context, filter models.ReportFilter) ([]models.Report, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.Report), args.Error(1)
+}
+
+func (m *MockModerationRepository) GetReport(ctx context.Context, reportID int) (*models.Report, error) {
+	args := m.Called(ctx, reportID)
+	return args.Get(0).(*models.Report), args.Error(1)
+}
+
+func (m *MockModerationRepository) ResolveReports(ctx context.Context, targetType, targetID, status, resolution string,
+	resolvedBy int, now time.Time) ([]int, error) {
+	args := m.Called(ctx, targetType, targetID, status, resolution, resolvedBy, now)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockModerationRepository) GetReportTarget(ctx context.Context, targetType, targetID string) (*models.ReportTarget, error) {
+	args := m.Called(ctx, targetType, targetID)
+	return args.Get(0).(*models.ReportTarget), args.Error(1)
+}
+
+func (m *MockModerationRepository) SetPostHidden(ctx context.Context, postID int, hiddenAt *time.Time) (bool, error) {
+	args := m.Called(ctx, postID, hiddenAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockModerationRepository) SetImageHidden(ctx context.Context, imageSK string, hiddenAt *time.Time) ([]int, error) {
+	args := m.Called(ctx, imageSK, hiddenAt)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockModerationRepository) GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
+////////////////////
+
+type MockContentRemover struct {
+	mock.Mock
+}
+
+func (m *MockContentRemover) RemovePost(ctx context.Context, postID int) error {
+	return m.Called(ctx, postID).Error(0)
+}
+
+func (m *MockContentRemover) RemovePicture(ctx context.Context, imgSK string) error {
+	return m.Called(ctx, imgSK).Error(0)
+}
+
+////////////////////
+
+type MockUserBanner struct {
+	mock.Mock
+}
+
+func (m *MockUserBanner) BanForReport(ctx context.Context, actor service.Actor, userID int, reason string, reportID int) error {
+	return m.Called(ctx, actor, userID, reason, reportID).Error(0)
+}
+
+////////////////////
+
+type MockCache struct {
+	mock.Mock
+}
+
+func (m *MockCache) InvalidatePost(ctx context.Context, postID int) (bool, error) {
+	args := m.Called(ctx, postID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCache) InvalidateMostLikedPosts(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+////////////////////
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) PublishModerationDecided(ctx context.Context, decision *models.ModerationDecision) error {
+	return m.Called(ctx, decision).Error(0)
+}
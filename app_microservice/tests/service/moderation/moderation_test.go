@@ -0,0 +1,213 @@
+package moderation
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"testing"
+	"time"
+)
+
+type mocks struct {
+	repo      *MockModerationRepository
+	content   *MockContentRemover
+	bans      *MockUserBanner
+	cache     *MockCache
+	publisher *MockPublisher
+}
+
+func setupTest() (*service.ModerationService, mocks) {
+	m := mocks{
+		repo:      new(MockModerationRepository),
+		content:   new(MockContentRemover),
+		bans:      new(MockUserBanner),
+		cache:     new(MockCache),
+		publisher: new(MockPublisher),
+	}
+	return service.NewModerationService(m.repo, m.content, m.content, m.bans, m.cache, m.publisher), m
+}
+
+var moderator = service.Actor{UserID: 1, Role: models.RoleModerator}
+
+func TestModerationService_Report(t *testing.T) {
+	ctx := context.Background()
+	moderationService, m := setupTest()
+
+	m.repo.On("GetReportTarget", ctx, models.ReportTargetPost, "12").Return(&models.ReportTarget{OwnerID: 5, Name: "cats"}, nil)
+	m.repo.On("HasOpenReport", ctx, 3, models.ReportTargetPost, "12").Return(false, nil)
+	m.repo.On("CreateReport", ctx, mock.Anything).Return(nil)
+
+	report, err := moderationService.Report(ctx, 3, models.ReportCreate{TargetType: models.ReportTargetPost, TargetID: " 12 ", Reason: "spam"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, report.OwnerID)
+	assert.Equal(t, models.ReportOpen, report.Status)
+	m.repo.AssertExpectations(t)
+}
+
+func TestModerationService_Report_OwnContent(t *testing.T) {
+	ctx := context.Background()
+	moderationService, m := setupTest()
+
+	m.repo.On("GetReportTarget", ctx, models.ReportTargetPost, "12").Return(&models.ReportTarget{OwnerID: 3}, nil)
+
+	_, err := moderationService.Report(ctx, 3, models.ReportCreate{TargetType: models.ReportTargetPost, TargetID: "12", Reason: "spam"})
+
+	var validationErr *service.ReportValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	m.repo.AssertNotCalled(t, "CreateReport")
+}
+
+func TestModerationService_Report_Duplicate(t *testing.T) {
+	ctx := context.Background()
+	moderationService, m := setupTest()
+
+	m.repo.On("GetReportTarget", ctx, models.ReportTargetImage, "cat1234").Return(&models.ReportTarget{OwnerID: 5}, nil)
+	m.repo.On("HasOpenReport", ctx, 3, models.ReportTargetImage, "cat1234").Return(true, nil)
+
+	_, err := moderationService.Report(ctx, 3, models.ReportCreate{TargetType: models.ReportTargetImage, TargetID: "cat1234", Reason: "abuse"})
+
+	assert.ErrorIs(t, err, service.ErrDuplicateReport)
+	m.repo.AssertNotCalled(t, "CreateReport")
+}
+
+func TestModerationService_Resolve_HidePost(t *testing.T) {
+	ctx := context.Background()
+	moderationService, m := setupTest()
+
+	report := &models.Report{ID: 9, TargetType: models.ReportTargetPost, TargetID: "12", OwnerID: 5, Status: models.ReportOpen}
+	m.repo.On("GetReport", ctx, 9).Return(report, nil)
+	m.repo.On("GetReportTarget", ctx, models.ReportTargetPost, "12").Return(&models.ReportTarget{OwnerID: 5, Name: "cats"}, nil)
+	m.repo.On("SetPostHidden", ctx, 12, mock.Anything).Return(true, nil)
+	m.cache.On("InvalidatePost", ctx, 12).Return(true, nil)
+	m.cache.On("InvalidateMostLikedPosts", ctx).Return(nil)
+	m.repo.On("ResolveReports", ctx, models.ReportTargetPost, "12", models.ReportActioned, models.ModerationHide, 1, mock.Anything).
+		Return([]int{3, 4}, nil)
+	m.repo.On("CreateAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.ModerationHide && entry.ActorID == 1 && *entry.ReportID == 9
+	})).Return(nil)
+	m.publisher.On("PublishModerationDecided", ctx, mock.MatchedBy(func(decision *models.ModerationDecision) bool {
+		return decision.OwnerID == 5 && decision.TargetName == "cats" && len(decision.Reporters) == 2
+	})).Return(nil)
+
+	err := moderationService.Resolve(ctx, moderator, 9, models.ReportResolve{Action: models.ModerationHide})
+
+	assert.NoError(t, err)
+	m.repo.AssertExpectations(t)
+	m.cache.AssertExpectations(t)
+	m.publisher.AssertExpectations(t)
+	m.content.AssertNotCalled(t, "RemovePost")
+}
+
+func TestModerationService_Resolve_UnhidePost(t *testing.T) {
+	ctx := context.Background()
+	moderationService, m := setupTest()
+
+	// пост уже скрыт, модератор по новой жалобе решил, что нарушения нет
+	report := &models.Report{ID: 9, TargetType: models.ReportTargetPost, TargetID: "12", OwnerID: 5, Status: models.ReportOpen}
+	m.repo.On("GetReport", ctx, 9).Return(report, nil)
+	m.repo.On("GetReportTarget", ctx, models.ReportTargetPost, "12").Return(&models.ReportTarget{OwnerID: 5, Name: "cats"}, nil)
+	m.repo.On("SetPostHidden", ctx, 12, (*time.Time)(nil)).Return(true, nil)
+	m.cache.On("InvalidatePost", ctx, 12).Return(true, nil)
+	m.cache.On("InvalidateMostLikedPosts", ctx).Return(nil)
+	m.repo.On("ResolveReports", ctx, models.ReportTargetPost, "12", models.ReportDismissed, models.ModerationUnhide, 1, mock.Anything).
+		Return([]int{3}, nil)
+	m.repo.On("CreateAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.ModerationUnhide && entry.TargetID == "12" && *entry.ReportID == 9
+	})).Return(nil)
+	m.publisher.On("PublishModerationDecided", ctx, mock.MatchedBy(func(decision *models.ModerationDecision) bool {
+		return decision.Action == models.ModerationUnhide && decision.OwnerID == 5
+	})).Return(nil)
+
+	err := moderationService.Resolve(ctx, moderator, 9, models.ReportResolve{Action: models.ModerationUnhide})
+
+	assert.NoError(t, err)
+	m.repo.AssertExpectations(t)
+	m.cache.AssertExpectations(t)
+	m.publisher.AssertExpectations(t)
+}
+
+func TestModerationService_RestoreContent_Image(t *testing.T) {
+	ctx := context.Background()
+	moderationService, m := setupTest()
+
+	m.repo.On("GetReportTarget", ctx, models.ReportTargetImage, "cat1234").Return(&models.ReportTarget{OwnerID: 5}, nil)
+	m.repo.On("SetImageHidden", ctx, "cat1234", (*time.Time)(nil)).Return([]int{12, 13}, nil)
+	m.cache.On("InvalidatePost", ctx, mock.Anything).Return(true, nil)
+	m.cache.On("InvalidateMostLikedPosts", ctx).Return(nil)
+	m.repo.On("ResolveReports", ctx, models.ReportTargetImage, "cat1234", models.ReportDismissed, models.ModerationUnhide, 1, mock.Anything).
+		Return([]int{}, nil)
+	m.repo.On("CreateAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.ModerationUnhide && entry.ReportID == nil
+	})).Return(nil)
+	m.publisher.On("PublishModerationDecided", ctx, mock.Anything).Return(nil)
+
+	err := moderationService.RestoreContent(ctx, moderator, models.ReportTargetImage, "cat1234")
+
+	assert.NoError(t, err)
+	m.repo.AssertExpectations(t)
+	m.cache.AssertNumberOfCalls(t, "InvalidatePost", 2)
+}
+
+func TestModerationService_Resolve_DismissRemovedContent(t *testing.T) {
+	ctx := context.Background()
+	moderationService, m := setupTest()
+
+	report := &models.Report{ID: 9, TargetType: models.ReportTargetImage, TargetID: "cat1234", OwnerID: 5, Status: models.ReportOpen}
+	m.repo.On("GetReport", ctx, 9).Return(report, nil)
+	m.repo.On("GetReportTarget", ctx, models.ReportTargetImage, "cat1234").Return((*models.ReportTarget)(nil), nil)
+	m.repo.On("ResolveReports", ctx, models.ReportTargetImage, "cat1234", models.ReportDismissed, models.ModerationDismiss, 1, mock.Anything).
+		Return([]int{3}, nil)
+	m.repo.On("CreateAuditEntry", ctx, mock.Anything).Return(nil)
+	m.publisher.On("PublishModerationDecided", ctx, mock.Anything).Return(nil)
+
+	err := moderationService.Resolve(ctx, moderator, 9, models.ReportResolve{Action: models.ModerationDismiss})
+
+	assert.NoError(t, err)
+	m.repo.AssertExpectations(t)
+}
+
+func TestModerationService_Resolve_BanDelegatesAudit(t *testing.T) {
+	ctx := context.Background()
+	moderationService, m := setupTest()
+
+	report := &models.Report{ID: 9, TargetType: models.ReportTargetUser, TargetID: "5", OwnerID: 5, Status: models.ReportOpen}
+	m.repo.On("GetReport", ctx, 9).Return(report, nil)
+	m.repo.On("GetReportTarget", ctx, models.ReportTargetUser, "5").Return(&models.ReportTarget{OwnerID: 5, Name: "spammer"}, nil)
+	m.bans.On("BanForReport", ctx, moderator, 5, "spam bot", 9).Return(nil)
+	m.repo.On("ResolveReports", ctx, models.ReportTargetUser, "5", models.ReportActioned, models.ModerationBan, 1, mock.Anything).
+		Return([]int{3}, nil)
+	m.publisher.On("PublishModerationDecided", ctx, mock.Anything).Return(nil)
+
+	err := moderationService.Resolve(ctx, moderator, 9, models.ReportResolve{Action: models.ModerationBan, Note: "spam bot"})
+
+	assert.NoError(t, err)
+	m.bans.AssertExpectations(t)
+	m.repo.AssertNotCalled(t, "CreateAuditEntry")
+}
+
+func TestModerationService_Resolve_InvalidAction(t *testing.T) {
+	ctx := context.Background()
+	moderationService, m := setupTest()
+
+	report := &models.Report{ID: 9, TargetType: models.ReportTargetUser, TargetID: "5", Status: models.ReportOpen}
+	m.repo.On("GetReport", ctx, 9).Return(report, nil)
+
+	err := moderationService.Resolve(ctx, moderator, 9, models.ReportResolve{Action: models.ModerationHide})
+
+	assert.ErrorIs(t, err, service.ErrInvalidModerationAction)
+	m.repo.AssertNotCalled(t, "ResolveReports")
+}
+
+func TestModerationService_Resolve_AlreadyResolved(t *testing.T) {
+	ctx := context.Background()
+	moderationService, m := setupTest()
+
+	m.repo.On("GetReport", ctx, 9).Return(&models.Report{ID: 9, Status: models.ReportDismissed}, nil)
+
+	err := moderationService.Resolve(ctx, moderator, 9, models.ReportResolve{Action: models.ModerationDismiss})
+
+	assert.ErrorIs(t, err, service.ErrReportResolved)
+}
@@ -0,0 +1,85 @@
+package oidc
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/safety/oidcauth"
+	"time"
+)
+
+type MockIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdentityRepository) GetIdentity(ctx context.Context, issuer, subject string) (*models.ExternalIdentity, error) {
+	args := m.Called(ctx, issuer, subject)
+	return args.Get(0).(*models.ExternalIdentity), args.Error(1)
+}
+
+func (m *MockIdentityRepository) CreateIdentity(ctx context.Context, identity *models.ExternalIdentity) error {
+	return m.Called(ctx, identity).Error(0)
+}
+
+func (m *MockIdentityRepository) CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.ExternalIdentity) error {
+	return m.Called(ctx, user, identity).Error(0)
+}
+
+func (m *MockIdentityRepository) GetIdentities(ctx context.Context, userID int) ([]models.ExternalIdentity, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.ExternalIdentity), args.Error(1)
+}
+
+func (m *MockIdentityRepository) DeleteIdentity(ctx context.Context, userID, identityID int) (bool, error) {
+	args := m.Called(ctx, userID, identityID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdentityRepository) HasPassword(ctx context.Context, userID int) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdentityRepository) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	args := m.Called(ctx, username)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdentityRepository) EmailTaken(ctx context.Context, email string) (bool, error) {
+	args := m.Called(ctx, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdentityRepository) GetUserAccess(ctx context.Context, userID int) (*models.UserAccess, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.UserAccess), args.Error(1)
+}
+
+// MockStateStore хранит state в памяти, как Redis между редиректами
+type MockStateStore struct {
+	states map[string]string
+}
+
+func (m *MockStateStore) SaveOIDCState(ctx context.Context, state, value string, ttl time.Duration) error {
+	m.states[state] = value
+	return nil
+}
+
+func (m *MockStateStore) TakeOIDCState(ctx context.Context, state string) (string, error) {
+	value := m.states[state]
+	delete(m.states, state)
+	return value, nil
+}
+
+type MockProvider struct {
+	mock.Mock
+}
+
+func (m *MockProvider) AuthCodeURL(state, nonce, verifier string) string {
+	return "https://idp.example.com/authorize?state=" + state
+}
+
+func (m *MockProvider) Exchange(ctx context.Context, code, nonce, verifier string) (*oidcauth.Identity, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(*oidcauth.Identity), args.Error(1)
+}
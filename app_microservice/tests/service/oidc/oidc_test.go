@@ -0,0 +1,86 @@
+package oidc
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/safety/oidcauth"
+	"pictureloader/app_microservice/service"
+	"testing"
+	"time"
+)
+
+const issuer = "https://idp.example.com"
+
+func setupTest(t *testing.T) (*service.OIDCService, *MockIdentityRepository, *MockProvider, string) {
+	repo := new(MockIdentityRepository)
+	provider := new(MockProvider)
+	oidcService := service.NewOIDCService(repo, &MockStateStore{states: map[string]string{}})
+	// имя в конфиге не совпадает с тем, под которым привязка создавалась
+	oidcService.AddProvider(models.OIDCProviderInfo{Name: "company-sso"}, provider)
+	_, state, err := oidcService.Begin(context.Background(), "company-sso", 0)
+	require.NoError(t, err)
+	provider.On("Exchange", mock.Anything, "code").
+		Return(&oidcauth.Identity{Provider: "company-sso", Issuer: issuer, Subject: "1001", Email: "alice@example.com"}, nil)
+	return oidcService, repo, provider, state
+}
+
+func TestOIDCService_Complete_ExistingIdentity(t *testing.T) {
+	ctx := context.Background()
+	oidcService, repo, _, state := setupTest(t)
+
+	repo.On("GetIdentity", ctx, issuer, "1001").Return(&models.ExternalIdentity{UserID: 8, Provider: "company"}, nil)
+	repo.On("GetUserAccess", ctx, 8).Return(&models.UserAccess{Role: models.RoleUser}, nil)
+
+	userID, linking, err := oidcService.Complete(ctx, state, "code")
+
+	require.NoError(t, err)
+	assert.Equal(t, 8, userID)
+	assert.False(t, linking)
+	repo.AssertNotCalled(t, "CreateUserWithIdentity", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOIDCService_Complete_Banned(t *testing.T) {
+	ctx := context.Background()
+	oidcService, repo, _, state := setupTest(t)
+
+	now := time.Now()
+	repo.On("GetIdentity", ctx, issuer, "1001").Return(&models.ExternalIdentity{UserID: 8}, nil)
+	repo.On("GetUserAccess", ctx, 8).Return(&models.UserAccess{Role: models.RoleUser, BannedAt: &now}, nil)
+
+	_, _, err := oidcService.Complete(ctx, state, "code")
+
+	assert.ErrorIs(t, err, service.ErrUserBanned)
+}
+
+func TestOIDCService_Complete_PendingDeletion(t *testing.T) {
+	ctx := context.Background()
+	oidcService, repo, _, state := setupTest(t)
+
+	now := time.Now()
+	repo.On("GetIdentity", ctx, issuer, "1001").Return(&models.ExternalIdentity{UserID: 8}, nil)
+	repo.On("GetUserAccess", ctx, 8).Return(&models.UserAccess{Role: models.RoleUser, DeletionRequestedAt: &now}, nil)
+
+	_, _, err := oidcService.Complete(ctx, state, "code")
+
+	assert.ErrorIs(t, err, service.ErrPendingDeletion)
+}
+
+func TestOIDCService_Complete_ProvisionStoresIssuer(t *testing.T) {
+	ctx := context.Background()
+	oidcService, repo, _, state := setupTest(t)
+
+	repo.On("GetIdentity", ctx, issuer, "1001").Return((*models.ExternalIdentity)(nil), nil)
+	repo.On("EmailTaken", ctx, "alice@example.com").Return(false, nil)
+	repo.On("UsernameTaken", ctx, "alice").Return(false, nil)
+	repo.On("CreateUserWithIdentity", ctx, mock.Anything, mock.MatchedBy(func(identity *models.ExternalIdentity) bool {
+		return identity.Issuer == issuer && identity.Subject == "1001" && identity.Provider == "company-sso"
+	})).Return(nil)
+
+	_, _, err := oidcService.Complete(ctx, state, "code")
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,50 @@
+package profile
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+)
+
+type MockProfileRepository struct {
+	mock.Mock
+}
+
+func (m *MockProfileRepository) GetPublicProfile(ctx context.Context, username string) (*models.PublicProfile, error) {
+	args := m.Called(ctx, username)
+	profile, _ := args.Get(0).(*models.PublicProfile)
+	return profile, args.Error(1)
+}
+
+func (m *MockProfileRepository) GetRecentPosts(ctx context.Context, userID int, limit int) ([]models.ProfilePost, error) {
+	args := m.Called(ctx, userID, limit)
+	return args.Get(0).([]models.ProfilePost), args.Error(1)
+}
+
+func (m *MockProfileRepository) UpdateProfile(ctx context.Context, userID int, update models.ProfileUpdate) error {
+	return m.Called(ctx, userID, update).Error(0)
+}
+
+func (m *MockProfileRepository) GetActiveUserID(ctx context.Context, username string) (int, error) {
+	args := m.Called(ctx, username)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockProfileRepository) Follow(ctx context.Context, followerID, followeeID int) error {
+	return m.Called(ctx, followerID, followeeID).Error(0)
+}
+
+func (m *MockProfileRepository) Unfollow(ctx context.Context, followerID, followeeID int) error {
+	return m.Called(ctx, followerID, followeeID).Error(0)
+}
+
+////////////////////
+
+type MockStorage struct {
+	mock.Mock
+}
+
+func (m *MockStorage) GetFileURL(ctx context.Context, key string) (string, error) {
+	args := m.Called(ctx, key)
+	return args.String(0), args.Error(1)
+}
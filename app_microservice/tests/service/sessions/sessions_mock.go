@@ -0,0 +1,75 @@
+package sessions
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) CreateSession(ctx context.Context, session *models.Session, tokenHash string) error {
+	return m.Called(ctx, session, tokenHash).Error(0)
+}
+
+func (m *MockSessionRepository) UseRefreshToken(ctx context.Context, tokenHash string, now time.Time) (*models.RefreshToken, error) {
+	args := m.Called(ctx, tokenHash, now)
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockSessionRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockSessionRepository) RotateRefreshToken(ctx context.Context, session *models.Session, tokenHash string) error {
+	return m.Called(ctx, session, tokenHash).Error(0)
+}
+
+func (m *MockSessionRepository) GetActiveSessions(ctx context.Context, userID int, now time.Time) ([]models.Session, error) {
+	args := m.Called(ctx, userID, now)
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeSession(ctx context.Context, userID int, sessionID string, now time.Time) (bool, error) {
+	args := m.Called(ctx, userID, sessionID, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeAllSessions(ctx context.Context, userID int, now time.Time) ([]string, error) {
+	args := m.Called(ctx, userID, now)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockSessionRepository) GetUserAccess(ctx context.Context, userID int) (*models.UserAccess, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.UserAccess), args.Error(1)
+}
+
+////////////////////
+
+type MockDenylist struct {
+	mock.Mock
+}
+
+func (m *MockDenylist) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	return m.Called(ctx, sessionID, ttl).Error(0)
+}
+
+////////////////////
+
+type MockTokenIssuer struct {
+	mock.Mock
+}
+
+func (m *MockTokenIssuer) GenerateToken(userID int, sessionID string, role string) (string, error) {
+	args := m.Called(userID, sessionID, role)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTokenIssuer) AccessTTL() time.Duration {
+	return 15 * time.Minute
+}
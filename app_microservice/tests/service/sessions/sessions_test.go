@@ -0,0 +1,82 @@
+package sessions
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"testing"
+	"time"
+)
+
+func setupTest() (*service.SessionService, *MockSessionRepository, *MockDenylist, *MockTokenIssuer) {
+	repo := new(MockSessionRepository)
+	denylist := new(MockDenylist)
+	tokens := new(MockTokenIssuer)
+	return service.NewSessionService(repo, denylist, tokens, 24*time.Hour), repo, denylist, tokens
+}
+
+func TestSessionService_Refresh_RotatesToken(t *testing.T) {
+	ctx := context.Background()
+	sessionService, repo, _, tokens := setupTest()
+
+	token := &models.RefreshToken{
+		SessionID: "s1",
+		Session:   models.Session{ID: "s1", UserID: 3, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	repo.On("UseRefreshToken", ctx, mock.Anything, mock.Anything).Return(token, nil)
+	repo.On("RotateRefreshToken", ctx, mock.Anything, mock.Anything).Return(nil)
+	repo.On("GetUserAccess", ctx, 3).Return(&models.UserAccess{Role: models.RoleModerator}, nil)
+	tokens.On("GenerateToken", 3, "s1", models.RoleModerator).Return("access", nil)
+
+	issued, err := sessionService.Refresh(ctx, "old-refresh", service.Device{UserAgent: "curl", IP: "10.0.0.1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access", issued.AccessToken)
+	assert.NotEmpty(t, issued.RefreshToken)
+	assert.NotEqual(t, "old-refresh", issued.RefreshToken)
+	repo.AssertExpectations(t)
+}
+
+func TestSessionService_Refresh_ReuseRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	sessionService, repo, denylist, tokens := setupTest()
+
+	usedAt := time.Now().Add(-time.Minute)
+	token := &models.RefreshToken{
+		SessionID: "s1",
+		UsedAt:    &usedAt,
+		Session:   models.Session{ID: "s1", UserID: 3, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	repo.On("UseRefreshToken", ctx, mock.Anything, mock.Anything).Return(token, nil)
+	repo.On("RevokeSession", ctx, 3, "s1", mock.Anything).Return(true, nil)
+	denylist.On("RevokeSession", ctx, "s1", 15*time.Minute).Return(nil)
+
+	_, err := sessionService.Refresh(ctx, "stolen-refresh", service.Device{})
+
+	assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+	repo.AssertExpectations(t)
+	denylist.AssertExpectations(t)
+	repo.AssertNotCalled(t, "RotateRefreshToken")
+	tokens.AssertNotCalled(t, "GenerateToken")
+}
+
+func TestSessionService_Refresh_BannedUser(t *testing.T) {
+	ctx := context.Background()
+	sessionService, repo, _, tokens := setupTest()
+
+	token := &models.RefreshToken{
+		SessionID: "s1",
+		Session:   models.Session{ID: "s1", UserID: 3, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	bannedAt := time.Now()
+	repo.On("UseRefreshToken", ctx, mock.Anything, mock.Anything).Return(token, nil)
+	repo.On("GetUserAccess", ctx, 3).Return(&models.UserAccess{Role: models.RoleUser, BannedAt: &bannedAt}, nil)
+
+	_, err := sessionService.Refresh(ctx, "refresh", service.Device{})
+
+	assert.ErrorIs(t, err, service.ErrUserBanned)
+	repo.AssertNotCalled(t, "RotateRefreshToken")
+	tokens.AssertNotCalled(t, "GenerateToken")
+}
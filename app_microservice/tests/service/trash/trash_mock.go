@@ -0,0 +1,63 @@
+package trash
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"time"
+)
+
+type MockTrashRepository struct {
+	mock.Mock
+}
+
+func (m *MockTrashRepository) GetTrash(ctx context.Context, userID int) ([]models.TrashItem, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.TrashItem), args.Error(1)
+}
+
+func (m *MockTrashRepository) RestorePost(ctx context.Context, userID, postID int) (bool, error) {
+	args := m.Called(ctx, userID, postID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTrashRepository) RestoreImage(ctx context.Context, userID int, imageSK string) ([]int, error) {
+	args := m.Called(ctx, userID, imageSK)
+	postIDs, _ := args.Get(0).([]int)
+	return postIDs, args.Error(1)
+}
+
+func (m *MockTrashRepository) GetExpiredPosts(ctx context.Context, before time.Time, limit int) ([]int, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockTrashRepository) GetExpiredImages(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+////////////////////
+
+type MockContentRemover struct {
+	mock.Mock
+}
+
+func (m *MockContentRemover) RemovePost(ctx context.Context, postID int) error {
+	return m.Called(ctx, postID).Error(0)
+}
+
+func (m *MockContentRemover) RemovePicture(ctx context.Context, imgSK string) error {
+	return m.Called(ctx, imgSK).Error(0)
+}
+
+////////////////////
+
+type MockCache struct {
+	mock.Mock
+}
+
+func (m *MockCache) InvalidatePost(ctx context.Context, postID int) (bool, error) {
+	args := m.Called(ctx, postID)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,78 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"testing"
+	"time"
+)
+
+const retention = 30 * 24 * time.Hour
+
+func setupTest() (*service.TrashService, *MockTrashRepository, *MockContentRemover, *MockCache) {
+	repo := new(MockTrashRepository)
+	content := new(MockContentRemover)
+	cache := new(MockCache)
+	return service.NewTrashService(repo, content, content, cache, retention), repo, content, cache
+}
+
+func TestTrashService_ListTrash(t *testing.T) {
+	ctx := context.Background()
+	trashService, repo, _, _ := setupTest()
+
+	deletedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo.On("GetTrash", ctx, 1).Return([]models.TrashItem{{Type: models.TrashPost, ID: "7", DeletedAt: deletedAt}}, nil)
+
+	items, err := trashService.ListTrash(ctx, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, deletedAt.Add(retention), items[0].PurgeAt)
+}
+
+func TestTrashService_RestorePicture(t *testing.T) {
+	ctx := context.Background()
+	trashService, repo, _, cache := setupTest()
+
+	repo.On("RestoreImage", ctx, 1, "cat1234").Return([]int{4, 9}, nil)
+	cache.On("InvalidatePost", ctx, mock.Anything).Return(true, nil)
+
+	err := trashService.RestorePicture(ctx, 1, "cat1234")
+
+	assert.NoError(t, err)
+	cache.AssertCalled(t, "InvalidatePost", ctx, 4)
+	cache.AssertCalled(t, "InvalidatePost", ctx, 9)
+}
+
+func TestTrashService_RestorePicture_NotInTrash(t *testing.T) {
+	ctx := context.Background()
+	trashService, repo, _, cache := setupTest()
+
+	repo.On("RestoreImage", ctx, 1, "cat1234").Return(nil, nil)
+
+	err := trashService.RestorePicture(ctx, 1, "cat1234")
+
+	assert.ErrorIs(t, err, service.ErrTrashItemNotFound)
+	cache.AssertNotCalled(t, "InvalidatePost")
+}
+
+func TestTrashService_Purge(t *testing.T) {
+	ctx := context.Background()
+	trashService, repo, content, _ := setupTest()
+
+	now := time.Now()
+	repo.On("GetExpiredPosts", ctx, now.Add(-retention), mock.Anything).Return([]int{3, 5}, nil)
+	repo.On("GetExpiredImages", ctx, now.Add(-retention), mock.Anything).Return([]string{"cat1234"}, nil)
+	content.On("RemovePost", ctx, 3).Return(nil)
+	content.On("RemovePost", ctx, 5).Return(errors.New("storage is down"))
+	content.On("RemovePicture", ctx, "cat1234").Return(nil)
+
+	purged, err := trashService.Purge(ctx, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	content.AssertExpectations(t)
+}
@@ -0,0 +1,97 @@
+package twofactor
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/service"
+	"time"
+)
+
+type MockTwoFactorRepository struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorRepository) GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.TwoFactor), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) SavePendingTwoFactor(ctx context.Context, userID int, secret string) error {
+	return m.Called(ctx, userID, secret).Error(0)
+}
+
+func (m *MockTwoFactorRepository) EnableTwoFactor(ctx context.Context, userID int, step int64, codeHashes []string, now time.Time) error {
+	return m.Called(ctx, userID, step, codeHashes, now).Error(0)
+}
+
+func (m *MockTwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	return m.Called(ctx, userID, codeHashes).Error(0)
+}
+
+func (m *MockTwoFactorRepository) DisableTwoFactor(ctx context.Context, userID int) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func (m *MockTwoFactorRepository) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string, now time.Time) (bool, error) {
+	args := m.Called(ctx, userID, codeHash, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) GetCredentials(ctx context.Context, userID int) (*models.User, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+////////////////////
+
+type MockChallengeStore struct {
+	mock.Mock
+}
+
+func (m *MockChallengeStore) SaveLoginChallenge(ctx context.Context, challengeHash string, userID int, ttl time.Duration) error {
+	return m.Called(ctx, challengeHash, userID, ttl).Error(0)
+}
+
+func (m *MockChallengeStore) GetLoginChallenge(ctx context.Context, challengeHash string) (int, error) {
+	args := m.Called(ctx, challengeHash)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockChallengeStore) FailLoginChallenge(ctx context.Context, challengeHash string, ttl time.Duration) (int, error) {
+	args := m.Called(ctx, challengeHash, ttl)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockChallengeStore) DeleteLoginChallenge(ctx context.Context, challengeHash string) error {
+	return m.Called(ctx, challengeHash).Error(0)
+}
+
+////////////////////
+
+type MockLoginLimiter struct {
+	mock.Mock
+}
+
+func (m *MockLoginLimiter) BlockedFor(ctx context.Context, username string, device service.Device) (time.Duration, error) {
+	args := m.Called(ctx, username, device)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *MockLoginLimiter) Fail(ctx context.Context, username string, device service.Device) error {
+	return m.Called(ctx, username, device).Error(0)
+}
+
+func (m *MockLoginLimiter) Succeed(ctx context.Context, username string) {
+	m.Called(ctx, username)
+}
@@ -0,0 +1,218 @@
+package twofactor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"pictureloader/app_microservice/models"
+	"pictureloader/app_microservice/safety/totp"
+	"pictureloader/app_microservice/service"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var device = service.Device{UserAgent: "test", IP: "10.0.0.1"}
+
+func setupTest(t *testing.T) (*service.TwoFactorService, *MockTwoFactorRepository, *MockChallengeStore, *MockLoginLimiter, string) {
+	repo := new(MockTwoFactorRepository)
+	challenges := new(MockChallengeStore)
+	guard := new(MockLoginLimiter)
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	repo.On("GetTwoFactor", mock.Anything, 8).Return(&models.TwoFactor{UserID: 8, Secret: secret, Enabled: true}, nil)
+	return service.NewTwoFactorService(repo, challenges, guard), repo, challenges, guard, secret
+}
+
+func TestTwoFactorService_CompleteChallenge(t *testing.T) {
+	ctx := context.Background()
+	twoFactorService, repo, challenges, guard, secret := setupTest(t)
+
+	var challengeHash string
+	challenges.On("SaveLoginChallenge", ctx, mock.Anything, 8, mock.Anything).Run(func(args mock.Arguments) {
+		challengeHash = args.String(1)
+	}).Return(nil)
+	challenge, err := twoFactorService.StartChallenge(ctx, 8)
+	require.NoError(t, err)
+	assert.NotEqual(t, challenge, challengeHash)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	challenges.On("GetLoginChallenge", ctx, challengeHash).Return(8, nil)
+	challenges.On("DeleteLoginChallenge", ctx, challengeHash).Return(nil)
+	repo.On("GetCredentials", ctx, 8).Return(&models.User{ID: 8, Username: "alice"}, nil)
+	repo.On("UseStep", ctx, 8, mock.Anything).Return(true, nil)
+	guard.On("BlockedFor", ctx, "alice", device).Return(time.Duration(0), nil)
+	guard.On("Succeed", ctx, "alice").Return()
+
+	userID, err := twoFactorService.CompleteChallenge(ctx, challenge, code, device)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 8, userID)
+	challenges.AssertCalled(t, "DeleteLoginChallenge", ctx, challengeHash)
+	// счетчик неудач сбрасывается только после второго шага
+	guard.AssertCalled(t, "Succeed", ctx, "alice")
+}
+
+func TestTwoFactorService_CompleteChallenge_ReplayedCode(t *testing.T) {
+	ctx := context.Background()
+	twoFactorService, repo, challenges, guard, secret := setupTest(t)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	challenges.On("GetLoginChallenge", ctx, mock.Anything).Return(8, nil)
+	challenges.On("FailLoginChallenge", ctx, mock.Anything, mock.Anything).Return(1, nil)
+	repo.On("GetCredentials", ctx, 8).Return(&models.User{ID: 8, Username: "alice"}, nil)
+	// код этого шага уже принимался
+	repo.On("UseStep", ctx, 8, mock.Anything).Return(false, nil)
+	guard.On("BlockedFor", ctx, "alice", device).Return(time.Duration(0), nil)
+	guard.On("Fail", ctx, "alice", device).Return(nil)
+
+	_, err = twoFactorService.CompleteChallenge(ctx, "challenge", code, device)
+
+	assert.ErrorIs(t, err, service.ErrInvalidTwoFactor)
+	challenges.AssertNotCalled(t, "DeleteLoginChallenge", mock.Anything, mock.Anything)
+	guard.AssertCalled(t, "Fail", ctx, "alice", device)
+	guard.AssertNotCalled(t, "Succeed", mock.Anything, mock.Anything)
+}
+
+func TestTwoFactorService_CompleteChallenge_TooManyAttempts(t *testing.T) {
+	ctx := context.Background()
+	twoFactorService, repo, challenges, guard, _ := setupTest(t)
+
+	challenges.On("GetLoginChallenge", ctx, mock.Anything).Return(8, nil)
+	challenges.On("FailLoginChallenge", ctx, mock.Anything, mock.Anything).Return(5, nil)
+	challenges.On("DeleteLoginChallenge", ctx, mock.Anything).Return(nil)
+	repo.On("GetCredentials", ctx, 8).Return(&models.User{ID: 8, Username: "alice"}, nil)
+	repo.On("UseRecoveryCode", ctx, 8, mock.Anything, mock.Anything).Return(false, nil)
+	guard.On("BlockedFor", ctx, "alice", device).Return(time.Duration(0), nil)
+	guard.On("Fail", ctx, "alice", device).Return(nil)
+
+	_, err := twoFactorService.CompleteChallenge(ctx, "challenge", "wrong-code", device)
+
+	assert.ErrorIs(t, err, service.ErrInvalidChallenge)
+	challenges.AssertCalled(t, "DeleteLoginChallenge", ctx, mock.Anything)
+}
+
+func TestTwoFactorService_CompleteChallenge_AccountLocked(t *testing.T) {
+	ctx := context.Background()
+	twoFactorService, repo, challenges, guard, secret := setupTest(t)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	challenges.On("GetLoginChallenge", ctx, mock.Anything).Return(8, nil)
+	repo.On("GetCredentials", ctx, 8).Return(&models.User{ID: 8, Username: "alice"}, nil)
+	// аккаунт заблокирован неверными кодами из прошлых челленджей
+	guard.On("BlockedFor", ctx, "alice", device).Return(time.Minute, nil)
+
+	_, err = twoFactorService.CompleteChallenge(ctx, "challenge", code, device)
+
+	var blocked *service.LoginBlockedError
+	require.ErrorAs(t, err, &blocked)
+	assert.Equal(t, time.Minute, blocked.RetryAfter)
+	repo.AssertNotCalled(t, "UseStep", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTwoFactorService_Disable_WithoutPassword(t *testing.T) {
+	ctx := context.Background()
+	twoFactorService, repo, _, guard, secret := setupTest(t)
+
+	// пользователь создан через OIDC, пароля у него нет
+	repo.On("GetCredentials", ctx, 8).Return(&models.User{ID: 8, Username: "alice"}, nil)
+	guard.On("BlockedFor", ctx, "alice", device).Return(time.Duration(0), nil)
+	repo.On("UseStep", ctx, 8, mock.Anything).Return(true, nil)
+	repo.On("DisableTwoFactor", ctx, 8).Return(nil)
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	err = twoFactorService.Disable(ctx, 8, models.TwoFactorReauth{Code: code}, device)
+
+	assert.NoError(t, err)
+	repo.AssertCalled(t, "DisableTwoFactor", ctx, 8)
+}
+
+func TestTwoFactorService_Disable_WithoutPassword_RecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	twoFactorService, repo, _, guard, _ := setupTest(t)
+
+	repo.On("GetCredentials", ctx, 8).Return(&models.User{ID: 8, Username: "alice"}, nil)
+	guard.On("BlockedFor", ctx, "alice", device).Return(time.Duration(0), nil)
+
+	guard.On("Fail", ctx, "alice", device).Return(nil)
+
+	err := twoFactorService.Disable(ctx, 8, models.TwoFactorReauth{Code: "abcde-fghjk"}, device)
+
+	assert.ErrorIs(t, err, service.ErrInvalidTwoFactor)
+	repo.AssertNotCalled(t, "UseRecoveryCode", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "DisableTwoFactor", mock.Anything, mock.Anything)
+}
+
+func TestTwoFactorService_Disable_WrongPassword(t *testing.T) {
+	ctx := context.Background()
+	twoFactorService, repo, _, guard, secret := setupTest(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	repo.On("GetCredentials", ctx, 8).Return(&models.User{ID: 8, Username: "alice", Password: string(hash)}, nil)
+	guard.On("BlockedFor", ctx, "alice", device).Return(time.Duration(0), nil)
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	guard.On("Fail", ctx, "alice", device).Return(nil)
+
+	// без пароля код из приложения не подходит, если пароль у пользователя есть
+	err = twoFactorService.Disable(ctx, 8, models.TwoFactorReauth{Code: code}, device)
+
+	assert.ErrorIs(t, err, service.ErrInvalidPassword)
+	repo.AssertNotCalled(t, "DisableTwoFactor", mock.Anything, mock.Anything)
+	// неверный пароль считается неудачным входом
+	guard.AssertCalled(t, "Fail", ctx, "alice", device)
+}
+
+func TestTwoFactorService_Disable_Locked(t *testing.T) {
+	ctx := context.Background()
+	twoFactorService, repo, _, guard, secret := setupTest(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	repo.On("GetCredentials", ctx, 8).Return(&models.User{ID: 8, Username: "alice", Password: string(hash)}, nil)
+	guard.On("BlockedFor", ctx, "alice", device).Return(time.Minute, nil)
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	// пока аккаунт заблокирован, не принимается даже верный пароль
+	err = twoFactorService.Disable(ctx, 8, models.TwoFactorReauth{Password: "password1", Code: code}, device)
+
+	var blocked *service.LoginBlockedError
+	assert.ErrorAs(t, err, &blocked)
+	repo.AssertNotCalled(t, "UseStep", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "DisableTwoFactor", mock.Anything, mock.Anything)
+}
+
+func TestTwoFactorService_RegenerateRecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+	twoFactorService, repo, _, guard, secret := setupTest(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	repo.On("GetCredentials", ctx, 8).Return(&models.User{ID: 8, Username: "alice", Password: string(hash)}, nil)
+	guard.On("BlockedFor", ctx, "alice", device).Return(time.Duration(0), nil)
+	repo.On("UseStep", ctx, 8, mock.Anything).Return(true, nil)
+	repo.On("ReplaceRecoveryCodes", ctx, 8, mock.Anything).Return(nil)
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	codes, err := twoFactorService.RegenerateRecoveryCodes(ctx, 8, models.TwoFactorReauth{Password: "password1", Code: code}, device)
+
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	format := regexp.MustCompile(`^[a-hjkmnp-z2-9]{5}-[a-hjkmnp-z2-9]{5}$`)
+	unique := map[string]bool{}
+	for _, recoveryCode := range codes {
+		assert.Regexp(t, format, recoveryCode)
+		unique[recoveryCode] = true
+	}
+	assert.Len(t, unique, 10)
+}
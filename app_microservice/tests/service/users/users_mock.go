@@ -14,13 +14,9 @@ func (m *MockUsersRepository) CreateNewUser(ctx context.Context, user *models.Us
 	return m.Called(ctx, user).Error(0)
 }
 
-func (m *MockUsersRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+func (m *MockUsersRepository) GetUserByID(ctx context.Context, id int) (*models.UserProfile, error) {
 	args := m.Called(ctx, id)
-	return args.Get(0).(*models.User), args.Error(1)
-}
-
-func (m *MockUsersRepository) DeleteUserByID(ctx context.Context, id int) error {
-	return m.Called(ctx, id).Error(0)
+	return args.Get(0).(*models.UserProfile), args.Error(1)
 }
 
 func (m *MockUsersRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
@@ -30,3 +26,7 @@ func (m *MockUsersRepository) GetUserByUsername(ctx context.Context, username st
 func (m *MockUsersRepository) ChangeUsernameByID(ctx context.Context, userID int, newUsername string) error {
 	return m.Called(ctx, userID, newUsername).Error(0)
 }
+
+func (m *MockUsersRepository) UpdatePasswordByID(ctx context.Context, userID int, newPassword string) error {
+	return m.Called(ctx, userID, newPassword).Error(0)
+}
@@ -10,7 +10,7 @@ import (
 
 func setupTest() (*service.UserService, *MockUsersRepository) {
 	mockUserRepository := new(MockUsersRepository)
-	userService := service.NewUserService(mockUserRepository)
+	userService := service.NewUserService(mockUserRepository, nil, nil)
 	return userService, mockUserRepository
 }
 
@@ -65,3 +65,25 @@ func TestUserService_RegisterUser_Error(t *testing.T) {
 
 	mockUserRepository.AssertNotCalled(t, "CreateNewUser")
 }
+
+func TestUserService_UpdatePassword_Weak(t *testing.T) {
+	userService, mockUserRepository := setupTest()
+	ctx := context.Background()
+
+	err := userService.UpdatePassword(ctx, 1, "short")
+
+	assert.ErrorIs(t, err, service.ErrWeakPassword)
+	mockUserRepository.AssertNotCalled(t, "UpdatePasswordByID")
+}
+
+func TestUserService_GetUserByID_NotFound(t *testing.T) {
+	userService, mockUserRepository := setupTest()
+	ctx := context.Background()
+
+	mockUserRepository.On("GetUserByID", ctx, 1).Return((*models.UserProfile)(nil), nil)
+
+	user, err := userService.GetUserByID(ctx, 1)
+
+	assert.Error(t, err)
+	assert.Nil(t, user)
+}
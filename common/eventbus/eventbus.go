@@ -0,0 +1,61 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	KindRabbitMQ = "rabbitmq"
+	KindNATS     = "nats"
+	KindMemory   = "memory"
+)
+
+// Handler обрабатывает одно сообщение из топика. Ошибка означает, что сообщение не обработано.
+//
+// Что будет с необработанным сообщением, зависит от шины:
+//   - rabbitmq - доставка at-least-once: сообщение возвращается в очередь до трех раз,
+//     потом уходит в dead-letter очередь. Обработчик должен быть идемпотентным.
+//   - nats и memory - доставка at-most-once: ошибка только логируется, сообщение теряется.
+//     Сообщение теряется и тогда, когда подписчика нет в момент публикации.
+//
+// Поэтому nats подходит только для событий, потеря которых допустима.
+type Handler func(ctx context.Context, payload []byte) error
+
+// EventPublisher публикует события в топик.
+type EventPublisher interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+}
+
+// EventSubscriber подписывает обработчик на топик. Подписчики с одинаковой группой делят
+// сообщения между собой, с пустой группой - каждый получает свою копию.
+// Подписка живет, пока не отменен ctx или не закрыта шина.
+type EventSubscriber interface {
+	Subscribe(ctx context.Context, topic, group string, handler Handler) error
+}
+
+type Bus interface {
+	EventPublisher
+	EventSubscriber
+	Close() error
+}
+
+type Config struct {
+	Kind     string // rabbitmq, nats или memory
+	URL      string
+	Exchange string // только для rabbitmq
+}
+
+// New создает шину выбранного в конфиге типа
+func New(cfg Config) (Bus, error) {
+	switch cfg.Kind {
+	case KindRabbitMQ, "":
+		return NewRabbitBus(cfg.URL, cfg.Exchange)
+	case KindNATS:
+		return NewNATSBus(cfg.URL)
+	case KindMemory:
+		return NewMemoryBus(), nil
+	default:
+		return nil, fmt.Errorf("unknown event bus kind %q", cfg.Kind)
+	}
+}
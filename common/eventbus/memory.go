@@ -0,0 +1,95 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+type memorySubscription struct {
+	group   string
+	handler Handler
+}
+
+// MemoryBus - шина внутри процесса для тестов и запуска без брокера.
+// Publish доставляет сообщения синхронно.
+type MemoryBus struct {
+	mu     sync.Mutex
+	subs   map[string][]*memorySubscription
+	next   map[string]int // round-robin внутри группы, ключ topic/group
+	closed bool
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		subs: make(map[string][]*memorySubscription),
+		next: make(map[string]int),
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return errors.New("event bus is closed")
+	}
+	var targets []Handler
+	groups := make(map[string][]*memorySubscription)
+	for _, sub := range b.subs[topic] {
+		if sub.group == "" {
+			targets = append(targets, sub.handler)
+			continue
+		}
+		groups[sub.group] = append(groups[sub.group], sub)
+	}
+	for group, members := range groups {
+		key := topic + "/" + group
+		targets = append(targets, members[b.next[key]%len(members)].handler)
+		b.next[key]++
+	}
+	b.mu.Unlock()
+
+	for _, handler := range targets {
+		if err := handler(ctx, payload); err != nil {
+			slog.Error("Memory bus handler error", "topic", topic, "error", err)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return errors.New("event bus is closed")
+	}
+	sub := &memorySubscription{group: group, handler: handler}
+	b.subs[topic] = append(b.subs[topic], sub)
+
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(topic, sub)
+	}()
+	return nil
+}
+
+func (b *MemoryBus) unsubscribe(topic string, sub *memorySubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := b.subs[topic]
+	for i, s := range subs {
+		if s == sub {
+			b.subs[topic] = append(subs[:i:i], subs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.subs = make(map[string][]*memorySubscription)
+	return nil
+}
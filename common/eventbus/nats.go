@@ -0,0 +1,59 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"log/slog"
+)
+
+// NATSBus - шина поверх core NATS, топик используется как subject, группа - как queue group.
+// Доставка at-most-once: сообщения не персистятся, подписчик, которого не было в момент публикации,
+// их не получит, а сообщение, на котором обработчик вернул ошибку, не доставляется повторно.
+type NATSBus struct {
+	conn *nats.Conn
+}
+
+func NewNATSBus(url string) (*NATSBus, error) {
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return &NATSBus{conn: conn}, nil
+}
+
+func (b *NATSBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	return b.conn.Publish(topic, payload)
+}
+
+func (b *NATSBus) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	cb := func(msg *nats.Msg) {
+		if err := handler(ctx, msg.Data); err != nil {
+			slog.Error("NATSBus handler error, message dropped", "topic", topic, "error", err)
+		}
+	}
+
+	var sub *nats.Subscription
+	var err error
+	if group == "" {
+		sub, err = b.conn.Subscribe(topic, cb)
+	} else {
+		sub, err = b.conn.QueueSubscribe(topic, group, cb)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		sub.Unsubscribe()
+	}()
+	return nil
+}
+
+func (b *NATSBus) Close() error {
+	return b.conn.Drain()
+}
@@ -0,0 +1,76 @@
+package events
+
+import "time"
+
+// Топики, через которые сервисы обмениваются событиями
+const (
+	TopicNewLike = "new_like"
+	// TopicNotificationCreated рассылается всем репликам нотификатора для доставки по SSE
+	TopicNotificationCreated = "notification.created"
+	TopicPostCreated         = "post.created"
+	TopicImageUploaded       = "image.uploaded"
+	TopicModerationDecided   = "moderation.decided"
+	TopicUserDeleted         = "user.deleted"
+	TopicExportReady         = "export.ready"
+)
+
+// NewLike публикуется при лайке поста, Liked - владелец поста.
+// EventID не меняется при повторной доставке или публикации того же события,
+// по нему получатели отбрасывают дубли.
+type NewLike struct {
+	EventID string `json:"event_id"`
+	PostID  int    `json:"post_id"`
+	Liker   int    `json:"liker"`
+	Liked   int    `json:"liked"`
+	// имена на момент лайка, чтобы получатель не ходил за ними в приложение
+	LikerName string `json:"liker_name"`
+	PostName  string `json:"post_name"`
+	// PostCreatedAt нужен, чтобы получатель мог заглушить лайки старых постов
+	PostCreatedAt time.Time `json:"post_created_at"`
+}
+
+type PostCreated struct {
+	EventID   string    `json:"event_id"`
+	PostID    int       `json:"post_id"`
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ImageUploaded struct {
+	EventID     string `json:"event_id"`
+	UserID      int    `json:"user_id"`
+	StorageKey  string `json:"storage_key"`
+	Description string `json:"description"`
+}
+
+// ModerationDecided публикуется, когда модератор разобрал жалобы на пост, картинку или пользователя.
+// Владельцу сообщается о скрытии, удалении или блокировке, пожаловавшимся - о решении по жалобе.
+type ModerationDecided struct {
+	EventID    string `json:"event_id"`
+	TargetType string `json:"target_type"` // post, image или user
+	TargetID   string `json:"target_id"`
+	TargetName string `json:"target_name"` // название поста, описание картинки или имя пользователя
+	OwnerID    int    `json:"owner_id"`
+	Action     string `json:"action"` // hide, remove, ban или dismiss
+	// Reporters - пользователи, чьи жалобы закрыты этим решением
+	Reporters []int     `json:"reporters"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// UserDeleted публикуется, когда аккаунт и все данные пользователя в приложении удалены.
+// Другие сервисы удаляют по нему свои данные пользователя.
+type UserDeleted struct {
+	EventID   string    `json:"event_id"`
+	UserID    int       `json:"user_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ExportReady публикуется, когда архив с данными пользователя собран.
+// Ссылка действует до ExpiresAt.
+type ExportReady struct {
+	EventID     string    `json:"event_id"`
+	UserID      int       `json:"user_id"`
+	DownloadURL string    `json:"download_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
@@ -0,0 +1,176 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWK - публичный ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 (OKP)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает публичные ключи набора. Секреты HS256 не публикуются.
+func (ks *KeySet) JWKS() JWKS {
+	result := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		jwk, ok := toJWK(key)
+		if ok {
+			result.Keys = append(result.Keys, jwk)
+		}
+	}
+	return result
+}
+
+// JWKSHandler отдает JWKS для сервисов, которые проверяют токены без общего секрета
+func (ks *KeySet) JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(ks.JWKS())
+	})
+}
+
+func toJWK(key *Key) (JWK, bool) {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch public := key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", Kid: key.ID, Alg: key.Alg, Use: "sig",
+			N: encode(public.N.Bytes()), E: encode(big.NewInt(int64(public.E)).Bytes())}, true
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Kid: key.ID, Alg: key.Alg, Use: "sig", Crv: "Ed25519", X: encode(public)}, true
+	}
+	return JWK{}, false
+}
+
+func fromJWK(jwk JWK) (*Key, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch {
+	case jwk.Kty == "RSA" && jwk.Alg == AlgRS256:
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return &Key{ID: jwk.Kid, Alg: jwk.Alg, verifyKey: public}, nil
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519" && jwk.Alg == AlgEdDSA:
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return &Key{ID: jwk.Kid, Alg: jwk.Alg, verifyKey: ed25519.PublicKey(x)}, nil
+	}
+	return nil, fmt.Errorf("unsupported JWK %s/%s", jwk.Kty, jwk.Alg)
+}
+
+// minRefreshInterval - не чаще этого RemoteKeySet перечитывает JWKS из-за незнакомого kid
+const minRefreshInterval = 30 * time.Second
+
+// RemoteKeySet проверяет токены ключами из JWKS другого сервиса.
+// Ключи кешируются и перечитываются, когда приходит токен с незнакомым kid (ротация).
+type RemoteKeySet struct {
+	url        string
+	issuer     string
+	audience   string
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]*Key
+	lastRefresh time.Time
+}
+
+func NewRemoteKeySet(url, issuer, audience string) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:        url,
+		issuer:     issuer,
+		audience:   audience,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		keys:       map[string]*Key{},
+	}
+}
+
+func (rk *RemoteKeySet) Verify(tokenString string) (jwt.MapClaims, error) {
+	return verify(tokenString, rk.issuer, rk.audience, rk.key)
+}
+
+func (rk *RemoteKeySet) key(kid string) (*Key, error) {
+	rk.mu.Lock()
+	defer rk.mu.Unlock()
+	if key, ok := rk.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(rk.lastRefresh) < minRefreshInterval {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if err := rk.refresh(); err != nil {
+		return nil, err
+	}
+	if key, ok := rk.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// refresh перечитывает JWKS, вызывается под mu
+func (rk *RemoteKeySet) refresh() error {
+	rk.lastRefresh = time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rk.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := rk.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var jwks JWKS
+	if err = json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+	keys := make(map[string]*Key, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		key, err := fromJWK(jwk)
+		if err != nil {
+			continue
+		}
+		keys[key.ID] = key
+	}
+	rk.keys = keys
+	return nil
+}
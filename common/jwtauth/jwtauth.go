@@ -0,0 +1,42 @@
+package jwtauth
+
+import (
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"strings"
+)
+
+// CookieName - кука, в которой приложение выдает токен пользователю
+const CookieName = "user-cookie"
+
+// Verifier проверяет подпись и claims токена
+type Verifier interface {
+	Verify(tokenString string) (jwt.MapClaims, error)
+}
+
+// UserID достает id пользователя из claim sub
+func UserID(claims jwt.MapClaims) (int, error) {
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return 0, errors.New("sub claim is missing or invalid")
+	}
+	return int(sub), nil
+}
+
+// TokenFromRequest достает токен пользователя из заголовка Authorization: Bearer,
+// а если его нет - из куки запроса
+func TokenFromRequest(r *http.Request) (string, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return "", errors.New("malformed authorization header")
+		}
+		return strings.TrimSpace(token), nil
+	}
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return "", err
+	}
+	return cookie.Value, nil
+}
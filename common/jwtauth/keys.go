@@ -0,0 +1,278 @@
+package jwtauth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Поддерживаемые алгоритмы подписи
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// leeway - допустимое расхождение часов сервисов при проверке exp и iat
+const leeway = 10 * time.Second
+
+// Key - ключ с идентификатором kid. Для HS256 signKey и verifyKey - один и тот же секрет,
+// у ключей, которые только проверяют подпись, signKey пустой.
+type Key struct {
+	ID        string
+	Alg       string
+	signKey   any
+	verifyKey any
+}
+
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case AlgHS256:
+		return jwt.SigningMethodHS256, nil
+	case AlgRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+}
+
+// VerifyKeyFile - ключ, которым подписаны еще действующие токены. Для RS256 и EdDSA это PEM
+// с публичным ключом, для HS256 - файл с секретом. Пустой Alg - алгоритм текущего ключа Config.Alg.
+type VerifyKeyFile struct {
+	Alg  string
+	Path string
+}
+
+// Config - откуда брать ключи и что проверять в токенах
+type Config struct {
+	Alg   string
+	KeyID string
+	// Secret - секрет для HS256
+	Secret string
+	// PrivateKeyFile - PEM с приватным ключом для RS256 и EdDSA
+	PrivateKeyFile string
+	// VerifyKeyFiles - kid -> ключ, которым подписаны еще действующие токены (ротация).
+	// У каждого ключа свой алгоритм, так что можно сменить, например, HS256 на EdDSA без выхода всех пользователей.
+	VerifyKeyFiles map[string]VerifyKeyFile
+	Issuer         string
+	Audience       string
+	// EphemeralKey разрешает без PrivateKeyFile подписывать временным ключом, только для разработки:
+	// после рестарта выданные токены перестают проверяться
+	EphemeralKey bool
+}
+
+// KeySet подписывает токены текущим ключом и проверяет их всеми известными
+type KeySet struct {
+	signing  *Key
+	keys     map[string]*Key
+	issuer   string
+	audience string
+}
+
+func NewKeySet(cfg Config) (*KeySet, error) {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("issuer and audience are required")
+	}
+	if cfg.KeyID == "" {
+		return nil, errors.New("key id is required")
+	}
+	if _, err := signingMethod(cfg.Alg); err != nil {
+		return nil, err
+	}
+
+	signing, err := loadSigningKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ks := &KeySet{signing: signing, keys: map[string]*Key{signing.ID: signing}, issuer: cfg.Issuer, audience: cfg.Audience}
+
+	for kid, file := range cfg.VerifyKeyFiles {
+		if _, exists := ks.keys[kid]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", kid)
+		}
+		alg := file.Alg
+		if alg == "" {
+			alg = cfg.Alg
+		}
+		if _, err = signingMethod(alg); err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+		key, err := loadVerifyKey(kid, alg, file.Path)
+		if err != nil {
+			return nil, err
+		}
+		ks.keys[kid] = key
+	}
+	return ks, nil
+}
+
+func loadSigningKey(cfg Config) (*Key, error) {
+	if cfg.Alg == AlgHS256 {
+		if len(cfg.Secret) < 32 {
+			return nil, errors.New("HS256 secret must be at least 32 bytes")
+		}
+		return &Key{ID: cfg.KeyID, Alg: cfg.Alg, signKey: []byte(cfg.Secret), verifyKey: []byte(cfg.Secret)}, nil
+	}
+
+	if cfg.PrivateKeyFile == "" {
+		if !cfg.EphemeralKey {
+			return nil, fmt.Errorf("private key file is required for %s", cfg.Alg)
+		}
+		slog.Warn("JWT private key is not configured, generating an ephemeral key", "alg", cfg.Alg, "kid", cfg.KeyID)
+		return generateKey(cfg.KeyID, cfg.Alg)
+	}
+	data, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	private, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return newAsymmetricKey(cfg.KeyID, cfg.Alg, private)
+}
+
+func loadVerifyKey(kid, alg, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %q: %w", kid, err)
+	}
+	if alg == AlgHS256 {
+		return &Key{ID: kid, Alg: alg, verifyKey: data}, nil
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q is not PEM encoded", kid)
+	}
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %q: %w", kid, err)
+	}
+	if err = checkKeyType(alg, public); err != nil {
+		return nil, fmt.Errorf("key %q: %w", kid, err)
+	}
+	return &Key{ID: kid, Alg: alg, verifyKey: public}, nil
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		return signer, nil
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return key, nil
+}
+
+func generateKey(kid, alg string) (*Key, error) {
+	var private crypto.Signer
+	var err error
+	if alg == AlgRS256 {
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	} else {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newAsymmetricKey(kid, alg, private)
+}
+
+func newAsymmetricKey(kid, alg string, private crypto.Signer) (*Key, error) {
+	public := private.Public()
+	if err := checkKeyType(alg, public); err != nil {
+		return nil, err
+	}
+	return &Key{ID: kid, Alg: alg, signKey: private, verifyKey: public}, nil
+}
+
+func checkKeyType(alg string, public crypto.PublicKey) error {
+	switch public.(type) {
+	case *rsa.PublicKey:
+		if alg == AlgRS256 {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if alg == AlgEdDSA {
+			return nil
+		}
+	}
+	return fmt.Errorf("key type %T does not match algorithm %s", public, alg)
+}
+
+// Sign подписывает claims текущим ключом, добавляя iss, aud и kid
+func (ks *KeySet) Sign(claims jwt.MapClaims) (string, error) {
+	method, err := signingMethod(ks.signing.Alg)
+	if err != nil {
+		return "", err
+	}
+	claims["iss"] = ks.issuer
+	claims["aud"] = ks.audience
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.signKey)
+}
+
+func (ks *KeySet) Verify(tokenString string) (jwt.MapClaims, error) {
+	return verify(tokenString, ks.issuer, ks.audience, func(kid string) (*Key, error) {
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return key, nil
+	})
+}
+
+// verify строго проверяет токен: известный kid, алгоритм ключа, подпись, iss, aud, exp и iat
+func verify(tokenString, issuer, audience string, lookup func(kid string) (*Key, error)) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(leeway),
+	)
+	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok || kid == "" {
+			return nil, errors.New("kid header is missing")
+		}
+		key, err := lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		// алгоритм берется из ключа, а не из заголовка токена
+		if token.Method.Alg() != key.Alg {
+			return nil, fmt.Errorf("token algorithm %s does not match key %q", token.Method.Alg(), kid)
+		}
+		return key.verifyKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := claims["iat"]; !ok {
+		return nil, errors.New("iat claim is missing")
+	}
+	return claims, nil
+}
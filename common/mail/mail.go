@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	KindSMTP   = "smtp"
+	KindFile   = "file"
+	KindMemory = "memory"
+)
+
+// Message - письмо с текстовой и HTML версиями, HTML может быть пустым
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender отправляет письма
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	Kind     string // smtp, file или memory
+	From     string
+	SMTPAddr string // host:port
+	Username string
+	Password string
+	Dir      string // куда складывать письма для file
+}
+
+// New создает отправителя выбранного в конфиге типа
+func New(cfg Config) (Sender, error) {
+	switch cfg.Kind {
+	case KindSMTP:
+		return NewSMTPSender(cfg.SMTPAddr, cfg.Username, cfg.Password, cfg.From), nil
+	case KindFile, "":
+		return NewFileSender(cfg.Dir, cfg.From)
+	case KindMemory:
+		return NewMemorySender(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail sender kind %q", cfg.Kind)
+	}
+}
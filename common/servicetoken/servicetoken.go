@@ -0,0 +1,31 @@
+package servicetoken
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// Header - заголовок, в котором сервисы передают друг другу общий токен
+const Header = "X-Service-Token"
+
+// Valid проверяет сервисный токен запроса. Пустой expected означает, что сервисный доступ выключен.
+func Valid(r *http.Request, expected string) bool {
+	token := r.Header.Get(Header)
+	if expected == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// Middleware пропускает только запросы с верным сервисным токеном
+func Middleware(expected string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Valid(r, expected) {
+				http.Error(w, "Unauthorized: Invalid service token", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
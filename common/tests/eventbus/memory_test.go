@@ -0,0 +1,46 @@
+package eventbus
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"pictureloader/common/eventbus"
+	"testing"
+)
+
+func TestMemoryBus_Groups(t *testing.T) {
+	ctx := context.Background()
+	bus := eventbus.NewMemoryBus()
+
+	counts := make(map[string]int)
+	subscribe := func(name, group string) {
+		err := bus.Subscribe(ctx, "topic", group, func(ctx context.Context, payload []byte) error {
+			counts[name]++
+			return nil
+		})
+		assert.NoError(t, err)
+	}
+	subscribe("replica1", "notifications")
+	subscribe("replica2", "notifications")
+	subscribe("broadcast1", "")
+	subscribe("broadcast2", "")
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, bus.Publish(ctx, "topic", []byte("{}")))
+	}
+
+	// реплики одной группы делят сообщения, подписчики без группы получают все
+	assert.Equal(t, 2, counts["replica1"])
+	assert.Equal(t, 2, counts["replica2"])
+	assert.Equal(t, 4, counts["broadcast1"])
+	assert.Equal(t, 4, counts["broadcast2"])
+}
+
+func TestMemoryBus_Close(t *testing.T) {
+	bus := eventbus.NewMemoryBus()
+	assert.NoError(t, bus.Close())
+
+	assert.Error(t, bus.Publish(context.Background(), "topic", nil))
+	assert.Error(t, bus.Subscribe(context.Background(), "topic", "", func(ctx context.Context, payload []byte) error {
+		return nil
+	}))
+}
@@ -0,0 +1,48 @@
+package eventbus
+
+import (
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pictureloader/common/eventbus"
+	"testing"
+)
+
+func TestRetryHeaders_CountsAttempts(t *testing.T) {
+	headers := amqp091.Table{"trace-id": "abc"}
+
+	// три возврата в очередь, четвертая ошибка отправляет сообщение в dead-letter
+	for attempt := int32(1); attempt <= 3; attempt++ {
+		next, ok := eventbus.RetryHeaders(headers)
+		require.True(t, ok, "attempt %d", attempt)
+		assert.Equal(t, attempt, next["x-retry-count"])
+		assert.Equal(t, "abc", next["trace-id"])
+		headers = next
+	}
+	_, ok := eventbus.RetryHeaders(headers)
+	assert.False(t, ok)
+}
+
+func TestRetryHeaders_DoesNotModifyDelivery(t *testing.T) {
+	headers := amqp091.Table{"x-retry-count": int32(1)}
+
+	next, ok := eventbus.RetryHeaders(headers)
+
+	require.True(t, ok)
+	assert.Equal(t, int32(2), next["x-retry-count"])
+	assert.Equal(t, int32(1), headers["x-retry-count"])
+}
+
+func TestRetryHeaders_OtherIntegerTypes(t *testing.T) {
+	// счетчик, записанный другим клиентом как int64, тоже учитывается
+	_, ok := eventbus.RetryHeaders(amqp091.Table{"x-retry-count": int64(3)})
+	assert.False(t, ok)
+
+	next, ok := eventbus.RetryHeaders(amqp091.Table{"x-retry-count": int64(2)})
+	require.True(t, ok)
+	assert.Equal(t, int32(3), next["x-retry-count"])
+
+	// непонятное значение не должно гонять сообщение по кругу
+	_, ok = eventbus.RetryHeaders(amqp091.Table{"x-retry-count": "2"})
+	assert.False(t, ok)
+}
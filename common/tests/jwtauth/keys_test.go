@@ -0,0 +1,145 @@
+package jwtauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"pictureloader/common/jwtauth"
+	"testing"
+	"time"
+)
+
+func newClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{"sub": 7, "iat": now.Unix(), "exp": now.Add(time.Minute).Unix()}
+}
+
+// writeEd25519Key сохраняет новый ключ в PEM и возвращает пути к приватному и публичному файлам
+func writeEd25519Key(t *testing.T, dir, name string) (string, string) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	privatePath := filepath.Join(dir, name+".pem")
+	publicPath := filepath.Join(dir, name+".pub.pem")
+	require.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600))
+	require.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600))
+	return privatePath, publicPath
+}
+
+func TestKeySet_RotationAndRemoteVerification(t *testing.T) {
+	dir := t.TempDir()
+	oldPrivate, oldPublic := writeEd25519Key(t, dir, "old")
+	newPrivate, _ := writeEd25519Key(t, dir, "new")
+
+	oldKeys, err := jwtauth.NewKeySet(jwtauth.Config{Alg: jwtauth.AlgEdDSA, KeyID: "old", PrivateKeyFile: oldPrivate,
+		Issuer: "app", Audience: "app"})
+	require.NoError(t, err)
+	oldToken, err := oldKeys.Sign(newClaims())
+	require.NoError(t, err)
+
+	// после ротации новым ключом подписываются новые токены, старые еще проверяются
+	keys, err := jwtauth.NewKeySet(jwtauth.Config{Alg: jwtauth.AlgEdDSA, KeyID: "new", PrivateKeyFile: newPrivate,
+		VerifyKeyFiles: map[string]jwtauth.VerifyKeyFile{"old": {Path: oldPublic}}, Issuer: "app", Audience: "app"})
+	require.NoError(t, err)
+	newToken, err := keys.Sign(newClaims())
+	require.NoError(t, err)
+
+	server := httptest.NewServer(keys.JWKSHandler())
+	defer server.Close()
+	remote := jwtauth.NewRemoteKeySet(server.URL, "app", "app")
+
+	for _, token := range []string{oldToken, newToken} {
+		claims, err := remote.Verify(token)
+		require.NoError(t, err)
+		userID, err := jwtauth.UserID(claims)
+		require.NoError(t, err)
+		assert.Equal(t, 7, userID)
+	}
+}
+
+func TestKeySet_AlgorithmRotation(t *testing.T) {
+	dir := t.TempDir()
+	secret := "0123456789abcdef0123456789abcdef"
+	secretPath := filepath.Join(dir, "old.secret")
+	require.NoError(t, os.WriteFile(secretPath, []byte(secret), 0o600))
+	newPrivate, _ := writeEd25519Key(t, dir, "new")
+
+	oldKeys, err := jwtauth.NewKeySet(jwtauth.Config{Alg: jwtauth.AlgHS256, KeyID: "old", Secret: secret,
+		Issuer: "app", Audience: "app"})
+	require.NoError(t, err)
+	oldToken, err := oldKeys.Sign(newClaims())
+	require.NoError(t, err)
+
+	// подпись переходит с HS256 на EdDSA, токены со старым секретом проверяются до истечения
+	keys, err := jwtauth.NewKeySet(jwtauth.Config{Alg: jwtauth.AlgEdDSA, KeyID: "new", Issuer: "app", Audience: "app",
+		PrivateKeyFile: newPrivate,
+		VerifyKeyFiles: map[string]jwtauth.VerifyKeyFile{"old": {Alg: jwtauth.AlgHS256, Path: secretPath}}})
+	require.NoError(t, err)
+	newToken, err := keys.Sign(newClaims())
+	require.NoError(t, err)
+
+	for _, token := range []string{oldToken, newToken} {
+		_, err = keys.Verify(token)
+		assert.NoError(t, err)
+	}
+
+	// старый секрет нельзя выдать за ключ EdDSA
+	forged, err := jwtauth.NewKeySet(jwtauth.Config{Alg: jwtauth.AlgHS256, KeyID: "new", Secret: secret,
+		Issuer: "app", Audience: "app"})
+	require.NoError(t, err)
+	forgedToken, err := forged.Sign(newClaims())
+	require.NoError(t, err)
+	_, err = keys.Verify(forgedToken)
+	assert.Error(t, err)
+}
+
+func TestKeySet_RequiresPrivateKey(t *testing.T) {
+	_, err := jwtauth.NewKeySet(jwtauth.Config{Alg: jwtauth.AlgEdDSA, KeyID: "k1", Issuer: "app", Audience: "app"})
+	assert.Error(t, err)
+}
+
+func TestKeySet_StrictClaims(t *testing.T) {
+	keys, err := jwtauth.NewKeySet(jwtauth.Config{Alg: jwtauth.AlgRS256, KeyID: "k1", Issuer: "app", Audience: "app",
+		EphemeralKey: true})
+	require.NoError(t, err)
+	otherAudience, err := jwtauth.NewKeySet(jwtauth.Config{Alg: jwtauth.AlgRS256, KeyID: "k1", Issuer: "app", Audience: "other",
+		EphemeralKey: true})
+	require.NoError(t, err)
+
+	valid, err := keys.Sign(newClaims())
+	require.NoError(t, err)
+	_, err = keys.Verify(valid)
+	assert.NoError(t, err)
+
+	expired := newClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	noExp := newClaims()
+	delete(noExp, "exp")
+	noIat := newClaims()
+	delete(noIat, "iat")
+	futureIat := newClaims()
+	futureIat["iat"] = time.Now().Add(time.Hour).Unix()
+
+	for name, claims := range map[string]jwt.MapClaims{"expired": expired, "no exp": noExp, "no iat": noIat, "future iat": futureIat} {
+		token, err := keys.Sign(claims)
+		require.NoError(t, err)
+		_, err = keys.Verify(token)
+		assert.Error(t, err, name)
+	}
+
+	wrongAudience, err := otherAudience.Sign(newClaims())
+	require.NoError(t, err)
+	_, err = keys.Verify(wrongAudience)
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"path/filepath"
+	"pictureloader/common/mail"
+	"strings"
+	"testing"
+)
+
+// startSMTPStandIn поднимает минимальный SMTP сервер, который принимает одно письмо
+func startSMTPStandIn(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP stand-in")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					received <- data.String()
+					reply("250 OK")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				inData = true
+				reply("354 End data with <CR><LF>.<CR><LF>")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	addr, received := startSMTPStandIn(t)
+	sender := mail.NewSMTPSender(addr, "", "", "noreply@imgur.local")
+
+	err := sender.Send(context.Background(), mail.Message{
+		To:      "vaflya@gmail.com",
+		Subject: "Новый лайк",
+		Text:    "User 5 liked your post",
+		HTML:    "<p>User 5 liked your post</p>",
+	})
+	require.NoError(t, err)
+
+	body := <-received
+	assert.Contains(t, body, "To: vaflya@gmail.com")
+	assert.Contains(t, body, "Subject: =?utf-8?q?")
+	assert.Contains(t, body, "Content-Type: multipart/alternative")
+	assert.Contains(t, body, "User 5 liked your post")
+	assert.Contains(t, body, "<p>User 5 liked your post</p>")
+}
+
+func TestFileSender_Send(t *testing.T) {
+	dir := t.TempDir()
+	sender, err := mail.NewFileSender(dir, "noreply@imgur.local")
+	require.NoError(t, err)
+
+	err = sender.Send(context.Background(), mail.Message{To: "vaflya@gmail.com", Subject: "Digest", Text: "hello"})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "Subject: Digest")
+	assert.Contains(t, string(content), "hello")
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"pictureloader/common/jwtauth"
+	"pictureloader/common/servicetoken"
+)
+
+// Caller - тот, кто выполняет запрос: пользователь или внутренний сервис
+type Caller struct {
+	UserID  int
+	Service bool
+}
+
+// CanAccessUser проверяет, может ли вызывающий читать данные пользователя userID
+func (c Caller) CanAccessUser(userID int) bool {
+	return c.Service || c.UserID == userID
+}
+
+type callerKey struct{}
+
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+type Middleware struct {
+	serviceToken string
+	verifier     jwtauth.Verifier
+}
+
+// NewMiddleware создает middleware аутентификации. Пустой serviceToken отключает сервисный доступ.
+func NewMiddleware(serviceToken string, verifier jwtauth.Verifier) *Middleware {
+	return &Middleware{serviceToken: serviceToken, verifier: verifier}
+}
+
+func (m *Middleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(servicetoken.Header) != "" {
+			if !servicetoken.Valid(r, m.serviceToken) {
+				http.Error(w, "Unauthorized: Invalid service token", http.StatusUnauthorized)
+				return
+			}
+			ctx := context.WithValue(r.Context(), callerKey{}, Caller{Service: true})
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		tokenString, err := jwtauth.TokenFromRequest(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: No token provided", http.StatusUnauthorized)
+			return
+		}
+		claims, err := m.verifier.Verify(tokenString)
+		if err != nil {
+			http.Error(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
+			return
+		}
+		userID, err := jwtauth.UserID(claims)
+		if err != nil {
+			http.Error(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), callerKey{}, Caller{UserID: userID})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// CurrentUser возвращает id пользователя, выполняющего запрос. Ручки "для себя" сервисам недоступны.
+func CurrentUser(w http.ResponseWriter, r *http.Request) (int, bool) {
+	caller, _ := CallerFromContext(r.Context())
+	if caller.Service {
+		http.Error(w, "Service callers must specify user ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return caller.UserID, true
+}
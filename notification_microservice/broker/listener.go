@@ -0,0 +1,87 @@
+package broker
+
+import (
+	"context"
+	"log/slog"
+	"pictureloader/common/eventbus"
+	"pictureloader/common/events"
+	"pictureloader/notification_microservice/notifications/accounts"
+	"pictureloader/notification_microservice/notifications/likes"
+	"pictureloader/notification_microservice/notifications/moderation"
+	"pictureloader/notification_microservice/notifications/webhooks"
+)
+
+// Группы подписчиков, реплики нотификатора делят сообщения между собой.
+// У вебхуков своя группа, чтобы получать свою копию лайков.
+const (
+	group         = "notifications"
+	webhooksGroup = "webhooks"
+)
+
+type Listener struct {
+	bus               eventbus.EventSubscriber
+	notifService      *likes.NotificationService
+	webhookService    *webhooks.Service
+	moderationService *moderation.Service
+	accountsService   *accounts.Service
+}
+
+func NewListener(bus eventbus.EventSubscriber, notifService *likes.NotificationService,
+	webhookService *webhooks.Service, moderationService *moderation.Service, accountsService *accounts.Service) *Listener {
+	return &Listener{bus: bus, notifService: notifService, webhookService: webhookService,
+		moderationService: moderationService, accountsService: accountsService}
+}
+
+func (l *Listener) ListenLikes(ctx context.Context) error {
+	return l.bus.Subscribe(ctx, events.TopicNewLike, group, func(ctx context.Context, payload []byte) error {
+		slog.Info("Received a like message", "message body", payload)
+		err := l.notifService.ProcessLikeMessage(ctx, payload)
+		if err != nil {
+			slog.Error("Failed to process like messages", "error", err)
+		}
+		return err
+	})
+}
+
+// ListenModeration подписывается на решения модераторов, чтобы сообщить о них по почте
+func (l *Listener) ListenModeration(ctx context.Context) error {
+	return l.bus.Subscribe(ctx, events.TopicModerationDecided, group, func(ctx context.Context, payload []byte) error {
+		err := l.moderationService.ProcessDecisionMessage(ctx, payload)
+		if err != nil {
+			slog.Error("Failed to process moderation messages", "error", err)
+		}
+		return err
+	})
+}
+
+// ListenAccounts подписывается на удаление аккаунтов, чтобы удалить данные пользователя,
+// и на готовые выгрузки данных, чтобы прислать ссылку на архив
+func (l *Listener) ListenAccounts(ctx context.Context) error {
+	err := l.bus.Subscribe(ctx, events.TopicUserDeleted, group, l.accountsService.ProcessUserDeletedMessage)
+	if err != nil {
+		return err
+	}
+	return l.bus.Subscribe(ctx, events.TopicExportReady, group, l.accountsService.ProcessExportReadyMessage)
+}
+
+// ListenWebhooks подписывает вебхуки на события аккаунтов
+func (l *Listener) ListenWebhooks(ctx context.Context) error {
+	handlers := map[string]eventbus.Handler{
+		events.TopicNewLike:       l.webhookService.ProcessLikeMessage,
+		events.TopicPostCreated:   l.webhookService.ProcessPostCreatedMessage,
+		events.TopicImageUploaded: l.webhookService.ProcessImageUploadedMessage,
+	}
+	for topic, handler := range handlers {
+		err := l.bus.Subscribe(ctx, topic, webhooksGroup, func(ctx context.Context, payload []byte) error {
+			err := handler(ctx, payload)
+			if err != nil {
+				slog.Error("Failed to enqueue webhook deliveries", "topic", topic, "error", err)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
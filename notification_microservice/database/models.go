@@ -1,7 +1,81 @@
 package database
 
+import "time"
+
+// LikesNotification - группа лайков одного поста: пока группа не прочитана и не вышла
+// за окно агрегации, новые лайки обновляют ее, а не создают новую строку
 type LikesNotification struct {
-	PostID int
-	Liker  int
-	Liked  int
+	ID         int `gorm:"primaryKey;autoIncrement"`
+	PostID     int
+	PostName   string
+	Liker      int   // последний лайкнувший
+	Liked      int   `gorm:"index"`
+	ActorCount int   `gorm:"default:1"`                  // число разных лайкнувших, все они есть в LikeGroupActor
+	Actors     []int `gorm:"serializer:json;type:jsonb"` // несколько последних лайкнувших, новые первыми
+	// ActorNames - имена пользователей из Actors на момент лайка
+	ActorNames map[int]string `gorm:"serializer:json;type:jsonb"`
+	// EmailOnly - группа сохранена только для писем и дайджеста: в списке, счетчике и стриме ее нет
+	EmailOnly bool `gorm:"not null;default:false"`
+	// EventSeq - номер последнего изменения группы из likes_notifications_event_seq, id события стрима
+	EventSeq  int64 `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ReadAt    *time.Time
+}
+
+// LikeGroupActor - пользователь, лайкнувший пост группы. Повторный лайк того же пользователя
+// (например, после снятия лайка) не увеличивает ActorCount группы.
+type LikeGroupActor struct {
+	GroupID int               `gorm:"primaryKey;autoIncrement:false"`
+	ActorID int               `gorm:"primaryKey;autoIncrement:false"`
+	Group   LikesNotification `gorm:"foreignKey:GroupID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// NotificationPreference - настройки уведомлений пользователя
+type NotificationPreference struct {
+	UserID                 int                 `gorm:"primaryKey;autoIncrement:false"`
+	Channels               map[string][]string `gorm:"serializer:json;type:jsonb"` // тип события -> включенные каналы
+	QuietHoursStart        *int
+	QuietHoursEnd          *int
+	Timezone               string `gorm:"default:UTC"`
+	DigestFrequency        string `gorm:"default:none"`
+	MuteLikesOlderThanDays int
+	MutedPosts             []int  `gorm:"serializer:json;type:jsonb"`
+	Locale                 string // пустой - язык берется из Accept-Language
+	LastDigestAt           *time.Time
+	UpdatedAt              time.Time
+}
+
+// Webhook - адрес пользователя, на который отправляются события его аккаунта
+type Webhook struct {
+	ID        int      `gorm:"primaryKey;autoIncrement"`
+	UserID    int      `gorm:"index"`
+	URL       string   `gorm:"not null"`
+	Secret    string   `gorm:"not null"`                   // ключ HMAC подписи
+	Events    []string `gorm:"serializer:json;type:jsonb"` // типы событий, на которые подписан вебхук
+	CreatedAt time.Time
+}
+
+// WebhookDelivery - одна доставка события на вебхук, она же запись в журнале доставок
+type WebhookDelivery struct {
+	ID             int    `gorm:"primaryKey;autoIncrement"`
+	WebhookID      int    `gorm:"index"`
+	EventID        string `gorm:"index"`
+	EventType      string
+	Payload        []byte    `gorm:"type:jsonb"`
+	Status         string    `gorm:"index"` // pending, succeeded или failed
+	Attempts       int       `gorm:"default:0"`
+	NextAttemptAt  time.Time `gorm:"index"`
+	LastStatusCode int       // код ответа последней попытки, 0 если ответа не было
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// ProcessedEvent - событие из шины, которое потребитель уже обработал
+type ProcessedEvent struct {
+	Consumer    string    `gorm:"primaryKey"`
+	EventID     string    `gorm:"primaryKey"`
+	ProcessedAt time.Time `gorm:"index;autoCreateTime"`
 }
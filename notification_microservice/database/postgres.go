@@ -4,14 +4,44 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
+	"time"
 )
 
 func NewPSQL(dbPath string) *gorm.DB {
-	database, err := gorm.Open(postgres.Open(dbPath), &gorm.Config{})
+	database, err := gorm.Open(postgres.Open(dbPath), &gorm.Config{
+		// postgres хранит время с точностью до микросекунд, время в ответах совпадает с сохраненным
+		NowFunc: func() time.Time { return time.Now().Truncate(time.Microsecond) },
+	})
 	if err != nil {
 		log.Fatalln(err)
 	}
-	err = database.AutoMigrate(&LikesNotification{})
+	err = database.Exec(`CREATE SEQUENCE IF NOT EXISTS likes_notifications_event_seq`).Error
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = database.AutoMigrate(&LikesNotification{}, &LikeGroupActor{}, &NotificationPreference{}, &Webhook{},
+		&WebhookDelivery{}, &ProcessedEvent{})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// уведомления, созданные до агрегации, становятся группами из одного лайка
+	err = database.Exec(`UPDATE likes_notifications SET actors = jsonb_build_array(liker) WHERE actors IS NULL`).Error
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// группы, созданные до последовательности, получают номера в порядке изменения
+	err = database.Exec(`UPDATE likes_notifications SET event_seq = s.seq
+		FROM (SELECT id, nextval('likes_notifications_event_seq') AS seq FROM
+			(SELECT id FROM likes_notifications WHERE event_seq IS NULL ORDER BY updated_at, id) ordered) s
+		WHERE likes_notifications.id = s.id`).Error
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// у групп, созданных до таблицы лайкнувших, известны только последние из них
+	err = database.Exec(`INSERT INTO like_group_actors (group_id, actor_id)
+		SELECT n.id, a.actor::int FROM likes_notifications n, jsonb_array_elements_text(n.actors) AS a(actor)
+		WHERE NOT EXISTS (SELECT 1 FROM like_group_actors g WHERE g.group_id = n.id)
+		ON CONFLICT DO NOTHING`).Error
 	if err != nil {
 		log.Fatalln(err)
 	}
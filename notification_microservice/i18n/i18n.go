@@ -0,0 +1,97 @@
+package i18n
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	English = "en"
+	Russian = "ru"
+
+	// Default - язык, если клиент и настройки пользователя его не задают
+	Default = Russian
+)
+
+var Supported = []string{English, Russian}
+
+// IsSupported проверяет, есть ли переводы для языка
+func IsSupported(locale string) bool {
+	return slices.Contains(Supported, locale)
+}
+
+// Negotiate выбирает поддерживаемый язык из заголовка Accept-Language с учетом q-весов
+func Negotiate(acceptLanguage string) string {
+	best, bestWeight := Default, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		// en-US -> en, берется только основной язык
+		language, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if IsSupported(language) && weight > bestWeight {
+			best, bestWeight = language, weight
+		}
+	}
+	return best
+}
+
+// Resolve возвращает язык из настроек пользователя, а если он не задан - из Accept-Language
+func Resolve(preferred, acceptLanguage string) string {
+	if IsSupported(preferred) {
+		return preferred
+	}
+	return Negotiate(acceptLanguage)
+}
+
+var funcs = template.FuncMap{
+	"plural": plural,
+}
+
+var catalog = map[string]*template.Template{}
+
+func init() {
+	for locale, entries := range messages {
+		for key, text := range entries {
+			catalog[locale+"/"+key] = template.Must(template.New(key).Funcs(funcs).Parse(text))
+		}
+	}
+}
+
+// Render рендерит сообщение key на языке locale, неизвестный язык заменяется на Default
+func Render(locale, key string, data any) (string, error) {
+	if !IsSupported(locale) {
+		locale = Default
+	}
+	tmpl, ok := catalog[locale+"/"+key]
+	if !ok {
+		return "", fmt.Errorf("no message %q for locale %q", key, locale)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// plural выбирает форму слова по правилам русского языка: one для 1, 21..., few для 2-4, 22-24...,
+// many для остальных
+func plural(n int, one, few, many string) string {
+	if n%10 == 1 && n%100 != 11 {
+		return one
+	}
+	if n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) {
+		return few
+	}
+	return many
+}
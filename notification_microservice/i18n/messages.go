@@ -0,0 +1,54 @@
+package i18n
+
+// Ключи сообщений
+const (
+	LikeSingle    = "like.single"
+	LikeMany      = "like.many"
+	LikeSubject   = "email.like.subject"
+	DigestSubject = "email.digest.subject"
+	DigestDaily   = "digest.daily"
+	DigestWeekly  = "digest.weekly"
+
+	ModerationOwner           = "moderation.owner"
+	ModerationReporter        = "moderation.reporter"
+	ModerationOwnerSubject    = "email.moderation.owner.subject"
+	ModerationReporterSubject = "email.moderation.reporter.subject"
+
+	ExportReadySubject = "email.export.subject"
+)
+
+// messages - шаблоны сообщений по языкам. Данные для лайков: Actor и ActorID - последний лайкнувший,
+// Others - сколько еще людей лайкнули, Post и PostID - пост. Пустое имя заменяется на id.
+// Данные для модерации: Type - post, image или user, Name - название, Action - hide, unhide, remove, ban или dismiss.
+var messages = map[string]map[string]string{
+	English: {
+		LikeSingle:    `{{if .Actor}}{{.Actor}}{{else}}User #{{.ActorID}}{{end}} liked your post {{if .Post}}“{{.Post}}”{{else}}#{{.PostID}}{{end}}`,
+		LikeMany:      `{{if .Actor}}{{.Actor}}{{else}}User #{{.ActorID}}{{end}} and {{.Others}} {{if eq .Others 1}}other{{else}}others{{end}} liked your post {{if .Post}}“{{.Post}}”{{else}}#{{.PostID}}{{end}}`,
+		LikeSubject:   `New like on your post`,
+		DigestSubject: `Your {{.}} activity summary`,
+		DigestDaily:   `daily`,
+		DigestWeekly:  `weekly`,
+
+		ModerationOwner:           `{{if eq .Action "ban"}}Your account was banned by moderators{{else}}Your {{if eq .Type "post"}}post{{else}}image{{end}}{{if .Name}} “{{.Name}}”{{end}} was {{if eq .Action "hide"}}hidden{{else if eq .Action "unhide"}}restored{{else}}removed{{end}} by moderators{{end}}`,
+		ModerationReporter:        `{{if or (eq .Action "dismiss") (eq .Action "unhide")}}Moderators reviewed your report and found no violation{{else}}Moderators reviewed your report and took action{{end}}`,
+		ModerationOwnerSubject:    `Moderation decision on your content`,
+		ModerationReporterSubject: `Your report has been reviewed`,
+
+		ExportReadySubject: `Your data export is ready`,
+	},
+	Russian: {
+		LikeSingle:    `Ваш пост {{if .Post}}«{{.Post}}»{{else}}#{{.PostID}}{{end}} понравился пользователю {{if .Actor}}{{.Actor}}{{else}}#{{.ActorID}}{{end}}`,
+		LikeMany:      `Ваш пост {{if .Post}}«{{.Post}}»{{else}}#{{.PostID}}{{end}} понравился пользователю {{if .Actor}}{{.Actor}}{{else}}#{{.ActorID}}{{end}} и ещё {{.Others}} {{plural .Others "пользователю" "пользователям" "пользователям"}}`,
+		LikeSubject:   `Новый лайк на вашем посте`,
+		DigestSubject: `Ваша {{.}} сводка активности`,
+		DigestDaily:   `ежедневная`,
+		DigestWeekly:  `еженедельная`,
+
+		ModerationOwner:           `{{if eq .Action "ban"}}Ваш аккаунт заблокирован модераторами{{else}}{{if eq .Type "post"}}Ваш пост{{else}}Ваша картинка{{end}}{{if .Name}} «{{.Name}}»{{end}} {{if eq .Type "post"}}{{if eq .Action "hide"}}скрыт{{else if eq .Action "unhide"}}восстановлен{{else}}удалён{{end}}{{else}}{{if eq .Action "hide"}}скрыта{{else if eq .Action "unhide"}}восстановлена{{else}}удалена{{end}}{{end}} модераторами{{end}}`,
+		ModerationReporter:        `{{if or (eq .Action "dismiss") (eq .Action "unhide")}}Модераторы рассмотрели вашу жалобу и не нашли нарушений{{else}}Модераторы рассмотрели вашу жалобу и приняли меры{{end}}`,
+		ModerationOwnerSubject:    `Решение модерации по вашему контенту`,
+		ModerationReporterSubject: `Ваша жалоба рассмотрена`,
+
+		ExportReadySubject: `Архив с вашими данными готов`,
+	},
+}
@@ -0,0 +1,67 @@
+package idempotency
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"pictureloader/notification_microservice/database"
+	"time"
+)
+
+// Потребители событий, у каждого свой учет обработанных событий
+const (
+	ConsumerLikes      = "likes"
+	ConsumerWebhooks   = "webhooks"
+	ConsumerModeration = "moderation"
+	ConsumerAccounts   = "accounts"
+)
+
+// Claim отмечает событие обработанным внутри транзакции tx и возвращает false, если это дубль.
+// Отметка откатывается вместе с транзакцией, поэтому событие, которое не удалось обработать,
+// при повторной доставке обработается снова.
+// События без id, опубликованные старыми версиями сервисов, всегда считаются новыми.
+func Claim(tx *gorm.DB, consumer, eventID string) (bool, error) {
+	if eventID == "" {
+		return true, nil
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&database.ProcessedEvent{Consumer: consumer, EventID: eventID})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Cleaner удаляет отметки старше retention: повтор события позже этого окна уже не распознается
+type Cleaner struct {
+	db        *gorm.DB
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewCleaner(db *gorm.DB, retention, interval time.Duration) *Cleaner {
+	return &Cleaner{db, retention, interval}
+}
+
+// Run чистит отметки раз в interval, пока не отменен ctx
+func (c *Cleaner) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		c.clean()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Cleaner) clean() {
+	result := c.db.Where("processed_at < ?", time.Now().Add(-c.retention)).Delete(&database.ProcessedEvent{})
+	if result.Error != nil {
+		slog.Error("Clean processed events", "error", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		slog.Info("Processed events cleaned", "removed", result.RowsAffected)
+	}
+}
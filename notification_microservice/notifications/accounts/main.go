@@ -0,0 +1,7 @@
+package accounts
+
+import "gorm.io/gorm"
+
+func NewAccounts(db *gorm.DB, preferences PreferencesProvider, email EmailNotifier) *Service {
+	return NewService(NewPSQLRepository(db), preferences, email)
+}
@@ -0,0 +1,58 @@
+package accounts
+
+import (
+	"encoding/json"
+	"gorm.io/gorm"
+	"pictureloader/notification_microservice/database"
+	"pictureloader/notification_microservice/idempotency"
+	"strconv"
+)
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+func NewPSQLRepository(db *gorm.DB) *Repository {
+	return &Repository{db}
+}
+
+// Claim отмечает событие обработанным, false - событие уже обрабатывалось
+func (r *Repository) Claim(eventID string) (bool, error) {
+	return idempotency.Claim(r.DB, idempotency.ConsumerAccounts, eventID)
+}
+
+// DeleteUserData удаляет уведомления, настройки и вебхуки пользователя, а из чужих уведомлений
+// убирает его имя. Возвращает false, если событие eventID уже обрабатывалось.
+func (r *Repository) DeleteUserData(eventID string, userID int) (bool, error) {
+	isNew := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		isNew, err = idempotency.Claim(tx, idempotency.ConsumerAccounts, eventID)
+		if err != nil || !isNew {
+			return err
+		}
+		if err = tx.Where("liked = ?", userID).Delete(&database.LikesNotification{}).Error; err != nil {
+			return err
+		}
+		// в чужих группах лайков остается только id, имя удаленного пользователя больше не показывается
+		actor, err := json.Marshal([]int{userID})
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&database.LikesNotification{}).Where("actors @> ?::jsonb", string(actor)).
+			UpdateColumn("actor_names", gorm.Expr("actor_names - ?", strconv.Itoa(userID))).Error
+		if err != nil {
+			return err
+		}
+		if err = tx.Where("user_id = ?", userID).Delete(&database.NotificationPreference{}).Error; err != nil {
+			return err
+		}
+		err = tx.Where("webhook_id IN (?)", tx.Model(&database.Webhook{}).Select("id").Where("user_id = ?", userID)).
+			Delete(&database.WebhookDelivery{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&database.Webhook{}).Error
+	})
+	return isNew, err
+}
@@ -0,0 +1,82 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"pictureloader/common/events"
+	"pictureloader/notification_microservice/notifications/preferences"
+	"time"
+)
+
+// PreferencesProvider отдает настройки уведомлений получателя
+type PreferencesProvider interface {
+	GetPreferences(userID int) (preferences.Preferences, error)
+}
+
+// EmailNotifier отправляет письмо со ссылкой на выгрузку данных
+type EmailNotifier interface {
+	NotifyExportReady(ctx context.Context, userID int, locale, downloadURL string, expiresAt time.Time) error
+}
+
+// Service обрабатывает события аккаунта: удаляет данные пользователя в нотификаторе,
+// когда приложение удалило его аккаунт, и присылает ссылку на готовую выгрузку данных
+type Service struct {
+	repo        *Repository
+	preferences PreferencesProvider
+	email       EmailNotifier
+}
+
+func NewService(repo *Repository, preferences PreferencesProvider, email EmailNotifier) *Service {
+	return &Service{repo, preferences, email}
+}
+
+func (s *Service) ProcessUserDeletedMessage(ctx context.Context, message []byte) error {
+	var msg events.UserDeleted
+	if err := json.Unmarshal(message, &msg); err != nil {
+		// битое сообщение не станет лучше при повторной доставке
+		slog.Error("Error unmarshalling user deleted message", "error", err)
+		return nil
+	}
+	isNew, err := s.repo.DeleteUserData(msg.EventID, msg.UserID)
+	if err != nil {
+		slog.Error("Error deleting user data", "error", err, "userID", msg.UserID)
+		return err
+	}
+	if !isNew {
+		slog.Info("Duplicate user deleted event skipped", "eventID", msg.EventID)
+		return nil
+	}
+	slog.Info("User data deleted", "userID", msg.UserID)
+	return nil
+}
+
+// ProcessExportReadyMessage отправляет ссылку на архив. Письмо служебное и уходит независимо
+// от настроек уведомлений, из них берется только язык.
+func (s *Service) ProcessExportReadyMessage(ctx context.Context, message []byte) error {
+	var msg events.ExportReady
+	if err := json.Unmarshal(message, &msg); err != nil {
+		slog.Error("Error unmarshalling export ready message", "error", err)
+		return nil
+	}
+	prefs, err := s.preferences.GetPreferences(msg.UserID)
+	if err != nil {
+		slog.Error("Error getting preferences", "error", err, "userID", msg.UserID)
+		return err
+	}
+	isNew, err := s.repo.Claim(msg.EventID)
+	if err != nil {
+		return err
+	}
+	if !isNew {
+		slog.Info("Duplicate export ready event skipped", "eventID", msg.EventID)
+		return nil
+	}
+	// событие уже отмечено обработанным, повтор доставки письмо не пришлет, поэтому ошибка только логируется:
+	// ссылка остается доступной в списке выгрузок
+	err = s.email.NotifyExportReady(ctx, msg.UserID, prefs.EmailLocale(), msg.DownloadURL, msg.ExpiresAt)
+	if err != nil {
+		slog.Error("Error sending export ready email", "error", err, "userID", msg.UserID)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package email
+
+import (
+	"context"
+	"log/slog"
+	"pictureloader/notification_microservice/notifications/likes"
+	"pictureloader/notification_microservice/notifications/preferences"
+	"time"
+)
+
+type DigestSchedule interface {
+	DueDigests(now time.Time) ([]preferences.DigestDue, error)
+	ClaimDigest(due preferences.DigestDue, now time.Time) (bool, error)
+}
+
+type NotificationSource interface {
+	GetUnreadSince(userID int, since time.Time) ([]likes.LikeNotification, error)
+}
+
+// DigestRunner периодически отправляет дневные и недельные дайджесты
+type DigestRunner struct {
+	schedule      DigestSchedule
+	notifications NotificationSource
+	mailer        *Mailer
+	interval      time.Duration
+}
+
+func NewDigestRunner(schedule DigestSchedule, notifications NotificationSource, mailer *Mailer,
+	interval time.Duration) *DigestRunner {
+	return &DigestRunner{schedule, notifications, mailer, interval}
+}
+
+// Run проверяет расписание раз в interval, пока не отменен ctx
+func (d *DigestRunner) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.sendDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *DigestRunner) sendDue(ctx context.Context) {
+	now := time.Now()
+	due, err := d.schedule.DueDigests(now)
+	if err != nil {
+		return
+	}
+
+	for _, digest := range due {
+		claimed, err := d.schedule.ClaimDigest(digest, now)
+		if err != nil || !claimed {
+			continue
+		}
+		notifications, err := d.notifications.GetUnreadSince(digest.UserID, digest.Since)
+		if err != nil {
+			continue
+		}
+		if len(notifications) == 0 {
+			continue
+		}
+		err = d.mailer.SendDigest(ctx, digest.UserID, digest.Locale, digest.Frequency, notifications)
+		if err != nil {
+			slog.Error("Send digest", "error", err, "userID", digest.UserID)
+			continue
+		}
+		slog.Info("Digest sent", "userID", digest.UserID, "notifications", len(notifications))
+	}
+}
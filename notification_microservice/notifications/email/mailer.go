@@ -0,0 +1,151 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	htmltemplate "html/template"
+	"pictureloader/common/mail"
+	"pictureloader/notification_microservice/i18n"
+	"pictureloader/notification_microservice/notifications/likes"
+	"pictureloader/notification_microservice/notifications/moderation"
+	"pictureloader/notification_microservice/notifications/preferences"
+	"pictureloader/notification_microservice/users"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+// UserLookup достает контакты пользователя из основного приложения
+type UserLookup interface {
+	GetUser(ctx context.Context, userID int) (users.User, error)
+}
+
+// templates - шаблоны писем одного языка
+type templates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Mailer рендерит письма по шаблонам на языке получателя и отправляет их через mail.Sender
+type Mailer struct {
+	sender    mail.Sender
+	users     UserLookup
+	templates map[string]templates
+}
+
+func NewMailer(sender mail.Sender, users UserLookup) (*Mailer, error) {
+	mailer := &Mailer{sender: sender, users: users, templates: map[string]templates{}}
+	for _, locale := range i18n.Supported {
+		text, err := texttemplate.ParseFS(templatesFS, "templates/"+locale+"/*.txt.tmpl")
+		if err != nil {
+			return nil, err
+		}
+		html, err := htmltemplate.ParseFS(templatesFS, "templates/"+locale+"/*.html.tmpl")
+		if err != nil {
+			return nil, err
+		}
+		mailer.templates[locale] = templates{text, html}
+	}
+	return mailer, nil
+}
+
+type likeData struct {
+	Username     string
+	Notification likes.LikeNotification
+}
+
+// NotifyLike отправляет письмо о новой группе лайков
+func (m *Mailer) NotifyLike(ctx context.Context, userID int, locale string, notification likes.LikeNotification) error {
+	user, err := m.users.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	notification.Localize(locale)
+	subject, err := i18n.Render(locale, i18n.LikeSubject, nil)
+	if err != nil {
+		return err
+	}
+	return m.send(ctx, locale, user.Email, subject, "like", likeData{user.Username, notification})
+}
+
+type digestData struct {
+	Username      string
+	Frequency     string
+	Notifications []likes.LikeNotification
+}
+
+// SendDigest отправляет сводку уведомлений за период
+func (m *Mailer) SendDigest(ctx context.Context, userID int, locale, frequency string,
+	notifications []likes.LikeNotification) error {
+	user, err := m.users.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	likes.Localize(notifications, locale)
+	frequencyKey := i18n.DigestDaily
+	if frequency == preferences.DigestWeekly {
+		frequencyKey = i18n.DigestWeekly
+	}
+	frequencyName, err := i18n.Render(locale, frequencyKey, nil)
+	if err != nil {
+		return err
+	}
+	subject, err := i18n.Render(locale, i18n.DigestSubject, frequencyName)
+	if err != nil {
+		return err
+	}
+	data := digestData{Username: user.Username, Frequency: frequencyName, Notifications: notifications}
+	return m.send(ctx, locale, user.Email, subject, "digest", data)
+}
+
+type moderationData struct {
+	Username string
+	Notice   moderation.Notice
+}
+
+// NotifyModeration отправляет письмо о решении модерации
+func (m *Mailer) NotifyModeration(ctx context.Context, userID int, locale string, notice moderation.Notice) error {
+	user, err := m.users.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	notice.Localize(locale)
+	return m.send(ctx, locale, user.Email, notice.Subject, "moderation", moderationData{user.Username, notice})
+}
+
+type exportData struct {
+	Username    string
+	DownloadURL string
+	ExpiresAt   time.Time
+}
+
+// NotifyExportReady отправляет ссылку на готовый архив с данными пользователя
+func (m *Mailer) NotifyExportReady(ctx context.Context, userID int, locale, downloadURL string, expiresAt time.Time) error {
+	user, err := m.users.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	subject, err := i18n.Render(locale, i18n.ExportReadySubject, nil)
+	if err != nil {
+		return err
+	}
+	return m.send(ctx, locale, user.Email, subject, "export_ready", exportData{user.Username, downloadURL, expiresAt})
+}
+
+func (m *Mailer) send(ctx context.Context, locale, to, subject, template string, data any) error {
+	tmpl, ok := m.templates[locale]
+	if !ok {
+		tmpl = m.templates[i18n.Default]
+	}
+	var text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&text, template+".txt.tmpl", data); err != nil {
+		return err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, template+".html.tmpl", data); err != nil {
+		return err
+	}
+	return m.sender.Send(ctx, mail.Message{To: to, Subject: subject, Text: text.String(), HTML: html.String()})
+}
@@ -2,38 +2,145 @@ package likes
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
 	"net/http"
+	"pictureloader/notification_microservice/auth"
+	"pictureloader/notification_microservice/stream"
 	"strconv"
 )
 
 type LikesServer struct {
 	service *NotificationService
+	hub     *stream.Hub
 }
 
-func NewLikesServer(service *NotificationService) *LikesServer {
-	return &LikesServer{service}
+func NewLikesServer(service *NotificationService, hub *stream.Hub) *LikesServer {
+	return &LikesServer{service, hub}
 }
 
-func LikesNotificationsRouter(api *mux.Router, server *LikesServer) {
+func LikesNotificationsRouter(api *mux.Router, server *LikesServer, authMiddleware *auth.Middleware) {
 	router := api.PathPrefix("/notifications").Subrouter()
+	router.HandleFunc("", server.GetMyNotifications).Methods("GET")
+	router.HandleFunc("/stream", server.StreamNotifications).Methods("GET")
+	router.HandleFunc("/unread-count", server.GetUnreadCount).Methods("GET")
+	router.HandleFunc("/read-all", server.MarkAllAsRead).Methods("POST")
+	router.HandleFunc("/{notificationID:[0-9]+}/read", server.MarkAsRead).Methods("POST")
+	router.HandleFunc("/{notificationID:[0-9]+}", server.DeleteNotification).Methods("DELETE")
 	router.HandleFunc("/likes/{userID}", server.GetLikesNotifications).Methods("GET")
+	router.Use(authMiddleware.Authenticate)
 }
 
+// GetMyNotifications отдает уведомления текущего пользователя
+func (server *LikesServer) GetMyNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+	server.writeNotifications(w, r, userID)
+}
+
+// GetLikesNotifications отдает уведомления пользователя userID, чужие может читать только сервис
 func (server *LikesServer) GetLikesNotifications(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID, err := strconv.Atoi(vars["userID"])
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	caller, _ := auth.CallerFromContext(r.Context())
+	if !caller.CanAccessUser(userID) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	server.writeNotifications(w, r, userID)
+}
+
+func (server *LikesServer) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := server.service.CountUnread(userID)
+	if err != nil {
+		http.Error(w, "Failed to count notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"unread": count})
+}
+
+func (server *LikesServer) MarkAsRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+	notificationID, _ := strconv.Atoi(mux.Vars(r)["notificationID"])
+
+	err := server.service.MarkAsRead(userID, notificationID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"Notification marked as read"}`))
+}
+
+func (server *LikesServer) MarkAllAsRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := server.service.MarkAllAsRead(userID)
+	if err != nil {
+		http.Error(w, "Failed to update notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"updated": updated})
+}
+
+func (server *LikesServer) DeleteNotification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+	notificationID, _ := strconv.Atoi(mux.Vars(r)["notificationID"])
+
+	err := server.service.DeleteNotification(userID, notificationID)
+	if err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
-	userIDs, err := server.service.GetAllLikeNotifications(userID)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"Notification deleted"}`))
+}
+
+func (server *LikesServer) writeNotifications(w http.ResponseWriter, r *http.Request, userID int) {
+	notifications, err := server.service.GetAllLikeNotifications(userID)
 	if err != nil {
 		http.Error(w, "Failed to get notifications", http.StatusInternalServerError)
 		return
 	}
+	locale := server.service.Locale(userID, r.Header.Get("Accept-Language"))
+	Localize(notifications, locale)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(userIDs)
+	w.Header().Set("Content-Language", locale)
+	json.NewEncoder(w).Encode(notifications)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
 }
@@ -1,10 +1,15 @@
 package likes
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"pictureloader/notification_microservice/stream"
+	"time"
+)
 
-func NewLikesNotifications(db *gorm.DB) (*LikesServer, *NotificationService) {
+func NewLikesNotifications(db *gorm.DB, broadcaster Broadcaster, hub *stream.Hub,
+	preferences PreferencesProvider, email EmailNotifier, aggregationWindow time.Duration) (*LikesServer, *NotificationService) {
 	notificationRepository := NewPSQLNotificationsRepository(db)
-	notificationService := NewNotificationService(notificationRepository)
-	notificationServer := NewLikesServer(notificationService)
+	notificationService := NewNotificationService(notificationRepository, broadcaster, preferences, email, aggregationWindow)
+	notificationServer := NewLikesServer(notificationService, hub)
 	return notificationServer, notificationService
 }
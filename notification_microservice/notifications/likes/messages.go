@@ -0,0 +1,43 @@
+package likes
+
+import (
+	"log/slog"
+	"pictureloader/notification_microservice/i18n"
+)
+
+type likeMessageData struct {
+	Actor   string
+	ActorID int
+	Others  int
+	Post    string
+	PostID  int
+}
+
+// Localize заполняет Message текстом уведомления на языке locale
+func (n *LikeNotification) Localize(locale string) {
+	data := likeMessageData{Others: n.ActorCount - 1, Post: n.PostName, PostID: n.PostID}
+	if len(n.Actors) > 0 {
+		data.ActorID = n.Actors[0]
+	}
+	if len(n.ActorNames) > 0 {
+		data.Actor = n.ActorNames[0]
+	}
+	key := i18n.LikeSingle
+	if data.Others > 0 {
+		key = i18n.LikeMany
+	}
+
+	message, err := i18n.Render(locale, key, data)
+	if err != nil {
+		slog.Error("Render like notification", "error", err, "locale", locale)
+		return
+	}
+	n.Message = message
+}
+
+// Localize рендерит сообщения всех уведомлений
+func Localize(notifications []LikeNotification, locale string) {
+	for i := range notifications {
+		notifications[i].Localize(locale)
+	}
+}
@@ -1,10 +1,24 @@
 package likes
 
 import (
+	"errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pictureloader/common/events"
 	"pictureloader/notification_microservice/database"
+	"pictureloader/notification_microservice/idempotency"
+	"time"
 )
 
+var (
+	ErrNotFound = errors.New("notification not found")
+	// ErrDuplicate - событие лайка уже было обработано
+	ErrDuplicate = errors.New("like event already processed")
+)
+
+// actorsSample - сколько последних лайкнувших хранится в группе
+const actorsSample = 3
+
 func NewPSQLNotificationsRepository(db *gorm.DB) *LikeNotificationRepository {
 	return &LikeNotificationRepository{db}
 }
@@ -13,24 +27,240 @@ type LikeNotificationRepository struct {
 	DB *gorm.DB
 }
 
-func (np *LikeNotificationRepository) CreateLikeNotification(postID, likerID, likedID int) error {
-	err := np.DB.Create(&database.LikesNotification{PostID: postID, Liker: likerID, Liked: likedID}).Error
+// AddLike добавляет лайк в непрочитанную группу поста, созданную после since, или создает новую группу.
+// Без inApp новая группа сохраняется только для писем, а существующая становится видимой в приложении, если inApp.
+// Повторное событие с тем же EventID возвращает ErrDuplicate и ничего не меняет.
+func (np *LikeNotificationRepository) AddLike(like events.NewLike, since time.Time, inApp bool) (LikeNotification, error) {
+	postID, likedID := like.PostID, like.Liked
+	var notification database.LikesNotification
+	err := np.DB.Transaction(func(tx *gorm.DB) error {
+		isNew, err := idempotency.Claim(tx, idempotency.ConsumerLikes, like.EventID)
+		if err != nil {
+			return err
+		}
+		if !isNew {
+			return ErrDuplicate
+		}
+
+		// лайки одного поста обрабатываются по очереди, чтобы реплики не создали две группы
+		err = tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", likedID, postID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("liked = ? AND post_id = ? AND read_at IS NULL AND created_at > ?", likedID, postID, since).
+			Order("created_at DESC").First(&notification).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			notification = NewLikeGroup(like)
+			notification.EmailOnly = !inApp
+			if notification.EventSeq, err = nextEventSeq(tx); err != nil {
+				return err
+			}
+			if err = tx.Create(&notification).Error; err != nil {
+				return err
+			}
+			_, err = addActor(tx, notification.ID, like.Liker)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		isNewActor, err := addActor(tx, notification.ID, like.Liker)
+		if err != nil {
+			return err
+		}
+		notification = MergeLike(notification, like, isNewActor)
+		notification.EmailOnly = notification.EmailOnly && !inApp
+		if notification.EventSeq, err = nextEventSeq(tx); err != nil {
+			return err
+		}
+		return tx.Save(&notification).Error
+	})
 	if err != nil {
-		return err
+		return LikeNotification{}, err
+	}
+	return toLikeNotification(notification), nil
+}
+
+// nextEventSeq выдает номер изменения группы. Номера растут, но транзакции с разными постами
+// могут закончиться не в том порядке, в котором их получили.
+func nextEventSeq(tx *gorm.DB) (int64, error) {
+	var seq int64
+	err := tx.Raw("SELECT nextval('likes_notifications_event_seq')").Scan(&seq).Error
+	return seq, err
+}
+
+// addActor запоминает лайкнувшего в группе, false - он уже лайкал этот пост в группе
+func addActor(tx *gorm.DB, groupID, actorID int) (bool, error) {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&database.LikeGroupActor{GroupID: groupID, ActorID: actorID})
+	return result.RowsAffected == 1, result.Error
+}
+
+// NewLikeGroup - группа из одного лайка
+func NewLikeGroup(like events.NewLike) database.LikesNotification {
+	return database.LikesNotification{
+		PostID:     like.PostID,
+		PostName:   like.PostName,
+		Liker:      like.Liker,
+		Liked:      like.Liked,
+		ActorCount: 1,
+		Actors:     []int{like.Liker},
+		ActorNames: actorNames([]int{like.Liker}, nil, like.Liker, like.LikerName),
 	}
-	return nil
 }
 
+// MergeLike добавляет лайк в группу: лайкнувший становится первым в Actors, имена ушедших из выборки удаляются.
+// ActorCount растет, только если isNewActor - этот пользователь еще не лайкал пост в группе.
+func MergeLike(notification database.LikesNotification, like events.NewLike, isNewActor bool) database.LikesNotification {
+	notification.Liker = like.Liker
+	if isNewActor {
+		notification.ActorCount++
+	}
+	notification.Actors = prependActor(notification.Actors, like.Liker)
+	notification.ActorNames = actorNames(notification.Actors, notification.ActorNames, like.Liker, like.LikerName)
+	if like.PostName != "" {
+		notification.PostName = like.PostName
+	}
+	return notification
+}
+
+func prependActor(actors []int, actor int) []int {
+	result := []int{actor}
+	for _, a := range actors {
+		if a != actor && len(result) < actorsSample {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// actorNames оставляет имена только тех, кто остался в actors, и добавляет имя нового лайкнувшего
+func actorNames(actors []int, names map[int]string, liker int, likerName string) map[int]string {
+	result := make(map[int]string, len(actors))
+	for _, actor := range actors {
+		if name, ok := names[actor]; ok {
+			result[actor] = name
+		}
+	}
+	if likerName != "" {
+		result[liker] = likerName
+	}
+	return result
+}
+
+// LikeNotification хранит данные группы, Message рендерится под язык запроса
 type LikeNotification struct {
-	PostID int `json:"post_id"`
-	Liker  int `json:"liker"`
+	ID       int    `json:"id"`
+	Type     string `json:"type"`
+	Message  string `json:"message,omitempty"`
+	PostID   int    `json:"post_id"`
+	PostName string `json:"post_name"`
+	Actors   []int  `json:"actors"`
+	// ActorNames - имена из Actors в том же порядке, пустая строка если имя неизвестно
+	ActorNames []string   `json:"actor_names"`
+	ActorCount int        `json:"actor_count"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LatestAt   time.Time  `json:"latest_at"`
+	ReadAt     *time.Time `json:"read_at"`
+	EventSeq   int64      `json:"-"`
+}
+
+func toLikeNotification(n database.LikesNotification) LikeNotification {
+	names := make([]string, len(n.Actors))
+	for i, actor := range n.Actors {
+		names[i] = n.ActorNames[actor]
+	}
+	return LikeNotification{
+		ID:         n.ID,
+		Type:       TypeLike,
+		PostID:     n.PostID,
+		PostName:   n.PostName,
+		Actors:     n.Actors,
+		ActorNames: names,
+		ActorCount: n.ActorCount,
+		CreatedAt:  n.CreatedAt,
+		LatestAt:   n.UpdatedAt,
+		ReadAt:     n.ReadAt,
+		EventSeq:   n.EventSeq,
+	}
+}
+
+func toLikeNotifications(rows []database.LikesNotification) []LikeNotification {
+	result := make([]LikeNotification, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, toLikeNotification(row))
+	}
+	return result
 }
 
 func (np *LikeNotificationRepository) GetAllLikeNotifications(likedID int) ([]LikeNotification, error) {
-	var likeNotif []LikeNotification
-	err := np.DB.Model(&database.LikesNotification{}).Where("liked = ?", likedID).Find(&likeNotif).Error
+	var rows []database.LikesNotification
+	err := np.DB.Where("liked = ? AND NOT email_only", likedID).Order("updated_at DESC").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return toLikeNotifications(rows), nil
+}
+
+// GetLikeNotificationsAfterEvent возвращает группы, измененные после события eventSeq, в порядке изменения,
+// для докачки стрима
+func (np *LikeNotificationRepository) GetLikeNotificationsAfterEvent(likedID int, eventSeq int64) ([]LikeNotification, error) {
+	var rows []database.LikesNotification
+	err := np.DB.Where("liked = ? AND NOT email_only AND event_seq > ?", likedID, eventSeq).
+		Order("event_seq ASC").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return toLikeNotifications(rows), nil
+}
+
+// GetUnreadUpdatedAfter возвращает и группы только для писем: они нужны дайджесту
+func (np *LikeNotificationRepository) GetUnreadUpdatedAfter(likedID int, after time.Time) ([]LikeNotification, error) {
+	var rows []database.LikesNotification
+	err := np.DB.Where("liked = ? AND read_at IS NULL AND updated_at > ?", likedID, after).
+		Order("updated_at DESC").Find(&rows).Error
 	if err != nil {
 		return nil, err
 	}
-	return likeNotif, nil
+	return toLikeNotifications(rows), nil
+}
+
+func (np *LikeNotificationRepository) CountUnread(likedID int) (int, error) {
+	var count int64
+	err := np.DB.Model(&database.LikesNotification{}).Where("liked = ? AND NOT email_only AND read_at IS NULL", likedID).
+		Count(&count).Error
+	return int(count), err
+}
+
+func (np *LikeNotificationRepository) MarkAsRead(likedID, notificationID int) error {
+	result := np.DB.Model(&database.LikesNotification{}).
+		Where("id = ? AND liked = ?", notificationID, likedID).
+		UpdateColumn("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (np *LikeNotificationRepository) MarkAllAsRead(likedID int) (int, error) {
+	result := np.DB.Model(&database.LikesNotification{}).
+		Where("liked = ? AND NOT email_only AND read_at IS NULL", likedID).
+		UpdateColumn("read_at", time.Now())
+	return int(result.RowsAffected), result.Error
+}
+
+func (np *LikeNotificationRepository) DeleteNotification(likedID, notificationID int) error {
+	result := np.DB.Where("id = ? AND liked = ?", notificationID, likedID).Delete(&database.LikesNotification{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
@@ -0,0 +1,111 @@
+package likes
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"gorm.io/gorm"
+	"log/slog"
+	"time"
+)
+
+// ErrRetentionLocked - чистку уже выполняет другая реплика
+var ErrRetentionLocked = errors.New("retention is running on another replica")
+
+// retentionLockName - имя задачи, из которого hashtext выводит ключ advisory lock: чистку выполняет
+// одна реплика за раз. Блокировки групп лайков берутся по паре ключей и с этим ключом не пересекаются.
+const retentionLockName = "likes_notifications_retention"
+
+// Метрики чистки, доступны на /debug/vars
+var (
+	retentionRuns        = expvar.NewInt("notifications_retention_runs")
+	retentionRemovedRead = expvar.NewInt("notifications_retention_removed_read")
+	retentionRemovedCap  = expvar.NewInt("notifications_retention_removed_over_cap")
+)
+
+// RetentionJob удаляет уведомления, прочитанные больше readAge назад, и оставляет каждому пользователю
+// не больше maxPerUser самых свежих уведомлений. Нулевое значение отключает соответствующее правило.
+type RetentionJob struct {
+	db         *gorm.DB
+	readAge    time.Duration
+	maxPerUser int
+	interval   time.Duration
+}
+
+func NewRetentionJob(db *gorm.DB, readAge time.Duration, maxPerUser int, interval time.Duration) *RetentionJob {
+	return &RetentionJob{db, readAge, maxPerUser, interval}
+}
+
+// Run запускает чистку раз в interval, пока не отменен ctx
+func (j *RetentionJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		j.runOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *RetentionJob) runOnce(ctx context.Context) {
+	removedRead, removedCap, err := j.Purge(ctx, time.Now())
+	if errors.Is(err, ErrRetentionLocked) {
+		// чистку уже выполняет другая реплика
+		return
+	}
+	if err != nil {
+		slog.Error("Notifications retention", "error", err)
+		return
+	}
+
+	retentionRuns.Add(1)
+	retentionRemovedRead.Add(removedRead)
+	retentionRemovedCap.Add(removedCap)
+	if removedRead > 0 || removedCap > 0 {
+		slog.Info("Notifications retention", "removed read", removedRead, "removed over cap", removedCap)
+	}
+}
+
+// Purge применяет оба правила и возвращает, сколько уведомлений удалено каждым.
+// Если чистку сейчас выполняет другая реплика, возвращается ErrRetentionLocked.
+func (j *RetentionJob) Purge(ctx context.Context, now time.Time) (removedRead, removedCap int64, err error) {
+	err = j.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// lock транзакционный: снимется сам при коммите или обрыве соединения
+		locked := false
+		err := tx.Raw("SELECT pg_try_advisory_xact_lock(hashtext(?))", retentionLockName).Scan(&locked).Error
+		if err != nil {
+			return err
+		}
+		if !locked {
+			return ErrRetentionLocked
+		}
+
+		if j.readAge > 0 {
+			result := tx.Exec(`DELETE FROM likes_notifications WHERE read_at < ?`, now.Add(-j.readAge))
+			if result.Error != nil {
+				return result.Error
+			}
+			removedRead = result.RowsAffected
+		}
+
+		if j.maxPerUser > 0 {
+			result := tx.Exec(`DELETE FROM likes_notifications WHERE id IN (
+				SELECT id FROM (
+					SELECT id, row_number() OVER (PARTITION BY liked ORDER BY updated_at DESC, id DESC) AS position
+					FROM likes_notifications
+				) ranked WHERE position > ?)`, j.maxPerUser)
+			if result.Error != nil {
+				return result.Error
+			}
+			removedCap = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return removedRead, removedCap, nil
+}
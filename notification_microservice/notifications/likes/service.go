@@ -1,51 +1,204 @@
 package likes
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log/slog"
+	"pictureloader/common/events"
+	"pictureloader/notification_microservice/i18n"
+	"pictureloader/notification_microservice/notifications/preferences"
+	"pictureloader/notification_microservice/stream"
+	"time"
 )
 
-type Message struct {
-	PostID int `json:"post_id"`
-	Liker  int `json:"liker"`
-	Liked  int `json:"liked"`
+// NotificationRepository хранит группы лайков
+type NotificationRepository interface {
+	AddLike(like events.NewLike, since time.Time, inApp bool) (LikeNotification, error)
+	GetAllLikeNotifications(likedID int) ([]LikeNotification, error)
+	GetLikeNotificationsAfterEvent(likedID int, eventSeq int64) ([]LikeNotification, error)
+	GetUnreadUpdatedAfter(likedID int, after time.Time) ([]LikeNotification, error)
+	CountUnread(likedID int) (int, error)
+	MarkAsRead(likedID, notificationID int) error
+	MarkAllAsRead(likedID int) (int, error)
+	DeleteNotification(likedID, notificationID int) error
 }
 
+// Broadcaster доставляет новые уведомления подключенным клиентам на всех репликах
+type Broadcaster interface {
+	Broadcast(ctx context.Context, event stream.Event) error
+}
+
+// PreferencesProvider отдает настройки уведомлений получателя
+type PreferencesProvider interface {
+	GetPreferences(userID int) (preferences.Preferences, error)
+}
+
+// EmailNotifier отправляет письмо о новом уведомлении
+type EmailNotifier interface {
+	NotifyLike(ctx context.Context, userID int, locale string, notification LikeNotification) error
+}
+
+const TypeLike = preferences.TypeLike
+
 type NotificationService struct {
-	repo *LikeNotificationRepository
+	repo        NotificationRepository
+	broadcaster Broadcaster
+	preferences PreferencesProvider
+	email       EmailNotifier
+	// aggregationWindow - в течение какого времени лайки поста собираются в одну группу
+	aggregationWindow time.Duration
 }
 
-func NewNotificationService(repo *LikeNotificationRepository) *NotificationService {
-	return &NotificationService{repo}
+func NewNotificationService(repo NotificationRepository, broadcaster Broadcaster,
+	preferences PreferencesProvider, email EmailNotifier, aggregationWindow time.Duration) *NotificationService {
+	return &NotificationService{repo, broadcaster, preferences, email, aggregationWindow}
 }
 
-func (ns *NotificationService) ProcessLikeMessage(message []byte) error {
-	var msg Message
+func (ns *NotificationService) ProcessLikeMessage(ctx context.Context, message []byte) error {
+	var msg events.NewLike
 	err := json.Unmarshal(message, &msg)
 	if err != nil {
-		slog.Info("Error unmarshalling message", "error", err)
+		// без данных события уведомление создать нельзя, сообщение уйдет в dead-letter очередь
+		slog.Error("Error unmarshalling like message", "error", err)
+		return err
+	}
+	prefs, err := ns.preferences.GetPreferences(msg.Liked)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if prefs.LikeMuted(msg.PostID, msg.PostCreatedAt, now) {
+		slog.Info("Like notification muted by preferences", "postID", msg.PostID, "userID", msg.Liked)
+		return nil
+	}
+	wantsInApp := prefs.Wants(TypeLike, preferences.ChannelInApp)
+	wantsEmail := prefs.Wants(TypeLike, preferences.ChannelEmail)
+	if !wantsInApp && !wantsEmail {
+		return nil
+	}
+
+	// группа хранится и для email: по ней видно, первый ли это лайк в окне агрегации, и она попадет в дайджест.
+	// Без канала in_app она не показывается в списке уведомлений.
+	notification, err := ns.repo.AddLike(msg, now.Add(-ns.aggregationWindow), wantsInApp)
+	if errors.Is(err, ErrDuplicate) {
+		slog.Info("Duplicate like event skipped", "eventID", msg.EventID)
+		return nil
 	}
-	err = ns.repo.CreateLikeNotification(msg.PostID, msg.Liker, msg.Liked)
 	if err != nil {
 		slog.Error("Error creating like notification", "error", err)
 		return err
 	}
+	if prefs.InQuietHours(now) {
+		// в тихие часы уведомление только сохраняется, без пуша в открытые вкладки и писем
+		return nil
+	}
+
+	if wantsInApp {
+		ns.broadcast(ctx, msg.Liked, notification)
+	}
+	// письмо отправляется на первый лайк группы, остальные попадут в дайджест
+	if wantsEmail && notification.ActorCount == 1 {
+		err = ns.email.NotifyLike(ctx, msg.Liked, prefs.EmailLocale(), notification)
+		if err != nil {
+			slog.Error("Error sending like email", "error", err, "userID", msg.Liked)
+		}
+	}
 	return nil
 }
 
-func (ns *NotificationService) GetAllLikeNotifications(userID int) ([]string, error) {
+func (ns *NotificationService) broadcast(ctx context.Context, userID int, notification LikeNotification) {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return
+	}
+	event := stream.Event{ID: EventID(notification), UserID: userID, Data: data}
+	err = ns.broadcaster.Broadcast(ctx, event)
+	if err != nil {
+		// уведомление уже сохранено, клиент получит его при следующей загрузке
+		slog.Error("Error broadcasting like notification", "error", err)
+	}
+}
+
+// Locale выбирает язык уведомлений: из настроек пользователя, иначе из Accept-Language запроса
+func (ns *NotificationService) Locale(userID int, acceptLanguage string) string {
+	prefs, err := ns.preferences.GetPreferences(userID)
+	if err != nil {
+		return i18n.Negotiate(acceptLanguage)
+	}
+	return i18n.Resolve(prefs.Locale, acceptLanguage)
+}
+
+// EventID - id события стрима для группы: номер последнего изменения из последовательности в базе,
+// так что обновление группы приходит клиенту как новое событие
+func EventID(notification LikeNotification) int64 {
+	return notification.EventSeq
+}
+
+// GetLikeNotificationsAfter возвращает группы, созданные или обновленные после события lastEventID
+func (ns *NotificationService) GetLikeNotificationsAfter(userID int, lastEventID int64) ([]LikeNotification, error) {
+	likeNotif, err := ns.repo.GetLikeNotificationsAfterEvent(userID, lastEventID)
+	if err != nil {
+		slog.Error("db error", "error", err)
+		return nil, err
+	}
+	return likeNotif, nil
+}
+
+func (ns *NotificationService) GetAllLikeNotifications(userID int) ([]LikeNotification, error) {
 	likeNotif, err := ns.repo.GetAllLikeNotifications(userID)
 	if err != nil {
 		slog.Error("db error", "error", err)
 		return nil, err
 	}
+	if likeNotif == nil {
+		likeNotif = []LikeNotification{}
+	}
+	return likeNotif, nil
+}
+
+// GetUnreadSince возвращает непрочитанные группы, обновленные после since, для дайджеста
+func (ns *NotificationService) GetUnreadSince(userID int, since time.Time) ([]LikeNotification, error) {
+	likeNotif, err := ns.repo.GetUnreadUpdatedAfter(userID, since)
+	if err != nil {
+		slog.Error("db error", "error", err)
+		return nil, err
+	}
+	return likeNotif, nil
+}
+
+func (ns *NotificationService) CountUnread(userID int) (int, error) {
+	count, err := ns.repo.CountUnread(userID)
+	if err != nil {
+		slog.Error("db error", "error", err)
+		return 0, err
+	}
+	return count, nil
+}
 
-	var result []string
+func (ns *NotificationService) MarkAsRead(userID, notificationID int) error {
+	err := ns.repo.MarkAsRead(userID, notificationID)
+	if err != nil {
+		slog.Error("Mark notification as read", "error", err, "notificationID", notificationID)
+		return err
+	}
+	return nil
+}
 
-	for _, el := range likeNotif {
-		result = append(result, fmt.Sprintf("User %d liked your post number %d", el.Liker, el.PostID))
+func (ns *NotificationService) MarkAllAsRead(userID int) (int, error) {
+	updated, err := ns.repo.MarkAllAsRead(userID)
+	if err != nil {
+		slog.Error("Mark all notifications as read", "error", err, "userID", userID)
+		return 0, err
 	}
+	return updated, nil
+}
 
-	return result, err
+func (ns *NotificationService) DeleteNotification(userID, notificationID int) error {
+	err := ns.repo.DeleteNotification(userID, notificationID)
+	if err != nil {
+		slog.Error("Delete notification", "error", err, "notificationID", notificationID)
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,117 @@
+package likes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"pictureloader/notification_microservice/auth"
+	"strconv"
+	"time"
+)
+
+// heartbeatInterval - как часто отправлять комментарий-пинг, чтобы прокси не закрывали соединение
+const heartbeatInterval = 15 * time.Second
+
+// StreamNotifications отдает новые уведомления текущего пользователя через Server-Sent Events.
+// После переподключения клиент присылает Last-Event-ID и получает пропущенные уведомления.
+func (server *LikesServer) StreamNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var lastEventID int64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastEventID = id
+	}
+
+	locale := server.service.Locale(userID, r.Header.Get("Accept-Language"))
+
+	// подписываемся до чтения пропущенных, чтобы не потерять уведомления между запросом в базу и подпиской
+	client := server.hub.Subscribe(userID)
+	defer server.hub.Unsubscribe(client)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Set("Content-Language", locale)
+	w.WriteHeader(http.StatusOK)
+
+	// события из хаба могут прийти не по порядку номеров: транзакции разных реплик и потребителей
+	// завершаются в своем порядке. Поэтому отбрасываются только события группы, более новое состояние
+	// которой уже отдано клиенту, в том числе при докачке.
+	sent := map[int]int64{}
+	if lastEventID > 0 {
+		missed, err := server.service.GetLikeNotificationsAfter(userID, lastEventID)
+		if err != nil {
+			return
+		}
+		for _, notification := range missed {
+			notification.Localize(locale)
+			data, err := json.Marshal(notification)
+			if err != nil {
+				continue
+			}
+			eventID := EventID(notification)
+			if writeEvent(w, eventID, data) != nil {
+				return
+			}
+			sent[notification.ID] = eventID
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-client.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event := <-client.Events():
+			var notification LikeNotification
+			if err := json.Unmarshal(event.Data, &notification); err != nil {
+				slog.Error("Invalid stream event", "error", err, "eventID", event.ID)
+				continue
+			}
+			if event.ID <= sent[notification.ID] {
+				continue
+			}
+			notification.Localize(locale)
+			data, err := json.Marshal(notification)
+			if err != nil {
+				continue
+			}
+			if err = writeEvent(w, event.ID, data); err != nil {
+				slog.Info("Stream client disconnected", "userID", userID, "error", err)
+				return
+			}
+			sent[notification.ID] = event.ID
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, id int64, data []byte) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", id, data)
+	return err
+}
@@ -0,0 +1,7 @@
+package moderation
+
+import "gorm.io/gorm"
+
+func NewModerationNotifications(db *gorm.DB, preferences PreferencesProvider, email EmailNotifier) *Service {
+	return NewService(NewPSQLRepository(db), preferences, email)
+}
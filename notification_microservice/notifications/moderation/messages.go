@@ -0,0 +1,41 @@
+package moderation
+
+import (
+	"log/slog"
+	"pictureloader/notification_microservice/i18n"
+)
+
+// Notice - письмо о решении модерации владельцу контента или пожаловавшемуся
+type Notice struct {
+	Reporter bool
+	Type     string
+	Name     string
+	Action   string
+	Subject  string
+	Message  string
+}
+
+type noticeMessageData struct {
+	Type   string
+	Name   string
+	Action string
+}
+
+// Localize заполняет Subject и Message на языке locale
+func (n *Notice) Localize(locale string) {
+	key, subjectKey := i18n.ModerationOwner, i18n.ModerationOwnerSubject
+	if n.Reporter {
+		key, subjectKey = i18n.ModerationReporter, i18n.ModerationReporterSubject
+	}
+	message, err := i18n.Render(locale, key, noticeMessageData{n.Type, n.Name, n.Action})
+	if err != nil {
+		slog.Error("Render moderation notice", "error", err, "locale", locale)
+		return
+	}
+	subject, err := i18n.Render(locale, subjectKey, nil)
+	if err != nil {
+		slog.Error("Render moderation subject", "error", err, "locale", locale)
+		return
+	}
+	n.Message, n.Subject = message, subject
+}
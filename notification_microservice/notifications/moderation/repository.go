@@ -0,0 +1,19 @@
+package moderation
+
+import (
+	"gorm.io/gorm"
+	"pictureloader/notification_microservice/idempotency"
+)
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+func NewPSQLRepository(db *gorm.DB) *Repository {
+	return &Repository{db}
+}
+
+// Claim отмечает решение модерации обработанным, false - событие уже обрабатывалось
+func (r *Repository) Claim(eventID string) (bool, error) {
+	return idempotency.Claim(r.DB, idempotency.ConsumerModeration, eventID)
+}
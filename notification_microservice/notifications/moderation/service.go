@@ -0,0 +1,82 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"pictureloader/common/events"
+	"pictureloader/notification_microservice/notifications/preferences"
+	"slices"
+)
+
+const (
+	TypeModeration = preferences.TypeModeration
+
+	actionDismiss = "dismiss"
+)
+
+// PreferencesProvider отдает настройки уведомлений получателя
+type PreferencesProvider interface {
+	GetPreferences(userID int) (preferences.Preferences, error)
+}
+
+// EmailNotifier отправляет письмо о решении модерации
+type EmailNotifier interface {
+	NotifyModeration(ctx context.Context, userID int, locale string, notice Notice) error
+}
+
+// Service сообщает о решениях модераторов владельцу контента и тем, кто на него пожаловался
+type Service struct {
+	repo        *Repository
+	preferences PreferencesProvider
+	email       EmailNotifier
+}
+
+func NewService(repo *Repository, preferences PreferencesProvider, email EmailNotifier) *Service {
+	return &Service{repo, preferences, email}
+}
+
+func (s *Service) ProcessDecisionMessage(ctx context.Context, message []byte) error {
+	var msg events.ModerationDecided
+	if err := json.Unmarshal(message, &msg); err != nil {
+		// битое сообщение не станет лучше при повторной доставке
+		slog.Error("Error unmarshalling moderation message", "error", err)
+		return nil
+	}
+	isNew, err := s.repo.Claim(msg.EventID)
+	if err != nil {
+		return err
+	}
+	if !isNew {
+		slog.Info("Duplicate moderation event skipped", "eventID", msg.EventID)
+		return nil
+	}
+
+	notice := Notice{Type: msg.TargetType, Name: msg.TargetName, Action: msg.Action}
+	// если жалобу отклонили, владельцу сообщать не о чем
+	if msg.Action != actionDismiss && msg.OwnerID != 0 && !slices.Contains(msg.Reporters, msg.OwnerID) {
+		s.notify(ctx, msg.OwnerID, notice)
+	}
+	notice.Reporter = true
+	for _, reporterID := range msg.Reporters {
+		s.notify(ctx, reporterID, notice)
+	}
+	return nil
+}
+
+// notify отправляет письмо, если пользователь его хочет. Событие уже отмечено обработанным,
+// поэтому ошибки только логируются: повтор доставки разослал бы письма остальным второй раз.
+func (s *Service) notify(ctx context.Context, userID int, notice Notice) {
+	prefs, err := s.preferences.GetPreferences(userID)
+	if err != nil {
+		slog.Error("Error getting preferences", "error", err, "userID", userID)
+		return
+	}
+	if !prefs.Wants(TypeModeration, preferences.ChannelEmail) {
+		return
+	}
+	err = s.email.NotifyModeration(ctx, userID, prefs.EmailLocale(), notice)
+	if err != nil {
+		slog.Error("Error sending moderation email", "error", err, "userID", userID)
+	}
+}
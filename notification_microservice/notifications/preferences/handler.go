@@ -0,0 +1,97 @@
+package preferences
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"pictureloader/notification_microservice/auth"
+	"strconv"
+)
+
+type Server struct {
+	service *Service
+}
+
+func NewPreferencesServer(service *Service) *Server {
+	return &Server{service}
+}
+
+func PreferencesRouter(api *mux.Router, server *Server, authMiddleware *auth.Middleware) {
+	router := api.PathPrefix("/notifications/preferences").Subrouter()
+	router.HandleFunc("", server.GetPreferences).Methods("GET")
+	router.HandleFunc("", server.UpdatePreferences).Methods("PUT")
+	router.HandleFunc("/muted-posts/{postID:[0-9]+}", server.MutePost).Methods("PUT")
+	router.HandleFunc("/muted-posts/{postID:[0-9]+}", server.UnmutePost).Methods("DELETE")
+	router.Use(authMiddleware.Authenticate)
+}
+
+func (server *Server) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	prefs, err := server.service.GetPreferences(userID)
+	if err != nil {
+		http.Error(w, "Failed to get preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// UpdatePreferences полностью заменяет настройки пользователя
+func (server *Server) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var prefs Preferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	prefs.UserID = userID
+
+	err := server.service.UpdatePreferences(prefs)
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to save preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"Preferences updated"}`))
+}
+
+func (server *Server) MutePost(w http.ResponseWriter, r *http.Request) {
+	server.setPostMuted(w, r, true)
+}
+
+func (server *Server) UnmutePost(w http.ResponseWriter, r *http.Request) {
+	server.setPostMuted(w, r, false)
+}
+
+func (server *Server) setPostMuted(w http.ResponseWriter, r *http.Request, muted bool) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+	postID, _ := strconv.Atoi(mux.Vars(r)["postID"])
+
+	err := server.service.SetPostMuted(userID, postID, muted)
+	if err != nil {
+		http.Error(w, "Failed to save preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"Preferences updated"}`))
+}
@@ -0,0 +1,10 @@
+package preferences
+
+import "gorm.io/gorm"
+
+func NewPreferences(db *gorm.DB) (*Server, *Service) {
+	repository := NewPSQLPreferencesRepository(db)
+	service := NewPreferencesService(repository)
+	server := NewPreferencesServer(service)
+	return server, service
+}
@@ -0,0 +1,147 @@
+package preferences
+
+import (
+	"errors"
+	"fmt"
+	"pictureloader/notification_microservice/i18n"
+	"slices"
+	"time"
+)
+
+const (
+	ChannelInApp   = "in_app"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+
+	DigestNone   = "none"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+
+	TypeLike       = "like"
+	TypeModeration = "moderation"
+)
+
+var (
+	knownChannels = []string{ChannelInApp, ChannelEmail, ChannelWebhook}
+	knownDigests  = []string{DigestNone, DigestDaily, DigestWeekly}
+	knownTypes    = []string{TypeLike, TypeModeration}
+
+	// defaultChannels - каналы для типов событий, которые пользователь не настраивал
+	defaultChannels = map[string][]string{
+		TypeLike: {ChannelInApp, ChannelWebhook},
+		// о решениях модерации пишем на почту: у них нет ленты в приложении
+		TypeModeration: {ChannelEmail},
+	}
+)
+
+// QuietHours - часы [Start, End) в часовом поясе пользователя, когда не нужно беспокоить.
+// Start больше End означает интервал через полночь.
+type QuietHours struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type Preferences struct {
+	UserID                 int                 `json:"user_id"`
+	Channels               map[string][]string `json:"channels"`
+	QuietHours             *QuietHours         `json:"quiet_hours"`
+	Timezone               string              `json:"timezone"`
+	DigestFrequency        string              `json:"digest_frequency"`
+	MuteLikesOlderThanDays int                 `json:"mute_likes_older_than_days"`
+	MutedPosts             []int               `json:"muted_posts"`
+	// Locale - язык уведомлений, пустой - по заголовку Accept-Language
+	Locale string `json:"locale"`
+}
+
+// Default - настройки пользователя, который их не менял
+func Default(userID int) Preferences {
+	return Preferences{
+		UserID:          userID,
+		Channels:        map[string][]string{},
+		Timezone:        "UTC",
+		DigestFrequency: DigestNone,
+		MutedPosts:      []int{},
+	}
+}
+
+func (p Preferences) Validate() error {
+	for eventType, channels := range p.Channels {
+		if !slices.Contains(knownTypes, eventType) {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+		for _, channel := range channels {
+			if !slices.Contains(knownChannels, channel) {
+				return fmt.Errorf("unknown channel %q", channel)
+			}
+		}
+	}
+	if p.QuietHours != nil {
+		if p.QuietHours.Start < 0 || p.QuietHours.Start > 23 || p.QuietHours.End < 0 || p.QuietHours.End > 23 {
+			return errors.New("quiet hours must be between 0 and 23")
+		}
+		if p.QuietHours.Start == p.QuietHours.End {
+			return errors.New("quiet hours start and end must differ")
+		}
+	}
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", p.Timezone)
+	}
+	if !slices.Contains(knownDigests, p.DigestFrequency) {
+		return fmt.Errorf("unknown digest frequency %q", p.DigestFrequency)
+	}
+	if p.MuteLikesOlderThanDays < 0 {
+		return errors.New("mute_likes_older_than_days must not be negative")
+	}
+	if p.Locale != "" && !i18n.IsSupported(p.Locale) {
+		return fmt.Errorf("unsupported locale %q", p.Locale)
+	}
+	return nil
+}
+
+// ChannelsFor возвращает включенные каналы для типа события
+func (p Preferences) ChannelsFor(eventType string) []string {
+	if channels, ok := p.Channels[eventType]; ok {
+		return channels
+	}
+	return defaultChannels[eventType]
+}
+
+// Wants проверяет, включен ли канал для типа события
+func (p Preferences) Wants(eventType, channel string) bool {
+	return slices.Contains(p.ChannelsFor(eventType), channel)
+}
+
+// EmailLocale - язык писем: у писем нет запроса, поэтому без настройки используется язык по умолчанию
+func (p Preferences) EmailLocale() string {
+	if p.Locale != "" {
+		return p.Locale
+	}
+	return i18n.Default
+}
+
+// InQuietHours проверяет, попадает ли момент now в тихие часы пользователя
+func (p Preferences) InQuietHours(now time.Time) bool {
+	if p.QuietHours == nil {
+		return false
+	}
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	hour := now.In(location).Hour()
+	if p.QuietHours.Start < p.QuietHours.End {
+		return hour >= p.QuietHours.Start && hour < p.QuietHours.End
+	}
+	return hour >= p.QuietHours.Start || hour < p.QuietHours.End
+}
+
+// LikeMuted проверяет, заглушены ли лайки поста: вручную или потому, что пост старый
+func (p Preferences) LikeMuted(postID int, postCreatedAt, now time.Time) bool {
+	if slices.Contains(p.MutedPosts, postID) {
+		return true
+	}
+	if p.MuteLikesOlderThanDays == 0 || postCreatedAt.IsZero() {
+		return false
+	}
+	return postCreatedAt.Before(now.AddDate(0, 0, -p.MuteLikesOlderThanDays))
+}
@@ -0,0 +1,89 @@
+package preferences
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pictureloader/notification_microservice/database"
+	"time"
+)
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+func NewPSQLPreferencesRepository(db *gorm.DB) *Repository {
+	return &Repository{db}
+}
+
+// GetPreferences возвращает настройки пользователя или настройки по умолчанию
+func (r *Repository) GetPreferences(userID int) (Preferences, error) {
+	var row database.NotificationPreference
+	err := r.DB.Where("user_id = ?", userID).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Default(userID), nil
+	}
+	if err != nil {
+		return Preferences{}, err
+	}
+	return toPreferences(row), nil
+}
+
+func (r *Repository) SavePreferences(prefs Preferences) error {
+	row := database.NotificationPreference{
+		UserID:                 prefs.UserID,
+		Channels:               prefs.Channels,
+		Timezone:               prefs.Timezone,
+		DigestFrequency:        prefs.DigestFrequency,
+		MuteLikesOlderThanDays: prefs.MuteLikesOlderThanDays,
+		MutedPosts:             prefs.MutedPosts,
+		Locale:                 prefs.Locale,
+	}
+	if prefs.QuietHours != nil {
+		row.QuietHoursStart = &prefs.QuietHours.Start
+		row.QuietHoursEnd = &prefs.QuietHours.End
+	}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channels", "quiet_hours_start", "quiet_hours_end", "timezone",
+			"digest_frequency", "mute_likes_older_than_days", "muted_posts", "locale", "updated_at"}),
+	}).Create(&row).Error
+}
+
+// GetDigestSubscribers возвращает настройки пользователей, подписанных на дайджест
+func (r *Repository) GetDigestSubscribers() ([]database.NotificationPreference, error) {
+	var rows []database.NotificationPreference
+	err := r.DB.Where("digest_frequency IN ?", []string{DigestDaily, DigestWeekly}).Find(&rows).Error
+	return rows, err
+}
+
+// ClaimDigest отмечает, что дайджест отправлен в now, если с before его никто не отправлял.
+// Условный апдейт не дает двум репликам отправить один дайджест дважды.
+func (r *Repository) ClaimDigest(userID int, before, now time.Time) (bool, error) {
+	result := r.DB.Model(&database.NotificationPreference{}).
+		Where("user_id = ? AND (last_digest_at IS NULL OR last_digest_at <= ?)", userID, before).
+		UpdateColumn("last_digest_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+func toPreferences(row database.NotificationPreference) Preferences {
+	prefs := Preferences{
+		UserID:                 row.UserID,
+		Channels:               row.Channels,
+		Timezone:               row.Timezone,
+		DigestFrequency:        row.DigestFrequency,
+		MuteLikesOlderThanDays: row.MuteLikesOlderThanDays,
+		MutedPosts:             row.MutedPosts,
+		Locale:                 row.Locale,
+	}
+	if row.QuietHoursStart != nil && row.QuietHoursEnd != nil {
+		prefs.QuietHours = &QuietHours{Start: *row.QuietHoursStart, End: *row.QuietHoursEnd}
+	}
+	if prefs.Channels == nil {
+		prefs.Channels = map[string][]string{}
+	}
+	if prefs.MutedPosts == nil {
+		prefs.MutedPosts = []int{}
+	}
+	return prefs
+}
@@ -0,0 +1,136 @@
+package preferences
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewPreferencesService(repo *Repository) *Service {
+	return &Service{repo}
+}
+
+func (s *Service) GetPreferences(userID int) (Preferences, error) {
+	prefs, err := s.repo.GetPreferences(userID)
+	if err != nil {
+		slog.Error("Get preferences", "error", err, "userID", userID)
+		return Preferences{}, err
+	}
+	return prefs, nil
+}
+
+func (s *Service) UpdatePreferences(prefs Preferences) error {
+	if prefs.Channels == nil {
+		prefs.Channels = map[string][]string{}
+	}
+	if prefs.MutedPosts == nil {
+		prefs.MutedPosts = []int{}
+	}
+	if prefs.Timezone == "" {
+		prefs.Timezone = "UTC"
+	}
+	if prefs.DigestFrequency == "" {
+		prefs.DigestFrequency = DigestNone
+	}
+	if err := prefs.Validate(); err != nil {
+		return &ValidationError{err}
+	}
+
+	err := s.repo.SavePreferences(prefs)
+	if err != nil {
+		slog.Error("Save preferences", "error", err, "userID", prefs.UserID)
+		return err
+	}
+	return nil
+}
+
+// SetPostMuted включает или выключает уведомления о лайках конкретного поста
+func (s *Service) SetPostMuted(userID, postID int, muted bool) error {
+	prefs, err := s.GetPreferences(userID)
+	if err != nil {
+		return err
+	}
+
+	index := slices.Index(prefs.MutedPosts, postID)
+	switch {
+	case muted && index == -1:
+		prefs.MutedPosts = append(prefs.MutedPosts, postID)
+	case !muted && index != -1:
+		prefs.MutedPosts = slices.Delete(prefs.MutedPosts, index, index+1)
+	default:
+		return nil
+	}
+
+	err = s.repo.SavePreferences(prefs)
+	if err != nil {
+		slog.Error("Save preferences", "error", err, "userID", userID)
+		return err
+	}
+	return nil
+}
+
+// DigestDue - пользователь, которому пора отправить дайджест
+type DigestDue struct {
+	UserID    int
+	Frequency string
+	Since     time.Time // начало периода дайджеста
+	Locale    string
+}
+
+func digestPeriod(frequency string) time.Duration {
+	if frequency == DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// DueDigests возвращает пользователей, у которых к моменту now подошел срок дайджеста
+func (s *Service) DueDigests(now time.Time) ([]DigestDue, error) {
+	rows, err := s.repo.GetDigestSubscribers()
+	if err != nil {
+		slog.Error("Get digest subscribers", "error", err)
+		return nil, err
+	}
+
+	var result []DigestDue
+	for _, row := range rows {
+		periodStart := now.Add(-digestPeriod(row.DigestFrequency))
+		if row.LastDigestAt != nil && row.LastDigestAt.After(periodStart) {
+			continue
+		}
+		since := periodStart
+		if row.LastDigestAt != nil {
+			since = *row.LastDigestAt
+		}
+		result = append(result, DigestDue{
+			UserID:    row.UserID,
+			Frequency: row.DigestFrequency,
+			Since:     since,
+			Locale:    toPreferences(row).EmailLocale(),
+		})
+	}
+	return result, nil
+}
+
+// ClaimDigest резервирует отправку дайджеста, false - его уже отправила другая реплика
+func (s *Service) ClaimDigest(due DigestDue, now time.Time) (bool, error) {
+	claimed, err := s.repo.ClaimDigest(due.UserID, now.Add(-digestPeriod(due.Frequency)), now)
+	if err != nil {
+		slog.Error("Claim digest", "error", err, "userID", due.UserID)
+		return false, err
+	}
+	return claimed, nil
+}
+
+// ValidationError - настройки пришли в неверном формате
+type ValidationError struct {
+	err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.err.Error()
+}
@@ -0,0 +1,141 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"pictureloader/notification_microservice/auth"
+	"strconv"
+)
+
+type Server struct {
+	service *Service
+}
+
+func NewWebhookServer(service *Service) *Server {
+	return &Server{service}
+}
+
+func WebhooksRouter(api *mux.Router, server *Server, authMiddleware *auth.Middleware) {
+	router := api.PathPrefix("/webhooks").Subrouter()
+	router.HandleFunc("", server.CreateWebhook).Methods("POST")
+	router.HandleFunc("", server.GetWebhooks).Methods("GET")
+	router.HandleFunc("/{webhookID:[0-9]+}", server.DeleteWebhook).Methods("DELETE")
+	router.HandleFunc("/{webhookID:[0-9]+}/deliveries", server.GetDeliveries).Methods("GET")
+	router.HandleFunc("/{webhookID:[0-9]+}/deliveries/{deliveryID:[0-9]+}/redeliver", server.Redeliver).Methods("POST")
+	router.Use(authMiddleware.Authenticate)
+}
+
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// CreateWebhook регистрирует вебхук, секрет возвращается только в этом ответе
+func (server *Server) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req createWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := server.service.CreateWebhook(r.Context(), userID, req.URL, req.Secret, req.Events)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+func (server *Server) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	webhooks, err := server.service.GetWebhooks(userID)
+	if err != nil {
+		http.Error(w, "Failed to get webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+func (server *Server) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+	webhookID, _ := strconv.Atoi(mux.Vars(r)["webhookID"])
+
+	err := server.service.DeleteWebhook(userID, webhookID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"Webhook deleted"}`))
+}
+
+// GetDeliveries отдает журнал последних доставок вебхука
+func (server *Server) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+	webhookID, _ := strconv.Atoi(mux.Vars(r)["webhookID"])
+
+	deliveries, err := server.service.GetDeliveries(userID, webhookID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+func (server *Server) Redeliver(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.CurrentUser(w, r)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	webhookID, _ := strconv.Atoi(vars["webhookID"])
+	deliveryID, _ := strconv.Atoi(vars["deliveryID"])
+
+	delivery, err := server.service.Redeliver(userID, webhookID, deliveryID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,14 @@
+package webhooks
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+func NewWebhooks(db *gorm.DB, preferences PreferencesProvider, pollInterval time.Duration) (*Server, *Service, *DeliveryWorker) {
+	repository := NewPSQLWebhookRepository(db)
+	service := NewWebhookService(repository, preferences)
+	server := NewWebhookServer(service)
+	worker := NewDeliveryWorker(repository, pollInterval)
+	return server, service, worker
+}
@@ -0,0 +1,151 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pictureloader/notification_microservice/database"
+	"pictureloader/notification_microservice/idempotency"
+	"time"
+)
+
+var ErrNotFound = errors.New("webhook not found")
+
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+func NewPSQLWebhookRepository(db *gorm.DB) *Repository {
+	return &Repository{db}
+}
+
+func (r *Repository) CreateWebhook(webhook *database.Webhook) error {
+	return r.DB.Create(webhook).Error
+}
+
+func (r *Repository) GetWebhooks(userID int) ([]database.Webhook, error) {
+	var webhooks []database.Webhook
+	err := r.DB.Where("user_id = ?", userID).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// GetWebhook возвращает вебхук, только если он принадлежит пользователю
+func (r *Repository) GetWebhook(userID, webhookID int) (database.Webhook, error) {
+	var webhook database.Webhook
+	err := r.DB.Where("id = ? AND user_id = ?", webhookID, userID).First(&webhook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return webhook, ErrNotFound
+	}
+	return webhook, err
+}
+
+// GetSubscribedWebhooks возвращает вебхуки пользователя, подписанные на тип события
+func (r *Repository) GetSubscribedWebhooks(userID int, eventType string) ([]database.Webhook, error) {
+	filter, err := json.Marshal([]string{eventType})
+	if err != nil {
+		return nil, err
+	}
+	var webhooks []database.Webhook
+	err = r.DB.Where("user_id = ? AND events @> ?::jsonb", userID, string(filter)).Find(&webhooks).Error
+	return webhooks, err
+}
+
+// DeleteWebhook удаляет вебхук вместе с журналом его доставок
+func (r *Repository) DeleteWebhook(userID, webhookID int) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", webhookID, userID).Delete(&database.Webhook{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Where("webhook_id = ?", webhookID).Delete(&database.WebhookDelivery{}).Error
+	})
+}
+
+// CreateEventDeliveries сохраняет доставки события, если событие eventID еще не обрабатывалось.
+// Возвращает false для дубля.
+func (r *Repository) CreateEventDeliveries(eventID string, deliveries []database.WebhookDelivery) (bool, error) {
+	isNew := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		isNew, err = idempotency.Claim(tx, idempotency.ConsumerWebhooks, eventID)
+		if err != nil || !isNew {
+			return err
+		}
+		return tx.Create(&deliveries).Error
+	})
+	return isNew, err
+}
+
+func (r *Repository) CreateDeliveries(deliveries []database.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.DB.Create(&deliveries).Error
+}
+
+func (r *Repository) GetDeliveries(webhookID, limit int) ([]database.WebhookDelivery, error) {
+	var deliveries []database.WebhookDelivery
+	err := r.DB.Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *Repository) GetDelivery(webhookID, deliveryID int) (database.WebhookDelivery, error) {
+	var delivery database.WebhookDelivery
+	err := r.DB.Where("id = ? AND webhook_id = ?", deliveryID, webhookID).First(&delivery).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return delivery, ErrNotFound
+	}
+	return delivery, err
+}
+
+// ClaimDueDeliveries забирает до limit доставок, которым пора уходить, и откладывает их на lease.
+// SKIP LOCKED не дает двум репликам взять одну доставку, а lease вернет ее в очередь,
+// если реплика упадет, не записав результат.
+func (r *Repository) ClaimDueDeliveries(now time.Time, limit int, lease time.Duration) ([]database.WebhookDelivery, error) {
+	var deliveries []database.WebhookDelivery
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+			Order("next_attempt_at").Limit(limit).Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+		ids := make([]int, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+		return tx.Model(&database.WebhookDelivery{}).Where("id IN ?", ids).
+			UpdateColumn("next_attempt_at", now.Add(lease)).Error
+	})
+	return deliveries, err
+}
+
+// GetWebhooksByIDs возвращает вебхуки по id, ключ карты - id вебхука
+func (r *Repository) GetWebhooksByIDs(ids []int) (map[int]database.Webhook, error) {
+	var webhooks []database.Webhook
+	err := r.DB.Where("id IN ?", ids).Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int]database.Webhook, len(webhooks))
+	for _, webhook := range webhooks {
+		result[webhook.ID] = webhook
+	}
+	return result, nil
+}
+
+// SaveAttempt записывает результат попытки доставки
+func (r *Repository) SaveAttempt(delivery *database.WebhookDelivery) error {
+	return r.DB.Model(delivery).Select("status", "attempts", "next_attempt_at", "last_status_code",
+		"last_error", "delivered_at", "updated_at").Updates(delivery).Error
+}
@@ -0,0 +1,286 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"pictureloader/common/events"
+	"pictureloader/notification_microservice/database"
+	"pictureloader/notification_microservice/notifications/preferences"
+	"slices"
+	"time"
+)
+
+// Типы событий, которые можно получать на вебхук
+const (
+	EventLikeCreated   = "like.created"
+	EventPostCreated   = "post.created"
+	EventImageUploaded = "image.uploaded"
+)
+
+var knownEvents = []string{EventLikeCreated, EventPostCreated, EventImageUploaded}
+
+// deliveriesLimit - сколько последних доставок отдается в журнале
+const deliveriesLimit = 100
+
+// PreferencesProvider отдает настройки уведомлений владельца вебхука
+type PreferencesProvider interface {
+	GetPreferences(userID int) (preferences.Preferences, error)
+}
+
+type ValidationError struct {
+	msg string
+}
+
+func (e *ValidationError) Error() string {
+	return e.msg
+}
+
+// Envelope - тело запроса, которое получает вебхук
+type Envelope struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Webhook - вебхук в ответах API, секрет отдается только при создании
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Delivery struct {
+	ID             int             `json:"id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+type Service struct {
+	repo        *Repository
+	preferences PreferencesProvider
+}
+
+func NewWebhookService(repo *Repository, preferences PreferencesProvider) *Service {
+	return &Service{repo, preferences}
+}
+
+// CreateWebhook регистрирует вебхук, пустой секрет генерируется
+func (s *Service) CreateWebhook(ctx context.Context, userID int, webhookURL, secret string, eventTypes []string) (Webhook, error) {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return Webhook{}, &ValidationError{"url must be an absolute http or https URL"}
+	}
+	if parsed.User != nil {
+		return Webhook{}, &ValidationError{"url must not contain credentials"}
+	}
+	if err = checkHost(ctx, parsed.Hostname()); errors.Is(err, ErrBlockedAddress) {
+		return Webhook{}, &ValidationError{"url must point to a public address"}
+	} else if err != nil {
+		return Webhook{}, &ValidationError{"url host cannot be resolved"}
+	}
+	if len(eventTypes) == 0 {
+		return Webhook{}, &ValidationError{"at least one event type is required"}
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(knownEvents, eventType) {
+			return Webhook{}, &ValidationError{fmt.Sprintf("unknown event type %q", eventType)}
+		}
+	}
+	if secret == "" {
+		secret, err = randomHex(32)
+		if err != nil {
+			return Webhook{}, err
+		}
+	}
+
+	row := database.Webhook{UserID: userID, URL: webhookURL, Secret: secret, Events: eventTypes}
+	err = s.repo.CreateWebhook(&row)
+	if err != nil {
+		return Webhook{}, err
+	}
+	webhook := toWebhook(row)
+	webhook.Secret = row.Secret
+	return webhook, nil
+}
+
+func (s *Service) GetWebhooks(userID int) ([]Webhook, error) {
+	rows, err := s.repo.GetWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Webhook, len(rows))
+	for i, row := range rows {
+		result[i] = toWebhook(row)
+	}
+	return result, nil
+}
+
+func (s *Service) DeleteWebhook(userID, webhookID int) error {
+	return s.repo.DeleteWebhook(userID, webhookID)
+}
+
+func (s *Service) GetDeliveries(userID, webhookID int) ([]Delivery, error) {
+	if _, err := s.repo.GetWebhook(userID, webhookID); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.GetDeliveries(webhookID, deliveriesLimit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Delivery, len(rows))
+	for i, row := range rows {
+		result[i] = toDelivery(row)
+	}
+	return result, nil
+}
+
+// Redeliver ставит в очередь новую доставку с тем же телом, исходная запись журнала не меняется
+func (s *Service) Redeliver(userID, webhookID, deliveryID int) (Delivery, error) {
+	if _, err := s.repo.GetWebhook(userID, webhookID); err != nil {
+		return Delivery{}, err
+	}
+	original, err := s.repo.GetDelivery(webhookID, deliveryID)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	deliveries := []database.WebhookDelivery{{
+		WebhookID:     webhookID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        StatusPending,
+		NextAttemptAt: time.Now(),
+	}}
+	err = s.repo.CreateDeliveries(deliveries)
+	if err != nil {
+		return Delivery{}, err
+	}
+	return toDelivery(deliveries[0]), nil
+}
+
+func (s *Service) ProcessLikeMessage(ctx context.Context, message []byte) error {
+	var msg events.NewLike
+	if err := json.Unmarshal(message, &msg); err != nil {
+		slog.Info("Error unmarshalling message", "error", err)
+		return nil
+	}
+	prefs, err := s.preferences.GetPreferences(msg.Liked)
+	if err != nil {
+		return err
+	}
+	if prefs.LikeMuted(msg.PostID, msg.PostCreatedAt, time.Now()) || !prefs.Wants(preferences.TypeLike, preferences.ChannelWebhook) {
+		return nil
+	}
+	return s.enqueue(msg.EventID, msg.Liked, EventLikeCreated, map[string]any{
+		"post_id":    msg.PostID,
+		"post_name":  msg.PostName,
+		"liker":      msg.Liker,
+		"liker_name": msg.LikerName,
+	})
+}
+
+func (s *Service) ProcessPostCreatedMessage(ctx context.Context, message []byte) error {
+	var msg events.PostCreated
+	if err := json.Unmarshal(message, &msg); err != nil {
+		slog.Info("Error unmarshalling message", "error", err)
+		return nil
+	}
+	return s.enqueue(msg.EventID, msg.UserID, EventPostCreated, msg)
+}
+
+func (s *Service) ProcessImageUploadedMessage(ctx context.Context, message []byte) error {
+	var msg events.ImageUploaded
+	if err := json.Unmarshal(message, &msg); err != nil {
+		slog.Info("Error unmarshalling message", "error", err)
+		return nil
+	}
+	return s.enqueue(msg.EventID, msg.UserID, EventImageUploaded, msg)
+}
+
+// enqueue создает по доставке на каждый вебхук пользователя, подписанный на событие.
+// id события из шины передается получателю, чтобы и он мог отбрасывать дубли.
+func (s *Service) enqueue(eventID string, userID int, eventType string, data any) error {
+	webhooks, err := s.repo.GetSubscribedWebhooks(userID, eventType)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	envelopeID := eventID
+	if envelopeID == "" {
+		envelopeID, err = randomHex(16)
+		if err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	payload, err := json.Marshal(Envelope{ID: envelopeID, Type: eventType, CreatedAt: now, Data: data})
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]database.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = database.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       envelopeID,
+			EventType:     eventType,
+			Payload:       payload,
+			Status:        StatusPending,
+			NextAttemptAt: now,
+		}
+	}
+	isNew, err := s.repo.CreateEventDeliveries(eventID, deliveries)
+	if err == nil && !isNew {
+		slog.Info("Duplicate webhook event skipped", "eventID", eventID)
+	}
+	return err
+}
+
+func toWebhook(row database.Webhook) Webhook {
+	return Webhook{ID: row.ID, URL: row.URL, Events: row.Events, CreatedAt: row.CreatedAt}
+}
+
+func toDelivery(row database.WebhookDelivery) Delivery {
+	delivery := Delivery{
+		ID:             row.ID,
+		EventID:        row.EventID,
+		EventType:      row.EventType,
+		Payload:        row.Payload,
+		Status:         row.Status,
+		Attempts:       row.Attempts,
+		LastStatusCode: row.LastStatusCode,
+		LastError:      row.LastError,
+		CreatedAt:      row.CreatedAt,
+		DeliveredAt:    row.DeliveredAt,
+	}
+	if row.Status == StatusPending {
+		delivery.NextAttemptAt = &row.NextAttemptAt
+	}
+	return delivery
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,163 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"pictureloader/notification_microservice/database"
+	"strconv"
+	"time"
+)
+
+// Заголовки запроса доставки
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	maxAttempts  = 8
+	backoffBase  = 30 * time.Second
+	backoffLimit = 6 * time.Hour
+	batchSize    = 50
+	// claimLease - на сколько откладывается взятая доставка, должно быть больше таймаута запроса
+	claimLease     = time.Minute
+	requestTimeout = 10 * time.Second
+)
+
+// Sign считает подпись тела: "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// Метка времени входит в подпись, чтобы получатель мог отбросить старые повторы.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff - задержка перед следующей попыткой после attempts неудачных
+func Backoff(attempts int) time.Duration {
+	delay := backoffBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= backoffLimit {
+			return backoffLimit
+		}
+	}
+	return delay
+}
+
+// DeliveryWorker периодически отправляет доставки, которым пора уходить
+type DeliveryWorker struct {
+	repo     *Repository
+	client   *http.Client
+	interval time.Duration
+}
+
+func NewDeliveryWorker(repo *Repository, interval time.Duration) *DeliveryWorker {
+	return &DeliveryWorker{repo, newDeliveryClient(), interval}
+}
+
+// Run проверяет очередь раз в interval, пока не отменен ctx
+func (w *DeliveryWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *DeliveryWorker) deliverDue(ctx context.Context) {
+	deliveries, err := w.repo.ClaimDueDeliveries(time.Now(), batchSize, claimLease)
+	if err != nil {
+		slog.Error("Claim webhook deliveries", "error", err)
+		return
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	ids := make([]int, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.WebhookID
+	}
+	webhooks, err := w.repo.GetWebhooksByIDs(ids)
+	if err != nil {
+		slog.Error("Get webhooks", "error", err)
+		return
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			// вебхук удалили после того, как доставку взяли в работу
+			continue
+		}
+		w.attempt(ctx, webhook, delivery)
+		if err := w.repo.SaveAttempt(delivery); err != nil {
+			slog.Error("Save webhook delivery", "error", err, "deliveryID", delivery.ID)
+		}
+	}
+}
+
+// attempt отправляет доставку и записывает в нее результат и время следующей попытки
+func (w *DeliveryWorker) attempt(ctx context.Context, webhook database.Webhook, delivery *database.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	statusCode, err := w.send(ctx, webhook, delivery, now)
+	delivery.LastStatusCode = statusCode
+	if err == nil {
+		delivery.Status = StatusSucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = StatusFailed
+		slog.Info("Webhook delivery failed", "deliveryID", delivery.ID, "webhookID", webhook.ID, "error", err)
+		return
+	}
+	delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+}
+
+func (w *DeliveryWorker) send(ctx context.Context, webhook database.Webhook, delivery *database.WebhookDelivery,
+	now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,39 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"pictureloader/common/eventbus"
+	"pictureloader/common/events"
+)
+
+// Fanout рассылает события всем репликам нотификатора через шину:
+// реплика, сохранившая уведомление, публикует его, а каждая реплика отдает своим клиентам
+type Fanout struct {
+	bus eventbus.Bus
+	hub *Hub
+}
+
+func NewFanout(bus eventbus.Bus, hub *Hub) *Fanout {
+	return &Fanout{bus: bus, hub: hub}
+}
+
+func (f *Fanout) Broadcast(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return f.bus.Publish(ctx, events.TopicNotificationCreated, body)
+}
+
+// Listen подписывает реплику на события без группы, чтобы каждая получила свою копию
+func (f *Fanout) Listen(ctx context.Context) error {
+	return f.bus.Subscribe(ctx, events.TopicNotificationCreated, "", func(ctx context.Context, payload []byte) error {
+		var event Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return err
+		}
+		f.hub.Deliver(event)
+		return nil
+	})
+}
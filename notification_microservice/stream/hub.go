@@ -0,0 +1,90 @@
+package stream
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+)
+
+// clientBuffer - сколько событий может накопиться у медленного клиента, прежде чем его отключат
+const clientBuffer = 32
+
+// Event - уведомление, которое нужно доставить пользователю в реальном времени.
+// ID - номер изменения из последовательности в базе, клиент присылает его как Last-Event-ID.
+// События могут прийти в хаб не в порядке номеров.
+type Event struct {
+	ID     int64           `json:"id"`
+	UserID int             `json:"user_id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Client - одно открытое соединение пользователя (вкладка, устройство)
+type Client struct {
+	userID int
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Done закрывается, когда хаб отключил клиента
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// Hub хранит соединения пользователей на этой реплике
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[int]map[*Client]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{clients: make(map[int]map[*Client]struct{})}
+}
+
+func (h *Hub) Subscribe(userID int) *Client {
+	client := &Client{userID: userID, events: make(chan Event, clientBuffer), done: make(chan struct{})}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[userID] == nil {
+		h.clients[userID] = make(map[*Client]struct{})
+	}
+	h.clients[userID][client] = struct{}{}
+	return client
+}
+
+func (h *Hub) Unsubscribe(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients[client.userID], client)
+	if len(h.clients[client.userID]) == 0 {
+		delete(h.clients, client.userID)
+	}
+	client.close()
+}
+
+// Deliver отправляет событие всем соединениям пользователя на этой реплике
+func (h *Hub) Deliver(event Event) {
+	h.mu.RLock()
+	var slow []*Client
+	for client := range h.clients[event.UserID] {
+		select {
+		case client.events <- event:
+		default:
+			slow = append(slow, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range slow {
+		slog.Info("Dropping slow stream client", "userID", client.userID)
+		h.Unsubscribe(client)
+	}
+}
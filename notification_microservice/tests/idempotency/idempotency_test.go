@@ -0,0 +1,66 @@
+package idempotency
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"pictureloader/notification_microservice/idempotency"
+	"regexp"
+	"testing"
+)
+
+var claimQuery = regexp.QuoteMeta(`INSERT INTO "processed_events" ("consumer","event_id","processed_at") VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	conn, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}),
+		&gorm.Config{Logger: logger.Discard, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	return db, sqlMock
+}
+
+func TestClaim_Dedupe(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+
+	// первая отметка вставляет строку, повторная упирается в первичный ключ и ничего не вставляет
+	sqlMock.ExpectExec(claimQuery).WithArgs(idempotency.ConsumerLikes, "like-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec(claimQuery).WithArgs(idempotency.ConsumerLikes, "like-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	isNew, err := idempotency.Claim(db, idempotency.ConsumerLikes, "like-1")
+	assert.NoError(t, err)
+	assert.True(t, isNew)
+
+	isNew, err = idempotency.Claim(db, idempotency.ConsumerLikes, "like-1")
+	assert.NoError(t, err)
+	assert.False(t, isNew)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestClaim_WithoutEventID(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+
+	// события старых версий без id не дедуплицируются и в базу не пишутся
+	isNew, err := idempotency.Claim(db, idempotency.ConsumerLikes, "")
+
+	assert.NoError(t, err)
+	assert.True(t, isNew)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestClaim_Error(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+
+	sqlMock.ExpectExec(claimQuery).WillReturnError(gorm.ErrInvalidDB)
+
+	isNew, err := idempotency.Claim(db, idempotency.ConsumerWebhooks, "like-1")
+
+	assert.Error(t, err)
+	assert.False(t, isNew)
+}
@@ -0,0 +1,103 @@
+package likes
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/common/events"
+	"pictureloader/notification_microservice/notifications/likes"
+	"pictureloader/notification_microservice/notifications/preferences"
+	"pictureloader/notification_microservice/stream"
+	"time"
+)
+
+type MockNotificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockNotificationRepository) AddLike(like events.NewLike, since time.Time, inApp bool) (likes.LikeNotification, error) {
+	args := m.Called(like, since, inApp)
+	return args.Get(0).(likes.LikeNotification), args.Error(1)
+}
+
+func (m *MockNotificationRepository) GetAllLikeNotifications(likedID int) ([]likes.LikeNotification, error) {
+	args := m.Called(likedID)
+	notifications, _ := args.Get(0).([]likes.LikeNotification)
+	return notifications, args.Error(1)
+}
+
+func (m *MockNotificationRepository) GetLikeNotificationsAfterEvent(likedID int, eventSeq int64) ([]likes.LikeNotification, error) {
+	args := m.Called(likedID, eventSeq)
+	notifications, _ := args.Get(0).([]likes.LikeNotification)
+	return notifications, args.Error(1)
+}
+
+func (m *MockNotificationRepository) GetUnreadUpdatedAfter(likedID int, after time.Time) ([]likes.LikeNotification, error) {
+	args := m.Called(likedID, after)
+	notifications, _ := args.Get(0).([]likes.LikeNotification)
+	return notifications, args.Error(1)
+}
+
+func (m *MockNotificationRepository) CountUnread(likedID int) (int, error) {
+	args := m.Called(likedID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockNotificationRepository) MarkAsRead(likedID, notificationID int) error {
+	args := m.Called(likedID, notificationID)
+	return args.Error(0)
+}
+
+func (m *MockNotificationRepository) MarkAllAsRead(likedID int) (int, error) {
+	args := m.Called(likedID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockNotificationRepository) DeleteNotification(likedID, notificationID int) error {
+	args := m.Called(likedID, notificationID)
+	return args.Error(0)
+}
+
+////////////////////
+
+type MockBroadcaster struct {
+	mock.Mock
+}
+
+func (m *MockBroadcaster) Broadcast(ctx context.Context, event stream.Event) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+////////////////////
+
+type MockPreferences struct {
+	mock.Mock
+}
+
+func (m *MockPreferences) GetPreferences(userID int) (preferences.Preferences, error) {
+	args := m.Called(userID)
+	return args.Get(0).(preferences.Preferences), args.Error(1)
+}
+
+////////////////////
+
+type MockEmailNotifier struct {
+	mock.Mock
+}
+
+func (m *MockEmailNotifier) NotifyLike(ctx context.Context, userID int, locale string, notification likes.LikeNotification) error {
+	args := m.Called(ctx, userID, locale, notification)
+	return args.Error(0)
+}
+
+////////////////////
+
+// MockVerifier принимает любой токен как токен пользователя userID
+type MockVerifier struct {
+	userID int
+}
+
+func (m *MockVerifier) Verify(tokenString string) (jwt.MapClaims, error) {
+	return jwt.MapClaims{"sub": float64(m.userID)}, nil
+}
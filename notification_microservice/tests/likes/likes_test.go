@@ -0,0 +1,178 @@
+package likes
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"pictureloader/common/events"
+	"pictureloader/notification_microservice/database"
+	"pictureloader/notification_microservice/i18n"
+	"pictureloader/notification_microservice/notifications/likes"
+	"pictureloader/notification_microservice/notifications/preferences"
+	"testing"
+	"time"
+)
+
+const aggregationWindow = time.Hour
+
+func setupTest() (*likes.NotificationService, *MockNotificationRepository, *MockBroadcaster, *MockPreferences,
+	*MockEmailNotifier) {
+	repo := new(MockNotificationRepository)
+	broadcaster := new(MockBroadcaster)
+	prefs := new(MockPreferences)
+	email := new(MockEmailNotifier)
+	return likes.NewNotificationService(repo, broadcaster, prefs, email, aggregationWindow), repo, broadcaster, prefs, email
+}
+
+func likeMessage(t *testing.T, like events.NewLike) []byte {
+	message, err := json.Marshal(like)
+	assert.NoError(t, err)
+	return message
+}
+
+func withEventID(eventID string) any {
+	return mock.MatchedBy(func(like events.NewLike) bool { return like.EventID == eventID })
+}
+
+func TestMergeLike(t *testing.T) {
+	group := likes.NewLikeGroup(events.NewLike{PostID: 7, Liker: 1, Liked: 9, LikerName: "alice", PostName: "cat"})
+	assert.Equal(t, 1, group.ActorCount)
+	assert.Equal(t, []int{1}, group.Actors)
+	assert.Equal(t, map[int]string{1: "alice"}, group.ActorNames)
+
+	group = likes.MergeLike(group, events.NewLike{PostID: 7, Liker: 2, Liked: 9, LikerName: "bob"}, true)
+	group = likes.MergeLike(group, events.NewLike{PostID: 7, Liker: 3, Liked: 9, LikerName: "carol", PostName: "cat 2"}, true)
+	group = likes.MergeLike(group, events.NewLike{PostID: 7, Liker: 4, Liked: 9, LikerName: "dave"}, true)
+
+	assert.Equal(t, 4, group.ActorCount)
+	assert.Equal(t, 4, group.Liker)
+	// в группе хранятся только три последних лайкнувших, новые первыми
+	assert.Equal(t, []int{4, 3, 2}, group.Actors)
+	assert.Equal(t, map[int]string{4: "dave", 3: "carol", 2: "bob"}, group.ActorNames)
+	// пустое имя поста в событии не затирает известное
+	assert.Equal(t, "cat 2", group.PostName)
+}
+
+func TestMergeLike_RepeatedLiker(t *testing.T) {
+	group := database.LikesNotification{ActorCount: 2, Actors: []int{2, 1}, ActorNames: map[int]string{1: "alice", 2: "bob"}}
+
+	// лайк снят и поставлен снова: новое событие, но тот же пользователь
+	group = likes.MergeLike(group, events.NewLike{Liker: 1}, false)
+
+	assert.Equal(t, 2, group.ActorCount)
+	assert.Equal(t, []int{1, 2}, group.Actors)
+	// без нового имени остается сохраненное
+	assert.Equal(t, map[int]string{1: "alice", 2: "bob"}, group.ActorNames)
+}
+
+func TestNotificationService_ProcessLikeMessage_FirstLike(t *testing.T) {
+	ctx := context.Background()
+	notificationService, repo, broadcaster, prefs, email := setupTest()
+
+	userPrefs := preferences.Default(9)
+	userPrefs.Channels[preferences.TypeLike] = []string{preferences.ChannelInApp, preferences.ChannelEmail}
+	prefs.On("GetPreferences", 9).Return(userPrefs, nil)
+	notification := likes.LikeNotification{ID: 5, PostID: 7, Actors: []int{1}, ActorCount: 1, LatestAt: time.Now()}
+	repo.On("AddLike", withEventID("like-1"), mock.Anything, true).Return(notification, nil)
+	broadcaster.On("Broadcast", ctx, mock.Anything).Return(nil)
+	email.On("NotifyLike", ctx, 9, i18n.Default, notification).Return(nil)
+
+	err := notificationService.ProcessLikeMessage(ctx, likeMessage(t, events.NewLike{EventID: "like-1", PostID: 7, Liker: 1, Liked: 9}))
+
+	assert.NoError(t, err)
+	broadcaster.AssertNumberOfCalls(t, "Broadcast", 1)
+	email.AssertNumberOfCalls(t, "NotifyLike", 1)
+}
+
+func TestNotificationService_ProcessLikeMessage_Merged(t *testing.T) {
+	ctx := context.Background()
+	notificationService, repo, broadcaster, prefs, email := setupTest()
+
+	userPrefs := preferences.Default(9)
+	userPrefs.Channels[preferences.TypeLike] = []string{preferences.ChannelInApp, preferences.ChannelEmail}
+	prefs.On("GetPreferences", 9).Return(userPrefs, nil)
+	notification := likes.LikeNotification{ID: 5, PostID: 7, Actors: []int{2, 1}, ActorCount: 2, LatestAt: time.Now()}
+	repo.On("AddLike", withEventID("like-2"), mock.Anything, true).Return(notification, nil)
+	broadcaster.On("Broadcast", ctx, mock.Anything).Return(nil)
+
+	err := notificationService.ProcessLikeMessage(ctx, likeMessage(t, events.NewLike{EventID: "like-2", PostID: 7, Liker: 2, Liked: 9}))
+
+	assert.NoError(t, err)
+	// обновленная группа уходит в открытые вкладки, а письмо было только на первый лайк
+	broadcaster.AssertNumberOfCalls(t, "Broadcast", 1)
+	email.AssertNotCalled(t, "NotifyLike")
+}
+
+func TestNotificationService_ProcessLikeMessage_Duplicate(t *testing.T) {
+	ctx := context.Background()
+	notificationService, repo, broadcaster, prefs, email := setupTest()
+
+	prefs.On("GetPreferences", 9).Return(preferences.Default(9), nil)
+	repo.On("AddLike", withEventID("like-1"), mock.Anything, true).Return(likes.LikeNotification{}, likes.ErrDuplicate)
+
+	err := notificationService.ProcessLikeMessage(ctx, likeMessage(t, events.NewLike{EventID: "like-1", PostID: 7, Liker: 1, Liked: 9}))
+
+	assert.NoError(t, err)
+	broadcaster.AssertNotCalled(t, "Broadcast")
+	email.AssertNotCalled(t, "NotifyLike")
+}
+
+func TestNotificationService_ProcessLikeMessage_ChannelsDisabled(t *testing.T) {
+	ctx := context.Background()
+	notificationService, repo, broadcaster, prefs, _ := setupTest()
+
+	userPrefs := preferences.Default(9)
+	userPrefs.Channels[preferences.TypeLike] = []string{preferences.ChannelWebhook}
+	prefs.On("GetPreferences", 9).Return(userPrefs, nil)
+
+	err := notificationService.ProcessLikeMessage(ctx, likeMessage(t, events.NewLike{EventID: "like-1", PostID: 7, Liker: 1, Liked: 9}))
+
+	assert.NoError(t, err)
+	repo.AssertNotCalled(t, "AddLike")
+	broadcaster.AssertNotCalled(t, "Broadcast")
+}
+
+func TestNotificationService_ProcessLikeMessage_EmailOnly(t *testing.T) {
+	ctx := context.Background()
+	notificationService, repo, broadcaster, prefs, email := setupTest()
+
+	userPrefs := preferences.Default(9)
+	userPrefs.Channels[preferences.TypeLike] = []string{preferences.ChannelEmail}
+	prefs.On("GetPreferences", 9).Return(userPrefs, nil)
+	notification := likes.LikeNotification{ID: 5, PostID: 7, Actors: []int{1}, ActorCount: 1, LatestAt: time.Now()}
+	// группа сохраняется для письма и дайджеста, но не для списка в приложении
+	repo.On("AddLike", withEventID("like-1"), mock.Anything, false).Return(notification, nil)
+	email.On("NotifyLike", ctx, 9, i18n.Default, notification).Return(nil)
+
+	err := notificationService.ProcessLikeMessage(ctx, likeMessage(t, events.NewLike{EventID: "like-1", PostID: 7, Liker: 1, Liked: 9}))
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	broadcaster.AssertNotCalled(t, "Broadcast")
+	email.AssertNumberOfCalls(t, "NotifyLike", 1)
+}
+
+func TestNotificationService_ProcessLikeMessage_MutedPost(t *testing.T) {
+	ctx := context.Background()
+	notificationService, repo, _, prefs, _ := setupTest()
+
+	userPrefs := preferences.Default(9)
+	userPrefs.MutedPosts = []int{7}
+	prefs.On("GetPreferences", 9).Return(userPrefs, nil)
+
+	err := notificationService.ProcessLikeMessage(ctx, likeMessage(t, events.NewLike{EventID: "like-1", PostID: 7, Liker: 1, Liked: 9}))
+
+	assert.NoError(t, err)
+	repo.AssertNotCalled(t, "AddLike")
+}
+
+func TestNotificationService_ProcessLikeMessage_InvalidJSON(t *testing.T) {
+	notificationService, repo, _, prefs, _ := setupTest()
+
+	err := notificationService.ProcessLikeMessage(context.Background(), []byte("{"))
+
+	assert.Error(t, err)
+	prefs.AssertNotCalled(t, "GetPreferences")
+	repo.AssertNotCalled(t, "AddLike")
+}
@@ -0,0 +1,114 @@
+package likes
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pictureloader/common/events"
+	"pictureloader/notification_microservice/notifications/likes"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var nextvalQuery = regexp.QuoteMeta(`SELECT nextval('likes_notifications_event_seq')`)
+
+func TestLikeNotificationRepository_AddLike_Relike(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+	repo := likes.NewPSQLNotificationsRepository(db)
+	createdAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	group := sqlmock.NewRows([]string{"id", "post_id", "post_name", "liker", "liked", "actor_count", "actors",
+		"actor_names", "email_only", "created_at", "updated_at", "read_at"}).
+		AddRow(5, 7, "cat", 2, 9, 2, `[2,1]`, `{"1":"alice","2":"bob"}`, false, createdAt, createdAt, nil)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "processed_events"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "likes_notifications"`)).WillReturnRows(group)
+	// alice уже есть в группе: лайк снят и поставлен снова
+	sqlMock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "like_group_actors" ("group_id","actor_id") VALUES ($1,$2) ON CONFLICT DO NOTHING`)).
+		WithArgs(5, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery(nextvalQuery).WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(42))
+	sqlMock.ExpectExec(regexp.QuoteMeta(`UPDATE "likes_notifications"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	notification, err := repo.AddLike(events.NewLike{EventID: "like-1-7-again", PostID: 7, Liker: 1, Liked: 9,
+		LikerName: "alice"}, createdAt.Add(-time.Hour), true)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, notification.ActorCount)
+	assert.Equal(t, []int{1, 2}, notification.Actors)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestLikeNotificationRepository_AddLike_NewActor(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+	repo := likes.NewPSQLNotificationsRepository(db)
+	createdAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	group := sqlmock.NewRows([]string{"id", "post_id", "post_name", "liker", "liked", "actor_count", "actors",
+		"actor_names", "email_only", "created_at", "updated_at", "read_at"}).
+		AddRow(5, 7, "cat", 2, 9, 2, `[2,1]`, `{"1":"alice","2":"bob"}`, false, createdAt, createdAt, nil)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "processed_events"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "likes_notifications"`)).WillReturnRows(group)
+	sqlMock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "like_group_actors"`)).
+		WithArgs(5, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectQuery(nextvalQuery).WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(42))
+	sqlMock.ExpectExec(regexp.QuoteMeta(`UPDATE "likes_notifications"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	notification, err := repo.AddLike(events.NewLike{EventID: "like-3-7", PostID: 7, Liker: 3, Liked: 9,
+		LikerName: "carol"}, createdAt.Add(-time.Hour), true)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, notification.ActorCount)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestLikeNotificationRepository_AddLike_EmailOnly(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+	repo := likes.NewPSQLNotificationsRepository(db)
+	since := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "processed_events"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "likes_notifications"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sqlMock.ExpectQuery(nextvalQuery).WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(42))
+	// канал in_app выключен: группа помечается как только для писем
+	sqlMock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "likes_notifications" ("post_id","post_name","liker","liked","actor_count","actors","actor_names","email_only","event_seq","created_at","updated_at","read_at")`)).
+		WithArgs(7, "", 1, 9, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), true, 42, sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	sqlMock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "like_group_actors"`)).
+		WithArgs(5, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	_, err := repo.AddLike(events.NewLike{EventID: "like-1-7", PostID: 7, Liker: 1, Liked: 9}, since, false)
+
+	require.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestLikeNotificationRepository_SkipsEmailOnlyGroups(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+	repo := likes.NewPSQLNotificationsRepository(db)
+
+	// группы только для писем не попадают ни в список, ни в счетчик непрочитанных
+	sqlMock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "likes_notifications" WHERE liked = $1 AND NOT email_only ORDER BY updated_at DESC`)).
+		WithArgs(9).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sqlMock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT count(*) FROM "likes_notifications" WHERE liked = $1 AND NOT email_only AND read_at IS NULL`)).
+		WithArgs(9).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	notifications, err := repo.GetAllLikeNotifications(9)
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+	count, err := repo.CountUnread(9)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
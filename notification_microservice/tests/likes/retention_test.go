@@ -0,0 +1,88 @@
+package likes
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"pictureloader/notification_microservice/notifications/likes"
+	"regexp"
+	"testing"
+	"time"
+)
+
+const (
+	readAge    = 30 * 24 * time.Hour
+	maxPerUser = 1000
+)
+
+var (
+	lockQuery = regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock(hashtext($1))`)
+	readQuery = regexp.QuoteMeta(`DELETE FROM likes_notifications WHERE read_at < $1`)
+	capQuery  = `DELETE FROM likes_notifications WHERE id IN \(\s*SELECT id FROM \(\s*` +
+		regexp.QuoteMeta(`SELECT id, row_number() OVER (PARTITION BY liked ORDER BY updated_at DESC, id DESC) AS position`) +
+		`\s*FROM likes_notifications\s*\) ranked WHERE position > \$1\)`
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	conn, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	return db, sqlMock
+}
+
+func TestRetentionJob_Purge(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+	job := likes.NewRetentionJob(db, readAge, maxPerUser, time.Hour)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(lockQuery).WithArgs("likes_notifications_retention").WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	// прочитанные уведомления удаляются по времени прочтения, а не последнего лайка
+	sqlMock.ExpectExec(readQuery).WithArgs(now.Add(-readAge)).WillReturnResult(sqlmock.NewResult(0, 3))
+	sqlMock.ExpectExec(capQuery).WithArgs(maxPerUser).WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlMock.ExpectCommit()
+
+	removedRead, removedCap, err := job.Purge(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), removedRead)
+	assert.Equal(t, int64(2), removedCap)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestRetentionJob_Purge_OnlyCap(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+	job := likes.NewRetentionJob(db, 0, maxPerUser, time.Hour)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(lockQuery).WithArgs("likes_notifications_retention").WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	sqlMock.ExpectExec(capQuery).WithArgs(maxPerUser).WillReturnResult(sqlmock.NewResult(0, 5))
+	sqlMock.ExpectCommit()
+
+	removedRead, removedCap, err := job.Purge(context.Background(), time.Now())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), removedRead)
+	assert.Equal(t, int64(5), removedCap)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestRetentionJob_Purge_Locked(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+	job := likes.NewRetentionJob(db, readAge, maxPerUser, time.Hour)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(lockQuery).WithArgs("likes_notifications_retention").WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+	sqlMock.ExpectRollback()
+
+	_, _, err := job.Purge(context.Background(), time.Now())
+
+	assert.ErrorIs(t, err, likes.ErrRetentionLocked)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
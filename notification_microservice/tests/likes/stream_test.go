@@ -0,0 +1,82 @@
+package likes
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"pictureloader/notification_microservice/auth"
+	"pictureloader/notification_microservice/notifications/likes"
+	"pictureloader/notification_microservice/notifications/preferences"
+	"pictureloader/notification_microservice/stream"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEventIDs читает из SSE ответа id первых count событий
+func readEventIDs(reader *bufio.Reader, count int) ([]string, error) {
+	var ids []string
+	for len(ids) < count {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return ids, err
+		}
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, strings.TrimSpace(id))
+		}
+	}
+	return ids, nil
+}
+
+func groupEvent(t *testing.T, seq int64, notification likes.LikeNotification) stream.Event {
+	data, err := json.Marshal(notification)
+	require.NoError(t, err)
+	return stream.Event{ID: seq, UserID: 9, Data: data}
+}
+
+func TestLikesServer_StreamNotifications_OutOfOrderEvents(t *testing.T) {
+	notificationService, repo, _, prefs, _ := setupTest()
+	prefs.On("GetPreferences", 9).Return(preferences.Default(9), nil)
+	// при докачке клиент получает группу 5 в состоянии события 12
+	repo.On("GetLikeNotificationsAfterEvent", 9, int64(10)).
+		Return([]likes.LikeNotification{{ID: 5, PostID: 7, Actors: []int{1}, ActorCount: 1, EventSeq: 12}}, nil)
+	hub := stream.NewHub()
+	server := likes.NewLikesServer(notificationService, hub)
+	handler := auth.NewMiddleware("", &MockVerifier{userID: 9}).Authenticate(http.HandlerFunc(server.StreamNotifications))
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	req, err := http.NewRequest(http.MethodGet, httpServer.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Last-Event-ID", "10")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	ids, err := readEventIDs(reader, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"12"}, ids)
+
+	// другая реплика закончила транзакцию позже: событие 11 группы 6 приходит после 12
+	hub.Deliver(groupEvent(t, 11, likes.LikeNotification{ID: 6, PostID: 8, Actors: []int{2}, ActorCount: 1}))
+	// старое состояние группы 5 и повтор уже отданного при докачке события отбрасываются
+	hub.Deliver(groupEvent(t, 11, likes.LikeNotification{ID: 5, PostID: 7, Actors: []int{1}, ActorCount: 1}))
+	hub.Deliver(groupEvent(t, 12, likes.LikeNotification{ID: 5, PostID: 7, Actors: []int{1}, ActorCount: 1}))
+	hub.Deliver(groupEvent(t, 13, likes.LikeNotification{ID: 5, PostID: 7, Actors: []int{2, 1}, ActorCount: 2}))
+
+	done := make(chan []string)
+	go func() {
+		ids, _ := readEventIDs(reader, 2)
+		done <- ids
+	}()
+	select {
+	case ids = <-done:
+		assert.Equal(t, []string{"11", "13"}, ids)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream events were not delivered")
+	}
+}
@@ -0,0 +1,75 @@
+package preferences
+
+import (
+	"github.com/stretchr/testify/assert"
+	"pictureloader/notification_microservice/i18n"
+	"pictureloader/notification_microservice/notifications/preferences"
+	"testing"
+	"time"
+)
+
+func TestPreferences_Wants_Defaults(t *testing.T) {
+	prefs := preferences.Default(1)
+
+	assert.True(t, prefs.Wants(preferences.TypeLike, preferences.ChannelInApp))
+	assert.True(t, prefs.Wants(preferences.TypeLike, preferences.ChannelWebhook))
+	assert.False(t, prefs.Wants(preferences.TypeLike, preferences.ChannelEmail))
+	assert.True(t, prefs.Wants(preferences.TypeModeration, preferences.ChannelEmail))
+	assert.False(t, prefs.Wants(preferences.TypeModeration, preferences.ChannelInApp))
+}
+
+func TestPreferences_Wants_Configured(t *testing.T) {
+	prefs := preferences.Default(1)
+	prefs.Channels[preferences.TypeLike] = []string{preferences.ChannelEmail}
+	prefs.Channels[preferences.TypeModeration] = []string{}
+
+	assert.True(t, prefs.Wants(preferences.TypeLike, preferences.ChannelEmail))
+	assert.False(t, prefs.Wants(preferences.TypeLike, preferences.ChannelInApp))
+	// пустой список - пользователь выключил все каналы, а не настройки по умолчанию
+	assert.False(t, prefs.Wants(preferences.TypeModeration, preferences.ChannelEmail))
+}
+
+func TestPreferences_InQuietHours(t *testing.T) {
+	prefs := preferences.Default(1)
+	prefs.Timezone = "Europe/Moscow"
+	prefs.QuietHours = &preferences.QuietHours{Start: 23, End: 7}
+
+	// 21:00 UTC - полночь по Москве
+	assert.True(t, prefs.InQuietHours(time.Date(2026, 1, 1, 21, 0, 0, 0, time.UTC)))
+	assert.True(t, prefs.InQuietHours(time.Date(2026, 1, 1, 3, 59, 0, 0, time.UTC)))
+	assert.False(t, prefs.InQuietHours(time.Date(2026, 1, 1, 4, 0, 0, 0, time.UTC)))
+	assert.False(t, prefs.InQuietHours(time.Date(2026, 1, 1, 19, 0, 0, 0, time.UTC)))
+}
+
+func TestPreferences_LikeMuted(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	prefs := preferences.Default(1)
+	prefs.MutedPosts = []int{7}
+	prefs.MuteLikesOlderThanDays = 30
+
+	assert.True(t, prefs.LikeMuted(7, now, now))
+	assert.True(t, prefs.LikeMuted(8, now.AddDate(0, 0, -31), now))
+	assert.False(t, prefs.LikeMuted(8, now.AddDate(0, 0, -29), now))
+	// у событий старых версий нет даты поста, их не глушим
+	assert.False(t, prefs.LikeMuted(8, time.Time{}, now))
+}
+
+func TestPreferences_Validate(t *testing.T) {
+	prefs := preferences.Default(1)
+	assert.NoError(t, prefs.Validate())
+
+	prefs.Channels[preferences.TypeLike] = []string{"sms"}
+	assert.Error(t, prefs.Validate())
+
+	prefs = preferences.Default(1)
+	prefs.QuietHours = &preferences.QuietHours{Start: 5, End: 5}
+	assert.Error(t, prefs.Validate())
+}
+
+func TestPreferences_EmailLocale(t *testing.T) {
+	prefs := preferences.Default(1)
+	assert.Equal(t, i18n.Default, prefs.EmailLocale())
+
+	prefs.Locale = i18n.English
+	assert.Equal(t, i18n.English, prefs.EmailLocale())
+}
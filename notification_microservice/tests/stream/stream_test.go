@@ -0,0 +1,81 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pictureloader/common/eventbus"
+	"pictureloader/notification_microservice/stream"
+	"testing"
+)
+
+func TestHub_DeliverToUserClients(t *testing.T) {
+	hub := stream.NewHub()
+	first := hub.Subscribe(9)
+	second := hub.Subscribe(9)
+	other := hub.Subscribe(10)
+	defer hub.Unsubscribe(first)
+	defer hub.Unsubscribe(second)
+	defer hub.Unsubscribe(other)
+
+	hub.Deliver(stream.Event{ID: 1, UserID: 9, Data: json.RawMessage(`{}`)})
+
+	// событие получают все вкладки пользователя и никто другой
+	assert.Equal(t, int64(1), (<-first.Events()).ID)
+	assert.Equal(t, int64(1), (<-second.Events()).ID)
+	assert.Empty(t, other.Events())
+}
+
+func TestHub_DropsSlowClient(t *testing.T) {
+	hub := stream.NewHub()
+	client := hub.Subscribe(9)
+
+	// клиент не читает события: после заполнения буфера хаб его отключает
+	for i := int64(1); i <= 33; i++ {
+		hub.Deliver(stream.Event{ID: i, UserID: 9, Data: json.RawMessage(`{}`)})
+	}
+
+	select {
+	case <-client.Done():
+	default:
+		t.Fatal("slow client was not dropped")
+	}
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+	hub := stream.NewHub()
+	client := hub.Subscribe(9)
+
+	hub.Unsubscribe(client)
+	hub.Deliver(stream.Event{ID: 1, UserID: 9, Data: json.RawMessage(`{}`)})
+
+	assert.Empty(t, client.Events())
+	select {
+	case <-client.Done():
+	default:
+		t.Fatal("client is not closed after unsubscribe")
+	}
+}
+
+func TestFanout_BroadcastReachesEveryReplica(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := eventbus.NewMemoryBus()
+	defer bus.Close()
+	firstHub, secondHub := stream.NewHub(), stream.NewHub()
+	require.NoError(t, stream.NewFanout(bus, firstHub).Listen(ctx))
+	require.NoError(t, stream.NewFanout(bus, secondHub).Listen(ctx))
+	first := firstHub.Subscribe(9)
+	second := secondHub.Subscribe(9)
+
+	err := stream.NewFanout(bus, firstHub).Broadcast(ctx, stream.Event{ID: 7, UserID: 9, Data: json.RawMessage(`{"id":5}`)})
+
+	require.NoError(t, err)
+	// клиент может быть подключен к любой реплике
+	for _, client := range []*stream.Client{first, second} {
+		event := <-client.Events()
+		assert.Equal(t, int64(7), event.ID)
+		assert.JSONEq(t, `{"id":5}`, string(event.Data))
+	}
+}
@@ -0,0 +1,55 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pictureloader/common/servicetoken"
+	"time"
+)
+
+// User - контактные данные пользователя из основного приложения
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// Client ходит во внутренние ручки приложения с сервисным токеном
+type Client struct {
+	baseURL      string
+	serviceToken string
+	httpClient   *http.Client
+}
+
+func NewClient(baseURL, serviceToken string) *Client {
+	return &Client{
+		baseURL:      baseURL,
+		serviceToken: serviceToken,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) GetUser(ctx context.Context, userID int) (User, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/internal/users/%d", c.baseURL, userID), nil)
+	if err != nil {
+		return User{}, err
+	}
+	req.Header.Set(servicetoken.Header, c.serviceToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return User{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return User{}, fmt.Errorf("get user %d: unexpected status %d", userID, resp.StatusCode)
+	}
+
+	var user User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}